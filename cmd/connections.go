@@ -440,6 +440,7 @@ func PingConnection() *cli.Command {
 				}
 				return cli.Exit("", 1)
 			}
+			defer func() { _ = manager.Close() }()
 
 			conn, err := manager.GetConnection(name)
 			if err != nil {
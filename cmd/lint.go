@@ -130,6 +130,7 @@ func Lint(isDebug *bool) *cli.Command {
 				printErrors(errs, c.String("output"), "Failed to register connections")
 				return cli.Exit("", 1)
 			}
+			defer func() { _ = connectionManager.Close() }()
 
 			logger.Debugf("built the connection manager instance")
 
@@ -384,6 +384,7 @@ func Run(isDebug *bool) *cli.Command {
 				printErrors(errs, runConfig.Output, "Failed to register connections")
 				return cli.Exit("", 1)
 			}
+			defer func() { _ = connectionManager.Close() }()
 
 			s := scheduler.NewScheduler(logger, foundPipeline, runID)
 
@@ -464,6 +465,11 @@ func Run(isDebug *bool) *cli.Command {
 			exeCtx, cancel := signal.NotifyContext(runCtx, syscall.SIGINT, syscall.SIGTERM)
 			defer cancel()
 
+			if err := prepareDatasets(exeCtx, s, foundPipeline, mainExecutors); err != nil {
+				errorPrinter.Printf("Failed to prepare the datasets: %v\n", err)
+				return cli.Exit("", 1)
+			}
+
 			ex.Start(exeCtx, s.WorkQueue, s.Results)
 
 			start := time.Now()
@@ -698,6 +704,35 @@ func printErrorsInResults(errorsInTaskResults []*scheduler.TaskExecutionResult,
 	}
 }
 
+type datasetPreparer interface {
+	PrepareDatasets(ctx context.Context, p *pipeline.Pipeline, assets []*pipeline.Asset) error
+}
+
+// prepareDatasets creates the datasets of the assets that are about to run before the workers start, so that the
+// assets materializing into the same dataset do not all try to create it at once.
+func prepareDatasets(ctx context.Context, s *scheduler.Scheduler, p *pipeline.Pipeline, executors map[pipeline.AssetType]executor.Config) error {
+	assetsByType := make(map[pipeline.AssetType][]*pipeline.Asset)
+	for _, instance := range s.GetTaskInstancesByStatus(scheduler.Pending) {
+		if instance.GetType() != scheduler.TaskInstanceTypeMain {
+			continue
+		}
+		asset := instance.GetAsset()
+		assetsByType[asset.Type] = append(assetsByType[asset.Type], asset)
+	}
+
+	for assetType, assets := range assetsByType {
+		preparer, ok := executors[assetType][scheduler.TaskInstanceTypeMain].(datasetPreparer)
+		if !ok {
+			continue
+		}
+		if err := preparer.PrepareDatasets(ctx, p, assets); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func setupExecutors(
 	s *scheduler.Scheduler,
 	config *config.Config,
@@ -0,0 +1,133 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+var entityTypeNames = map[bigquery.EntityType]string{
+	bigquery.DomainEntity:       "domain",
+	bigquery.GroupEmailEntity:   "groupByEmail",
+	bigquery.UserEmailEntity:    "userByEmail",
+	bigquery.SpecialGroupEntity: "specialGroup",
+	bigquery.ViewEntity:         "view",
+	bigquery.IAMMemberEntity:    "iamMember",
+	bigquery.RoutineEntity:      "routine",
+	bigquery.DatasetEntity:      "dataset",
+}
+
+// DatasetAccessEntry is a comparable representation of a single access entry of a dataset. For authorized
+// views, routines and datasets, Entity holds the fully-qualified name of the authorized resource.
+type DatasetAccessEntry struct {
+	Role       string
+	EntityType string
+	Entity     string
+}
+
+func (e DatasetAccessEntry) String() string {
+	if e.Role == "" {
+		return fmt.Sprintf("%s:%s", e.EntityType, e.Entity)
+	}
+
+	return fmt.Sprintf("%s %s:%s", e.Role, e.EntityType, e.Entity)
+}
+
+// AccessDiff holds the access entries that only exist on one of two compared datasets.
+type AccessDiff struct {
+	OnlyInA []DatasetAccessEntry
+	OnlyInB []DatasetAccessEntry
+}
+
+func (d *AccessDiff) IsEmpty() bool {
+	return len(d.OnlyInA) == 0 && len(d.OnlyInB) == 0
+}
+
+func newDatasetAccessEntry(entry *bigquery.AccessEntry) DatasetAccessEntry {
+	converted := DatasetAccessEntry{
+		Role:       string(entry.Role),
+		EntityType: entityTypeNames[entry.EntityType],
+		Entity:     entry.Entity,
+	}
+
+	switch {
+	case entry.View != nil:
+		converted.Entity = fmt.Sprintf("%s.%s.%s", entry.View.ProjectID, entry.View.DatasetID, entry.View.TableID)
+	case entry.Routine != nil:
+		converted.Entity = fmt.Sprintf("%s.%s.%s", entry.Routine.ProjectID, entry.Routine.DatasetID, entry.Routine.RoutineID)
+	case entry.Dataset != nil && entry.Dataset.Dataset != nil:
+		converted.Entity = fmt.Sprintf("%s.%s", entry.Dataset.Dataset.ProjectID, entry.Dataset.Dataset.DatasetID)
+	}
+
+	return converted
+}
+
+// GetDatasetAccess reads the access entries of the given dataset, either `dataset` or `project.dataset`.
+func (d *Client) GetDatasetAccess(ctx context.Context, datasetName string) ([]DatasetAccessEntry, error) {
+	projectID, datasetID, err := d.resolveDataset(datasetName)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := d.client.DatasetInProject(projectID, datasetID).Metadata(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(formatError(err), "failed to fetch metadata for dataset '%s'", datasetName)
+	}
+
+	entries := make([]DatasetAccessEntry, 0, len(meta.Access))
+	for _, entry := range meta.Access {
+		entries = append(entries, newDatasetAccessEntry(entry))
+	}
+
+	return entries, nil
+}
+
+// DiffDatasetAccess compares the access entries of two datasets, which may live in different projects, and
+// returns the entries that only exist on one of them.
+func (d *Client) DiffDatasetAccess(ctx context.Context, datasetA, datasetB string) (*AccessDiff, error) {
+	var accessA, accessB []DatasetAccessEntry
+
+	wg, ctx := errgroup.WithContext(ctx)
+	wg.Go(func() error {
+		var err error
+		accessA, err = d.GetDatasetAccess(ctx, datasetA)
+		return err
+	})
+	wg.Go(func() error {
+		var err error
+		accessB, err = d.GetDatasetAccess(ctx, datasetB)
+		return err
+	})
+	if err := wg.Wait(); err != nil {
+		return nil, err
+	}
+
+	return &AccessDiff{
+		OnlyInA: accessEntriesMissingFrom(accessA, accessB),
+		OnlyInB: accessEntriesMissingFrom(accessB, accessA),
+	}, nil
+}
+
+func accessEntriesMissingFrom(entries, other []DatasetAccessEntry) []DatasetAccessEntry {
+	existing := make(map[DatasetAccessEntry]bool, len(other))
+	for _, entry := range other {
+		existing[entry] = true
+	}
+
+	missing := make([]DatasetAccessEntry, 0)
+	for _, entry := range entries {
+		if !existing[entry] {
+			missing = append(missing, entry)
+		}
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].String() < missing[j].String()
+	})
+
+	return missing
+}
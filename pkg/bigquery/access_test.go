@@ -0,0 +1,66 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_DiffDatasetAccess(t *testing.T) {
+	t.Parallel()
+
+	datasets := map[string]*bigquery2.Dataset{
+		"/projects/dev-project/datasets/analytics": {
+			Access: []*bigquery2.DatasetAccess{
+				{Role: "OWNER", SpecialGroup: "projectOwners"},
+				{Role: "READER", GroupByEmail: "analysts@example.com"},
+				{Role: "READER", UserByEmail: "intern@example.com"},
+				{View: &bigquery2.TableReference{ProjectId: "dev-project", DatasetId: "reporting", TableId: "daily"}},
+			},
+		},
+		"/projects/prod-project/datasets/analytics": {
+			Access: []*bigquery2.DatasetAccess{
+				{Role: "OWNER", SpecialGroup: "projectOwners"},
+				{Role: "WRITER", GroupByEmail: "analysts@example.com"},
+				{View: &bigquery2.TableReference{ProjectId: "dev-project", DatasetId: "reporting", TableId: "daily"}},
+			},
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ds, ok := datasets[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Dataset"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(ds)
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	diff, err := d.DiffDatasetAccess(context.Background(), "dev-project.analytics", "prod-project.analytics")
+	require.NoError(t, err)
+	assert.False(t, diff.IsEmpty())
+	assert.Equal(t, []DatasetAccessEntry{
+		{Role: "READER", EntityType: "groupByEmail", Entity: "analysts@example.com"},
+		{Role: "READER", EntityType: "userByEmail", Entity: "intern@example.com"},
+	}, diff.OnlyInA)
+	assert.Equal(t, []DatasetAccessEntry{
+		{Role: "WRITER", EntityType: "groupByEmail", Entity: "analysts@example.com"},
+	}, diff.OnlyInB)
+
+	same, err := d.DiffDatasetAccess(context.Background(), "prod-project.analytics", "prod-project.analytics")
+	require.NoError(t, err)
+	assert.True(t, same.IsEmpty())
+
+	_, err = d.DiffDatasetAccess(context.Background(), "dev-project.analytics", "missing")
+	require.EqualError(t, err, "failed to fetch metadata for dataset 'missing': Not found: Dataset")
+}
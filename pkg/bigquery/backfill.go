@@ -0,0 +1,152 @@
+package bigquery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/bruin-data/bruin/pkg/jinja"
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// BackfillDateParameter is the name of the query parameter that holds the partition date during a backfill,
+// asset queries refer to it as `@partition_date`.
+const BackfillDateParameter = "partition_date"
+
+// BackfillPartitions runs the asset query once per given date and writes each result into the matching daily
+// partition of the asset table, replacing its contents. The query is rendered for the day of each partition, e.g.
+// `{{ start_date }}` is the partition date, and receives the date as the DATE parameter `@partition_date` as well.
+// At most `concurrency` partitions are processed at the same time.
+//
+// Job IDs are derived from the table, the date and the query, which makes the backfill idempotent: a partition
+// whose job was already submitted, e.g. by a previous run that was interrupted, is not run a second time, unless
+// that job failed, see runOrAttach. The returned map holds the outcome for each date, keyed by the date in
+// YYYY-MM-DD format; if any of the partitions failed, the error lists them.
+func (d *Client) BackfillPartitions(ctx context.Context, asset *pipeline.Asset, dates []time.Time, concurrency int) (map[string]error, error) {
+	if asset.Materialization.PartitionBy == "" {
+		return nil, fmt.Errorf("asset '%s' is not partitioned, cannot backfill partitions", asset.Name)
+	}
+	if concurrency < 1 {
+		return nil, errors.New("backfill concurrency must be at least 1")
+	}
+	if d.config != nil && d.config.ReservationID != "" {
+		// the reservation turns the queries into scripts, which BigQuery does not allow to write into a destination table
+		return nil, fmt.Errorf("cannot backfill the partitions of asset '%s' with the reservation '%s' configured, the partitions are written as destination tables which script jobs do not support, unset the reservation of the connection", asset.Name, d.config.ReservationID)
+	}
+
+	resolved, err := d.ResolveTable(asset.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(asset.ExecutableFile.Content) == "" {
+		return nil, fmt.Errorf("asset '%s' has no query to backfill", asset.Name)
+	}
+
+	results := make(map[string]error, len(dates))
+	var mu sync.Mutex
+
+	var wg errgroup.Group
+	wg.SetLimit(concurrency)
+	for _, date := range dates {
+		wg.Go(func() error {
+			err := d.backfillPartition(ctx, resolved, asset, date)
+
+			mu.Lock()
+			results[date.Format(time.DateOnly)] = err
+			mu.Unlock()
+
+			// errors are reported per date, a failed partition must not stop the others
+			return nil
+		})
+	}
+	_ = wg.Wait()
+
+	failed := make([]string, 0)
+	for date, err := range results {
+		if err != nil {
+			failed = append(failed, date)
+		}
+	}
+	if len(failed) > 0 {
+		slices.Sort(failed)
+		return results, fmt.Errorf("failed to backfill %d of %d partitions of asset '%s': %s", len(failed), len(results), asset.Name, strings.Join(failed, ", "))
+	}
+
+	return results, nil
+}
+
+func (d *Client) backfillPartition(ctx context.Context, table *ResolvedTable, asset *pipeline.Asset, date time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	partition := date.Format("20060102")
+	assetQuery, err := renderBackfillQuery(ctx, asset, date)
+	if err != nil {
+		return errors.Wrapf(err, "failed to render the query for partition '%s'", partition)
+	}
+
+	destination := d.client.DatasetInProject(table.ProjectID, table.DatasetID).Table(table.TableID + "$" + partition)
+
+	q, err := d.newQuery(ctx, &query.Query{
+		Query:      assetQuery,
+		Parameters: []query.Parameter{{Name: BackfillDateParameter, Value: civil.DateOf(date)}},
+	})
+	if err != nil {
+		return err
+	}
+	q.Dst = destination
+	q.WriteDisposition = bigquery.WriteTruncate
+	q.JobID = backfillJobID(table, assetQuery, partition)
+
+	job, err := d.runOrAttach(ctx, q)
+	if err != nil {
+		return formatError(err)
+	}
+
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return formatError(err)
+	}
+
+	if err := status.Err(); err != nil {
+		return fmt.Errorf("failed to backfill partition '%s': %w", partition, formatError(err))
+	}
+
+	return nil
+}
+
+// renderBackfillQuery renders the Jinja templating of the asset query for the day of the partition, the run
+// variables such as the pipeline name are taken from the context if it carries them.
+func renderBackfillQuery(ctx context.Context, asset *pipeline.Asset, date time.Time) (string, error) {
+	startDate := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	endDate := startDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	pipelineName, _ := ctx.Value(pipeline.RunConfigPipelineName).(string)
+	runID, _ := ctx.Value(pipeline.RunConfigRunID).(string)
+
+	return jinja.NewRendererWithStartEndDates(&startDate, &endDate, pipelineName, runID).Render(asset.ExecutableFile.Content)
+}
+
+func backfillJobID(table *ResolvedTable, assetQuery, partition string) string {
+	sum := sha256.Sum256([]byte(assetQuery))
+
+	sanitized := strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, table.String())
+
+	return fmt.Sprintf("bruin_backfill_%s_%s_%s", sanitized, partition, hex.EncodeToString(sum[:6]))
+}
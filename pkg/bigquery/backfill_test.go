@@ -0,0 +1,195 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+// backfillHandler simulates query jobs that are submitted with explicit job IDs. Jobs for partitions in
+// failing report an error, jobs for partitions in existing are reported as already submitted, and the ones in
+// existingFailed as already submitted and failed, while their attempts after that succeed.
+type backfillHandler struct {
+	failing        map[string]bool
+	existing       map[string]bool
+	existingFailed map[string]bool
+
+	mu   sync.Mutex
+	jobs map[string]*bigquery2.JobConfiguration
+}
+
+var backfillPartitionRegex = regexp.MustCompile(`_(\d{8})_[0-9a-f]+(_\d+)?$`)
+
+func (h *backfillHandler) partitionOf(jobID string) string {
+	return backfillPartitionRegex.FindStringSubmatch(jobID)[1]
+}
+
+func (h *backfillHandler) isAttempt(jobID string) bool {
+	return backfillPartitionRegex.FindStringSubmatch(jobID)[2] != ""
+}
+
+func (h *backfillHandler) status(jobID string) *bigquery2.JobStatus {
+	status := &bigquery2.JobStatus{State: "DONE"}
+	partition := h.partitionOf(jobID)
+	if h.failing[partition] || (h.existingFailed[partition] && !h.isAttempt(jobID)) {
+		status.ErrorResult = &bigquery2.ErrorProto{Reason: "invalidQuery", Message: "Division by zero"}
+	}
+	return status
+}
+
+func (h *backfillHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	jobsPath := fmt.Sprintf("/projects/%s/jobs", testProjectID)
+	queriesPath := fmt.Sprintf("/projects/%s/queries/", testProjectID)
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == jobsPath:
+		var job bigquery2.Job
+		_ = json.NewDecoder(r.Body).Decode(&job)
+
+		partition := h.partitionOf(job.JobReference.JobId)
+		if h.existing[partition] || (h.existingFailed[partition] && !h.isAttempt(job.JobReference.JobId)) {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error": {"code": 409, "message": "Already Exists: Job"}}`))
+			return
+		}
+
+		h.mu.Lock()
+		h.jobs[job.JobReference.JobId] = job.Configuration
+		h.mu.Unlock()
+
+		job.Status = &bigquery2.JobStatus{State: "RUNNING"}
+		_ = json.NewEncoder(w).Encode(&job)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, queriesPath):
+		jobID := strings.TrimPrefix(r.URL.Path, queriesPath)
+		_ = json.NewEncoder(w).Encode(&bigquery2.GetQueryResultsResponse{
+			JobComplete:  true,
+			JobReference: &bigquery2.JobReference{ProjectId: testProjectID, JobId: jobID},
+		})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, jobsPath+"/"):
+		jobID := strings.TrimPrefix(r.URL.Path, jobsPath+"/")
+		_ = json.NewEncoder(w).Encode(&bigquery2.Job{
+			JobReference:  &bigquery2.JobReference{ProjectId: testProjectID, JobId: jobID},
+			Configuration: &bigquery2.JobConfiguration{Query: &bigquery2.JobConfigurationQuery{}},
+			Status:        h.status(jobID),
+		})
+	default:
+		http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+	}
+}
+
+func TestClient_BackfillPartitions(t *testing.T) {
+	t.Parallel()
+
+	handler := &backfillHandler{
+		failing:        map[string]bool{"20240102": true},
+		existing:       map[string]bool{"20240103": true},
+		existingFailed: map[string]bool{"20240104": true},
+		jobs:           make(map[string]*bigquery2.JobConfiguration),
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	asset := &pipeline.Asset{
+		Name:            "dataset.events",
+		Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, PartitionBy: "event_date"},
+		ExecutableFile:  pipeline.ExecutableFile{Content: "SELECT * FROM raw.events WHERE event_date = @partition_date AND day = '{{ start_date }}'"},
+	}
+	dates := []time.Time{
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC),
+	}
+
+	results, err := d.BackfillPartitions(context.Background(), asset, dates, 2)
+	require.EqualError(t, err, "failed to backfill 1 of 4 partitions of asset 'dataset.events': 2024-01-02")
+	require.Len(t, results, 4)
+	require.NoError(t, results["2024-01-01"])
+	require.Error(t, results["2024-01-02"])
+	assert.Contains(t, results["2024-01-02"].Error(), "failed to backfill partition '20240102'")
+	assert.Contains(t, results["2024-01-02"].Error(), "Division by zero")
+	require.NoError(t, results["2024-01-03"], "an already submitted job must be awaited instead of failing")
+	require.NoError(t, results["2024-01-04"], "a partition whose earlier job failed must be run again")
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	require.Len(t, handler.jobs, 3)
+
+	for jobID, config := range handler.jobs {
+		assert.True(t, strings.HasPrefix(jobID, "bruin_backfill_test-project_dataset_events_"), jobID)
+
+		partition := handler.partitionOf(jobID)
+		assert.Equal(t, handler.existingFailed[partition], handler.isAttempt(jobID), jobID)
+		day, _ := time.Parse("20060102", partition)
+		assert.Contains(t, config.Query.Query, "day = '"+day.Format(time.DateOnly)+"'")
+		assert.Equal(t, "events$"+partition, config.Query.DestinationTable.TableId)
+		assert.Equal(t, "WRITE_TRUNCATE", config.Query.WriteDisposition)
+		require.Len(t, config.Query.QueryParameters, 1)
+		assert.Equal(t, "partition_date", config.Query.QueryParameters[0].Name)
+		assert.Equal(t, "DATE", config.Query.QueryParameters[0].ParameterType.Type)
+	}
+}
+
+func TestClient_BackfillPartitions_Validation(t *testing.T) {
+	t.Parallel()
+
+	d := &Client{config: &Config{ProjectID: testProjectID}}
+
+	_, err := d.BackfillPartitions(context.Background(), &pipeline.Asset{Name: "dataset.events"}, nil, 1)
+	require.EqualError(t, err, "asset 'dataset.events' is not partitioned, cannot backfill partitions")
+
+	partitioned := &pipeline.Asset{Name: "dataset.events", Materialization: pipeline.Materialization{PartitionBy: "event_date"}}
+	_, err = d.BackfillPartitions(context.Background(), partitioned, nil, 0)
+	require.EqualError(t, err, "backfill concurrency must be at least 1")
+}
+
+func TestClient_BackfillPartitions_Reservation(t *testing.T) {
+	t.Parallel()
+
+	handler := &backfillHandler{jobs: make(map[string]*bigquery2.JobConfiguration)}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	d.config.ReservationID = "projects/test-project/locations/US/reservations/etl"
+
+	asset := &pipeline.Asset{
+		Name:            "dataset.events",
+		Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, PartitionBy: "event_date"},
+		ExecutableFile:  pipeline.ExecutableFile{Content: "SELECT * FROM raw.events WHERE event_date = @partition_date"},
+	}
+	dates := []time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	results, err := d.BackfillPartitions(context.Background(), asset, dates, 1)
+	require.EqualError(t, err, "cannot backfill the partitions of asset 'dataset.events' with the reservation 'projects/test-project/locations/US/reservations/etl' configured, the partitions are written as destination tables which script jobs do not support, unset the reservation of the connection")
+	assert.Nil(t, results)
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	assert.Empty(t, handler.jobs, "no job must be submitted with a destination table and a reservation")
+}
+
+func TestBackfillJobID_IsStable(t *testing.T) {
+	t.Parallel()
+
+	table := &ResolvedTable{ProjectID: "my-project", DatasetID: "dataset", TableID: "events"}
+
+	first := backfillJobID(table, "SELECT 1", "20240101")
+	assert.Equal(t, first, backfillJobID(table, "SELECT 1", "20240101"))
+	assert.NotEqual(t, first, backfillJobID(table, "SELECT 2", "20240101"))
+	assert.NotEqual(t, first, backfillJobID(table, "SELECT 1", "20240102"))
+}
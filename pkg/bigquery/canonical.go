@@ -0,0 +1,60 @@
+package bigquery
+
+import (
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// canonicalTypes maps the BigQuery field types to the cross-platform type vocabulary shared with the other
+// platforms, see query.QueryResult.CanonicalColumnTypes.
+var canonicalTypes = map[bigquery.FieldType]string{
+	bigquery.StringFieldType:     "string",
+	bigquery.BytesFieldType:      "bytes",
+	bigquery.IntegerFieldType:    "int64",
+	bigquery.FloatFieldType:      "float64",
+	bigquery.BooleanFieldType:    "bool",
+	bigquery.TimestampFieldType:  "timestamp",
+	bigquery.DateFieldType:       "date",
+	bigquery.TimeFieldType:       "time",
+	bigquery.DateTimeFieldType:   "datetime",
+	bigquery.NumericFieldType:    "numeric",
+	bigquery.BigNumericFieldType: "bignumeric",
+	bigquery.GeographyFieldType:  "geography",
+	bigquery.IntervalFieldType:   "interval",
+	bigquery.JSONFieldType:       "json",
+}
+
+// CanonicalType returns the cross-platform type of the field, e.g. `int64` or `array<struct<id int64, tags
+// array<string>>>`. Repeated fields become arrays and records become structs listing their fields, so that
+// the full shape of the field can be recovered from the type. Unknown types are lowercased as they are.
+func CanonicalType(field *bigquery.FieldSchema) string {
+	typ := canonicalElementType(field)
+	if field.Repeated {
+		return "array<" + typ + ">"
+	}
+
+	return typ
+}
+
+func canonicalElementType(field *bigquery.FieldSchema) string {
+	switch field.Type { //nolint:exhaustive
+	case bigquery.RecordFieldType:
+		fields := make([]string, 0, len(field.Schema))
+		for _, nested := range field.Schema {
+			fields = append(fields, nested.Name+" "+CanonicalType(nested))
+		}
+		return "struct<" + strings.Join(fields, ", ") + ">"
+	case bigquery.RangeFieldType:
+		if field.RangeElementType != nil {
+			return "range<" + canonicalElementType(&bigquery.FieldSchema{Type: field.RangeElementType.Type}) + ">"
+		}
+		return "range"
+	}
+
+	if typ, ok := canonicalTypes[field.Type]; ok {
+		return typ
+	}
+
+	return strings.ToLower(string(field.Type))
+}
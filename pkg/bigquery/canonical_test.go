@@ -0,0 +1,52 @@
+package bigquery
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		field *bigquery.FieldSchema
+		want  string
+	}{
+		{name: "integer", field: &bigquery.FieldSchema{Type: bigquery.IntegerFieldType}, want: "int64"},
+		{name: "float", field: &bigquery.FieldSchema{Type: bigquery.FloatFieldType}, want: "float64"},
+		{name: "boolean", field: &bigquery.FieldSchema{Type: bigquery.BooleanFieldType}, want: "bool"},
+		{name: "timestamp", field: &bigquery.FieldSchema{Type: bigquery.TimestampFieldType}, want: "timestamp"},
+		{name: "datetime", field: &bigquery.FieldSchema{Type: bigquery.DateTimeFieldType}, want: "datetime"},
+		{name: "repeated string", field: &bigquery.FieldSchema{Type: bigquery.StringFieldType, Repeated: true}, want: "array<string>"},
+		{
+			name: "repeated record with nested fields",
+			field: &bigquery.FieldSchema{
+				Type:     bigquery.RecordFieldType,
+				Repeated: true,
+				Schema: bigquery.Schema{
+					{Name: "id", Type: bigquery.IntegerFieldType},
+					{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+					{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{{Name: "city", Type: bigquery.StringFieldType}}},
+				},
+			},
+			want: "array<struct<id int64, tags array<string>, address struct<city string>>>",
+		},
+		{
+			name:  "range",
+			field: &bigquery.FieldSchema{Type: bigquery.RangeFieldType, RangeElementType: &bigquery.RangeElementType{Type: bigquery.DateFieldType}},
+			want:  "range<date>",
+		},
+		{name: "unknown type", field: &bigquery.FieldSchema{Type: "NEW_TYPE"}, want: "new_type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, CanonicalType(tt.field))
+		})
+	}
+}
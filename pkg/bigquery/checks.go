@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 
+	"cloud.google.com/go/bigquery"
 	"github.com/bruin-data/bruin/pkg/ansisql"
+	"github.com/bruin-data/bruin/pkg/helpers"
 	"github.com/bruin-data/bruin/pkg/query"
 	"github.com/bruin-data/bruin/pkg/scheduler"
 	"github.com/pkg/errors"
@@ -20,10 +23,14 @@ func (c *PatternCheck) Check(ctx context.Context, ti *scheduler.ColumnCheckInsta
 	if ti.Check.Value.String == nil {
 		return errors.Errorf("unexpected value %s for pattern check, the value must be a string", ti.Check.Value.ToString())
 	}
+	table, err := checkedTable(c.conn, ti)
+	if err != nil {
+		return err
+	}
 	qq := fmt.Sprintf(
 		"SELECT count(*) FROM %s WHERE REGEXP_CONTAINS(%s, r'%s')",
-		ti.GetAsset().Name,
-		ti.Column.Name,
+		table,
+		QuoteColumn(ti.Column.Name),
 		*ti.Check.Value.String,
 	)
 
@@ -66,8 +73,196 @@ func (c *AcceptedValuesCheck) Check(ctx context.Context, ti *scheduler.ColumnChe
 	sz := len(res)
 	res = res[1 : sz-1]
 
-	qq := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE CAST(%s as STRING) NOT IN (%s)", ti.GetAsset().Name, ti.Column.Name, res)
+	table, err := checkedTable(c.conn, ti)
+	if err != nil {
+		return err
+	}
+	qq := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE CAST(%s as STRING) NOT IN (%s)", table, QuoteColumn(ti.Column.Name), res)
 	return ansisql.NewCountableQueryCheck(c.conn, 0, &query.Query{Query: qq}, "accepted_values", func(count int64) error {
 		return errors.Errorf("column %s has %d rows that are not in the accepted values", ti.Column.Name, count)
 	}).Check(ctx, ti)
 }
+
+// checkedTable returns the reference to the table of the checked asset in the check queries, see
+// Client.TableReference.
+func checkedTable(conn connectionFetcher, ti *scheduler.ColumnCheckInstance) (string, error) {
+	asset := ti.GetAsset()
+	connName, err := ti.GetPipeline().GetConnectionNameForAsset(asset)
+	if err != nil {
+		return "", err
+	}
+	db, err := conn.GetConnection(connName)
+	if err != nil {
+		return "", err
+	}
+	if referencer, ok := db.(tableReferencer); ok {
+		return referencer.TableReference(asset.Name)
+	}
+
+	return asset.Name, nil
+}
+
+// QuoteIdentifier wraps a column or table identifier in backticks so that reserved words and
+// special characters are safe to use in generated SQL.
+func QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
+
+// QuoteColumn quotes a column reference for generated SQL so that columns named like reserved words, e.g. `order`,
+// can be used. Every part of a dotted path to a nested field is quoted on its own, e.g. `address`.`city`, and
+// references that are already quoted are kept as they are.
+func QuoteColumn(name string) string {
+	if len(name) > 1 && strings.HasPrefix(name, "`") && strings.HasSuffix(name, "`") {
+		return name
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = QuoteIdentifier(part)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// columnCountCheck is a check that counts the rows violating it, with the checked column quoted. It stands in for
+// the ANSI SQL checks, which leave the column unquoted.
+type columnCountCheck struct {
+	conn       connectionFetcher
+	name       string
+	buildQuery func(table, column string) string
+	message    string
+}
+
+func (c *columnCountCheck) Check(ctx context.Context, ti *scheduler.ColumnCheckInstance) error {
+	table, err := checkedTable(c.conn, ti)
+	if err != nil {
+		return err
+	}
+	qq := c.buildQuery(table, QuoteColumn(ti.Column.Name))
+	return ansisql.NewCountableQueryCheck(c.conn, 0, &query.Query{Query: qq}, c.name, func(count int64) error {
+		return errors.Errorf(c.message, ti.Column.Name, count)
+	}).Check(ctx, ti)
+}
+
+func newNotNullCheck(conn connectionFetcher) *columnCountCheck {
+	return &columnCountCheck{conn: conn, name: "not_null", message: "column '%s' has %d null values", buildQuery: func(table, column string) string {
+		return fmt.Sprintf("SELECT count(*) FROM %s WHERE %s IS NULL", table, column)
+	}}
+}
+
+func newUniqueCheck(conn connectionFetcher) *columnCountCheck {
+	return &columnCountCheck{conn: conn, name: "unique", message: "column '%s' has %d non-unique values", buildQuery: func(table, column string) string {
+		return fmt.Sprintf("SELECT COUNT(%s) - COUNT(DISTINCT %s) FROM %s", column, column, table)
+	}}
+}
+
+func newComparisonCheck(conn connectionFetcher, name, condition, message string) *columnCountCheck {
+	return &columnCountCheck{conn: conn, name: name, message: message, buildQuery: func(table, column string) string {
+		return fmt.Sprintf("SELECT count(*) FROM %s WHERE %s %s", table, column, condition)
+	}}
+}
+
+// BuildCompositeUniqueCheck builds a query that counts the key combinations that appear more than once
+// in the given table. NULL values are grouped together, which means rows sharing a NULL in the same key
+// column are considered duplicates, matching the expectation for a composite primary key.
+func BuildCompositeUniqueCheck(tableName string, columns []string) (string, error) {
+	if len(columns) == 0 {
+		return "", errors.New("at least one column is required for a composite unique check")
+	}
+
+	quoted := make([]string, 0, len(columns))
+	for _, col := range columns {
+		if col == "" {
+			return "", errors.New("column names for a composite unique check cannot be empty")
+		}
+		quoted = append(quoted, QuoteColumn(col))
+	}
+
+	return fmt.Sprintf(
+		"SELECT COUNT(*) FROM (SELECT 1 FROM %s GROUP BY %s HAVING COUNT(*) > 1)",
+		tableName,
+		strings.Join(quoted, ", "),
+	), nil
+}
+
+// CheckCompositeUnique returns the number of key combinations that violate the uniqueness of the given columns.
+// The table is referenced like in the other checks, see TableReference.
+func (d *Client) CheckCompositeUnique(ctx context.Context, tableName string, columns []string) (int64, error) {
+	table, err := d.TableReference(tableName)
+	if err != nil {
+		return 0, err
+	}
+
+	qq, err := BuildCompositeUniqueCheck(table, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := d.Select(ctx, &query.Query{Query: qq})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to run composite unique check")
+	}
+
+	count, err := helpers.CastResultToInteger(res)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to parse composite unique check result")
+	}
+
+	return count, nil
+}
+
+// fullRowUniqueSampleSize is the number of duplicated rows CheckFullRowUnique returns for debugging.
+const fullRowUniqueSampleSize = 5
+
+// BuildFullRowUniqueCheck builds a query that counts the distinct rows that appear more than once in the given
+// table and samples the most duplicated ones as JSON. Rows are compared by the fingerprint of their JSON encoding,
+// which covers all the columns; pseudo-columns such as _PARTITIONTIME or _FILE_NAME are not part of the row and
+// therefore cannot make otherwise identical rows differ.
+func BuildFullRowUniqueCheck(tableName string) string {
+	return fmt.Sprintf(`WITH duplicates AS (
+  SELECT ANY_VALUE(TO_JSON_STRING(t)) AS row_json, COUNT(*) AS occurrences
+  FROM %s AS t
+  GROUP BY FARM_FINGERPRINT(TO_JSON_STRING(t))
+  HAVING COUNT(*) > 1
+)
+SELECT
+  (SELECT COUNT(*) FROM duplicates) AS duplicate_count,
+  ARRAY(SELECT row_json FROM duplicates ORDER BY occurrences DESC, row_json LIMIT %d) AS sample`,
+		tableName,
+		fullRowUniqueSampleSize,
+	)
+}
+
+// CheckFullRowUnique returns the number of distinct rows that appear more than once in the given table, e.g. due to
+// the fan-out of a bad join, along with a sample of them encoded as JSON. The table is referenced like in the other
+// checks, see TableReference.
+func (d *Client) CheckFullRowUnique(ctx context.Context, tableName string) (int64, []string, error) {
+	table, err := d.TableReference(tableName)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	res, err := d.Select(ctx, &query.Query{Query: BuildFullRowUniqueCheck(table)})
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to run full row unique check")
+	}
+
+	if len(res) != 1 || len(res[0]) != 2 {
+		return 0, nil, errors.New("unexpected result of full row unique check, expected a single row with two columns")
+	}
+
+	count, ok := res[0][0].(int64)
+	if !ok {
+		return 0, nil, errors.Errorf("unexpected duplicate count of type %T in full row unique check", res[0][0])
+	}
+
+	values, _ := res[0][1].([]bigquery.Value)
+	sample := make([]string, 0, len(values))
+	for _, value := range values {
+		if row, ok := value.(string); ok {
+			sample = append(sample, row)
+		}
+	}
+
+	return count, sample, nil
+}
@@ -3,6 +3,8 @@ package bigquery
 import (
 	"context"
 	"fmt"
+	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"cloud.google.com/go/bigquery"
@@ -12,6 +14,8 @@ import (
 	"github.com/bruin-data/bruin/pkg/scheduler"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
 )
 
 type mockQuerierWithResult struct {
@@ -90,9 +94,22 @@ func (m *mockQuerierWithResult) DropTableOnMismatch(ctx context.Context, tableNa
 	return args.Error(0)
 }
 
-func (m *mockQuerierWithResult) BuildTableExistsQuery(tableName string) (string, error) {
+func (m *mockQuerierWithResult) BuildTableExistsQuery(tableName string) (*query.Query, error) {
 	args := m.Called(tableName)
-	return args.String(0), args.Error(1)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*query.Query), args.Error(1)
+}
+
+func (m *mockQuerierWithResult) ResolveTable(tableName string) (*ResolvedTable, error) {
+	args := m.Called(tableName)
+	get := args.Get(0)
+	if get == nil {
+		return nil, args.Error(1)
+	}
+
+	return get.(*ResolvedTable), args.Error(1)
 }
 
 type mockConnectionFetcher struct {
@@ -129,7 +146,7 @@ func TestAcceptedValuesCheck_Check(t *testing.T) {
 			conn.On("GetConnection", "test").Return(q, nil)
 			return &AcceptedValuesCheck{conn: conn}
 		},
-		"SELECT COUNT(*) FROM dataset.test_asset WHERE CAST(test_column as STRING) NOT IN (\"test\",\"test2\")",
+		"SELECT COUNT(*) FROM dataset.test_asset WHERE CAST(`test_column` as STRING) NOT IN (\"test\",\"test2\")",
 		"column test_column has 5 rows that are not in the accepted values",
 		&pipeline.ColumnCheck{
 			Name: "accepted_values",
@@ -146,7 +163,7 @@ func TestAcceptedValuesCheck_Check(t *testing.T) {
 			conn.On("GetConnection", "test").Return(q, nil)
 			return &AcceptedValuesCheck{conn: conn}
 		},
-		"SELECT COUNT(*) FROM dataset.test_asset WHERE CAST(test_column as STRING) NOT IN (\"1\",\"2\")",
+		"SELECT COUNT(*) FROM dataset.test_asset WHERE CAST(`test_column` as STRING) NOT IN (\"1\",\"2\")",
 		"column test_column has 5 rows that are not in the accepted values",
 		&pipeline.ColumnCheck{
 			Name: "accepted_values",
@@ -347,7 +364,7 @@ func TestPatternCheck_Check(t *testing.T) {
 			conn.On("GetConnection", "test").Return(q, nil)
 			return &PatternCheck{conn: conn}
 		},
-		"SELECT count(*) FROM dataset.test_asset WHERE REGEXP_CONTAINS(test_column, r'(a|b)')",
+		"SELECT count(*) FROM dataset.test_asset WHERE REGEXP_CONTAINS(`test_column`, r'(a|b)')",
 		"column test_column has 5 values that don't satisfy the pattern (a|b)",
 		&pipeline.ColumnCheck{
 			Name: "pattern",
@@ -357,3 +374,261 @@ func TestPatternCheck_Check(t *testing.T) {
 		},
 	)
 }
+
+func TestNotNullCheck_Check(t *testing.T) {
+	t.Parallel()
+
+	runTestsFoCountZeroCheck(
+		t,
+		func(q *mockQuerierWithResult) checkRunner {
+			conn := new(mockConnectionFetcher)
+			conn.On("GetConnection", "test").Return(q, nil)
+			return newNotNullCheck(conn)
+		},
+		"SELECT count(*) FROM dataset.test_asset WHERE `test_column` IS NULL",
+		"column 'test_column' has 5 null values",
+		&pipeline.ColumnCheck{Name: "not_null"},
+	)
+}
+
+func TestUniqueCheck_Check(t *testing.T) {
+	t.Parallel()
+
+	runTestsFoCountZeroCheck(
+		t,
+		func(q *mockQuerierWithResult) checkRunner {
+			conn := new(mockConnectionFetcher)
+			conn.On("GetConnection", "test").Return(q, nil)
+			return newUniqueCheck(conn)
+		},
+		"SELECT COUNT(`test_column`) - COUNT(DISTINCT `test_column`) FROM dataset.test_asset",
+		"column 'test_column' has 5 non-unique values",
+		&pipeline.ColumnCheck{Name: "unique"},
+	)
+}
+
+func TestComparisonCheck_Check(t *testing.T) {
+	t.Parallel()
+
+	runTestsFoCountZeroCheck(
+		t,
+		func(q *mockQuerierWithResult) checkRunner {
+			conn := new(mockConnectionFetcher)
+			conn.On("GetConnection", "test").Return(q, nil)
+			return newComparisonCheck(conn, "positive", "<= 0", "column '%s' has %d non-positive values")
+		},
+		"SELECT count(*) FROM dataset.test_asset WHERE `test_column` <= 0",
+		"column 'test_column' has 5 non-positive values",
+		&pipeline.ColumnCheck{Name: "positive"},
+	)
+}
+
+func TestQuoteColumn(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		column string
+		want   string
+	}{
+		{name: "plain column", column: "id", want: "`id`"},
+		{name: "reserved word", column: "order", want: "`order`"},
+		{name: "nested field", column: "address.city", want: "`address`.`city`"},
+		{name: "already quoted", column: "`select`", want: "`select`"},
+		{name: "backticks are escaped", column: "we`ird", want: "`we\\`ird`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, QuoteColumn(tt.column))
+		})
+	}
+}
+
+func TestBuildCompositeUniqueCheck(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		tableName string
+		columns   []string
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "single column",
+			tableName: "dataset.table",
+			columns:   []string{"id"},
+			want:      "SELECT COUNT(*) FROM (SELECT 1 FROM dataset.table GROUP BY `id` HAVING COUNT(*) > 1)",
+		},
+		{
+			name:      "multiple columns are quoted",
+			tableName: "dataset.table",
+			columns:   []string{"order", "line item"},
+			want:      "SELECT COUNT(*) FROM (SELECT 1 FROM dataset.table GROUP BY `order`, `line item` HAVING COUNT(*) > 1)",
+		},
+		{
+			name:      "nested columns are quoted per field",
+			tableName: "dataset.table",
+			columns:   []string{"address.city", "`zip`"},
+			want:      "SELECT COUNT(*) FROM (SELECT 1 FROM dataset.table GROUP BY `address`.`city`, `zip` HAVING COUNT(*) > 1)",
+		},
+		{
+			name:      "no columns",
+			tableName: "dataset.table",
+			wantErr:   "at least one column is required for a composite unique check",
+		},
+		{
+			name:      "empty column name",
+			tableName: "dataset.table",
+			columns:   []string{"id", ""},
+			wantErr:   "column names for a composite unique check cannot be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := BuildCompositeUniqueCheck(tt.tableName, tt.columns)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClient_CheckCompositeUnique(t *testing.T) {
+	t.Parallel()
+
+	jobID := "test-job"
+	server := httptest.NewServer(mockBqHandler(t, testProjectID, jobID, successfulJobResponse(jobID), singleValueQueryResponse("INTEGER", "3")))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	count, err := d.CheckCompositeUnique(context.Background(), "dataset.table", []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestClient_CheckCompositeUnique_DataProject(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			response := stringQueryResponse("0")
+			response.Schema.Fields[0].Type = "INTEGER"
+			return response
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	d.config.DataProjectID = "data-project"
+	d.config.ProjectResolution = ProjectResolutionData
+
+	count, err := d.CheckCompositeUnique(context.Background(), "dataset.table", []string{"a"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+	assert.Equal(t, []string{
+		"SELECT COUNT(*) FROM (SELECT 1 FROM `data-project.dataset.table` GROUP BY `a` HAVING COUNT(*) > 1)",
+	}, handler.recordedQueries())
+}
+
+func TestBuildFullRowUniqueCheck(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "WITH duplicates AS (\n"+
+		"  SELECT ANY_VALUE(TO_JSON_STRING(t)) AS row_json, COUNT(*) AS occurrences\n"+
+		"  FROM dataset.table AS t\n"+
+		"  GROUP BY FARM_FINGERPRINT(TO_JSON_STRING(t))\n"+
+		"  HAVING COUNT(*) > 1\n"+
+		")\n"+
+		"SELECT\n"+
+		"  (SELECT COUNT(*) FROM duplicates) AS duplicate_count,\n"+
+		"  ARRAY(SELECT row_json FROM duplicates ORDER BY occurrences DESC, row_json LIMIT 5) AS sample",
+		BuildFullRowUniqueCheck("dataset.table"),
+	)
+}
+
+func TestClient_CheckFullRowUnique(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		dataProject string
+		count       string
+		sample      []string
+		wantSample  []string
+		wantTable   string
+	}{
+		{
+			name:       "duplicated rows",
+			count:      "2",
+			sample:     []string{`{"id":1,"name":"a"}`, `{"id":2,"name":"b"}`},
+			wantSample: []string{`{"id":1,"name":"a"}`, `{"id":2,"name":"b"}`},
+			wantTable:  "dataset.table",
+		},
+		{
+			name:       "unique rows",
+			count:      "0",
+			wantSample: []string{},
+			wantTable:  "dataset.table",
+		},
+		{
+			name:        "tables of a separate data project are qualified",
+			dataProject: "data-project",
+			count:       "0",
+			wantSample:  []string{},
+			wantTable:   "`data-project.dataset.table`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sample := make([]interface{}, 0, len(tt.sample))
+			for _, row := range tt.sample {
+				sample = append(sample, map[string]interface{}{"v": row})
+			}
+
+			handler := &recordingQueryHandler{
+				response: func(q string) *bigquery2.QueryResponse {
+					return &bigquery2.QueryResponse{
+						JobComplete:  true,
+						JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+						Schema: &bigquery2.TableSchema{Fields: []*bigquery2.TableFieldSchema{
+							{Name: "duplicate_count", Type: "INTEGER"},
+							{Name: "sample", Type: "STRING", Mode: "REPEATED"},
+						}},
+						Rows:      []*bigquery2.TableRow{{F: []*bigquery2.TableCell{{V: tt.count}, {V: sample}}}},
+						TotalRows: 1,
+					}
+				},
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			if tt.dataProject != "" {
+				d.config.DataProjectID = tt.dataProject
+				d.config.ProjectResolution = ProjectResolutionData
+			}
+
+			count, got, err := d.CheckFullRowUnique(context.Background(), "dataset.table")
+			require.NoError(t, err)
+			assert.Equal(t, tt.count, strconv.FormatInt(count, 10))
+			assert.Equal(t, tt.wantSample, got)
+			assert.Equal(t, []string{BuildFullRowUniqueCheck(tt.wantTable)}, handler.recordedQueries())
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+)
+
+// BuildResultChecksumQuery wraps the given query in an aggregation that hashes its whole result set.
+// Every row is fingerprinted individually and the fingerprints are summed, which makes the checksum
+// independent of the row order while still accounting for duplicate rows, unlike an XOR aggregation.
+// The row count is part of the hashed value so that an empty result has a stable checksum as well.
+func BuildResultChecksumQuery(q string) string {
+	return fmt.Sprintf(
+		"SELECT TO_HEX(MD5(FORMAT('%%d:%%t', COUNT(*), SUM(CAST(FARM_FINGERPRINT(TO_JSON_STRING(t)) AS BIGNUMERIC))))) FROM (\n%s\n) AS t",
+		strings.TrimSuffix(strings.TrimSpace(q), ";"),
+	)
+}
+
+// ComputeResultChecksum returns a deterministic checksum of the rows returned by the given query, which can be
+// compared across runs to verify that a rebuild produced identical data. The checksums of queries that are not
+// deterministic, see IsDeterministic, differ between runs even if the data did not change.
+func (d *Client) ComputeResultChecksum(ctx context.Context, queryObj *query.Query) (string, error) {
+	rows, err := d.Select(ctx, &query.Query{Query: BuildResultChecksumQuery(queryObj.String())})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to compute the result checksum")
+	}
+
+	if len(rows) != 1 || len(rows[0]) != 1 {
+		return "", errors.New("unexpected result while computing the result checksum")
+	}
+
+	checksum, ok := rows[0][0].(string)
+	if !ok {
+		return "", errors.Errorf("unexpected checksum value of type %T", rows[0][0])
+	}
+
+	return checksum, nil
+}
@@ -0,0 +1,45 @@
+package bigquery
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestBuildResultChecksumQuery(t *testing.T) {
+	t.Parallel()
+
+	got := BuildResultChecksumQuery("  SELECT * FROM dataset.table;  ")
+	assert.Equal(
+		t,
+		"SELECT TO_HEX(MD5(FORMAT('%d:%t', COUNT(*), SUM(CAST(FARM_FINGERPRINT(TO_JSON_STRING(t)) AS BIGNUMERIC))))) FROM (\nSELECT * FROM dataset.table\n) AS t",
+		got,
+	)
+}
+
+func TestClient_ComputeResultChecksum(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			return stringQueryResponse("0cc175b9c0f1b6a831c399e269772661")
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	got, err := d.ComputeResultChecksum(context.Background(), &query.Query{Query: "SELECT 1"})
+	require.NoError(t, err)
+	assert.Equal(t, "0cc175b9c0f1b6a831c399e269772661", got)
+
+	queries := handler.recordedQueries()
+	require.Len(t, queries, 1)
+	assert.Equal(t, BuildResultChecksumQuery("SELECT 1"), queries[0])
+}
@@ -0,0 +1,77 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+// BuildCloneTableQuery builds the statement that creates the destination table as a clone of the source table.
+func BuildCloneTableQuery(source, destination *ResolvedTable) string {
+	return fmt.Sprintf("CREATE TABLE %s CLONE %s", QuoteIdentifier(destination.String()), QuoteIdentifier(source.String()))
+}
+
+// CloneTable creates the destination table as a clone of the source table, e.g. to snapshot a table before a
+// destructive backfill. A clone only copies the metadata, the storage is shared with the source until either of
+// them changes, which makes it cheap regardless of the table size. The destination table must not exist yet; its
+// dataset is created in the location of the source if it is missing.
+func (d *Client) CloneTable(ctx context.Context, source, destination string) error {
+	sourceTable, err := d.ResolveTable(source)
+	if err != nil {
+		return errors.Wrap(err, "invalid source table")
+	}
+	destinationTable, err := d.ResolveTable(destination)
+	if err != nil {
+		return errors.Wrap(err, "invalid destination table")
+	}
+
+	sourceRef := d.client.DatasetInProject(sourceTable.ProjectID, sourceTable.DatasetID).Table(sourceTable.TableID)
+	sourceMeta, err := sourceRef.Metadata(ctx)
+	if err != nil {
+		return errors.Wrapf(formatError(err), "failed to fetch metadata for table '%s'", source)
+	}
+
+	if err := d.checkCloneLocation(ctx, source, destinationTable, sourceMeta.Location); err != nil {
+		return err
+	}
+
+	asset := &pipeline.Asset{
+		Name:       destination,
+		Parameters: map[string]string{DatasetLocationParameter: sourceMeta.Location},
+	}
+	if err := d.CreateDataSetIfNotExist(asset, ctx); err != nil {
+		return err
+	}
+
+	if err := d.RunQueryWithoutResult(ctx, &query.Query{Query: BuildCloneTableQuery(sourceTable, destinationTable)}); err != nil {
+		return errors.Wrapf(err, "failed to clone table '%s' into '%s'", source, destination)
+	}
+
+	return nil
+}
+
+// checkCloneLocation fails if the dataset of the destination already exists in another location than the source
+// table, since tables can only be cloned within the same location.
+func (d *Client) checkCloneLocation(ctx context.Context, source string, destination *ResolvedTable, sourceLocation string) error {
+	dataset := d.client.DatasetInProject(destination.ProjectID, destination.DatasetID)
+	meta, err := dataset.Metadata(ctx)
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil
+		}
+		return errors.Wrapf(formatError(err), "failed to fetch metadata for dataset '%s.%s'", destination.ProjectID, destination.DatasetID)
+	}
+
+	if sourceLocation == "" || meta.Location == "" || strings.EqualFold(sourceLocation, meta.Location) {
+		return nil
+	}
+
+	return fmt.Errorf("cannot clone table '%s' in location '%s' into dataset '%s.%s' in location '%s', tables can only be cloned within the same location; clone it into a dataset in '%s' instead", source, sourceLocation, destination.ProjectID, destination.DatasetID, meta.Location, sourceLocation)
+}
@@ -0,0 +1,127 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_CloneTable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name                string
+		source              string
+		destination         string
+		destinationLocation string
+		wantCreatedIn       string
+		wantQueries         []string
+		wantErr             string
+	}{
+		{
+			name:          "destination dataset is created in the location of the source",
+			source:        "clone_source.events",
+			destination:   "clone_backup_new.events_snapshot",
+			wantCreatedIn: "EU",
+			wantQueries:   []string{"CREATE TABLE `test-project.clone_backup_new.events_snapshot` CLONE `test-project.clone_source.events`"},
+		},
+		{
+			name:                "destination dataset in the same location",
+			source:              "clone_source.events",
+			destination:         "other-project.clone_backup_eu.events_snapshot",
+			destinationLocation: "eu",
+			wantQueries:         []string{"CREATE TABLE `other-project.clone_backup_eu.events_snapshot` CLONE `test-project.clone_source.events`"},
+		},
+		{
+			name:                "destination dataset in another location",
+			source:              "clone_source.events",
+			destination:         "clone_backup_us.events_snapshot",
+			destinationLocation: "US",
+			wantErr:             "cannot clone table 'clone_source.events' in location 'EU' into dataset 'test-project.clone_backup_us' in location 'US', tables can only be cloned within the same location; clone it into a dataset in 'EU' instead",
+		},
+		{
+			name:        "missing source table",
+			source:      "clone_source.missing",
+			destination: "clone_backup_missing.events_snapshot",
+			wantErr:     "failed to fetch metadata for table 'clone_source.missing': Not found: Table test-project:clone_source.missing",
+		},
+		{
+			name:        "invalid destination name",
+			source:      "clone_source.events",
+			destination: "events_snapshot",
+			wantErr:     "invalid destination table: table name must be in dataset.table or project.dataset.table format, 'events_snapshot' given",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			destination, err := (&Client{config: &Config{ProjectID: testProjectID}}).ResolveTable(tt.destination)
+			if err != nil {
+				destination = &ResolvedTable{}
+			}
+
+			var mu sync.Mutex
+			createdIn := ""
+			handler := &recordingQueryHandler{
+				fallback: func(w http.ResponseWriter, r *http.Request) {
+					switch {
+					case r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/datasets/clone_source/tables/events", testProjectID):
+						_ = json.NewEncoder(w).Encode(&bigquery2.Table{
+							TableReference: &bigquery2.TableReference{ProjectId: testProjectID, DatasetId: "clone_source", TableId: "events"},
+							Location:       "EU",
+						})
+					case r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/datasets/%s", destination.ProjectID, destination.DatasetID):
+						if tt.destinationLocation == "" {
+							w.WriteHeader(http.StatusNotFound)
+							_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Dataset"}}`))
+							return
+						}
+						_ = json.NewEncoder(w).Encode(&bigquery2.Dataset{
+							DatasetReference: &bigquery2.DatasetReference{ProjectId: destination.ProjectID, DatasetId: destination.DatasetID},
+							Location:         tt.destinationLocation,
+						})
+					case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/datasets", destination.ProjectID):
+						var ds bigquery2.Dataset
+						_ = json.NewDecoder(r.Body).Decode(&ds)
+						mu.Lock()
+						createdIn = ds.Location
+						mu.Unlock()
+						_ = json.NewEncoder(w).Encode(&ds)
+					case r.Method == http.MethodGet:
+						w.WriteHeader(http.StatusNotFound)
+						_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table test-project:clone_source.missing"}}`))
+					default:
+						http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+					}
+				},
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			err = d.CloneTable(context.Background(), tt.source, tt.destination)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				assert.Empty(t, handler.recordedQueries())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantQueries, handler.recordedQueries())
+			mu.Lock()
+			assert.Equal(t, tt.wantCreatedIn, createdIn)
+			mu.Unlock()
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+)
+
+const (
+	// ClusteringSamplePercent is the share of the storage blocks of the table GetClusteringStats samples.
+	ClusteringSamplePercent = 10
+
+	// ReclusterThreshold is the clustering effectiveness under which a table is worth reclustering.
+	ReclusterThreshold = 0.5
+
+	// minClusteringStatsBytes is the size under which the effectiveness of the clustering is not estimated. Small
+	// tables are stored in a handful of blocks, which makes block sampling meaningless, and are cheap to scan anyway.
+	minClusteringStatsBytes = 64 << 20
+)
+
+// ClusteringStats estimates how well the rows of a clustered table are clustered, see GetClusteringStats.
+type ClusteringStats struct {
+	Table            string
+	ClusteringFields []string
+	NumRows          uint64
+	NumBytes         int64
+	LastModified     time.Time
+
+	// SampledField is the clustering column the effectiveness is estimated on, the first one, since the rows are
+	// sorted on it before any other clustering column.
+	SampledField          string
+	DistinctValues        int64
+	SampledDistinctValues int64
+
+	// Effectiveness goes from 0, for rows spread across the storage blocks regardless of their clustering column,
+	// to 1 for perfectly clustered rows. It is -1 if the table is too small for it to be estimated.
+	Effectiveness float64
+}
+
+// NeedsRecluster reports whether the clustering has degraded enough for the table to be worth reclustering.
+func (s *ClusteringStats) NeedsRecluster() bool {
+	return s.Effectiveness >= 0 && s.Effectiveness < ReclusterThreshold
+}
+
+// GetClusteringStats estimates how well the given table is clustered. BigQuery reclusters tables in the background
+// but does not expose how clustered they are, so the effectiveness is approximated by sampling
+// ClusteringSamplePercent of the storage blocks of the table with TABLESAMPLE SYSTEM and comparing the number of
+// distinct values of the first clustering column in the sample with the number in the whole table. In a well
+// clustered table every block holds a narrow range of values and the sample only sees its share of them, while in
+// a badly clustered one every block holds most of the values and so does the sample.
+//
+// The estimate is approximate: the distinct values are counted with APPROX_COUNT_DISTINCT, block sampling is
+// random and so is the result, columns with very few distinct values look well clustered regardless of the layout,
+// and the clustering columns after the first one are not looked at. It scans the first clustering column once,
+// which is billed. Tables under 64 MiB are not sampled and their Effectiveness is -1.
+func (d *Client) GetClusteringStats(ctx context.Context, tableName string) (*ClusteringStats, error) {
+	resolved, err := d.ResolveTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := d.client.DatasetInProject(resolved.ProjectID, resolved.DatasetID).Table(resolved.TableID).Metadata(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(formatError(err), "failed to fetch metadata for table '%s'", tableName)
+	}
+	if meta.Clustering == nil || len(meta.Clustering.Fields) == 0 {
+		return nil, fmt.Errorf("table '%s' is not clustered", tableName)
+	}
+
+	stats := &ClusteringStats{
+		Table:            resolved.String(),
+		ClusteringFields: meta.Clustering.Fields,
+		NumRows:          meta.NumRows,
+		NumBytes:         meta.NumBytes,
+		LastModified:     meta.LastModifiedTime,
+		SampledField:     meta.Clustering.Fields[0],
+		Effectiveness:    -1,
+	}
+	if meta.NumBytes < minClusteringStatsBytes {
+		return stats, nil
+	}
+
+	res, err := d.Select(ctx, &query.Query{Query: BuildClusteringSampleQuery(resolved.String(), stats.SampledField)})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to sample the clustering of table '%s'", tableName)
+	}
+	if len(res) != 1 || len(res[0]) != 2 {
+		return nil, fmt.Errorf("unexpected result while sampling the clustering of table '%s'", tableName)
+	}
+
+	var ok bool
+	if stats.DistinctValues, ok = res[0][0].(int64); !ok {
+		return nil, fmt.Errorf("unexpected distinct value count of type %T", res[0][0])
+	}
+	if stats.SampledDistinctValues, ok = res[0][1].(int64); !ok {
+		return nil, fmt.Errorf("unexpected sampled distinct value count of type %T", res[0][1])
+	}
+
+	stats.Effectiveness = clusteringEffectiveness(stats.DistinctValues, stats.SampledDistinctValues)
+	return stats, nil
+}
+
+// BuildClusteringSampleQuery builds the query that counts the distinct values of the column in the whole table and
+// in a sample of its storage blocks, see GetClusteringStats.
+func BuildClusteringSampleQuery(table, column string) string {
+	return fmt.Sprintf(
+		"SELECT\n  (SELECT APPROX_COUNT_DISTINCT(%[2]s) FROM %[1]s),\n  (SELECT APPROX_COUNT_DISTINCT(%[2]s) FROM %[1]s TABLESAMPLE SYSTEM (%[3]d PERCENT))",
+		QuoteIdentifier(table),
+		QuoteColumn(column),
+		ClusteringSamplePercent,
+	)
+}
+
+// clusteringEffectiveness scales the share of the distinct values found in the sample between the share of the
+// blocks sampled, which perfectly clustered rows would produce, and all of them, which randomly spread rows would.
+func clusteringEffectiveness(distinct, sampledDistinct int64) float64 {
+	if distinct <= 0 {
+		return -1
+	}
+
+	sampled := float64(ClusteringSamplePercent) / 100
+	share := float64(sampledDistinct) / float64(distinct)
+
+	return math.Max(0, math.Min(1, (1-share)/(1-sampled)))
+}
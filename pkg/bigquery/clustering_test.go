@@ -0,0 +1,91 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestBuildClusteringSampleQuery(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t,
+		"SELECT\n"+
+			"  (SELECT APPROX_COUNT_DISTINCT(`order`) FROM `p.d.events`),\n"+
+			"  (SELECT APPROX_COUNT_DISTINCT(`order`) FROM `p.d.events` TABLESAMPLE SYSTEM (10 PERCENT))",
+		BuildClusteringSampleQuery("p.d.events", "order"),
+	)
+}
+
+func TestClusteringEffectiveness(t *testing.T) {
+	t.Parallel()
+
+	assert.InDelta(t, 1, clusteringEffectiveness(1000, 100), 0.001)
+	assert.InDelta(t, 0, clusteringEffectiveness(1000, 1000), 0.001)
+	assert.InDelta(t, 0.5, clusteringEffectiveness(1000, 550), 0.001)
+	// samples are random, the estimate is kept within bounds
+	assert.InDelta(t, 1, clusteringEffectiveness(1000, 50), 0.001)
+	assert.InDelta(t, -1, clusteringEffectiveness(0, 0), 0.001)
+}
+
+func TestClient_GetClusteringStats(t *testing.T) {
+	t.Parallel()
+
+	tables := map[string]*bigquery2.Table{
+		"events":       {Clustering: &bigquery2.Clustering{Fields: []string{"customer_id", "event_type"}}, NumBytes: 10 << 30, NumRows: 5000000},
+		"small_events": {Clustering: &bigquery2.Clustering{Fields: []string{"customer_id"}}, NumBytes: 1024, NumRows: 10},
+		"users":        {NumBytes: 10 << 30},
+	}
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			fields := []*bigquery2.TableFieldSchema{{Name: "f0_", Type: "INTEGER"}, {Name: "f1_", Type: "INTEGER"}}
+			return rowsQueryResponse(fields, []interface{}{"1000", "820"})
+		},
+		fallback: func(w http.ResponseWriter, r *http.Request) {
+			for name, table := range tables {
+				if r.URL.Path == fmt.Sprintf("/projects/%s/datasets/dataset/tables/%s", testProjectID, name) {
+					_ = json.NewEncoder(w).Encode(table)
+					return
+				}
+			}
+
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table test-project:dataset.missing"}}`))
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	stats, err := d.GetClusteringStats(context.Background(), "dataset.events")
+	require.NoError(t, err)
+	assert.Equal(t, "test-project.dataset.events", stats.Table)
+	assert.Equal(t, []string{"customer_id", "event_type"}, stats.ClusteringFields)
+	assert.Equal(t, "customer_id", stats.SampledField)
+	assert.Equal(t, int64(1000), stats.DistinctValues)
+	assert.Equal(t, int64(820), stats.SampledDistinctValues)
+	assert.InDelta(t, 0.2, stats.Effectiveness, 0.001)
+	assert.True(t, stats.NeedsRecluster())
+	assert.Equal(t, []string{BuildClusteringSampleQuery("test-project.dataset.events", "customer_id")}, handler.recordedQueries())
+
+	// small tables are not sampled
+	stats, err = d.GetClusteringStats(context.Background(), "dataset.small_events")
+	require.NoError(t, err)
+	assert.InDelta(t, -1, stats.Effectiveness, 0.001)
+	assert.False(t, stats.NeedsRecluster())
+	assert.Len(t, handler.recordedQueries(), 1)
+
+	_, err = d.GetClusteringStats(context.Background(), "dataset.users")
+	require.EqualError(t, err, "table 'dataset.users' is not clustered")
+
+	_, err = d.GetClusteringStats(context.Background(), "dataset.missing")
+	require.EqualError(t, err, "failed to fetch metadata for table 'dataset.missing': Not found: Table test-project:dataset.missing")
+}
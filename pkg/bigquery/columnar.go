@@ -0,0 +1,154 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// ColumnVector holds all the values of a single result column. Depending on the column type only one of
+// the typed slices is populated; complex types such as RECORD or repeated fields fall back to Values.
+// Nulls is a bitmap aligned with the rows, NULL values are stored as the zero value of the typed slice.
+type ColumnVector struct {
+	Name string
+	Type bigquery.FieldType
+
+	Strings  []string
+	Int64s   []int64
+	Float64s []float64
+	Bools    []bool
+	Times    []time.Time
+	Values   []interface{}
+
+	Nulls []bool
+}
+
+// ColumnarResult is a column-major representation of a query result.
+type ColumnarResult struct {
+	Columns []*ColumnVector
+	NumRows int
+}
+
+// Column returns the column with the given name, or nil if there is no such column.
+func (r *ColumnarResult) Column(name string) *ColumnVector {
+	for _, col := range r.Columns {
+		if col.Name == name {
+			return col
+		}
+	}
+
+	return nil
+}
+
+func newColumnVectors(schema bigquery.Schema) []*ColumnVector {
+	columns := make([]*ColumnVector, 0, len(schema))
+	for _, field := range schema {
+		col := &ColumnVector{Name: field.Name, Type: field.Type}
+		if field.Repeated {
+			// repeated fields are always kept as generic values
+			col.Type = bigquery.FieldType("REPEATED " + string(field.Type))
+		}
+		columns = append(columns, col)
+	}
+
+	return columns
+}
+
+func (c *ColumnVector) append(v bigquery.Value) error {
+	isNull := v == nil
+	c.Nulls = append(c.Nulls, isNull)
+
+	switch c.Type { //nolint:exhaustive
+	case bigquery.StringFieldType:
+		s, ok := v.(string)
+		if !isNull && !ok {
+			return fmt.Errorf("unexpected value of type %T for STRING column '%s'", v, c.Name)
+		}
+		c.Strings = append(c.Strings, s)
+	case bigquery.IntegerFieldType:
+		i, ok := v.(int64)
+		if !isNull && !ok {
+			return fmt.Errorf("unexpected value of type %T for INTEGER column '%s'", v, c.Name)
+		}
+		c.Int64s = append(c.Int64s, i)
+	case bigquery.FloatFieldType:
+		f, ok := v.(float64)
+		if !isNull && !ok {
+			return fmt.Errorf("unexpected value of type %T for FLOAT column '%s'", v, c.Name)
+		}
+		c.Float64s = append(c.Float64s, f)
+	case bigquery.BooleanFieldType:
+		b, ok := v.(bool)
+		if !isNull && !ok {
+			return fmt.Errorf("unexpected value of type %T for BOOLEAN column '%s'", v, c.Name)
+		}
+		c.Bools = append(c.Bools, b)
+	case bigquery.TimestampFieldType:
+		t, ok := v.(time.Time)
+		if !isNull && !ok {
+			return fmt.Errorf("unexpected value of type %T for TIMESTAMP column '%s'", v, c.Name)
+		}
+		c.Times = append(c.Times, t)
+	default:
+		c.Values = append(c.Values, v)
+	}
+
+	return nil
+}
+
+// SelectColumnar runs the query and returns the results in a column-major layout.
+func (d *Client) SelectColumnar(ctx context.Context, queryObj *query.Query) (*ColumnarResult, error) {
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer cancel()
+
+	q, err := d.newQuery(ctx, queryObj)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.readWithRetry(ctx, q)
+	if err != nil {
+		return nil, formatError(err)
+	}
+
+	result := &ColumnarResult{}
+	for {
+		var values []bigquery.Value
+		err := rows.Next(&values)
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read row: %w", timeoutCause(ctx, err))
+		}
+
+		// the schema is only available after the first call to Next
+		if result.Columns == nil {
+			result.Columns = newColumnVectors(rows.Schema)
+		}
+
+		if len(values) != len(result.Columns) {
+			return nil, fmt.Errorf("row %d has %d values but the schema has %d columns", result.NumRows, len(values), len(result.Columns))
+		}
+
+		for i, v := range values {
+			if err := result.Columns[i].append(v); err != nil {
+				return nil, err
+			}
+		}
+		result.NumRows++
+	}
+
+	if result.Columns == nil {
+		if rows.Schema == nil {
+			return nil, errors.New("schema information is not available")
+		}
+		result.Columns = newColumnVectors(rows.Schema)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package bigquery
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_SelectColumnar(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			return &bigquery2.QueryResponse{
+				JobComplete:  true,
+				JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+				Schema: &bigquery2.TableSchema{
+					Fields: []*bigquery2.TableFieldSchema{
+						{Name: "name", Type: "STRING"},
+						{Name: "age", Type: "INTEGER"},
+						{Name: "score", Type: "FLOAT"},
+						{Name: "active", Type: "BOOLEAN"},
+						{Name: "created_at", Type: "TIMESTAMP"},
+						{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+					},
+				},
+				Rows: []*bigquery2.TableRow{
+					{F: []*bigquery2.TableCell{{V: "jane"}, {V: "30"}, {V: "1.5"}, {V: "true"}, {V: "1700000000000000"}, {V: []interface{}{map[string]interface{}{"v": "a"}}}}},
+					{F: []*bigquery2.TableCell{{V: nil}, {V: nil}, {V: "2.5"}, {V: nil}, {V: nil}, {V: []interface{}{}}}},
+				},
+				TotalRows: 2,
+			}
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	got, err := d.SelectColumnar(context.Background(), &query.Query{Query: "SELECT * FROM users"})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, got.NumRows)
+	require.Len(t, got.Columns, 6)
+
+	name := got.Column("name")
+	assert.Equal(t, []string{"jane", ""}, name.Strings)
+	assert.Equal(t, []bool{false, true}, name.Nulls)
+
+	age := got.Column("age")
+	assert.Equal(t, []int64{30, 0}, age.Int64s)
+	assert.Equal(t, []bool{false, true}, age.Nulls)
+
+	assert.InDeltaSlice(t, []float64{1.5, 2.5}, got.Column("score").Float64s, 0.0001)
+	assert.Equal(t, []bool{true, false}, got.Column("active").Bools)
+	assert.Equal(t, []time.Time{time.Unix(1700000000, 0).UTC(), {}}, got.Column("created_at").Times)
+
+	tags := got.Column("tags")
+	assert.Equal(t, bigquery.FieldType("REPEATED STRING"), tags.Type)
+	assert.Len(t, tags.Values, 2)
+
+	assert.Nil(t, got.Column("missing"))
+}
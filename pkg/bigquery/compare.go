@@ -0,0 +1,250 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/helpers"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+)
+
+// MaxComparedRows is the number of rows up to which CompareQueries reads the results of both queries and compares
+// them row by row. Larger results are compared through their checksums instead, without being read.
+const MaxComparedRows = 1000
+
+// ComparisonMethod is the way CompareQueries compared the rows of the queries.
+type ComparisonMethod string
+
+const (
+	ComparisonMethodRows     ComparisonMethod = "rows"
+	ComparisonMethodChecksum ComparisonMethod = "checksum"
+)
+
+// ColumnTypeDifference is a column both queries return, but with different types.
+type ColumnTypeDifference struct {
+	Column string
+	TypeA  string
+	TypeB  string
+}
+
+// ComparisonResult is the outcome of comparing the results of two queries, see CompareQueries.
+type ComparisonResult struct {
+	RowCountA int64
+	RowCountB int64
+
+	// ComparedColumns are the columns both queries return with the same type, in the order of the first query.
+	// Only these columns are compared, they are matched by name regardless of their position.
+	ComparedColumns []string
+	ColumnsOnlyInA  []string
+	ColumnsOnlyInB  []string
+	TypeDifferences []ColumnTypeDifference
+
+	// ColumnOrderDiffers is set if the queries return the compared columns in a different order, which does not
+	// make their results different.
+	ColumnOrderDiffers bool
+
+	// Method is empty if the queries do not share any column to compare their rows on.
+	Method ComparisonMethod
+
+	// RowsOnlyInA and RowsOnlyInB are the rows, limited to the compared columns, that only one of the queries
+	// returns, duplicates included. They are only set when the rows are compared one by one.
+	RowsOnlyInA [][]interface{}
+	RowsOnlyInB [][]interface{}
+
+	// ChecksumA and ChecksumB are the checksums of the compared columns, see BuildResultChecksumQuery. They are
+	// only set when the results are too large to be compared row by row.
+	ChecksumA string
+	ChecksumB string
+}
+
+// IsIdentical reports whether both queries return the same columns, with the same types, and the same rows.
+func (r *ComparisonResult) IsIdentical() bool {
+	if len(r.ColumnsOnlyInA) > 0 || len(r.ColumnsOnlyInB) > 0 || len(r.TypeDifferences) > 0 || r.RowCountA != r.RowCountB {
+		return false
+	}
+
+	switch r.Method {
+	case ComparisonMethodRows:
+		return len(r.RowsOnlyInA) == 0 && len(r.RowsOnlyInB) == 0
+	case ComparisonMethodChecksum:
+		return r.ChecksumA == r.ChecksumB
+	default:
+		// without any compared column only the row counts could be compared
+		return len(r.ComparedColumns) > 0
+	}
+}
+
+// CompareQueries runs both queries and compares their results, e.g. to verify that a rewritten query returns the
+// same data as the original one. The columns are matched by name, case-insensitively, and the columns only one of
+// the queries returns or that have different types are reported rather than compared. The rows are compared
+// regardless of their order: results of up to MaxComparedRows rows are compared row by row, larger ones through
+// their checksums so that they are never loaded into memory.
+func (d *Client) CompareQueries(ctx context.Context, queryA, queryB query.Query) (*ComparisonResult, error) {
+	schemaA, err := d.DryRunSchema(ctx, &queryA)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the schema of the first query")
+	}
+	schemaB, err := d.DryRunSchema(ctx, &queryB)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the schema of the second query")
+	}
+
+	result, columnsB := compareQuerySchemas(schemaA, schemaB)
+
+	result.RowCountA, err = d.countRows(ctx, queryA.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to count the rows of the first query")
+	}
+	result.RowCountB, err = d.countRows(ctx, queryB.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to count the rows of the second query")
+	}
+
+	if len(result.ComparedColumns) == 0 {
+		return result, nil
+	}
+
+	// the second query is projected with the column names of the first one, so that both produce the same rows
+	projectedA := projectColumns(queryA.String(), result.ComparedColumns, result.ComparedColumns)
+	projectedB := projectColumns(queryB.String(), columnsB, result.ComparedColumns)
+
+	if result.RowCountA > MaxComparedRows || result.RowCountB > MaxComparedRows {
+		result.Method = ComparisonMethodChecksum
+		if result.ChecksumA, err = d.ComputeResultChecksum(ctx, &query.Query{Query: projectedA}); err != nil {
+			return nil, errors.Wrap(err, "failed to compute the checksum of the first query")
+		}
+		if result.ChecksumB, err = d.ComputeResultChecksum(ctx, &query.Query{Query: projectedB}); err != nil {
+			return nil, errors.Wrap(err, "failed to compute the checksum of the second query")
+		}
+
+		return result, nil
+	}
+
+	result.Method = ComparisonMethodRows
+	rowsA, err := d.SelectWithSchemaTyped(ctx, &query.Query{Query: projectedA})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the rows of the first query")
+	}
+	rowsB, err := d.SelectWithSchemaTyped(ctx, &query.Query{Query: projectedB})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the rows of the second query")
+	}
+
+	result.RowsOnlyInA, result.RowsOnlyInB, err = diffRows(rowsA.Rows, rowsB.Rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// compareQuerySchemas matches the columns of the queries by name and returns the comparison of their columns
+// together with the names the second query uses for the compared columns.
+func compareQuerySchemas(schemaA, schemaB bigquery.Schema) (*ComparisonResult, []string) {
+	result := &ComparisonResult{
+		ComparedColumns: make([]string, 0),
+		ColumnsOnlyInA:  make([]string, 0),
+		ColumnsOnlyInB:  make([]string, 0),
+		TypeDifferences: make([]ColumnTypeDifference, 0),
+	}
+
+	positionsB := make(map[string]int, len(schemaB))
+	for i, field := range schemaB {
+		positionsB[strings.ToLower(field.Name)] = i
+	}
+
+	matchedB := make(map[int]bool, len(schemaB))
+	columnsB := make([]string, 0)
+	lastPosition := -1
+	for _, field := range schemaA {
+		position, ok := positionsB[strings.ToLower(field.Name)]
+		if !ok {
+			result.ColumnsOnlyInA = append(result.ColumnsOnlyInA, field.Name)
+			continue
+		}
+		matchedB[position] = true
+
+		typeA, typeB := CanonicalType(field), CanonicalType(schemaB[position])
+		if typeA != typeB {
+			result.TypeDifferences = append(result.TypeDifferences, ColumnTypeDifference{Column: field.Name, TypeA: typeA, TypeB: typeB})
+			continue
+		}
+
+		if position < lastPosition {
+			result.ColumnOrderDiffers = true
+		}
+		lastPosition = position
+
+		result.ComparedColumns = append(result.ComparedColumns, field.Name)
+		columnsB = append(columnsB, schemaB[position].Name)
+	}
+
+	for i, field := range schemaB {
+		if !matchedB[i] {
+			result.ColumnsOnlyInB = append(result.ColumnsOnlyInB, field.Name)
+		}
+	}
+
+	return result, columnsB
+}
+
+// projectColumns selects the given columns of the query, renamed to the given aliases.
+func projectColumns(q string, columns, aliases []string) string {
+	selected := make([]string, 0, len(columns))
+	for i, column := range columns {
+		selected = append(selected, QuoteIdentifier(column)+" AS "+QuoteIdentifier(aliases[i]))
+	}
+
+	return fmt.Sprintf("SELECT %s FROM (\n%s\n)", strings.Join(selected, ", "), strings.TrimSuffix(strings.TrimSpace(q), ";"))
+}
+
+func (d *Client) countRows(ctx context.Context, q string) (int64, error) {
+	res, err := d.Select(ctx, &query.Query{Query: fmt.Sprintf("SELECT COUNT(*) FROM (\n%s\n)", strings.TrimSuffix(strings.TrimSpace(q), ";"))})
+	if err != nil {
+		return 0, err
+	}
+
+	return helpers.CastResultToInteger(res)
+}
+
+// diffRows returns the rows only one of the sides has, comparing them as multisets so that the order of the rows
+// does not matter but duplicates do.
+func diffRows(rowsA, rowsB [][]interface{}) ([][]interface{}, [][]interface{}, error) {
+	keysA := make([]string, len(rowsA))
+	remaining := make(map[string]int, len(rowsA))
+	for i, row := range rowsA {
+		key, err := json.Marshal(row)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to compare the rows of the queries")
+		}
+		keysA[i] = string(key)
+		remaining[keysA[i]]++
+	}
+
+	onlyInB := make([][]interface{}, 0)
+	for _, row := range rowsB {
+		key, err := json.Marshal(row)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to compare the rows of the queries")
+		}
+		if remaining[string(key)] > 0 {
+			remaining[string(key)]--
+			continue
+		}
+		onlyInB = append(onlyInB, row)
+	}
+
+	onlyInA := make([][]interface{}, 0)
+	for i, row := range rowsA {
+		if remaining[keysA[i]] > 0 {
+			remaining[keysA[i]]--
+			onlyInA = append(onlyInA, row)
+		}
+	}
+
+	return onlyInA, onlyInB, nil
+}
@@ -0,0 +1,168 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+// compareQueriesHandler answers the dry runs with the schema of the query, and the queries with the responses of
+// the recording handler.
+func compareQueriesHandler(schemas map[string][]*bigquery2.TableFieldSchema, response func(q string) *bigquery2.QueryResponse) *recordingQueryHandler {
+	return &recordingQueryHandler{
+		response: response,
+		fallback: func(w http.ResponseWriter, r *http.Request) {
+			var job bigquery2.Job
+			if err := json.NewDecoder(r.Body).Decode(&job); err != nil || job.Configuration == nil || job.Configuration.Query == nil {
+				http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+				return
+			}
+
+			// dry runs terminate the query with a semicolon
+			fields, ok := schemas[job.Configuration.Query.Query]
+			if !ok {
+				fields = schemas[strings.TrimSuffix(job.Configuration.Query.Query, ";")]
+			}
+
+			job.Status = &bigquery2.JobStatus{State: "DONE"}
+			job.Statistics = &bigquery2.JobStatistics{Query: &bigquery2.JobStatistics2{
+				Schema: &bigquery2.TableSchema{Fields: fields},
+			}}
+			_ = json.NewEncoder(w).Encode(&job)
+		},
+	}
+}
+
+func rowsQueryResponse(fields []*bigquery2.TableFieldSchema, rows ...[]interface{}) *bigquery2.QueryResponse {
+	tableRows := make([]*bigquery2.TableRow, 0, len(rows))
+	for _, row := range rows {
+		cells := make([]*bigquery2.TableCell, 0, len(row))
+		for _, v := range row {
+			cells = append(cells, &bigquery2.TableCell{V: v})
+		}
+		tableRows = append(tableRows, &bigquery2.TableRow{F: cells})
+	}
+
+	return &bigquery2.QueryResponse{
+		JobComplete:  true,
+		JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+		Schema:       &bigquery2.TableSchema{Fields: fields},
+		Rows:         tableRows,
+		TotalRows:    uint64(len(tableRows)),
+	}
+}
+
+func TestClient_CompareQueries(t *testing.T) {
+	t.Parallel()
+
+	queryA := "SELECT id, name, score FROM dataset.users_v1"
+	queryB := "SELECT NAME, id, score, note FROM dataset.users_v2;"
+	schemas := map[string][]*bigquery2.TableFieldSchema{
+		queryA: {{Name: "id", Type: "INTEGER"}, {Name: "name", Type: "STRING"}, {Name: "score", Type: "FLOAT"}},
+		queryB: {{Name: "NAME", Type: "STRING"}, {Name: "id", Type: "INTEGER"}, {Name: "score", Type: "STRING"}, {Name: "note", Type: "STRING"}},
+	}
+	countField := []*bigquery2.TableFieldSchema{{Name: "f0_", Type: "INTEGER"}}
+	projectedFields := []*bigquery2.TableFieldSchema{{Name: "id", Type: "INTEGER"}, {Name: "name", Type: "STRING"}}
+
+	tests := []struct {
+		name          string
+		rowCount      string
+		want          *ComparisonResult
+		wantIdentical bool
+		wantQueries   []string
+	}{
+		{
+			name:     "small results are compared row by row",
+			rowCount: "3",
+			want: &ComparisonResult{
+				RowCountA:          3,
+				RowCountB:          3,
+				ComparedColumns:    []string{"id", "name"},
+				ColumnsOnlyInA:     []string{},
+				ColumnsOnlyInB:     []string{"note"},
+				TypeDifferences:    []ColumnTypeDifference{{Column: "score", TypeA: "float64", TypeB: "string"}},
+				ColumnOrderDiffers: true,
+				Method:             ComparisonMethodRows,
+				RowsOnlyInA:        [][]interface{}{{int64(1), "a"}},
+				RowsOnlyInB:        [][]interface{}{{int64(3), "c"}},
+			},
+			wantQueries: []string{
+				"SELECT COUNT(*) FROM (\n" + queryA + "\n)",
+				"SELECT COUNT(*) FROM (\nSELECT NAME, id, score, note FROM dataset.users_v2\n)",
+				"SELECT `id` AS `id`, `name` AS `name` FROM (\n" + queryA + "\n)",
+				"SELECT `id` AS `id`, `NAME` AS `name` FROM (\nSELECT NAME, id, score, note FROM dataset.users_v2\n)",
+			},
+		},
+		{
+			name:     "large results are compared through their checksums",
+			rowCount: "5000",
+			want: &ComparisonResult{
+				RowCountA:          5000,
+				RowCountB:          5000,
+				ComparedColumns:    []string{"id", "name"},
+				ColumnsOnlyInA:     []string{},
+				ColumnsOnlyInB:     []string{"note"},
+				TypeDifferences:    []ColumnTypeDifference{{Column: "score", TypeA: "float64", TypeB: "string"}},
+				ColumnOrderDiffers: true,
+				Method:             ComparisonMethodChecksum,
+				ChecksumA:          "abc",
+				ChecksumB:          "abc",
+			},
+			wantQueries: []string{
+				"SELECT COUNT(*) FROM (\n" + queryA + "\n)",
+				"SELECT COUNT(*) FROM (\nSELECT NAME, id, score, note FROM dataset.users_v2\n)",
+				BuildResultChecksumQuery("SELECT `id` AS `id`, `name` AS `name` FROM (\n" + queryA + "\n)"),
+				BuildResultChecksumQuery("SELECT `id` AS `id`, `NAME` AS `name` FROM (\nSELECT NAME, id, score, note FROM dataset.users_v2\n)"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := compareQueriesHandler(schemas, func(q string) *bigquery2.QueryResponse {
+				switch {
+				case strings.HasPrefix(q, "SELECT COUNT(*)"):
+					return rowsQueryResponse(countField, []interface{}{tt.rowCount})
+				case strings.HasPrefix(q, "SELECT TO_HEX"):
+					return stringQueryResponse("abc")
+				case strings.Contains(q, "users_v1"):
+					return rowsQueryResponse(projectedFields, []interface{}{"1", "a"}, []interface{}{"2", "b"}, []interface{}{"2", "b"})
+				default:
+					return rowsQueryResponse(projectedFields, []interface{}{"2", "b"}, []interface{}{"3", "c"}, []interface{}{"2", "b"})
+				}
+			})
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			got, err := d.CompareQueries(context.Background(), query.Query{Query: queryA}, query.Query{Query: queryB})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.False(t, got.IsIdentical())
+			assert.Equal(t, tt.wantQueries, handler.recordedQueries())
+		})
+	}
+}
+
+func TestComparisonResult_IsIdentical(t *testing.T) {
+	t.Parallel()
+
+	identical := &ComparisonResult{RowCountA: 2, RowCountB: 2, ComparedColumns: []string{"id"}, Method: ComparisonMethodRows, ColumnOrderDiffers: true}
+	assert.True(t, identical.IsIdentical())
+
+	assert.True(t, (&ComparisonResult{RowCountA: 2, RowCountB: 2, ComparedColumns: []string{"id"}, Method: ComparisonMethodChecksum, ChecksumA: "a", ChecksumB: "a"}).IsIdentical())
+	assert.False(t, (&ComparisonResult{RowCountA: 2, RowCountB: 2, ComparedColumns: []string{"id"}, Method: ComparisonMethodChecksum, ChecksumA: "a", ChecksumB: "b"}).IsIdentical())
+	assert.False(t, (&ComparisonResult{RowCountA: 2, RowCountB: 3, ComparedColumns: []string{"id"}, Method: ComparisonMethodRows}).IsIdentical())
+	assert.False(t, (&ComparisonResult{RowCountA: 0, RowCountB: 0, ColumnsOnlyInA: []string{"id"}, ColumnsOnlyInB: []string{"name"}}).IsIdentical())
+}
@@ -5,16 +5,170 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"golang.org/x/oauth2/google"
 )
 
+const (
+	// ProjectResolutionBilling resolves two-part table names to ProjectID, the project the jobs are billed to.
+	ProjectResolutionBilling = "billing"
+	// ProjectResolutionData resolves two-part table names to DataProjectID, falling back to ProjectID when unset.
+	ProjectResolutionData = "data"
+)
+
 type Config struct {
 	ProjectID           string `envconfig:"BIGQUERY_PROJECT"`
 	CredentialsFilePath string `envconfig:"BIGQUERY_CREDENTIALS_FILE"`
 	CredentialsJSON     string
 	Credentials         *google.Credentials
 	Location            string `envconfig:"BIGQUERY_LOCATION"`
+
+	// WorkloadIdentity authenticates through workload identity federation, e.g. from GitHub Actions, when none of
+	// the other credentials are given. A credentials JSON of the `external_account` type is supported as well.
+	WorkloadIdentity *WorkloadIdentityConfig
+
+	// ErrorOnNoRows makes Select and SelectWithSchema return ErrNoRows when a query returns zero rows.
+	ErrorOnNoRows bool
+
+	// DatasetDefaults are applied to the datasets created by bruin, and used to detect drift on existing ones.
+	DatasetDefaults DatasetSettings
+
+	// AllowSchemaEvolution makes the strategies that write into the existing table, e.g. append or merge, add the
+	// new columns of the asset to the table before writing, see EvolveSchema.
+	AllowSchemaEvolution bool
+
+	// PartitionCountWarningThreshold enables an advisory check before partitioned tables are materialized,
+	// warning when the number of partitions would exceed it. Zero disables the check.
+	PartitionCountWarningThreshold int64
+
+	// ValidateCheckColumns makes column checks verify that the checked column exists on the table before running,
+	// at the cost of an additional metadata call per check.
+	ValidateCheckColumns bool
+
+	// DataProjectID is the project that holds the data, for setups where it differs from the billing project.
+	DataProjectID string
+
+	// ProjectResolution decides which project `dataset.table` names are resolved to, one of ProjectResolutionBilling
+	// or ProjectResolutionData. It defaults to the billing project, i.e. ProjectID. The SQL generated by bruin
+	// references the tables of another project than ProjectID by their full name, see Client.TableReference.
+	ProjectResolution string
+
+	// MigrationsDataset is the dataset that holds the table tracking the applied migrations, see ApplyMigrations.
+	// It defaults to DefaultMigrationsDataset.
+	MigrationsDataset string
+
+	// SkipBadRows makes Select skip the rows that cannot be read instead of failing the whole read, see
+	// SelectWithRowErrors. At most MaxBadRows rows are skipped, which defaults to DefaultMaxBadRows.
+	SkipBadRows bool
+	MaxBadRows  int
+
+	// ResultMemoryCap is the number of rows SelectIterator holds in memory, the rows after it are spilled to a
+	// temporary file. Select fails with a ResultMemoryCapError for results larger than it. Zero holds every row in
+	// memory.
+	ResultMemoryCap int
+
+	// DescriptionFromQueryComment makes the metadata push use the `-- description: ...` comment at the top of the
+	// asset query as the table description, for assets without an explicit description.
+	DescriptionFromQueryComment bool
+
+	// InheritColumnDescriptions makes the metadata push fill in the descriptions of the columns that have none with
+	// the descriptions of the columns with the same name in the upstream assets.
+	InheritColumnDescriptions bool
+
+	// MaxRetries is the number of times a request failing with a transient error (429, 500 or 503) or a job failing
+	// with a backend error is retried, waiting RetryBaseDelay before the first retry and twice as long before each
+	// following one. With retries enabled the queries are submitted under a job ID of their own, so that a retried
+	// statement never runs twice.
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+
+	// DatasetCreationTimeout bounds the time spent checking and creating a dataset, including retries.
+	DatasetCreationTimeout time.Duration
+
+	// DatasetCacheTTL is how long a dataset is remembered to exist once it was checked or created, so that it is
+	// not checked again before every asset. Zero uses DefaultDatasetCacheTTL, a negative value disables the cache.
+	DatasetCacheTTL time.Duration
+
+	// MaximumBytesBilled fails the queries that would bill more than this many bytes instead of running them.
+	// Zero leaves the limit to the project defaults; query.Query.MaxBytesBilled overrides it per query.
+	MaximumBytesBilled int64
+
+	// OnDemandPricePerTiB is the on-demand price in USD per TiB processed, used by EstimateCost. It defaults to
+	// DefaultOnDemandPricePerTiB.
+	OnDemandPricePerTiB float64
+
+	// QueryTagLabel is the job label that carries the query tag set through WithQueryTag. It defaults to
+	// DefaultQueryTagLabel.
+	QueryTagLabel string
+
+	// WatermarkLabel is the table label that stores the watermark, see SetWatermark. It defaults to
+	// DefaultWatermarkLabel.
+	WatermarkLabel string
+
+	// OutputTimeZone is the IANA time zone, e.g. "Europe/Berlin", that SelectWithSchema renders DATETIME values
+	// in. DATETIME values have no time zone of their own, by default they are returned as civil.DateTime.
+	OutputTimeZone string
+
+	// MetadataUpdateRetries is the number of times a metadata update is retried when the table was modified
+	// concurrently, re-reading the metadata before every attempt. Zero uses DefaultMetadataUpdateRetries, a
+	// negative value disables the retries.
+	MetadataUpdateRetries int
+
+	// QueryPriority is the priority of the query jobs, QueryPriorityInteractive or QueryPriorityBatch, and can be
+	// overridden per query through query.Query.Priority. It defaults to interactive. Batch jobs are queued until
+	// idle slots are available, which may take arbitrarily long, so they should run with a context deadline.
+	QueryPriority string
+
+	// FetchPerformanceInsights makes RunQueryWithJobInfo read the performance insights BigQuery gives on the job,
+	// see JobInfo.Insights, at the cost of an additional request per job.
+	FetchPerformanceInsights bool
+
+	// PingWithMetadata makes Ping check the connection through PingMetadata, listing the datasets of the project
+	// instead of running a query job.
+	PingWithMetadata bool
+
+	// DefaultEncryptionKey is the resource name of the Cloud KMS key the created datasets and tables are encrypted
+	// with, e.g. `projects/p/locations/eu/keyRings/r/cryptoKeys/k`. Assets may override it through KMSKeyParameter.
+	// Empty leaves the encryption to Google-managed keys.
+	DefaultEncryptionKey string
+
+	// SoftQueryTimeout logs a warning through the logger of the context when a query job runs longer than it, and
+	// HardQueryTimeout cancels the job in BigQuery once it runs longer than it, failing the query with a
+	// QueryTimeoutError. Unlike QueryTimeout they only measure the job, not the reading of its results. Both are
+	// disabled by default.
+	SoftQueryTimeout time.Duration
+	HardQueryTimeout time.Duration
+
+	// ReservationID is the resource name of the reservation the queries run in, e.g.
+	// `projects/my-project/locations/US/reservations/etl`, which must belong to ProjectID. The queries are then run as
+	// scripts that set the reservation right after their DECLARE statements. Empty runs them with on-demand pricing
+	// or in the reservation assigned to the project.
+	ReservationID string
+
+	// QueryTimeout bounds the time a query may take, including reading or streaming its results, on top of the
+	// deadline of the context given to it. It applies to every method that runs a query, e.g. Select, SelectStream,
+	// SelectColumnar, SelectPage, SelectIterator, SelectToFile and RunQueryWithoutResult. A query that runs longer
+	// fails with a QueryTimeoutError and its job is cancelled in BigQuery. Zero disables it.
+	QueryTimeout time.Duration
+}
+
+// DefaultTableProject returns the project that `dataset.table` names belong to. The precedence is:
+//   - the project given explicitly in `project.dataset.table` names, which never reaches this method,
+//   - DataProjectID, if ProjectResolution is ProjectResolutionData and DataProjectID is set,
+//   - ProjectID otherwise.
+func (c Config) DefaultTableProject() (string, error) {
+	switch c.ProjectResolution {
+	case "", ProjectResolutionBilling:
+		return c.ProjectID, nil
+	case ProjectResolutionData:
+		if c.DataProjectID != "" {
+			return c.DataProjectID, nil
+		}
+		return c.ProjectID, nil
+	default:
+		return "", fmt.Errorf("invalid project resolution '%s', must be one of %s or %s", c.ProjectResolution, ProjectResolutionBilling, ProjectResolutionData)
+	}
 }
 
 func (c Config) IsValid() bool {
@@ -34,6 +188,9 @@ func (c Config) GetConnectionURI() (string, error) {
 		}
 	case c.Credentials != nil:
 		return "", errors.New("only `service_account_json` or `service_account_file` supported")
+	case c.WorkloadIdentity != nil:
+		// the URI carries a credentials file, a workload identity configuration cannot be passed on through it
+		return "", errors.New("workload identity federation is not supported for connection URIs, e.g. for ingestr, only `service_account_json` or `service_account_file` supported")
 	default:
 		return "", errors.New("could not find google credentials")
 	}
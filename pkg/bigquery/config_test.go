@@ -80,3 +80,70 @@ func TestConfig_GetConnectionURI(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_GetConnectionURI_WorkloadIdentity(t *testing.T) {
+	t.Parallel()
+
+	config := Config{
+		ProjectID: "project-id",
+		WorkloadIdentity: &WorkloadIdentityConfig{
+			Audience:         "//iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/pool/providers/provider",
+			SubjectTokenFile: "/var/run/token",
+		},
+	}
+
+	_, err := config.GetConnectionURI()
+	require.EqualError(t, err, "workload identity federation is not supported for connection URIs, e.g. for ingestr, only `service_account_json` or `service_account_file` supported")
+}
+
+func TestConfig_DefaultTableProject(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  Config
+		want    string
+		wantErr string
+	}{
+		{
+			name:   "defaults to the billing project",
+			config: Config{ProjectID: "billing", DataProjectID: "data"},
+			want:   "billing",
+		},
+		{
+			name:   "billing strategy",
+			config: Config{ProjectID: "billing", DataProjectID: "data", ProjectResolution: ProjectResolutionBilling},
+			want:   "billing",
+		},
+		{
+			name:   "data strategy",
+			config: Config{ProjectID: "billing", DataProjectID: "data", ProjectResolution: ProjectResolutionData},
+			want:   "data",
+		},
+		{
+			name:   "data strategy falls back to the billing project",
+			config: Config{ProjectID: "billing", ProjectResolution: ProjectResolutionData},
+			want:   "billing",
+		},
+		{
+			name:    "unknown strategy",
+			config:  Config{ProjectID: "billing", ProjectResolution: "nearest"},
+			wantErr: "invalid project resolution 'nearest', must be one of billing or data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.config.DefaultTableProject()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+)
+
+// DefaultOnDemandPricePerTiB is the BigQuery on-demand price in USD per TiB processed.
+const DefaultOnDemandPricePerTiB = 6.25
+
+const bytesPerTiB = 1 << 40
+
+// CostEstimate is the cost of a query as estimated by a dry run.
+type CostEstimate struct {
+	BytesProcessed int64
+	BytesBilled    int64
+	CacheHit       bool
+
+	// EstimatedUSD is the on-demand price of the bytes processed, it does not account for reservations
+	// or the free tier.
+	EstimatedUSD float64
+}
+
+// CostExceededError is returned when a query would process more bytes than allowed. It is returned before
+// the query is executed, which means no costs were incurred.
+type CostExceededError struct {
+	EstimatedBytes int64
+	MaxBytes       int64
+}
+
+func (e *CostExceededError) Error() string {
+	return fmt.Sprintf("query would process %d bytes, which exceeds the maximum of %d bytes", e.EstimatedBytes, e.MaxBytes)
+}
+
+// dryRun validates the query without running it and returns the statistics BigQuery estimated for it.
+func (d *Client) dryRun(ctx context.Context, queryObj *query.Query) (*bigquery.JobStatistics, error) {
+	q := d.client.Query(queryObj.ToDryRunQuery())
+	q.DryRun = true
+	q.Parameters = queryParameters(queryObj.Parameters)
+
+	job, err := d.runWithRetry(ctx, q)
+	if err != nil {
+		return nil, formatError(err)
+	}
+
+	status := job.LastStatus()
+	if err := status.Err(); err != nil {
+		return nil, formatError(err)
+	}
+
+	if status.Statistics == nil {
+		return nil, errors.New("dry run did not return any statistics")
+	}
+
+	return status.Statistics, nil
+}
+
+// EstimateBytesProcessed dry-runs the query and returns the number of bytes it would process.
+func (d *Client) EstimateBytesProcessed(ctx context.Context, queryObj *query.Query) (int64, error) {
+	stats, err := d.dryRun(ctx, queryObj)
+	if err != nil {
+		return 0, err
+	}
+
+	return stats.TotalBytesProcessed, nil
+}
+
+// EstimateCost dry-runs the query and returns the bytes it would process together with their on-demand price,
+// see Config.OnDemandPricePerTiB.
+func (d *Client) EstimateCost(ctx context.Context, queryObj *query.Query) (*CostEstimate, error) {
+	stats, err := d.dryRun(ctx, queryObj)
+	if err != nil {
+		return nil, err
+	}
+
+	pricePerTiB := DefaultOnDemandPricePerTiB
+	if d.config != nil && d.config.OnDemandPricePerTiB > 0 {
+		pricePerTiB = d.config.OnDemandPricePerTiB
+	}
+
+	estimate := &CostEstimate{
+		BytesProcessed: stats.TotalBytesProcessed,
+		EstimatedUSD:   float64(stats.TotalBytesProcessed) / bytesPerTiB * pricePerTiB,
+	}
+	if details, ok := stats.Details.(*bigquery.QueryStatistics); ok {
+		estimate.BytesBilled = details.TotalBytesBilled
+		estimate.CacheHit = details.CacheHit
+	}
+
+	return estimate, nil
+}
+
+// DryRunSchema returns the schema of the results of the query, as inferred by a dry run.
+func (d *Client) DryRunSchema(ctx context.Context, queryObj *query.Query) (bigquery.Schema, error) {
+	stats, err := d.dryRun(ctx, queryObj)
+	if err != nil {
+		return nil, err
+	}
+
+	details, ok := stats.Details.(*bigquery.QueryStatistics)
+	if !ok || details.Schema == nil {
+		return nil, errors.New("dry run did not return the schema of the query")
+	}
+
+	return details.Schema, nil
+}
+
+// SelectWithCostGuard estimates the bytes the query would process through a dry run and only runs it if the
+// estimate is within maxBytes, returning a CostExceededError otherwise. Unlike a maximum bytes billed limit,
+// the job is never started when it is over the limit. A maxBytes of zero or less disables the guard.
+func (d *Client) SelectWithCostGuard(ctx context.Context, queryObj *query.Query, maxBytes int64) ([][]interface{}, error) {
+	if maxBytes > 0 {
+		estimate, err := d.EstimateBytesProcessed(ctx, queryObj)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to estimate the cost of the query")
+		}
+
+		if estimate > maxBytes {
+			return nil, &CostExceededError{EstimatedBytes: estimate, MaxBytes: maxBytes}
+		}
+	}
+
+	return d.Select(ctx, queryObj)
+}
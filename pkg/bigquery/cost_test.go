@@ -0,0 +1,138 @@
+package bigquery
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_SelectWithCostGuard(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		maxBytes      int64
+		want          [][]interface{}
+		wantErr       *CostExceededError
+		wantDryRuns   int
+		wantExecution bool
+	}{
+		{
+			name:          "estimate within the limit",
+			maxBytes:      2048,
+			want:          [][]interface{}{{"a"}},
+			wantDryRuns:   1,
+			wantExecution: true,
+		},
+		{
+			name:        "estimate over the limit",
+			maxBytes:    1000,
+			wantErr:     &CostExceededError{EstimatedBytes: 1024, MaxBytes: 1000},
+			wantDryRuns: 1,
+		},
+		{
+			name:          "guard disabled",
+			maxBytes:      0,
+			want:          [][]interface{}{{"a"}},
+			wantExecution: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			jobs := &jobsHandler{
+				jobStatus: func(job *bigquery2.Job) {
+					job.Statistics = &bigquery2.JobStatistics{TotalBytesProcessed: 1024}
+				},
+			}
+			handler := &recordingQueryHandler{
+				response: func(q string) *bigquery2.QueryResponse {
+					return stringQueryResponse("a")
+				},
+				fallback: jobs.ServeHTTP,
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			got, err := d.SelectWithCostGuard(context.Background(), &query.Query{Query: "SELECT * FROM dataset.big_table"}, tt.maxBytes)
+			if tt.wantErr != nil {
+				var costErr *CostExceededError
+				require.ErrorAs(t, err, &costErr)
+				assert.Equal(t, tt.wantErr, costErr)
+				assert.EqualError(t, err, "query would process 1024 bytes, which exceeds the maximum of 1000 bytes")
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+
+			dryRuns := jobs.recordedJobs()
+			require.Len(t, dryRuns, tt.wantDryRuns)
+			for _, job := range dryRuns {
+				assert.True(t, job.DryRun)
+			}
+
+			if tt.wantExecution {
+				assert.Equal(t, []string{"SELECT * FROM dataset.big_table"}, handler.recordedQueries())
+			} else {
+				assert.Empty(t, handler.recordedQueries())
+			}
+		})
+	}
+}
+
+func TestClient_EstimateCost(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		pricePerTiB float64
+		want        *CostEstimate
+	}{
+		{
+			name: "default on-demand price",
+			want: &CostEstimate{BytesProcessed: 2 << 40, BytesBilled: 2 << 40, CacheHit: false, EstimatedUSD: 12.5},
+		},
+		{
+			name:        "configured price",
+			pricePerTiB: 5,
+			want:        &CostEstimate{BytesProcessed: 2 << 40, BytesBilled: 2 << 40, CacheHit: false, EstimatedUSD: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			jobs := &jobsHandler{
+				jobStatus: func(job *bigquery2.Job) {
+					job.Statistics = &bigquery2.JobStatistics{
+						TotalBytesProcessed: 2 << 40,
+						Query:               &bigquery2.JobStatistics2{TotalBytesProcessed: 2 << 40, TotalBytesBilled: 2 << 40},
+					}
+				},
+			}
+			server := httptest.NewServer(jobs)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.OnDemandPricePerTiB = tt.pricePerTiB
+
+			got, err := d.EstimateCost(context.Background(), &query.Query{Query: "SELECT * FROM dataset.big_table"})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			recorded := jobs.recordedJobs()
+			require.Len(t, recorded, 1)
+			assert.True(t, recorded[0].DryRun)
+		})
+	}
+}
@@ -0,0 +1,411 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/api/googleapi"
+)
+
+// datasetPreparationConcurrency bounds the number of datasets that are checked or created in parallel.
+const datasetPreparationConcurrency = 8
+
+const (
+	RoundingModeHalfAwayFromZero = "ROUND_HALF_AWAY_FROM_ZERO"
+	RoundingModeHalfEven         = "ROUND_HALF_EVEN"
+)
+
+// The storage billing models of a dataset: logical storage bills the uncompressed bytes, physical storage the
+// compressed bytes including time travel and fail-safe storage. Datasets use logical storage unless configured
+// otherwise, and the model can only be changed once every 14 days.
+const (
+	StorageBillingModelLogical  = "LOGICAL"
+	StorageBillingModelPhysical = "PHYSICAL"
+)
+
+// DatasetSettings holds the dataset-level defaults that are inherited by the tables created in a dataset.
+// Empty values are treated as "not managed by bruin" and never reported as drift.
+type DatasetSettings struct {
+	DefaultCollation    string
+	DefaultRoundingMode string
+
+	// DefaultPartitionExpiration is the default lifetime of the partitions of partitioned tables in the dataset.
+	// It is distinct from the default table expiration, which drops whole tables.
+	DefaultPartitionExpiration time.Duration
+
+	// DefaultTableExpiration is the default lifetime of the tables created in the dataset.
+	DefaultTableExpiration time.Duration
+
+	// Labels are set on the datasets created by bruin, they are not reconciled on existing datasets.
+	Labels map[string]string
+
+	// StorageBillingModel is either StorageBillingModelLogical or StorageBillingModelPhysical.
+	StorageBillingModel string
+}
+
+func (s DatasetSettings) IsEmpty() bool {
+	return s.DefaultCollation == "" && s.DefaultRoundingMode == "" && s.DefaultPartitionExpiration == 0 &&
+		s.DefaultTableExpiration == 0 && len(s.Labels) == 0 && s.StorageBillingModel == ""
+}
+
+func (s DatasetSettings) Validate() error {
+	if s.DefaultPartitionExpiration < 0 {
+		return fmt.Errorf("invalid default partition expiration '%s', must not be negative", s.DefaultPartitionExpiration)
+	}
+	// BigQuery rejects table expirations below one hour
+	if s.DefaultTableExpiration != 0 && s.DefaultTableExpiration < time.Hour {
+		return fmt.Errorf("invalid default table expiration '%s', must be at least one hour", s.DefaultTableExpiration)
+	}
+	if err := validateStorageBillingModel(s.StorageBillingModel); err != nil {
+		return err
+	}
+
+	switch strings.ToUpper(s.DefaultRoundingMode) {
+	case "", RoundingModeHalfAwayFromZero, RoundingModeHalfEven:
+		return nil
+	default:
+		return fmt.Errorf("invalid default rounding mode '%s', must be one of %s or %s", s.DefaultRoundingMode, RoundingModeHalfAwayFromZero, RoundingModeHalfEven)
+	}
+}
+
+// numericDatasetOptions are the dataset options whose values must not be quoted in DDL.
+var numericDatasetOptions = map[string]bool{
+	"default_partition_expiration_days": true,
+	"default_table_expiration_days":     true,
+}
+
+// DatasetSettingDrift describes a single dataset option whose live value differs from the desired one.
+type DatasetSettingDrift struct {
+	Option  string
+	Current string
+	Desired string
+}
+
+// DetectDatasetSettingsDrift compares the live dataset settings with the desired ones.
+func DetectDatasetSettingsDrift(current, desired DatasetSettings) []DatasetSettingDrift {
+	drifts := make([]DatasetSettingDrift, 0)
+	if desired.DefaultCollation != "" && current.DefaultCollation != desired.DefaultCollation {
+		drifts = append(drifts, DatasetSettingDrift{
+			Option:  "default_collation",
+			Current: current.DefaultCollation,
+			Desired: desired.DefaultCollation,
+		})
+	}
+
+	if desired.DefaultRoundingMode != "" && !strings.EqualFold(current.DefaultRoundingMode, desired.DefaultRoundingMode) {
+		drifts = append(drifts, DatasetSettingDrift{
+			Option:  "default_rounding_mode",
+			Current: current.DefaultRoundingMode,
+			Desired: strings.ToUpper(desired.DefaultRoundingMode),
+		})
+	}
+
+	if desired.DefaultPartitionExpiration != 0 && current.DefaultPartitionExpiration != desired.DefaultPartitionExpiration {
+		drifts = append(drifts, DatasetSettingDrift{
+			Option:  "default_partition_expiration_days",
+			Current: formatExpirationDays(current.DefaultPartitionExpiration),
+			Desired: formatExpirationDays(desired.DefaultPartitionExpiration),
+		})
+	}
+
+	if desired.DefaultTableExpiration != 0 && current.DefaultTableExpiration != desired.DefaultTableExpiration {
+		drifts = append(drifts, DatasetSettingDrift{
+			Option:  "default_table_expiration_days",
+			Current: formatExpirationDays(current.DefaultTableExpiration),
+			Desired: formatExpirationDays(desired.DefaultTableExpiration),
+		})
+	}
+
+	if desired.StorageBillingModel != "" && normalizeStorageBillingModel(current.StorageBillingModel) != normalizeStorageBillingModel(desired.StorageBillingModel) {
+		drifts = append(drifts, DatasetSettingDrift{
+			Option:  "storage_billing_model",
+			Current: normalizeStorageBillingModel(current.StorageBillingModel),
+			Desired: normalizeStorageBillingModel(desired.StorageBillingModel),
+		})
+	}
+
+	return drifts
+}
+
+func validateStorageBillingModel(model string) error {
+	switch strings.ToUpper(model) {
+	case "", StorageBillingModelLogical, StorageBillingModelPhysical:
+		return nil
+	default:
+		return fmt.Errorf("invalid storage billing model '%s', must be one of %s or %s", model, StorageBillingModelLogical, StorageBillingModelPhysical)
+	}
+}
+
+// normalizeStorageBillingModel upper-cases the model, treating the unset model of the datasets that never changed it
+// as the logical model they are billed with.
+func normalizeStorageBillingModel(model string) string {
+	if model == "" || model == "STORAGE_BILLING_MODEL_UNSPECIFIED" {
+		return StorageBillingModelLogical
+	}
+
+	return strings.ToUpper(model)
+}
+
+// formatExpirationDays formats the duration as the fractional number of days BigQuery DDL expects.
+func formatExpirationDays(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+
+	return strconv.FormatFloat(d.Hours()/24, 'f', -1, 64)
+}
+
+// BuildAlterDatasetOptionsQuery builds the DDL that applies the given drifted options to a dataset.
+func BuildAlterDatasetOptionsQuery(projectID, datasetID string, drifts []DatasetSettingDrift) string {
+	options := make([]string, 0, len(drifts))
+	for _, drift := range drifts {
+		if numericDatasetOptions[drift.Option] {
+			options = append(options, fmt.Sprintf("%s = %s", drift.Option, drift.Desired))
+			continue
+		}
+		options = append(options, fmt.Sprintf("%s = %s", drift.Option, quoteStringLiteral(drift.Desired)))
+	}
+
+	return fmt.Sprintf("ALTER SCHEMA %s SET OPTIONS (%s)", QuoteIdentifier(projectID+"."+datasetID), strings.Join(options, ", "))
+}
+
+// resolveDataset resolves a `dataset` or `project.dataset` name, using the default project for the former.
+func (d *Client) resolveDataset(datasetName string) (string, string, error) {
+	components := strings.Split(datasetName, ".")
+	for _, component := range components {
+		if component == "" {
+			return "", "", fmt.Errorf("dataset name must be in dataset or project.dataset format, '%s' given", datasetName)
+		}
+	}
+
+	switch len(components) {
+	case 1:
+		projectID, err := d.config.DefaultTableProject()
+		if err != nil {
+			return "", "", err
+		}
+		return projectID, components[0], nil
+	case 2:
+		return components[0], components[1], nil
+	default:
+		return "", "", fmt.Errorf("dataset name must be in dataset or project.dataset format, '%s' given", datasetName)
+	}
+}
+
+// GetDatasetSettings reads the current dataset-level defaults of the given dataset.
+func (d *Client) GetDatasetSettings(ctx context.Context, projectID, datasetID string) (*DatasetSettings, error) {
+	meta, err := d.client.DatasetInProject(projectID, datasetID).Metadata(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metadata for dataset '%s': %w", datasetID, err)
+	}
+
+	roundingMode, err := d.getDatasetRoundingMode(ctx, projectID, datasetID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DatasetSettings{
+		DefaultCollation:           meta.DefaultCollation,
+		DefaultRoundingMode:        roundingMode,
+		DefaultPartitionExpiration: meta.DefaultPartitionExpiration,
+		DefaultTableExpiration:     meta.DefaultTableExpiration,
+		StorageBillingModel:        normalizeStorageBillingModel(meta.StorageBillingModel),
+	}, nil
+}
+
+// GetDatasetStorageBillingModel returns the storage billing model of the given `dataset` or `project.dataset`,
+// either StorageBillingModelLogical or StorageBillingModelPhysical.
+func (d *Client) GetDatasetStorageBillingModel(ctx context.Context, datasetName string) (string, error) {
+	projectID, datasetID, err := d.resolveDataset(datasetName)
+	if err != nil {
+		return "", err
+	}
+
+	meta, err := d.client.DatasetInProject(projectID, datasetID).Metadata(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch metadata for dataset '%s': %w", datasetName, formatError(err))
+	}
+
+	return normalizeStorageBillingModel(meta.StorageBillingModel), nil
+}
+
+// SetDatasetStorageBillingModel changes the storage billing model of the given `dataset` or `project.dataset`. It
+// does nothing if the dataset already uses the model, since BigQuery only allows changing it once every 14 days.
+func (d *Client) SetDatasetStorageBillingModel(ctx context.Context, datasetName, model string) error {
+	if model == "" {
+		return errors.New("the storage billing model is required")
+	}
+	if err := validateStorageBillingModel(model); err != nil {
+		return err
+	}
+
+	projectID, datasetID, err := d.resolveDataset(datasetName)
+	if err != nil {
+		return err
+	}
+
+	dataset := d.client.DatasetInProject(projectID, datasetID)
+	meta, err := dataset.Metadata(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch metadata for dataset '%s': %w", datasetName, formatError(err))
+	}
+
+	desired := normalizeStorageBillingModel(model)
+	if normalizeStorageBillingModel(meta.StorageBillingModel) == desired {
+		return nil
+	}
+
+	update := bigquery.DatasetMetadataToUpdate{StorageBillingModel: desired}
+	if desired == StorageBillingModelLogical {
+		update.StorageBillingModel = bigquery.LogicalStorageBillingModel
+	}
+	if _, err := dataset.Update(ctx, update, meta.ETag); err != nil {
+		if isStorageBillingModelChangeRestricted(err) {
+			return fmt.Errorf("cannot change the storage billing model of dataset '%s' to %s, it can only be changed once every 14 days: %w", datasetName, desired, formatError(err))
+		}
+		return fmt.Errorf("failed to change the storage billing model of dataset '%s': %w", datasetName, formatError(err))
+	}
+
+	return nil
+}
+
+// isStorageBillingModelChangeRestricted reports whether BigQuery rejected a change of the storage billing model
+// because it was already changed within the last 14 days.
+func isStorageBillingModelChangeRestricted(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest {
+		return false
+	}
+
+	message := strings.ToLower(apiErr.Message)
+	return strings.Contains(message, "billing model") && strings.Contains(message, "14 days")
+}
+
+// getDatasetRoundingMode reads the rounding mode from INFORMATION_SCHEMA since the dataset metadata API
+// does not expose it.
+func (d *Client) getDatasetRoundingMode(ctx context.Context, projectID, datasetID string) (string, error) {
+	region := ""
+	if d.config != nil && d.config.Location != "" {
+		region = "." + QuoteIdentifier("region-"+strings.ToLower(d.config.Location))
+	}
+
+	qq := fmt.Sprintf(
+		"SELECT option_value FROM %s%s.INFORMATION_SCHEMA.SCHEMATA_OPTIONS WHERE schema_name = @schema_name AND option_name = 'default_rounding_mode'",
+		QuoteIdentifier(projectID),
+		region,
+	)
+
+	rows, err := d.Select(ctx, &query.Query{
+		Query:      qq,
+		Parameters: []query.Parameter{{Name: "schema_name", Value: datasetID}},
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read the rounding mode of dataset '%s'", datasetID)
+	}
+
+	if len(rows) == 0 || len(rows[0]) == 0 || rows[0][0] == nil {
+		return "", nil
+	}
+
+	return strings.Trim(fmt.Sprint(rows[0][0]), `"`), nil
+}
+
+// EnsureDatasetSettings compares the dataset of the given asset against the configured dataset defaults
+// and updates the dataset in place if they drifted. The applied drifts are returned. CreateDataSetIfNotExist does the
+// same for the existing datasets it comes across.
+func (d *Client) EnsureDatasetSettings(ctx context.Context, asset *pipeline.Asset) ([]DatasetSettingDrift, error) {
+	if d.config == nil || d.config.DatasetDefaults.IsEmpty() {
+		return nil, nil
+	}
+
+	resolved, err := d.ResolveTable(asset.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := d.client.DatasetInProject(resolved.ProjectID, resolved.DatasetID).Metadata(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch metadata for dataset '%s': %w", resolved.DatasetID, err)
+	}
+
+	return d.reconcileDatasetSettings(ctx, resolved.ProjectID, resolved.DatasetID, meta)
+}
+
+// reconcileDatasetSettings updates the settings of the existing dataset with the given metadata that drifted from
+// the configured dataset defaults, returning the applied drifts.
+func (d *Client) reconcileDatasetSettings(ctx context.Context, projectID, datasetID string, meta *bigquery.DatasetMetadata) ([]DatasetSettingDrift, error) {
+	if d.config == nil || d.config.DatasetDefaults.IsEmpty() {
+		return nil, nil
+	}
+
+	desired := d.config.DatasetDefaults
+	if err := desired.Validate(); err != nil {
+		return nil, err
+	}
+
+	current := DatasetSettings{
+		DefaultCollation:           meta.DefaultCollation,
+		DefaultPartitionExpiration: meta.DefaultPartitionExpiration,
+		DefaultTableExpiration:     meta.DefaultTableExpiration,
+		StorageBillingModel:        meta.StorageBillingModel,
+	}
+	if desired.DefaultRoundingMode != "" {
+		var err error
+		current.DefaultRoundingMode, err = d.getDatasetRoundingMode(ctx, projectID, datasetID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	drifts := DetectDatasetSettingsDrift(current, desired)
+	if len(drifts) == 0 {
+		return drifts, nil
+	}
+
+	qq := BuildAlterDatasetOptionsQuery(projectID, datasetID, drifts)
+	if err := d.RunQueryWithoutResult(ctx, &query.Query{Query: qq}); err != nil {
+		return nil, errors.Wrapf(err, "failed to update the settings of dataset '%s'", datasetID)
+	}
+
+	return drifts, nil
+}
+
+// PrepareDatasets ensures that the dataset of every given asset exists before the assets are materialized.
+// Assets sharing a dataset are coalesced so that each dataset is checked, and created if needed, exactly once.
+func (d *Client) PrepareDatasets(ctx context.Context, assets []*pipeline.Asset) error {
+	seen := make(map[string]bool, len(assets))
+	unique := make([]*pipeline.Asset, 0, len(assets))
+	for _, asset := range assets {
+		resolved, err := d.ResolveTable(asset.Name)
+		if err != nil {
+			// names that cannot be resolved are ignored here, the same way CreateDataSetIfNotExist does
+			continue
+		}
+
+		key := resolved.ProjectID + "." + resolved.DatasetID
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, asset)
+	}
+
+	wg, ctx := errgroup.WithContext(ctx)
+	wg.SetLimit(datasetPreparationConcurrency)
+	for _, asset := range unique {
+		wg.Go(func() error {
+			return d.CreateDataSetIfNotExist(asset, ctx)
+		})
+	}
+
+	return wg.Wait()
+}
@@ -0,0 +1,696 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestDetectDatasetSettingsDrift(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		current DatasetSettings
+		desired DatasetSettings
+		want    []DatasetSettingDrift
+	}{
+		{
+			name:    "nothing desired, nothing drifts",
+			current: DatasetSettings{DefaultCollation: "und:ci", DefaultRoundingMode: RoundingModeHalfEven},
+			desired: DatasetSettings{},
+			want:    []DatasetSettingDrift{},
+		},
+		{
+			name:    "matching settings",
+			current: DatasetSettings{DefaultCollation: "und:ci", DefaultRoundingMode: RoundingModeHalfEven},
+			desired: DatasetSettings{DefaultCollation: "und:ci", DefaultRoundingMode: "round_half_even"},
+			want:    []DatasetSettingDrift{},
+		},
+		{
+			name:    "collation drifted",
+			current: DatasetSettings{DefaultCollation: ""},
+			desired: DatasetSettings{DefaultCollation: "und:ci"},
+			want: []DatasetSettingDrift{
+				{Option: "default_collation", Current: "", Desired: "und:ci"},
+			},
+		},
+		{
+			name:    "unmanaged collation is ignored",
+			current: DatasetSettings{DefaultCollation: "und:ci", DefaultRoundingMode: RoundingModeHalfAwayFromZero},
+			desired: DatasetSettings{DefaultCollation: "", DefaultRoundingMode: "round_half_even"},
+			want: []DatasetSettingDrift{
+				{Option: "default_rounding_mode", Current: RoundingModeHalfAwayFromZero, Desired: RoundingModeHalfEven},
+			},
+		},
+		{
+			name:    "partition expiration drifted",
+			current: DatasetSettings{DefaultPartitionExpiration: 0},
+			desired: DatasetSettings{DefaultPartitionExpiration: 36 * time.Hour},
+			want: []DatasetSettingDrift{
+				{Option: "default_partition_expiration_days", Current: "", Desired: "1.5"},
+			},
+		},
+		{
+			name:    "table expiration drifted",
+			current: DatasetSettings{DefaultTableExpiration: 7 * 24 * time.Hour},
+			desired: DatasetSettings{DefaultTableExpiration: 14 * 24 * time.Hour},
+			want: []DatasetSettingDrift{
+				{Option: "default_table_expiration_days", Current: "7", Desired: "14"},
+			},
+		},
+		{
+			name:    "matching partition expiration",
+			current: DatasetSettings{DefaultPartitionExpiration: 30 * 24 * time.Hour},
+			desired: DatasetSettings{DefaultPartitionExpiration: 30 * 24 * time.Hour},
+			want:    []DatasetSettingDrift{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, DetectDatasetSettingsDrift(tt.current, tt.desired))
+		})
+	}
+}
+
+func TestDatasetSettings_Validate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, DatasetSettings{}.Validate())
+	require.NoError(t, DatasetSettings{DefaultRoundingMode: "round_half_even"}.Validate())
+	require.Error(t, DatasetSettings{DefaultRoundingMode: "ROUND_UP"}.Validate())
+	require.Error(t, DatasetSettings{DefaultPartitionExpiration: -time.Hour}.Validate())
+	require.NoError(t, DatasetSettings{DefaultTableExpiration: 24 * time.Hour}.Validate())
+	require.Error(t, DatasetSettings{DefaultTableExpiration: time.Minute}.Validate())
+}
+
+func TestBuildAlterDatasetOptionsQuery(t *testing.T) {
+	t.Parallel()
+
+	got := BuildAlterDatasetOptionsQuery("project", "dataset", []DatasetSettingDrift{
+		{Option: "default_collation", Desired: "und:ci"},
+		{Option: "default_rounding_mode", Desired: RoundingModeHalfEven},
+		{Option: "default_partition_expiration_days", Desired: "30"},
+	})
+
+	assert.Equal(t, "ALTER SCHEMA `project.dataset` SET OPTIONS (default_collation = 'und:ci', default_rounding_mode = 'ROUND_HALF_EVEN', default_partition_expiration_days = 30)", got)
+
+	got = BuildAlterDatasetOptionsQuery("project", "dataset", []DatasetSettingDrift{
+		{Option: "default_collation", Desired: "und:ci'), description = ('x"},
+	})
+	assert.Equal(t, "ALTER SCHEMA `project.dataset` SET OPTIONS (default_collation = 'und:ci\\'), description = (\\'x')", got)
+}
+
+func TestClient_EnsureDatasetSettings(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			if strings.Contains(q, "SCHEMATA_OPTIONS") {
+				return stringQueryResponse(`"ROUND_HALF_AWAY_FROM_ZERO"`)
+			}
+			return nil
+		},
+		fallback: func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/datasets/mydataset", testProjectID) {
+				_ = json.NewEncoder(w).Encode(&bigquery2.Dataset{
+					DatasetReference: &bigquery2.DatasetReference{ProjectId: testProjectID, DatasetId: "mydataset"},
+					DefaultCollation: "und:ci",
+				})
+				return
+			}
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	d.config.DatasetDefaults = DatasetSettings{
+		DefaultCollation:    "und:ci",
+		DefaultRoundingMode: RoundingModeHalfEven,
+	}
+
+	drifts, err := d.EnsureDatasetSettings(context.Background(), &pipeline.Asset{Name: "mydataset.mytable"})
+	require.NoError(t, err)
+	assert.Equal(t, []DatasetSettingDrift{
+		{Option: "default_rounding_mode", Current: RoundingModeHalfAwayFromZero, Desired: RoundingModeHalfEven},
+	}, drifts)
+
+	queries := handler.recordedQueries()
+	require.Len(t, queries, 2)
+	assert.Contains(t, queries[0], "WHERE schema_name = @schema_name")
+	assert.Equal(t, "ALTER SCHEMA `test-project.mydataset` SET OPTIONS (default_rounding_mode = 'ROUND_HALF_EVEN')", queries[1])
+
+	requests := handler.recordedRequests()
+	require.Len(t, requests[0].QueryParameters, 1)
+	assert.Equal(t, "mydataset", requests[0].QueryParameters[0].ParameterValue.Value)
+}
+
+func TestClient_EnsureDatasetSettings_PartitionExpiration(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		fallback: func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/datasets/expiring", testProjectID) {
+				_ = json.NewEncoder(w).Encode(&bigquery2.Dataset{
+					DatasetReference:             &bigquery2.DatasetReference{ProjectId: testProjectID, DatasetId: "expiring"},
+					DefaultPartitionExpirationMs: 7 * 24 * 60 * 60 * 1000,
+					DefaultTableExpirationMs:     30 * 24 * 60 * 60 * 1000,
+					ForceSendFields:              []string{"DefaultPartitionExpirationMs"},
+				})
+				return
+			}
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	d.config.DatasetDefaults = DatasetSettings{DefaultPartitionExpiration: 30 * 24 * time.Hour}
+
+	drifts, err := d.EnsureDatasetSettings(context.Background(), &pipeline.Asset{Name: "expiring.mytable"})
+	require.NoError(t, err)
+	assert.Equal(t, []DatasetSettingDrift{
+		{Option: "default_partition_expiration_days", Current: "7", Desired: "30"},
+	}, drifts)
+
+	queries := handler.recordedQueries()
+	require.Len(t, queries, 1)
+	assert.Equal(t, "ALTER SCHEMA `test-project.expiring` SET OPTIONS (default_partition_expiration_days = 30)", queries[0])
+}
+
+func TestClient_CreateDataSetIfNotExist_PartitionExpiration(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var created *bigquery2.Dataset
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/datasets/fresh_expiring", testProjectID):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "not found"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/datasets", testProjectID):
+			var ds bigquery2.Dataset
+			_ = json.NewDecoder(r.Body).Decode(&ds)
+			mu.Lock()
+			created = &ds
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(&ds)
+		default:
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	d.config.DatasetDefaults = DatasetSettings{DefaultPartitionExpiration: 30 * 24 * time.Hour}
+
+	require.NoError(t, d.CreateDataSetIfNotExist(&pipeline.Asset{Name: "fresh_expiring.mytable"}, context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotNil(t, created)
+	assert.Equal(t, int64(30*24*60*60*1000), created.DefaultPartitionExpirationMs)
+	assert.Zero(t, created.DefaultTableExpirationMs)
+}
+
+func TestClient_CreateDataSetIfNotExist_ReconcilesSettings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		dataset string
+		// createdConcurrently makes the dataset appear missing first, and created by another process when bruin
+		// tries to create it
+		createdConcurrently bool
+	}{
+		{
+			name:    "existing dataset",
+			dataset: "drifted_existing",
+		},
+		{
+			name:                "dataset created by another process",
+			dataset:             "drifted_conflict",
+			createdConcurrently: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			created := false
+			handler := &recordingQueryHandler{
+				response: func(q string) *bigquery2.QueryResponse {
+					if strings.Contains(q, "SCHEMATA_OPTIONS") {
+						return stringQueryResponse(`"ROUND_HALF_AWAY_FROM_ZERO"`)
+					}
+					return nil
+				},
+				fallback: func(w http.ResponseWriter, r *http.Request) {
+					mu.Lock()
+					defer mu.Unlock()
+
+					switch {
+					case r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/datasets/%s", testProjectID, tt.dataset):
+						if tt.createdConcurrently && !created {
+							w.WriteHeader(http.StatusNotFound)
+							_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "not found"}}`))
+							return
+						}
+						_ = json.NewEncoder(w).Encode(&bigquery2.Dataset{
+							DatasetReference:             &bigquery2.DatasetReference{ProjectId: testProjectID, DatasetId: tt.dataset},
+							DefaultPartitionExpirationMs: 7 * 24 * 60 * 60 * 1000,
+						})
+					case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/datasets", testProjectID) && tt.createdConcurrently:
+						created = true
+						w.WriteHeader(http.StatusConflict)
+						_, _ = w.Write([]byte(`{"error": {"code": 409, "message": "Already Exists: Dataset"}}`))
+					default:
+						http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+					}
+				},
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.DatasetDefaults = DatasetSettings{
+				DefaultCollation:           "und:ci",
+				DefaultRoundingMode:        RoundingModeHalfEven,
+				DefaultPartitionExpiration: 30 * 24 * time.Hour,
+			}
+
+			asset := &pipeline.Asset{Name: tt.dataset + ".table"}
+			require.NoError(t, d.CreateDataSetIfNotExist(asset, context.Background()))
+			// the dataset is cached once reconciled, it is not reconciled again
+			require.NoError(t, d.CreateDataSetIfNotExist(asset, context.Background()))
+
+			queries := handler.recordedQueries()
+			require.Len(t, queries, 2)
+			assert.Contains(t, queries[0], "SCHEMATA_OPTIONS")
+			assert.Equal(t, "ALTER SCHEMA `test-project."+tt.dataset+"` SET OPTIONS (default_collation = 'und:ci', default_rounding_mode = 'ROUND_HALF_EVEN', default_partition_expiration_days = 30)", queries[1])
+		})
+	}
+}
+
+func TestClient_CreateDataSetIfNotExist_Location(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	created := make(map[string]*bigquery2.Dataset)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/datasets/located_existing", testProjectID):
+			_ = json.NewEncoder(w).Encode(&bigquery2.Dataset{Location: "US", DefaultTableExpirationMs: 7 * 24 * 60 * 60 * 1000})
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/datasets/located_", testProjectID)):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "not found"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/datasets", testProjectID):
+			var ds bigquery2.Dataset
+			_ = json.NewDecoder(r.Body).Decode(&ds)
+			created[ds.DatasetReference.DatasetId] = &ds
+			_ = json.NewEncoder(w).Encode(&ds)
+		default:
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	d.config.Location = "europe-west1"
+	d.config.DatasetDefaults = DatasetSettings{
+		DefaultTableExpiration: 7 * 24 * time.Hour,
+		Labels:                 map[string]string{"Team": "Data Platform"},
+	}
+
+	ctx := context.Background()
+	require.NoError(t, d.CreateDataSetIfNotExist(&pipeline.Asset{Name: "located_default.table"}, ctx))
+	require.NoError(t, d.CreateDataSetIfNotExist(&pipeline.Asset{
+		Name:       "located_override.table",
+		Parameters: map[string]string{DatasetLocationParameter: "US"},
+	}, ctx))
+
+	mu.Lock()
+	assert.Equal(t, "europe-west1", created["located_default"].Location)
+	assert.Equal(t, int64(7*24*60*60*1000), created["located_default"].DefaultTableExpirationMs)
+	assert.Equal(t, map[string]string{"team": "data_platform"}, created["located_default"].Labels)
+	assert.Equal(t, "US", created["located_override"].Location)
+	mu.Unlock()
+
+	// the datasets are cached with their location, a conflicting asset fails without another request
+	err := d.CreateDataSetIfNotExist(&pipeline.Asset{Name: "located_override.other_table"}, ctx)
+	require.ErrorContains(t, err, "dataset 'test-project.located_override' is in location 'US', but the asset expects it in 'europe-west1'")
+
+	err = d.CreateDataSetIfNotExist(&pipeline.Asset{Name: "located_existing.table"}, ctx)
+	require.ErrorContains(t, err, "dataset 'test-project.located_existing' is in location 'US', but the asset expects it in 'europe-west1'")
+
+	require.NoError(t, d.CreateDataSetIfNotExist(&pipeline.Asset{
+		Name:       "located_existing.table",
+		Parameters: map[string]string{DatasetLocationParameter: "us"},
+	}, ctx))
+}
+
+func TestClient_CreateDataSetIfNotExist_ConcurrentCreation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		dataset        string
+		createStatuses []int
+		wantErr        string
+		wantCreates    int
+	}{
+		{
+			name:           "dataset created by another process",
+			dataset:        "concurrent_conflict",
+			createStatuses: []int{http.StatusConflict},
+			wantCreates:    1,
+		},
+		{
+			name:           "transient failures are retried",
+			dataset:        "concurrent_rate_limited",
+			createStatuses: []int{http.StatusTooManyRequests, http.StatusOK},
+			wantCreates:    2,
+		},
+		{
+			name:           "other failures are returned",
+			dataset:        "concurrent_forbidden",
+			createStatuses: []int{http.StatusForbidden},
+			wantErr:        "failed to create dataset 'concurrent_forbidden'",
+			wantCreates:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			creates := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/datasets/%s", testProjectID, tt.dataset):
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "not found"}}`))
+				case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/datasets", testProjectID):
+					status := tt.createStatuses[creates]
+					creates++
+					if status != http.StatusOK {
+						w.WriteHeader(status)
+						_, _ = w.Write([]byte(fmt.Sprintf(`{"error": {"code": %d, "message": "%s"}}`, status, http.StatusText(status))))
+						return
+					}
+
+					var ds bigquery2.Dataset
+					_ = json.NewDecoder(r.Body).Decode(&ds)
+					_ = json.NewEncoder(w).Encode(&ds)
+				default:
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.MaxRetries = 2
+			d.config.RetryBaseDelay = time.Millisecond
+			d.config.DatasetCreationTimeout = 10 * time.Second
+
+			err := d.CreateDataSetIfNotExist(&pipeline.Asset{Name: tt.dataset + ".table"}, context.Background())
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tt.wantCreates, creates)
+		})
+	}
+}
+
+func TestClient_PrepareDatasets(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	metadataCalls := make(map[string]int)
+	createCalls := make(map[string]int)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/datasets/", testProjectID)):
+			name := strings.TrimPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/datasets/", testProjectID))
+			metadataCalls[name]++
+			if name == "prepare_missing" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "not found"}}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(&bigquery2.Dataset{
+				DatasetReference: &bigquery2.DatasetReference{ProjectId: testProjectID, DatasetId: name},
+			})
+		case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/datasets", testProjectID):
+			var ds bigquery2.Dataset
+			_ = json.NewDecoder(r.Body).Decode(&ds)
+			createCalls[ds.DatasetReference.DatasetId]++
+			_ = json.NewEncoder(w).Encode(&ds)
+		default:
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	assets := []*pipeline.Asset{
+		{Name: "prepare_existing.table1"},
+		{Name: "prepare_existing.table2"},
+		{Name: "prepare_missing.table1"},
+		{Name: "test-project.prepare_missing.table2"},
+		{Name: "not_a_table_name"},
+	}
+
+	require.NoError(t, d.PrepareDatasets(context.Background(), assets))
+	// running it again must not hit the API since the datasets are cached now
+	require.NoError(t, d.PrepareDatasets(context.Background(), assets))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]int{"prepare_existing": 1, "prepare_missing": 1}, metadataCalls)
+	assert.Equal(t, map[string]int{"prepare_missing": 1}, createCalls)
+}
+
+func TestClient_CreateDataSetIfNotExist_CacheTTL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		dataset     string
+		ttl         time.Duration
+		wait        time.Duration
+		wantLookups int
+	}{
+		{
+			name:        "cached datasets are not checked again",
+			dataset:     "cached_ttl_default",
+			wantLookups: 1,
+		},
+		{
+			name:        "expired datasets are checked again",
+			dataset:     "cached_ttl_expired",
+			ttl:         time.Millisecond,
+			wait:        10 * time.Millisecond,
+			wantLookups: 2,
+		},
+		{
+			name:        "disabled cache",
+			dataset:     "cached_ttl_disabled",
+			ttl:         -1,
+			wantLookups: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			lookups := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/datasets/%s", testProjectID, tt.dataset) {
+					mu.Lock()
+					lookups++
+					mu.Unlock()
+					_ = json.NewEncoder(w).Encode(&bigquery2.Dataset{Location: "US", DefaultTableExpirationMs: 7 * 24 * 60 * 60 * 1000})
+					return
+				}
+				http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.DatasetCacheTTL = tt.ttl
+
+			asset := &pipeline.Asset{Name: tt.dataset + ".table"}
+			require.NoError(t, d.CreateDataSetIfNotExist(asset, context.Background()))
+			time.Sleep(tt.wait)
+			require.NoError(t, d.CreateDataSetIfNotExist(asset, context.Background()))
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tt.wantLookups, lookups)
+		})
+	}
+}
+
+func TestDetectDatasetSettingsDrift_StorageBillingModel(t *testing.T) {
+	t.Parallel()
+
+	// datasets that never changed the model are billed for logical storage
+	assert.Empty(t, DetectDatasetSettingsDrift(DatasetSettings{}, DatasetSettings{StorageBillingModel: "logical"}))
+	assert.Equal(t, []DatasetSettingDrift{
+		{Option: "storage_billing_model", Current: StorageBillingModelLogical, Desired: StorageBillingModelPhysical},
+	}, DetectDatasetSettingsDrift(DatasetSettings{}, DatasetSettings{StorageBillingModel: "physical"}))
+
+	assert.Equal(t,
+		"ALTER SCHEMA `project.dataset` SET OPTIONS (storage_billing_model = 'PHYSICAL')",
+		BuildAlterDatasetOptionsQuery("project", "dataset", []DatasetSettingDrift{{Option: "storage_billing_model", Desired: StorageBillingModelPhysical}}),
+	)
+
+	require.EqualError(t, DatasetSettings{StorageBillingModel: "compressed"}.Validate(), "invalid storage billing model 'compressed', must be one of LOGICAL or PHYSICAL")
+}
+
+func TestClient_DatasetStorageBillingModel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		current     string
+		desired     string
+		updateError string
+		wantCurrent string
+		wantPatch   bool
+		wantUpdate  string
+		wantErr     string
+	}{
+		{
+			name:        "switch to physical",
+			desired:     "physical",
+			wantCurrent: StorageBillingModelLogical,
+			wantPatch:   true,
+			wantUpdate:  StorageBillingModelPhysical,
+		},
+		{
+			name:        "switch back to logical",
+			current:     StorageBillingModelPhysical,
+			desired:     StorageBillingModelLogical,
+			wantCurrent: StorageBillingModelPhysical,
+			wantPatch:   true,
+			wantUpdate:  "",
+		},
+		{
+			name:        "unchanged model is not updated",
+			current:     StorageBillingModelPhysical,
+			desired:     StorageBillingModelPhysical,
+			wantCurrent: StorageBillingModelPhysical,
+		},
+		{
+			name:        "changed within the last 14 days",
+			desired:     StorageBillingModelPhysical,
+			updateError: "Cannot change the storage billing model, it was already changed in the last 14 days.",
+			wantCurrent: StorageBillingModelLogical,
+			wantPatch:   true,
+			wantUpdate:  StorageBillingModelPhysical,
+			wantErr:     "cannot change the storage billing model of dataset 'billing' to PHYSICAL, it can only be changed once every 14 days",
+		},
+		{
+			name:        "invalid model",
+			desired:     "compressed",
+			wantCurrent: StorageBillingModelLogical,
+			wantErr:     "invalid storage billing model 'compressed', must be one of LOGICAL or PHYSICAL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			patched := false
+			updated := ""
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != fmt.Sprintf("/projects/%s/datasets/billing", testProjectID) {
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+					return
+				}
+
+				if r.Method == http.MethodPatch {
+					var ds bigquery2.Dataset
+					_ = json.NewDecoder(r.Body).Decode(&ds)
+					mu.Lock()
+					patched = true
+					updated = ds.StorageBillingModel
+					mu.Unlock()
+					if tt.updateError != "" {
+						w.WriteHeader(http.StatusBadRequest)
+						_, _ = w.Write([]byte(fmt.Sprintf(`{"error": {"code": 400, "message": %q}}`, tt.updateError)))
+						return
+					}
+					_ = json.NewEncoder(w).Encode(&ds)
+					return
+				}
+
+				_ = json.NewEncoder(w).Encode(&bigquery2.Dataset{
+					DatasetReference:    &bigquery2.DatasetReference{ProjectId: testProjectID, DatasetId: "billing"},
+					StorageBillingModel: tt.current,
+				})
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			current, err := d.GetDatasetStorageBillingModel(context.Background(), "billing")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCurrent, current)
+
+			err = d.SetDatasetStorageBillingModel(context.Background(), "billing", tt.desired)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tt.wantPatch, patched)
+			assert.Equal(t, tt.wantUpdate, updated)
+		})
+	}
+}
@@ -3,13 +3,17 @@ package bigquery
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"cloud.google.com/go/bigquery"
 	"github.com/bruin-data/bruin/pkg/pipeline"
 	"github.com/bruin-data/bruin/pkg/query"
 	"github.com/pkg/errors"
+	bigquery2 "google.golang.org/api/bigquery/v2"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
@@ -21,6 +25,18 @@ var scopes = []string{
 	"https://www.googleapis.com/auth/drive",
 }
 
+// DatasetLocationParameter is the asset parameter that overrides the location the dataset of the asset is created
+// in, e.g. `europe-west1`.
+const DatasetLocationParameter = "dataset_location"
+
+// DefaultMetadataUpdateRetries is the number of times a metadata update is retried after a concurrent modification
+// of the table, unless configured otherwise.
+const DefaultMetadataUpdateRetries = 3
+
+// DefaultDatasetCacheTTL is how long a dataset is remembered to exist, unless configured otherwise. Datasets
+// deleted in the meantime are created again once their cache entry expires.
+const DefaultDatasetCacheTTL = 10 * time.Minute
+
 type Querier interface {
 	RunQueryWithoutResult(ctx context.Context, query *query.Query) error
 	Ping(ctx context.Context) error
@@ -39,7 +55,8 @@ type TableManager interface {
 	CreateDataSetIfNotExist(asset *pipeline.Asset, ctx context.Context) error
 	IsMaterializationTypeMismatch(ctx context.Context, meta *bigquery.TableMetadata, asset *pipeline.Asset) bool
 	DropTableOnMismatch(ctx context.Context, tableName string, asset *pipeline.Asset) error
-	BuildTableExistsQuery(tableName string) (string, error)
+	BuildTableExistsQuery(tableName string) (*query.Query, error)
+	ResolveTable(tableName string) (*ResolvedTable, error)
 }
 
 type DB interface {
@@ -49,48 +66,125 @@ type DB interface {
 	TableManager
 }
 
+// ErrNoRows is returned by Select and SelectWithSchema when the query yields no rows and the client is
+// configured to treat empty results as an error.
+var ErrNoRows = errors.New("query returned no rows")
+
 var (
-	datasetNameCache sync.Map // Global cache for dataset existence
+	datasetNameCache sync.Map // Global cache for dataset existence, holding datasetCacheEntry values
 	datasetLocks     sync.Map // Global map for dataset-specific locks
 )
 
+// datasetCacheEntry records the location of a dataset known to exist until the entry expires.
+type datasetCacheEntry struct {
+	location  string
+	expiresAt time.Time
+}
+
 type Client struct {
 	client *bigquery.Client
 	config *Config
+
+	// service reads what the client does not expose, e.g. the performance insights of jobs. It is only set if
+	// Config.FetchPerformanceInsights is.
+	service *bigquery2.Service
+
+	// poolKey identifies the shared client in clientPool, it is empty for clients that are not pooled.
+	poolKey string
+	closed  atomic.Bool
 }
 
 func NewDB(c *Config) (*Client, error) {
+	if err := validateQueryPriority(c.QueryPriority); err != nil {
+		return nil, err
+	}
+	if _, err := c.outputLocation(); err != nil {
+		return nil, err
+	}
+	if err := validateReservationID(c.ProjectID, c.ReservationID); err != nil {
+		return nil, err
+	}
+
 	options := []option.ClientOption{
 		option.WithScopes(scopes...),
 	}
 
 	switch {
 	case c.CredentialsJSON != "":
-		options = append(options, option.WithCredentialsJSON([]byte(c.CredentialsJSON)))
+		creds, isExternalAccount, err := externalAccountCredentials(context.Background(), []byte(c.CredentialsJSON))
+		if err != nil {
+			return nil, err
+		}
+		if isExternalAccount {
+			options = append(options, option.WithCredentials(creds))
+		} else {
+			options = append(options, option.WithCredentialsJSON([]byte(c.CredentialsJSON)))
+		}
 	case c.CredentialsFilePath != "":
 		options = append(options, option.WithCredentialsFile(c.CredentialsFilePath))
 	case c.Credentials != nil:
 		options = append(options, option.WithCredentials(c.Credentials))
+	case c.WorkloadIdentity != nil:
+		ts, err := c.WorkloadIdentity.TokenSource(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, option.WithTokenSource(ts))
 	default:
 		return nil, errors.New("no credentials provided")
 	}
 
-	client, err := bigquery.NewClient(
-		context.Background(),
-		c.ProjectID,
-		options...,
-	)
+	key, err := clientPoolKey(c)
+	if err != nil {
+		return nil, err
+	}
+
+	newClient := func() (*bigquery.Client, error) {
+		client, err := bigquery.NewClient(
+			context.Background(),
+			c.ProjectID,
+			options...,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create bigquery client")
+		}
+
+		if c.Location != "" {
+			client.Location = c.Location
+		}
+
+		return client, nil
+	}
+
+	var client *bigquery.Client
+	if key == "" {
+		client, err = newClient()
+	} else {
+		client, err = clientPool.acquire(key, newClient)
+	}
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to create bigquery client")
+		return nil, err
 	}
 
-	if c.Location != "" {
-		client.Location = c.Location
+	var service *bigquery2.Service
+	if c.FetchPerformanceInsights {
+		service, err = bigquery2.NewService(context.Background(), options...)
+		if err != nil {
+			// the client was acquired for this client only, release it with the error
+			if key == "" {
+				_ = client.Close()
+			} else {
+				_ = clientPool.release(key)
+			}
+			return nil, errors.Wrap(err, "failed to create bigquery service")
+		}
 	}
 
 	return &Client{
-		client: client,
-		config: c,
+		client:  client,
+		config:  c,
+		service: service,
+		poolKey: key,
 	}, nil
 }
 
@@ -98,26 +192,99 @@ func (d *Client) GetIngestrURI() (string, error) {
 	return d.config.GetIngestrURI()
 }
 
+// newQuery creates the query for the given query object, applying the connection-wide limits, the overrides
+// of the query object and the job labels of the context.
+func (d *Client) newQuery(ctx context.Context, queryObj *query.Query) (*bigquery.Query, error) {
+	if err := validateQueryPriority(queryObj.Priority); err != nil {
+		return nil, err
+	}
+
+	q := d.client.Query(d.withReservation(queryObj.String()))
+	if d.config != nil {
+		q.MaxBytesBilled = d.config.MaximumBytesBilled
+	}
+	if queryObj.MaxBytesBilled != nil {
+		q.MaxBytesBilled = *queryObj.MaxBytesBilled
+	}
+	q.Priority = d.queryPriority(queryObj)
+	q.Labels = d.jobLabels(ctx)
+	q.Parameters = queryParameters(queryObj.Parameters)
+
+	return q, nil
+}
+
+// queryParameters converts the parameters of a query object to the parameters of a BigQuery query.
+func queryParameters(params []query.Parameter) []bigquery.QueryParameter {
+	if len(params) == 0 {
+		return nil
+	}
+
+	converted := make([]bigquery.QueryParameter, len(params))
+	for i, param := range params {
+		converted[i] = bigquery.QueryParameter{Name: param.Name, Value: param.Value}
+	}
+
+	return converted
+}
+
+const (
+	QueryPriorityInteractive = "interactive"
+	QueryPriorityBatch       = "batch"
+)
+
+// queryPriority returns the priority of the query, the one of the query object if set, the configured one otherwise.
+// Both are validated with validateQueryPriority.
+func (d *Client) queryPriority(queryObj *query.Query) bigquery.QueryPriority {
+	priority := queryObj.Priority
+	if priority == "" && d.config != nil {
+		priority = d.config.QueryPriority
+	}
+
+	return bigquery.QueryPriority(strings.ToUpper(priority))
+}
+
+func validateQueryPriority(priority string) error {
+	switch strings.ToLower(priority) {
+	case "", QueryPriorityInteractive, QueryPriorityBatch:
+		return nil
+	default:
+		return fmt.Errorf("invalid query priority '%s', must be one of %s or %s", priority, QueryPriorityInteractive, QueryPriorityBatch)
+	}
+}
+
+// IsValid dry-runs the query to validate it. Obviously broken queries, see PrecheckQuery, fail without calling
+// BigQuery.
 func (d *Client) IsValid(ctx context.Context, query *query.Query) (bool, error) {
+	if err := PrecheckQuery(query.Query); err != nil {
+		return false, err
+	}
+
 	q := d.client.Query(query.ToDryRunQuery())
 	q.DryRun = true
+	q.Parameters = queryParameters(query.Parameters)
 
-	job, err := q.Run(ctx)
+	job, err := d.runWithRetry(ctx, q)
 	if err != nil {
 		return false, formatError(err)
 	}
 
 	status := job.LastStatus()
 	if err := status.Err(); err != nil {
-		return false, err
+		return false, formatError(err)
 	}
 
 	return true, nil
 }
 
 func (d *Client) RunQueryWithoutResult(ctx context.Context, query *query.Query) error {
-	q := d.client.Query(query.String())
-	_, err := q.Read(ctx)
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer cancel()
+
+	q, err := d.newQuery(ctx, query)
+	if err != nil {
+		return err
+	}
+	_, err = d.readWithRetry(ctx, q)
 	if err != nil {
 		return formatError(err)
 	}
@@ -125,59 +292,80 @@ func (d *Client) RunQueryWithoutResult(ctx context.Context, query *query.Query)
 	return nil
 }
 
+// Select runs the query and returns its rows. If reading the results fails midway, the error is a
+// PartialResultError that holds the rows read before the failure. With Config.SkipBadRows set, the rows that cannot
+// be read are skipped and logged as a warning, see SelectWithRowErrors.
 func (d *Client) Select(ctx context.Context, query *query.Query) ([][]interface{}, error) {
-	q := d.client.Query(query.String())
-	rows, err := q.Read(ctx)
-	if err != nil {
-		return nil, formatError(err)
+	if d.config != nil && d.config.SkipBadRows {
+		return d.selectSkippingBadRows(ctx, query)
 	}
 
-	result := make([][]interface{}, 0)
-	for {
-		var values []bigquery.Value
-		err := rows.Next(&values)
-		if errors.Is(err, iterator.Done) {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-
-		interfaces := make([]interface{}, len(values))
-		for i, v := range values {
-			interfaces[i] = v
-		}
+	result, err := d.selectRows(ctx, query)
+	if err != nil {
+		return nil, err
+	}
 
-		result = append(result, interfaces)
+	if len(result) == 0 && d.errorOnNoRows() {
+		return nil, ErrNoRows
 	}
 
 	return result, nil
 }
 
+// SelectWithSchema runs the query and returns the rows together with the column names, types and modes. TIMESTAMP
+// columns hold time.Time instants while DATETIME columns hold civil.DateTime wall-clock values, unless
+// Config.OutputTimeZone is set, in which case DATETIME values are rendered as time.Time in that time zone.
 func (d *Client) SelectWithSchema(ctx context.Context, queryObj *query.Query) (*query.QueryResult, error) {
-	q := d.client.Query(queryObj.String())
-	rows, err := q.Read(ctx)
+	return d.selectWithSchema(ctx, queryObj, -1, false)
+}
+
+// SelectWithSchemaLimit is like SelectWithSchema, but stops reading after limit rows, e.g. for previews. A limit
+// of zero returns only the column metadata.
+func (d *Client) SelectWithSchemaLimit(ctx context.Context, queryObj *query.Query, limit int) (*query.QueryResult, error) {
+	if limit < 0 {
+		return nil, errors.Errorf("limit must not be negative, %d given", limit)
+	}
+
+	return d.selectWithSchema(ctx, queryObj, limit, false)
+}
+
+// selectWithSchema reads at most limit rows, or all of them if limit is negative.
+func (d *Client) selectWithSchema(ctx context.Context, queryObj *query.Query, limit int, typed bool) (*query.QueryResult, error) {
+	// an invalid time zone must fail before the query runs and is billed
+	loc, err := d.config.outputLocation()
 	if err != nil {
-		return nil, fmt.Errorf("failed to initiate query read: %w", err)
+		return nil, err
 	}
 
-	result := &query.QueryResult{
-		Columns:     []string{},
-		Rows:        [][]interface{}{},
-		ColumnTypes: []string{},
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer cancel()
+
+	q, err := d.newQuery(ctx, queryObj)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.readWithRetry(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initiate query read: %w", err)
 	}
 
-	// Add a ColumnTypes field to store the types
-	columnTypes := []string{}
+	result := &query.QueryResult{Rows: [][]interface{}{}}
 
-	for {
+	hasRows := false
+	for limit < 0 || len(result.Rows) < limit || !hasRows {
 		var values []bigquery.Value
 		err := rows.Next(&values)
 		if errors.Is(err, iterator.Done) {
 			break
 		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to read row: %w", err)
+			return nil, fmt.Errorf("failed to read row: %w", timeoutCause(ctx, err))
+		}
+		hasRows = true
+
+		// with a limit of zero the first row is only read to populate the schema
+		if limit == 0 {
+			break
 		}
 
 		row := make([]interface{}, len(values))
@@ -187,29 +375,92 @@ func (d *Client) SelectWithSchema(ctx context.Context, queryObj *query.Query) (*
 		result.Rows = append(result.Rows, row)
 	}
 
-	if rows.Schema != nil {
-		for _, field := range rows.Schema {
-			result.Columns = append(result.Columns, field.Name)
-			// Extract the type information from the schema
-			columnTypes = append(columnTypes, string(field.Type))
+	schema := rows.Schema
+	if schema == nil && !hasRows {
+		// empty results may come without a schema, the dry run still knows the shape of the output
+		schema, err = d.DryRunSchema(ctx, queryObj)
+		if err != nil {
+			return nil, errors.Wrap(err, "schema information is not available")
 		}
-	} else {
+	}
+
+	if schema == nil {
 		return nil, errors.New("schema information is not available")
 	}
+	setResultColumns(result, schema)
+
+	if loc != nil {
+		normalizeDateTimes(result.Rows, schema, loc)
+	}
+	if typed {
+		typeRows(result.Rows, schema)
+	}
 
-	// Store the column types in the result
-	result.ColumnTypes = columnTypes
+	if !hasRows && d.errorOnNoRows() {
+		return nil, ErrNoRows
+	}
 
 	return result, nil
 }
 
+// setResultColumns sets the names, types and modes of the columns of the result from the schema.
+func setResultColumns(result *query.QueryResult, schema bigquery.Schema) {
+	result.Columns = make([]string, 0, len(schema))
+	result.ColumnTypes = make([]string, 0, len(schema))
+	result.ColumnModes = make([]string, 0, len(schema))
+	result.CanonicalColumnTypes = make([]string, 0, len(schema))
+	for _, field := range schema {
+		result.Columns = append(result.Columns, field.Name)
+		result.ColumnTypes = append(result.ColumnTypes, string(field.Type))
+		result.ColumnModes = append(result.ColumnModes, FieldMode(field))
+		result.CanonicalColumnTypes = append(result.CanonicalColumnTypes, CanonicalType(field))
+	}
+}
+
+const (
+	FieldModeNullable = "NULLABLE"
+	FieldModeRequired = "REQUIRED"
+	FieldModeRepeated = "REPEATED"
+)
+
+// FieldMode returns the BigQuery mode of the given field.
+func FieldMode(field *bigquery.FieldSchema) string {
+	switch {
+	case field.Repeated:
+		return FieldModeRepeated
+	case field.Required:
+		return FieldModeRequired
+	default:
+		return FieldModeNullable
+	}
+}
+
+func (d *Client) errorOnNoRows() bool {
+	return d.config != nil && d.config.ErrorOnNoRows
+}
+
 type NoMetadataUpdatedError struct{}
 
 func (m NoMetadataUpdatedError) Error() string {
 	return "no metadata found for the given asset to be pushed to BigQuery"
 }
 
-func (d *Client) getTableRef(tableName string) (*bigquery.Table, error) {
+// ResolvedTable is the fully-qualified reference of a table, with the project resolved from the
+// client configuration when the asset name only has the dataset and table components. The location is the one of
+// the dataset of the table, it is only known once the dataset was looked up, see Materialize.
+type ResolvedTable struct {
+	ProjectID string
+	DatasetID string
+	TableID   string
+	Location  string
+}
+
+func (r ResolvedTable) String() string {
+	return fmt.Sprintf("%s.%s.%s", r.ProjectID, r.DatasetID, r.TableID)
+}
+
+// ResolveTable resolves a `dataset.table` or `project.dataset.table` name into a fully-qualified table reference.
+func (d *Client) ResolveTable(tableName string) (*ResolvedTable, error) {
 	tableComponents := strings.Split(tableName, ".")
 	// Check for empty components
 	for _, component := range tableComponents {
@@ -217,27 +468,116 @@ func (d *Client) getTableRef(tableName string) (*bigquery.Table, error) {
 			return nil, fmt.Errorf("table name must be in dataset.table or project.dataset.table format, '%s' given", tableName)
 		}
 	}
+
+	resolved := &ResolvedTable{}
 	switch len(tableComponents) {
 	case 2:
-		return d.client.DatasetInProject(d.config.ProjectID, tableComponents[0]).Table(tableComponents[1]), nil
+		projectID, err := d.config.DefaultTableProject()
+		if err != nil {
+			return nil, err
+		}
+		resolved.ProjectID = projectID
+		resolved.DatasetID = tableComponents[0]
+		resolved.TableID = tableComponents[1]
 	case 3:
-		return d.client.DatasetInProject(tableComponents[0], tableComponents[1]).Table(tableComponents[2]), nil
+		resolved.ProjectID = tableComponents[0]
+		resolved.DatasetID = tableComponents[1]
+		resolved.TableID = tableComponents[2]
 	default:
 		return nil, fmt.Errorf("table name must be in dataset.table or project.dataset.table format, '%s' given", tableName)
 	}
+
+	return resolved, nil
+}
+
+// resolveTableLocation resolves the table name like ResolveTable and looks up the location of its dataset.
+func (d *Client) resolveTableLocation(ctx context.Context, tableName string) (*ResolvedTable, error) {
+	resolved, err := d.ResolveTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := fmt.Sprintf("%s.%s", resolved.ProjectID, resolved.DatasetID)
+	if location, exists := d.cachedDatasetLocation(cacheKey); exists && location != "" {
+		resolved.Location = location
+		return resolved, nil
+	}
+
+	meta, err := d.client.DatasetInProject(resolved.ProjectID, resolved.DatasetID).Metadata(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(formatError(err), "failed to fetch metadata for dataset '%s.%s'", resolved.ProjectID, resolved.DatasetID)
+	}
+	d.cacheDatasetLocation(cacheKey, meta.Location)
+	resolved.Location = meta.Location
+
+	return resolved, nil
+}
+
+// TableReference returns the reference to the given table in generated SQL. BigQuery resolves two-part names in SQL
+// to the project running the query, so the names that ResolveTable resolves to another project, e.g. DataProjectID,
+// are fully qualified; the other names are kept as they are.
+func (d *Client) TableReference(tableName string) (string, error) {
+	resolved, err := d.ResolveTable(tableName)
+	if err != nil {
+		return "", err
+	}
+	if strings.Count(tableName, ".") == 2 || d.config == nil || resolved.ProjectID == d.config.ProjectID {
+		return tableName, nil
+	}
+
+	return QuoteIdentifier(resolved.String()), nil
+}
+
+// withTableName returns a copy of the asset that is named as the given table reference in the generated SQL, see
+// TableReference, or the asset itself if the reference is its name.
+func withTableName(asset *pipeline.Asset, reference string) *pipeline.Asset {
+	if reference == asset.Name {
+		return asset
+	}
+
+	renamed := *asset
+	renamed.Name = reference
+	return &renamed
+}
+
+func (d *Client) getTableRef(tableName string) (*bigquery.Table, error) {
+	resolved, err := d.ResolveTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.client.DatasetInProject(resolved.ProjectID, resolved.DatasetID).Table(resolved.TableID), nil
 }
 
 func (d *Client) UpdateTableMetadataIfNotExist(ctx context.Context, asset *pipeline.Asset) error {
 	anyColumnHasDescription := false
+	anyColumnHasSettings := false
 	colsByName := make(map[string]*pipeline.Column, len(asset.Columns))
 	for _, col := range asset.Columns {
 		colsByName[col.Name] = &col
 		if col.Description != "" {
 			anyColumnHasDescription = true
 		}
+		if col.Collation != "" || col.DefaultValueExpression != "" {
+			anyColumnHasSettings = true
+		}
+	}
+
+	description := asset.Description
+	if description == "" && d.config != nil && d.config.DescriptionFromQueryComment {
+		description = DescriptionFromQueryComment(asset.ExecutableFile.Content)
+	}
+
+	var labels map[string]string
+	if asset.Labels != nil {
+		var err error
+		labels, err = sanitizeTableLabels(asset.Labels)
+		if err != nil {
+			return errors.Wrapf(err, "invalid labels on asset '%s'", asset.Name)
+		}
 	}
 
-	if asset.Description == "" && (len(asset.Columns) == 0 || !anyColumnHasDescription) {
+	if description == "" && (len(asset.Columns) == 0 || !anyColumnHasDescription) && !anyColumnHasSettings && labels == nil && !asset.Materialization.NeverExpire {
 		return NoMetadataUpdatedError{}
 	}
 	tableRef, err := d.getTableRef(asset.Name)
@@ -245,61 +585,208 @@ func (d *Client) UpdateTableMetadataIfNotExist(ctx context.Context, asset *pipel
 		return err
 	}
 
-	meta, err := tableRef.Metadata(ctx)
-	if err != nil {
-		var apiErr *googleapi.Error
-		if errors.As(err, &apiErr) && apiErr.Code == 404 {
+	retries := DefaultMetadataUpdateRetries
+	if d.config != nil && d.config.MetadataUpdateRetries != 0 {
+		retries = max(d.config.MetadataUpdateRetries, 0)
+	}
+
+	// the update is conditional on the ETag of the metadata it was built from, if the table changed in the
+	// meantime the metadata is read again and the changes are re-applied on top of it
+	for attempt := 0; ; attempt++ {
+		meta, err := tableRef.Metadata(ctx)
+		if err != nil {
+			var apiErr *googleapi.Error
+			if errors.As(err, &apiErr) && apiErr.Code == 404 {
+				return nil
+			}
+			return err
+		}
+		schema := meta.Schema
+		colsChanged := applyColumnDescriptions(schema, colsByName, "")
+		if applyColumnSettings(schema, colsByName, "") {
+			colsChanged = true
+		}
+
+		update := bigquery.TableMetadataToUpdate{}
+
+		if colsChanged {
+			update.Schema = schema
+		}
+
+		if description != "" {
+			update.Description = description
+		}
+		primaryKeys := asset.ColumnNamesWithPrimaryKey()
+		if len(primaryKeys) > 0 {
+			update.TableConstraints = &bigquery.TableConstraints{
+				PrimaryKey: &bigquery.PrimaryKey{Columns: primaryKeys},
+			}
+		}
+
+		// the labels are only sent when they differ from the ones on the table, the watermark is kept regardless
+		labelsChanged := labels != nil && applyTableLabels(&update, meta.Labels, labels, d.watermarkLabel())
+		expirationChanged := neverExpireUpdate(&update, meta, asset)
+		if !colsChanged && description == "" && len(primaryKeys) == 0 && !labelsChanged && !expirationChanged {
 			return nil
 		}
+
+		// the context might have been cancelled while the metadata was being read, don't start a write in that case
+		if err := ctx.Err(); err != nil {
+			return errors.Wrap(err, "metadata update aborted")
+		}
+
+		_, err = tableRef.Update(ctx, update, meta.ETag)
+		if err == nil {
+			return nil
+		}
+		if !IsConcurrentModification(err) || attempt >= retries {
+			return errors.Wrap(err, "failed to update table metadata")
+		}
+	}
+}
+
+// ClearTableExpiration removes the expiration of the table of the asset if the asset never expires. Statements cannot
+// opt the tables they create out of the default table expiration of their dataset reliably, therefore the expiration
+// is cleared through a metadata update once the table is created.
+func (d *Client) ClearTableExpiration(ctx context.Context, asset *pipeline.Asset) error {
+	if !asset.Materialization.NeverExpire {
+		return nil
+	}
+
+	tableRef, err := d.getTableRef(asset.Name)
+	if err != nil {
 		return err
 	}
-	schema := meta.Schema
-	colsChanged := false
+	meta, err := tableRef.Metadata(ctx)
+	if IsNotFound(err) {
+		// nothing was created, e.g. for assets that are not materialized
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the metadata of table '%s'", asset.Name)
+	}
+
+	update := bigquery.TableMetadataToUpdate{}
+	if !neverExpireUpdate(&update, meta, asset) {
+		return nil
+	}
+	// only the expiration is sent, there is nothing a concurrent change could be overwritten with
+	if _, err := tableRef.Update(ctx, update, ""); err != nil {
+		return errors.Wrapf(err, "failed to remove the expiration of table '%s'", asset.Name)
+	}
+
+	return nil
+}
+
+// applyColumnDescriptions sets the descriptions of the columns on the matching schema fields, walking the fields
+// of records, including repeated ones, which the columns address by their dotted path, e.g. `address.city`.
+func applyColumnDescriptions(schema bigquery.Schema, colsByName map[string]*pipeline.Column, prefix string) bool {
+	changed := false
 	for _, field := range schema {
-		if col, ok := colsByName[field.Name]; ok {
+		path := prefix + field.Name
+		// the descriptions the columns do not declare are left as they are on the table
+		if col, ok := colsByName[path]; ok && col.Description != "" && field.Description != col.Description {
 			field.Description = col.Description
-			colsChanged = true
+			changed = true
+		}
+
+		if field.Type == bigquery.RecordFieldType && applyColumnDescriptions(field.Schema, colsByName, path+".") {
+			changed = true
 		}
 	}
 
-	update := bigquery.TableMetadataToUpdate{}
+	return changed
+}
 
-	if colsChanged {
-		update.Schema = schema
-	}
+// applyColumnSettings sets the collations and default value expressions the columns declare on the matching schema
+// fields whose settings drifted from them, the same changes DiffSchema reports as SchemaChangeCollationChanged and
+// SchemaChangeDefaultChanged. The settings the columns do not declare are left as they are on the table.
+func applyColumnSettings(schema bigquery.Schema, colsByName map[string]*pipeline.Column, prefix string) bool {
+	changed := false
+	for _, field := range schema {
+		path := prefix + field.Name
+		if col, ok := colsByName[path]; ok {
+			if col.Collation != "" && field.Collation != col.Collation {
+				field.Collation = col.Collation
+				changed = true
+			}
+			if col.DefaultValueExpression != "" && !isSameDefaultValue(field.DefaultValueExpression, col.DefaultValueExpression) {
+				field.DefaultValueExpression = col.DefaultValueExpression
+				changed = true
+			}
+		}
 
-	if asset.Description != "" {
-		update.Description = asset.Description
-	}
-	primaryKeys := asset.ColumnNamesWithPrimaryKey()
-	if len(primaryKeys) > 0 {
-		update.TableConstraints = &bigquery.TableConstraints{
-			PrimaryKey: &bigquery.PrimaryKey{Columns: primaryKeys},
+		if field.Type == bigquery.RecordFieldType && applyColumnSettings(field.Schema, colsByName, path+".") {
+			changed = true
 		}
 	}
 
-	if _, err = tableRef.Update(ctx, update, meta.ETag); err != nil {
-		return errors.Wrap(err, "failed to update table metadata")
+	return changed
+}
+
+// DescriptionFromQueryComment extracts the description from a `-- description: ...` comment in the leading
+// comment block of the given query, returning an empty string if there is none.
+func DescriptionFromQueryComment(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		comment, isComment := strings.CutPrefix(line, "--")
+		if !isComment {
+			// the leading comment block is over
+			return ""
+		}
+
+		comment = strings.TrimSpace(comment)
+		if len(comment) >= len("description:") && strings.EqualFold(comment[:len("description:")], "description:") {
+			return strings.TrimSpace(comment[len("description:"):])
+		}
 	}
 
-	return nil
+	return ""
 }
 
+// formatError maps the errors of BigQuery to the typed errors of this package, API and job errors that are not
+// recognized otherwise become a BigQueryError. Any other error is returned as is.
 func formatError(err error) error {
+	if concurrentErr := asConcurrentModificationError(err); concurrentErr != nil {
+		return concurrentErr
+	}
+
+	if bytesErr := asBytesBilledLimitError(err); bytesErr != nil {
+		return bytesErr
+	}
+
+	if filterErr := asPartitionFilterRequiredError(err); filterErr != nil {
+		return filterErr
+	}
+
+	if reservationErr := asReservationPermissionError(err); reservationErr != nil {
+		return reservationErr
+	}
+
 	var googleError *googleapi.Error
-	if !errors.As(err, &googleError) {
-		return err
+	if errors.As(err, &googleError) {
+		return newBigQueryError(googleError)
 	}
 
-	if googleError.Code == 404 || googleError.Code == 400 {
-		return fmt.Errorf("%s", googleError.Message)
+	var jobError *bigquery.Error
+	if errors.As(err, &jobError) {
+		return newBigQueryJobError(jobError)
 	}
 
-	return googleError
+	return err
 }
 
-// Test runs a simple query (SELECT 1) to validate the connection.
+// Test runs a simple query (SELECT 1) to validate the connection, or delegates to PingMetadata if
+// Config.PingWithMetadata is set.
 func (d *Client) Ping(ctx context.Context) error {
+	if d.config != nil && d.config.PingWithMetadata {
+		return d.PingMetadata(ctx)
+	}
+
 	// Define the test query
 	q := query.Query{
 		Query: "SELECT 1",
@@ -314,9 +801,24 @@ func (d *Client) Ping(ctx context.Context) error {
 	return nil // Return nil if the query runs successfully
 }
 
+// PingMetadata validates the credentials and the project by listing at most one of the datasets of the project.
+// Unlike Ping it does not run a query job, therefore it neither uses query slots nor depends on anything but the
+// permission to list the datasets.
+func (d *Client) PingMetadata(ctx context.Context) error {
+	it := d.client.Datasets(ctx)
+	it.PageInfo().MaxSize = 1
+
+	if _, err := it.Next(); err != nil && !errors.Is(err, iterator.Done) {
+		return errors.Wrap(formatError(err), "failed to list the datasets of the Bigquery connection")
+	}
+
+	return nil
+}
+
 func (d *Client) IsPartitioningOrClusteringMismatch(ctx context.Context, meta *bigquery.TableMetadata, asset *pipeline.Asset) bool {
-	if meta.TimePartitioning != nil || meta.RangePartitioning != nil || asset.Materialization.PartitionBy != "" || len(asset.Materialization.ClusterBy) > 0 {
-		if !IsSamePartitioning(meta, asset) || !IsSameClustering(meta, asset) {
+	spec := PartitioningSpecFromMetadata(meta)
+	if spec.IsPartitioned() || asset.Materialization.PartitionBy != "" || len(asset.Materialization.ClusterBy) > 0 {
+		if !spec.MatchesPartitioning(asset) || !spec.MatchesClustering(asset) {
 			return true
 		}
 	}
@@ -324,52 +826,11 @@ func (d *Client) IsPartitioningOrClusteringMismatch(ctx context.Context, meta *b
 }
 
 func IsSamePartitioning(meta *bigquery.TableMetadata, asset *pipeline.Asset) bool {
-	if asset.Materialization.PartitionBy != "" &&
-		meta.TimePartitioning == nil &&
-		meta.RangePartitioning == nil {
-		return false
-	}
-
-	if meta.TimePartitioning == nil && meta.RangePartitioning == nil {
-		return true
-	}
-
-	if meta.TimePartitioning != nil {
-		if meta.TimePartitioning.Field != asset.Materialization.PartitionBy {
-			return false
-		}
-	}
-	if meta.RangePartitioning != nil {
-		if meta.RangePartitioning.Field != asset.Materialization.PartitionBy {
-			return false
-		}
-	}
-	return true
+	return PartitioningSpecFromMetadata(meta).MatchesPartitioning(asset)
 }
 
 func IsSameClustering(meta *bigquery.TableMetadata, asset *pipeline.Asset) bool {
-	if len(asset.Materialization.ClusterBy) > 0 &&
-		(meta.Clustering == nil || len(meta.Clustering.Fields) == 0) {
-		return false
-	}
-	if meta.Clustering == nil {
-		return true
-	}
-
-	bigQueryFields := meta.Clustering.Fields
-	userFields := asset.Materialization.ClusterBy
-
-	if len(bigQueryFields) != len(userFields) {
-		return false
-	}
-
-	for i := range bigQueryFields {
-		if bigQueryFields[i] != userFields[i] {
-			return false
-		}
-	}
-
-	return true
+	return PartitioningSpecFromMetadata(meta).MatchesClustering(asset)
 }
 
 func (d *Client) CreateDataSetIfNotExist(asset *pipeline.Asset, ctx context.Context) error {
@@ -380,7 +841,11 @@ func (d *Client) CreateDataSetIfNotExist(asset *pipeline.Asset, ctx context.Cont
 
 	switch len(tableComponents) {
 	case 2:
-		projectID = d.config.ProjectID
+		var err error
+		projectID, err = d.config.DefaultTableProject()
+		if err != nil {
+			return err
+		}
 		datasetName = tableComponents[0]
 	case 3:
 		datasetName = tableComponents[1]
@@ -390,9 +855,11 @@ func (d *Client) CreateDataSetIfNotExist(asset *pipeline.Asset, ctx context.Cont
 	}
 
 	cacheKey := fmt.Sprintf("%s.%s", projectID, datasetName)
+	location := d.datasetLocation(asset)
 
-	if _, exists := datasetNameCache.Load(cacheKey); exists {
-		return nil
+	// the cache holds the location of the datasets known to exist, whose settings were reconciled before caching them
+	if cached, exists := d.cachedDatasetLocation(cacheKey); exists {
+		return checkDatasetLocation(cacheKey, cached, location)
 	}
 
 	lock, _ := datasetLocks.LoadOrStore(cacheKey, &sync.Mutex{})
@@ -401,37 +868,196 @@ func (d *Client) CreateDataSetIfNotExist(asset *pipeline.Asset, ctx context.Cont
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	if _, exists := datasetNameCache.Load(cacheKey); exists {
-		return nil
+	if cached, exists := d.cachedDatasetLocation(cacheKey); exists {
+		return checkDatasetLocation(cacheKey, cached, location)
+	}
+
+	if d.config != nil && d.config.DatasetCreationTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.config.DatasetCreationTimeout)
+		defer cancel()
 	}
 
 	dataset := d.client.DatasetInProject(projectID, datasetName)
-	_, err := dataset.Metadata(ctx)
+	var meta *bigquery.DatasetMetadata
+	err := d.withRetry(ctx, func() error {
+		var err error
+		meta, err = dataset.Metadata(ctx)
+		return err
+	})
 	if err != nil {
 		var apiErr *googleapi.Error
 		if errors.As(err, &apiErr) && apiErr.Code == 404 {
-			if err := dataset.Create(ctx, &bigquery.DatasetMetadata{}); err != nil {
-				return fmt.Errorf("failed to create dataset '%s': %w", datasetName, err)
+			if err := d.createDataset(ctx, projectID, datasetName, location, d.EncryptionKey(asset)); err != nil {
+				return err
 			}
-			datasetNameCache.Store(cacheKey, true)
 		} else {
 			return fmt.Errorf("failed to fetch metadata for table '%s': %w", tableName, err)
 		}
+	} else {
+		if err := checkDatasetLocation(cacheKey, meta.Location, location); err != nil {
+			return err
+		}
+		location = meta.Location
+
+		if _, err := d.reconcileDatasetSettings(ctx, projectID, datasetName, meta); err != nil {
+			return err
+		}
+	}
+
+	d.cacheDatasetLocation(cacheKey, location)
+
+	return nil
+}
+
+// datasetCacheTTL returns how long datasets are cached, zero if the cache is disabled.
+func (d *Client) datasetCacheTTL() time.Duration {
+	if d.config == nil || d.config.DatasetCacheTTL == 0 {
+		return DefaultDatasetCacheTTL
+	}
+
+	return max(d.config.DatasetCacheTTL, 0)
+}
+
+// cachedDatasetLocation returns the location of the dataset if it is cached as existing and the entry has not expired.
+func (d *Client) cachedDatasetLocation(cacheKey string) (string, bool) {
+	if d.datasetCacheTTL() == 0 {
+		return "", false
+	}
+
+	cached, exists := datasetNameCache.Load(cacheKey)
+	if !exists {
+		return "", false
+	}
+
+	entry := cached.(datasetCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		datasetNameCache.CompareAndDelete(cacheKey, cached)
+		return "", false
+	}
+
+	return entry.location, true
+}
+
+func (d *Client) cacheDatasetLocation(cacheKey, location string) {
+	ttl := d.datasetCacheTTL()
+	if ttl == 0 {
+		return
+	}
+
+	datasetNameCache.Store(cacheKey, datasetCacheEntry{location: location, expiresAt: time.Now().Add(ttl)})
+}
+
+// datasetLocation returns the location the dataset of the asset is expected in: the DatasetLocationParameter of
+// the asset if given, the configured location otherwise. An empty location leaves it to the project default.
+func (d *Client) datasetLocation(asset *pipeline.Asset) string {
+	if location := asset.Parameters[DatasetLocationParameter]; location != "" {
+		return location
+	}
+	if d.config != nil {
+		return d.config.Location
+	}
+
+	return ""
+}
+
+// checkDatasetLocation fails if the dataset is in a different location than expected. Locations are compared
+// case-insensitively, since BigQuery accepts both `EU` and `eu`; unknown locations are not checked.
+func checkDatasetLocation(dataset, actual, expected string) error {
+	if actual == "" || expected == "" || strings.EqualFold(actual, expected) {
+		return nil
+	}
+
+	return fmt.Errorf("dataset '%s' is in location '%s', but the asset expects it in '%s'; the location of a dataset cannot be changed, move the asset to a dataset in '%s' instead", dataset, actual, expected, expected)
+}
+
+func (d *Client) createDataset(ctx context.Context, projectID, datasetName, location, kmsKey string) error {
+	settings := DatasetSettings{}
+	if d.config != nil {
+		settings = d.config.DatasetDefaults
+	}
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	dataset := d.client.DatasetInProject(projectID, datasetName)
+	meta := &bigquery.DatasetMetadata{
+		Location:                   location,
+		DefaultCollation:           settings.DefaultCollation,
+		DefaultPartitionExpiration: settings.DefaultPartitionExpiration,
+		DefaultTableExpiration:     settings.DefaultTableExpiration,
+		StorageBillingModel:        strings.ToUpper(settings.StorageBillingModel),
+	}
+	if len(settings.Labels) > 0 {
+		meta.Labels = make(map[string]string, len(settings.Labels))
+		for key, value := range settings.Labels {
+			meta.Labels[sanitizeLabelKey(key)] = sanitizeLabelValue(value)
+		}
+	}
+	if kmsKey != "" {
+		meta.DefaultEncryptionConfig = &bigquery.EncryptionConfig{KMSKeyName: kmsKey}
+	}
+	err := d.withRetry(ctx, func() error {
+		return dataset.Create(ctx, meta)
+	})
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict {
+			// another process created the dataset in the meantime, possibly with other settings
+			if settings.IsEmpty() {
+				return nil
+			}
+			meta, err := dataset.Metadata(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to fetch metadata for dataset '%s': %w", datasetName, err)
+			}
+			_, err = d.reconcileDatasetSettings(ctx, projectID, datasetName, meta)
+			return err
+		}
+		return fmt.Errorf("failed to create dataset '%s': %w", datasetName, err)
+	}
+
+	// the rounding mode is not part of the dataset metadata API, therefore it is applied through DDL
+	if settings.DefaultRoundingMode != "" {
+		drifts := DetectDatasetSettingsDrift(DatasetSettings{}, DatasetSettings{DefaultRoundingMode: settings.DefaultRoundingMode})
+		qq := BuildAlterDatasetOptionsQuery(projectID, datasetName, drifts)
+		if err := d.RunQueryWithoutResult(ctx, &query.Query{Query: qq}); err != nil {
+			return fmt.Errorf("failed to set the default rounding mode of dataset '%s': %w", datasetName, err)
+		}
 	}
 
 	return nil
 }
 
+// materializationTableTypes maps the materialization types to the type of the table BigQuery creates for them.
+var materializationTableTypes = map[pipeline.MaterializationType]bigquery.TableType{
+	pipeline.MaterializationTypeTable:            bigquery.RegularTable,
+	pipeline.MaterializationTypeView:             bigquery.ViewTable,
+	pipeline.MaterializationTypeMaterializedView: bigquery.MaterializedView,
+}
+
 func (d *Client) IsMaterializationTypeMismatch(ctx context.Context, meta *bigquery.TableMetadata, asset *pipeline.Asset) bool {
 	if asset.Materialization.Type == pipeline.MaterializationTypeNone {
 		return false
 	}
 
-	tableType := meta.Type
-	return !strings.EqualFold(string(tableType), string(asset.Materialization.Type))
+	if tableType, ok := materializationTableTypes[asset.Materialization.Type]; ok {
+		return meta.Type != tableType
+	}
+
+	return !strings.EqualFold(string(meta.Type), string(asset.Materialization.Type))
 }
 
+// DropTableOnMismatch drops the table if it cannot be turned into the one the asset expects, e.g. if its
+// materialization type or its partitioning differ, so that it is recreated by the query of the asset. The settings
+// that can be changed in place, such as the partition options or a change of clustering columns, are updated
+// instead, see PartitionOptionsUpdate and ClusteringUpdate.
 func (d *Client) DropTableOnMismatch(ctx context.Context, tableName string, asset *pipeline.Asset) error {
+	// an invalid config would always look like a mismatch, the table must not be dropped because of it
+	if err := ValidateMaterialization(asset); err != nil {
+		return err
+	}
+
 	tableRef, err := d.getTableRef(tableName)
 	if err != nil {
 		return err
@@ -444,38 +1070,101 @@ func (d *Client) DropTableOnMismatch(ctx context.Context, tableName string, asse
 		}
 		return fmt.Errorf("failed to fetch metadata for table '%s': %w", tableName, err)
 	}
-	if d.IsMaterializationTypeMismatch(ctx, meta, asset) || d.IsPartitioningOrClusteringMismatch(ctx, meta, asset) {
-		if err := tableRef.Delete(ctx); err != nil {
-			return fmt.Errorf("failed to delete table '%s': %w", tableName, err)
-		}
+	clustering := ClusteringUpdate(meta, asset)
+	if d.IsMaterializationTypeMismatch(ctx, meta, asset) || (clustering == nil && d.IsPartitioningOrClusteringMismatch(ctx, meta, asset)) {
+		return deleteTable(ctx, tableRef, tableName)
+	}
+
+	// the partition options, the clustering and the expiration are table metadata, changing them does not require
+	// recreating the table
+	update := PartitionOptionsUpdate(meta, asset)
+	partitionOptionsChanged := update != nil
+	if !partitionOptionsChanged {
+		update = &bigquery.TableMetadataToUpdate{}
+	}
+	if clustering != nil {
+		update.Clustering = clustering
+	}
+	if expirationChanged := neverExpireUpdate(update, meta, asset); !partitionOptionsChanged && clustering == nil && !expirationChanged {
+		return nil
+	}
+
+	if _, err := tableRef.Update(ctx, *update, meta.ETag); err != nil {
+		return fmt.Errorf("failed to update the metadata of table '%s': %w", tableName, err)
 	}
 	return nil
 }
 
-func (d *Client) BuildTableExistsQuery(tableName string) (string, error) {
-	tableComponents := strings.Split(tableName, ".")
-	for _, component := range tableComponents {
-		if component == "" {
-			return "", fmt.Errorf("table name must be in dataset.table or project.dataset.table format, '%s' given", tableName)
+// SyncMaterializedView prepares the materialized view of the asset for its `CREATE MATERIALIZED VIEW IF NOT EXISTS`
+// statement: the view is dropped if its definition differs from the given one, so that the statement recreates it,
+// and its refresh options are updated in place if only they differ. A view whose definition is unchanged is kept,
+// recreating it would recompute all of its data. A table or view in its place is reported, it is only replaced by a
+// full refresh.
+func (d *Client) SyncMaterializedView(ctx context.Context, asset *pipeline.Asset, definition string) error {
+	if asset.Materialization.Type != pipeline.MaterializationTypeMaterializedView {
+		return nil
+	}
+
+	tableRef, err := d.getTableRef(asset.Name)
+	if err != nil {
+		return err
+	}
+	meta, err := tableRef.Metadata(ctx)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil
 		}
+		return errors.Wrapf(formatError(err), "failed to fetch metadata for table '%s'", asset.Name)
+	}
+	if meta.Type != bigquery.MaterializedView || meta.MaterializedView == nil {
+		return fmt.Errorf("table '%s' exists as a %s, run a full refresh to replace it with the materialized view", asset.Name, meta.Type)
 	}
 
-	var datasetRef, targetTable string
+	if normalizeDefinition(meta.MaterializedView.Query) != normalizeDefinition(definition) {
+		return deleteTable(ctx, tableRef, asset.Name)
+	}
 
-	switch len(tableComponents) {
-	case 2:
-		datasetRef = fmt.Sprintf("%s.%s.INFORMATION_SCHEMA.TABLES", d.config.ProjectID, tableComponents[0])
-		targetTable = tableComponents[1]
-	case 3:
-		datasetRef = fmt.Sprintf("%s.%s.INFORMATION_SCHEMA.TABLES", tableComponents[0], tableComponents[1])
-		targetTable = tableComponents[2]
-	default:
-		return "", fmt.Errorf("table name must be in dataset.table or project.dataset.table format, '%s' given", tableName)
+	mat := asset.Materialization
+	options := make([]string, 0, 2)
+	if mat.EnableRefresh != nil && *mat.EnableRefresh != meta.MaterializedView.EnableRefresh {
+		options = append(options, fmt.Sprintf("enable_refresh=%t", *mat.EnableRefresh))
+	}
+	if interval := time.Duration(mat.RefreshIntervalMinutes) * time.Minute; interval > 0 && interval != meta.MaterializedView.RefreshInterval {
+		options = append(options, fmt.Sprintf("refresh_interval_minutes=%d", mat.RefreshIntervalMinutes))
+	}
+	if len(options) == 0 {
+		return nil
 	}
 
-	// Use EXISTS to return true or false
-	query := fmt.Sprintf(`
-		SELECT EXISTS (SELECT 1 FROM %s WHERE table_name = '%s')`, datasetRef, targetTable)
+	reference, err := d.TableReference(asset.Name)
+	if err != nil {
+		return err
+	}
+	q := fmt.Sprintf("ALTER MATERIALIZED VIEW %s SET OPTIONS(%s)", reference, strings.Join(options, ", "))
+	if err := d.RunQueryWithoutResult(ctx, &query.Query{Query: q}); err != nil {
+		return errors.Wrapf(err, "failed to update the refresh options of materialized view '%s'", asset.Name)
+	}
+
+	return nil
+}
+
+// normalizeDefinition collapses the whitespace of a query and drops its trailing semicolon, so that definitions
+// that only differ in formatting compare equal.
+func normalizeDefinition(q string) string {
+	return strings.TrimSuffix(strings.Join(strings.Fields(q), " "), ";")
+}
 
-	return strings.TrimSpace(query), nil
+// BuildTableExistsQuery builds a query that returns whether the given table exists. The table name is bound as a
+// query parameter rather than interpolated into the SQL, only the dataset is part of it, as a quoted identifier.
+func (d *Client) BuildTableExistsQuery(tableName string) (*query.Query, error) {
+	resolved, err := d.ResolveTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use EXISTS to return true or false
+	return &query.Query{
+		Query:      fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s.INFORMATION_SCHEMA.TABLES WHERE table_name = @table_name)", QuoteIdentifier(resolved.ProjectID+"."+resolved.DatasetID)),
+		Parameters: []query.Parameter{{Name: "table_name", Value: resolved.TableID}},
+	}, nil
 }
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -86,6 +88,15 @@ func TestDB_IsValid(t *testing.T) {
 			statusCode: http.StatusNotFound,
 			err:        errors.New("not found: Table project:schema.table was not found in location ABC"),
 		},
+		{
+			name:  "broken query fails without a dry run",
+			query: "select count(* from users",
+			response: map[string]interface{}{
+				"error": googleapi.Error{Code: 400, Message: "the dry run must not be issued"},
+			},
+			statusCode: http.StatusBadRequest,
+			err:        errors.New("unbalanced parentheses: 1 '(' not closed"),
+		},
 		{
 			name:  "no error returned",
 			query: "select * from users",
@@ -656,6 +667,506 @@ func TestDB_UpdateTableMetadataIfNotExists(t *testing.T) {
 	}
 }
 
+// cancelledAfterReadContext reports the cancellation only through Err, its Done channel never fires. The HTTP
+// transport watches Done, so the metadata read completes, and only an explicit check of the context before the
+// update notices that the pipeline was shut down in the meantime.
+type cancelledAfterReadContext struct {
+	context.Context
+	cancelled atomic.Bool
+}
+
+func (c *cancelledAfterReadContext) Err() error {
+	if c.cancelled.Load() {
+		return context.Canceled
+	}
+
+	return c.Context.Err()
+}
+
+func TestDB_UpdateTableMetadataIfNotExist_CancelledAfterRead(t *testing.T) {
+	t.Parallel()
+
+	ctx := &cancelledAfterReadContext{Context: context.Background()}
+
+	var updateAttempted atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/datasets/myschema/tables/mytable", testProjectID) {
+			// the pipeline is shut down while the metadata is being read
+			ctx.cancelled.Store(true)
+			_ = json.NewEncoder(w).Encode(&bigquery2.Table{Description: "some old description"})
+			return
+		}
+
+		updateAttempted.Store(true)
+		_ = json.NewEncoder(w).Encode(&bigquery2.Table{Description: "test123"})
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	err := d.UpdateTableMetadataIfNotExist(ctx, &pipeline.Asset{Name: "myschema.mytable", Description: "test123"})
+	require.ErrorIs(t, err, context.Canceled)
+	assert.False(t, updateAttempted.Load(), "no update must be attempted after the context is cancelled")
+}
+
+func TestDB_UpdateTableMetadataIfNotExist_RetriesETagConflict(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		retries     int
+		conflicts   int
+		wantPatches int
+		wantErr     bool
+	}{
+		{name: "conflict then success", conflicts: 1, wantPatches: 2},
+		{name: "retries exhausted", retries: 2, conflicts: 5, wantPatches: 3, wantErr: true},
+		{name: "retries disabled", retries: -1, conflicts: 1, wantPatches: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var reads int
+			var ifMatch []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != fmt.Sprintf("/projects/%s/datasets/myschema/tables/mytable", testProjectID) {
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+					return
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				if r.Method == http.MethodPatch {
+					ifMatch = append(ifMatch, r.Header.Get("If-Match"))
+					if len(ifMatch) <= tt.conflicts {
+						http.Error(w, `{"error": {"code": 412, "message": "Precondition check failed."}}`, http.StatusPreconditionFailed)
+						return
+					}
+					_ = json.NewEncoder(w).Encode(&bigquery2.Table{})
+					return
+				}
+
+				reads++
+				_ = json.NewEncoder(w).Encode(&bigquery2.Table{Etag: fmt.Sprintf("etag-%d", reads)})
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.MetadataUpdateRetries = tt.retries
+
+			err := d.UpdateTableMetadataIfNotExist(context.Background(), &pipeline.Asset{Name: "myschema.mytable", Description: "test123"})
+			if tt.wantErr {
+				require.ErrorContains(t, err, "failed to update table metadata")
+			} else {
+				require.NoError(t, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			require.Len(t, ifMatch, tt.wantPatches)
+			for i, etag := range ifMatch {
+				// every attempt must be based on freshly read metadata
+				assert.Equal(t, fmt.Sprintf("etag-%d", i+1), etag)
+			}
+		})
+	}
+}
+
+func TestDescriptionFromQueryComment(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "leading description comment",
+			content: "-- description: Daily active users per country\nSELECT 1",
+			want:    "Daily active users per country",
+		},
+		{
+			name:    "description after other comments and blank lines",
+			content: "\n-- owner: data team\n--Description:  Orders table \n\nSELECT 1",
+			want:    "Orders table",
+		},
+		{
+			name:    "comments after the leading block are ignored",
+			content: "SELECT 1\n-- description: not the description",
+			want:    "",
+		},
+		{
+			name:    "no description comment",
+			content: "-- just a comment\nSELECT 1",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, DescriptionFromQueryComment(tt.content))
+		})
+	}
+}
+
+func TestDB_UpdateTableMetadataIfNotExist_NestedColumns(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var patched *bigquery2.Table
+	var patchCount int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != fmt.Sprintf("/projects/%s/datasets/myschema/tables/mytable", testProjectID) {
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+			return
+		}
+
+		if r.Method == http.MethodPatch {
+			var table bigquery2.Table
+			_ = json.NewDecoder(r.Body).Decode(&table)
+			mu.Lock()
+			patched = &table
+			patchCount++
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(&table)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(&bigquery2.Table{
+			Etag: "etag-1",
+			Schema: &bigquery2.TableSchema{
+				Fields: []*bigquery2.TableFieldSchema{
+					{Name: "id", Type: "INTEGER"},
+					{Name: "address", Type: "RECORD", Fields: []*bigquery2.TableFieldSchema{
+						{Name: "city", Type: "STRING"},
+						{Name: "zip", Type: "STRING"},
+					}},
+					{Name: "items", Type: "RECORD", Mode: "REPEATED", Fields: []*bigquery2.TableFieldSchema{
+						{Name: "sku", Type: "STRING"},
+						{Name: "price", Type: "RECORD", Fields: []*bigquery2.TableFieldSchema{
+							{Name: "amount", Type: "NUMERIC"},
+						}},
+					}},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	err := d.UpdateTableMetadataIfNotExist(context.Background(), &pipeline.Asset{
+		Name: "myschema.mytable",
+		Columns: []pipeline.Column{
+			{Name: "id", Description: "the id"},
+			{Name: "address.city", Description: "the city"},
+			{Name: "items.sku", Description: "the sku"},
+			{Name: "items.price.amount", Description: "the amount"},
+		},
+	})
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 1, patchCount)
+	require.NotNil(t, patched)
+
+	fields := patched.Schema.Fields
+	require.Len(t, fields, 3)
+	assert.Equal(t, "the id", fields[0].Description)
+	assert.Equal(t, "the city", fields[1].Fields[0].Description)
+	assert.Empty(t, fields[1].Fields[1].Description)
+	assert.Equal(t, "REPEATED", fields[2].Mode)
+	assert.Equal(t, "the sku", fields[2].Fields[0].Description)
+	assert.Equal(t, "the amount", fields[2].Fields[1].Fields[0].Description)
+}
+
+func TestDB_UpdateTableMetadataIfNotExist_ColumnSettings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		columns         []pipeline.Column
+		wantPatch       bool
+		wantCollation   string
+		wantDefault     string
+		wantDescription string
+	}{
+		{
+			name: "drifted collation and default value are updated",
+			columns: []pipeline.Column{
+				{Name: "name", Collation: "und:ci", DefaultValueExpression: "'n/a'"},
+			},
+			wantPatch:       true,
+			wantCollation:   "und:ci",
+			wantDefault:     "'n/a'",
+			wantDescription: "name of the user",
+		},
+		{
+			name: "matching settings are not patched",
+			columns: []pipeline.Column{
+				{Name: "name", Collation: "und:ci", DefaultValueExpression: "  'unknown'"},
+			},
+		},
+		{
+			name: "undeclared settings and descriptions are kept",
+			columns: []pipeline.Column{
+				{Name: "id", Description: "the ID"},
+				{Name: "name", DefaultValueExpression: "'n/a'"},
+			},
+			wantPatch:       true,
+			wantCollation:   "und:ci",
+			wantDefault:     "'n/a'",
+			wantDescription: "name of the user",
+		},
+		{
+			name: "matching descriptions are not patched",
+			columns: []pipeline.Column{
+				{Name: "name", Description: "name of the user", Collation: "und:ci"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var patched *bigquery2.Table
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPatch {
+					var table bigquery2.Table
+					_ = json.NewDecoder(r.Body).Decode(&table)
+					mu.Lock()
+					patched = &table
+					mu.Unlock()
+					_ = json.NewEncoder(w).Encode(&table)
+					return
+				}
+
+				_ = json.NewEncoder(w).Encode(&bigquery2.Table{
+					Etag: "etag-1",
+					Schema: &bigquery2.TableSchema{
+						Fields: []*bigquery2.TableFieldSchema{
+							{Name: "id", Type: "INTEGER"},
+							{Name: "name", Type: "STRING", Description: "name of the user", Collation: "und:ci", DefaultValueExpression: "'unknown'"},
+						},
+					},
+				})
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			err := d.UpdateTableMetadataIfNotExist(context.Background(), &pipeline.Asset{
+				Name:    "myschema.mytable",
+				Columns: tt.columns,
+			})
+			require.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !tt.wantPatch {
+				assert.Nil(t, patched, "the table must not be patched when nothing drifted")
+				return
+			}
+			require.NotNil(t, patched)
+			require.Len(t, patched.Schema.Fields, 2)
+			assert.Equal(t, tt.wantCollation, patched.Schema.Fields[1].Collation)
+			assert.Equal(t, tt.wantDefault, patched.Schema.Fields[1].DefaultValueExpression)
+			assert.Equal(t, tt.wantDescription, patched.Schema.Fields[1].Description)
+		})
+	}
+}
+
+func TestDB_UpdateTableMetadataIfNotExist_DescriptionFromQueryComment(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		enabled         bool
+		asset           *pipeline.Asset
+		wantDescription string
+		wantErr         error
+	}{
+		{
+			name:    "comment is used when enabled",
+			enabled: true,
+			asset: &pipeline.Asset{
+				Name:           "myschema.mytable",
+				ExecutableFile: pipeline.ExecutableFile{Content: "-- description: from the comment\nSELECT 1"},
+			},
+			wantDescription: "from the comment",
+		},
+		{
+			name:    "explicit description wins",
+			enabled: true,
+			asset: &pipeline.Asset{
+				Name:           "myschema.mytable",
+				Description:    "explicit",
+				ExecutableFile: pipeline.ExecutableFile{Content: "-- description: from the comment\nSELECT 1"},
+			},
+			wantDescription: "explicit",
+		},
+		{
+			name: "comment is ignored unless enabled",
+			asset: &pipeline.Asset{
+				Name:           "myschema.mytable",
+				ExecutableFile: pipeline.ExecutableFile{Content: "-- description: from the comment\nSELECT 1"},
+			},
+			wantErr: NoMetadataUpdatedError{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var patched *bigquery2.Table
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != fmt.Sprintf("/projects/%s/datasets/myschema/tables/mytable", testProjectID) {
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+					return
+				}
+
+				if r.Method == http.MethodPatch {
+					var table bigquery2.Table
+					_ = json.NewDecoder(r.Body).Decode(&table)
+					mu.Lock()
+					patched = &table
+					mu.Unlock()
+					_ = json.NewEncoder(w).Encode(&table)
+					return
+				}
+
+				_ = json.NewEncoder(w).Encode(&bigquery2.Table{Description: "old"})
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.DescriptionFromQueryComment = tt.enabled
+
+			err := d.UpdateTableMetadataIfNotExist(context.Background(), tt.asset)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			require.NotNil(t, patched)
+			assert.Equal(t, tt.wantDescription, patched.Description)
+		})
+	}
+}
+
+func TestDB_UpdateTableMetadataIfNotExist_Labels(t *testing.T) {
+	t.Parallel()
+
+	value := func(s string) *string { return &s }
+
+	tests := []struct {
+		name          string
+		labels        map[string]string
+		current       map[string]string
+		wantPatch     bool
+		wantLabels    map[string]*string
+		wantErr       string
+		wantErrIsNoop bool
+	}{
+		{
+			name:          "no labels on the asset leaves the table alone",
+			current:       map[string]string{"team": "growth"},
+			wantErrIsNoop: true,
+		},
+		{
+			name:    "matching labels are not sent",
+			labels:  map[string]string{"Team": "Growth"},
+			current: map[string]string{"team": "growth"},
+		},
+		{
+			name:      "labels are sanitized, changed and removed",
+			labels:    map[string]string{"Team": "Data Platform", "cost-center": "42"},
+			current:   map[string]string{"team": "growth", "cost-center": "42", "old": "x", DefaultWatermarkLabel: "1714557600000000"},
+			wantPatch: true,
+			wantLabels: map[string]*string{
+				"team": value("data_platform"),
+				"old":  nil,
+			},
+		},
+		{
+			name:      "an empty map removes all labels",
+			labels:    map[string]string{},
+			current:   map[string]string{"team": "growth"},
+			wantPatch: true,
+			wantLabels: map[string]*string{
+				"team": nil,
+			},
+		},
+		{
+			name:    "colliding keys are rejected",
+			labels:  map[string]string{"Team": "a", "team": "b"},
+			wantErr: "invalid labels on asset 'myschema.mytable': labels 'Team' and 'team' both turn into the label key 'team'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var patched *struct {
+				Labels map[string]*string `json:"labels"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != fmt.Sprintf("/projects/%s/datasets/myschema/tables/mytable", testProjectID) {
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+					return
+				}
+
+				if r.Method == http.MethodPatch {
+					mu.Lock()
+					_ = json.NewDecoder(r.Body).Decode(&patched)
+					mu.Unlock()
+					_ = json.NewEncoder(w).Encode(&bigquery2.Table{})
+					return
+				}
+
+				_ = json.NewEncoder(w).Encode(&bigquery2.Table{Labels: tt.current})
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			err := d.UpdateTableMetadataIfNotExist(context.Background(), &pipeline.Asset{Name: "myschema.mytable", Labels: tt.labels})
+			switch {
+			case tt.wantErrIsNoop:
+				require.ErrorIs(t, err, NoMetadataUpdatedError{})
+			case tt.wantErr != "":
+				require.EqualError(t, err, tt.wantErr)
+			default:
+				require.NoError(t, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !tt.wantPatch {
+				assert.Nil(t, patched)
+				return
+			}
+			require.NotNil(t, patched)
+			assert.Equal(t, tt.wantLabels, patched.Labels)
+		})
+	}
+}
+
 func TestDB_SelectWithSchema(t *testing.T) {
 	t.Parallel()
 
@@ -783,36 +1294,90 @@ func TestDB_SelectWithSchema(t *testing.T) {
 					{"joe", "doe", int64(28)},
 				},
 				ColumnTypes: []string{"STRING", "STRING", "INTEGER"},
+				ColumnModes: []string{"NULLABLE", "NULLABLE", "NULLABLE"},
+
+				CanonicalColumnTypes: []string{"string", "string", "int64"},
 			},
 		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
-			server := httptest.NewServer(mockBqHandler(t, projectID, jobID, tt.jobSubmitResponse, tt.queryResultResponse))
-			defer server.Close()
-
-			client, err := bigquery.NewClient(
-				context.Background(),
-				projectID,
-				option.WithEndpoint(server.URL),
-				option.WithCredentials(&google.Credentials{
-					ProjectID: projectID,
-					TokenSource: oauth2.StaticTokenSource(&oauth2.Token{
-						AccessToken: "some-token",
-					}),
-				}),
-			)
-			require.NoError(t, err)
-			client.Location = "US"
-
-			d := Client{client: client}
-
-			got, err := d.SelectWithSchema(context.Background(), &query.Query{Query: tt.query})
-			if tt.err == nil {
-				require.NoError(t, err)
-				assert.Equal(t, tt.want, got)
+		{
+			name:  "successful query with column modes",
+			query: "select * from users",
+			jobSubmitResponse: jobSubmitResponse{
+				response: &bigquery2.Job{
+					JobReference: &bigquery2.JobReference{
+						JobId:     jobID,
+						ProjectId: projectID,
+					},
+					Status: &bigquery2.JobStatus{
+						State: "DONE",
+					},
+				},
+				statusCode: http.StatusOK,
+			},
+			queryResultResponse: queryResultResponse{
+				response: &bigquery2.GetQueryResultsResponse{
+					JobReference: &bigquery2.JobReference{
+						JobId: "job-id",
+					},
+					JobComplete: true,
+					Schema: &bigquery2.TableSchema{
+						Fields: []*bigquery2.TableFieldSchema{
+							{Name: "id", Type: "INTEGER", Mode: "REQUIRED"},
+							{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+							{Name: "nickname", Type: "STRING", Mode: "NULLABLE"},
+						},
+					},
+					Rows: []*bigquery2.TableRow{
+						{
+							F: []*bigquery2.TableCell{
+								{V: "1"},
+								{V: []interface{}{map[string]interface{}{"v": "a"}}},
+								{V: nil},
+							},
+						},
+					},
+				},
+				statusCode: http.StatusOK,
+			},
+			want: &query.QueryResult{
+				Columns: []string{"id", "tags", "nickname"},
+				Rows: [][]interface{}{
+					{int64(1), []bigquery.Value{"a"}, nil},
+				},
+				ColumnTypes: []string{"INTEGER", "STRING", "STRING"},
+				ColumnModes: []string{"REQUIRED", "REPEATED", "NULLABLE"},
+
+				CanonicalColumnTypes: []string{"int64", "array<string>", "string"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(mockBqHandler(t, projectID, jobID, tt.jobSubmitResponse, tt.queryResultResponse))
+			defer server.Close()
+
+			client, err := bigquery.NewClient(
+				context.Background(),
+				projectID,
+				option.WithEndpoint(server.URL),
+				option.WithCredentials(&google.Credentials{
+					ProjectID: projectID,
+					TokenSource: oauth2.StaticTokenSource(&oauth2.Token{
+						AccessToken: "some-token",
+					}),
+				}),
+			)
+			require.NoError(t, err)
+			client.Location = "US"
+
+			d := Client{client: client}
+
+			got, err := d.SelectWithSchema(context.Background(), &query.Query{Query: tt.query})
+			if tt.err == nil {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
 			} else {
 				assert.EqualError(t, err, tt.err.Error())
 			}
@@ -820,6 +1385,65 @@ func TestDB_SelectWithSchema(t *testing.T) {
 	}
 }
 
+func TestDB_SelectWithSchema_EmptyResultFallsBackToDryRun(t *testing.T) {
+	t.Parallel()
+
+	jobs := &jobsHandler{
+		jobStatus: func(job *bigquery2.Job) {
+			job.Statistics = &bigquery2.JobStatistics{
+				Query: &bigquery2.JobStatistics2{
+					Schema: &bigquery2.TableSchema{
+						Fields: []*bigquery2.TableFieldSchema{
+							{Name: "id", Type: "INTEGER", Mode: "REQUIRED"},
+							{Name: "name", Type: "STRING"},
+						},
+					},
+				},
+			}
+		},
+	}
+	handler := &recordingQueryHandler{
+		// empty results without a schema
+		response: func(q string) *bigquery2.QueryResponse {
+			return &bigquery2.QueryResponse{
+				JobComplete:  true,
+				JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+			}
+		},
+		fallback: func(w http.ResponseWriter, r *http.Request) {
+			// the results are fetched again since the response did not contain a schema
+			if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/queries/", testProjectID)) {
+				_ = json.NewEncoder(w).Encode(&bigquery2.GetQueryResultsResponse{
+					JobComplete:  true,
+					JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+				})
+				return
+			}
+			jobs.ServeHTTP(w, r)
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	got, err := d.SelectWithSchema(context.Background(), &query.Query{Query: "SELECT id, name FROM users WHERE false"})
+	require.NoError(t, err)
+	assert.Equal(t, &query.QueryResult{
+		Columns:     []string{"id", "name"},
+		Rows:        [][]interface{}{},
+		ColumnTypes: []string{"INTEGER", "STRING"},
+		ColumnModes: []string{"REQUIRED", "NULLABLE"},
+
+		CanonicalColumnTypes: []string{"int64", "string"},
+	}, got)
+
+	dryRuns := jobs.recordedJobs()
+	require.Len(t, dryRuns, 1)
+	assert.True(t, dryRuns[0].DryRun)
+	assert.Equal(t, "SELECT id, name FROM users WHERE false;", dryRuns[0].Query.Query)
+}
+
 func TestClient_getTableRef(t *testing.T) {
 	t.Parallel()
 
@@ -1145,6 +1769,70 @@ func TestIsSamePartitioning(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "matching partitioning expression",
+			meta: &bigquery.TableMetadata{
+				TimePartitioning: &bigquery.TimePartitioning{Type: bigquery.DayPartitioningType, Field: "created_at"},
+			},
+			asset: &pipeline.Asset{
+				Materialization: pipeline.Materialization{PartitionBy: "DATE(created_at)"},
+			},
+			expected: true,
+		},
+		{
+			name: "daily partitions that should be monthly",
+			meta: &bigquery.TableMetadata{
+				TimePartitioning: &bigquery.TimePartitioning{Type: bigquery.DayPartitioningType, Field: "date_field"},
+			},
+			asset: &pipeline.Asset{
+				Materialization: pipeline.Materialization{PartitionBy: "date_field", PartitionGranularity: pipeline.PartitionGranularityMonth},
+			},
+			expected: false,
+		},
+		{
+			name: "matching monthly partitions",
+			meta: &bigquery.TableMetadata{
+				TimePartitioning: &bigquery.TimePartitioning{Type: bigquery.MonthPartitioningType, Field: "date_field"},
+			},
+			asset: &pipeline.Asset{
+				Materialization: pipeline.Materialization{PartitionBy: "date_field", PartitionGranularity: pipeline.PartitionGranularityMonth},
+			},
+			expected: true,
+		},
+		{
+			name: "granularity taken from the partitioning expression",
+			meta: &bigquery.TableMetadata{
+				TimePartitioning: &bigquery.TimePartitioning{Type: bigquery.DayPartitioningType, Field: "date_field"},
+			},
+			asset: &pipeline.Asset{
+				Materialization: pipeline.Materialization{PartitionBy: "DATE_TRUNC(date_field, YEAR)"},
+			},
+			expected: false,
+		},
+		{
+			name: "range partitioned table that should be partitioned by time",
+			meta: &bigquery.TableMetadata{
+				RangePartitioning: &bigquery.RangePartitioning{Field: "id_field"},
+			},
+			asset: &pipeline.Asset{
+				Materialization: pipeline.Materialization{PartitionBy: "id_field", PartitionType: pipeline.PartitionTypeTime},
+			},
+			expected: false,
+		},
+		{
+			name: "time partitioned table that should be partitioned by range",
+			meta: &bigquery.TableMetadata{
+				TimePartitioning: &bigquery.TimePartitioning{Field: "id_field"},
+			},
+			asset: &pipeline.Asset{
+				Materialization: pipeline.Materialization{
+					PartitionBy:    "id_field",
+					PartitionType:  pipeline.PartitionTypeRange,
+					PartitionRange: &pipeline.PartitionRange{Start: 0, End: 100, Interval: 10},
+				},
+			},
+			expected: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1293,6 +1981,30 @@ func TestClient_IsMaterializationTypeMismatch(t *testing.T) {
 			meta:             &bigquery.TableMetadata{Type: "TABLE"},
 			expectedMismatch: true,
 		},
+		{
+			name: "materialized view matches",
+			asset: &pipeline.Asset{
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeMaterializedView},
+			},
+			meta:             &bigquery.TableMetadata{Type: bigquery.MaterializedView},
+			expectedMismatch: false,
+		},
+		{
+			name: "view turned into a materialized view",
+			asset: &pipeline.Asset{
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeMaterializedView},
+			},
+			meta:             &bigquery.TableMetadata{Type: bigquery.ViewTable},
+			expectedMismatch: true,
+		},
+		{
+			name: "materialized view turned into a table",
+			asset: &pipeline.Asset{
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable},
+			},
+			meta:             &bigquery.TableMetadata{Type: bigquery.MaterializedView},
+			expectedMismatch: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -1309,13 +2021,146 @@ func TestClient_IsMaterializationTypeMismatch(t *testing.T) {
 	}
 }
 
+func TestClient_SyncMaterializedView(t *testing.T) {
+	t.Parallel()
+
+	enableRefresh := true
+	definition := "SELECT dt, SUM(revenue) AS revenue\nFROM raw.orders GROUP BY dt"
+	asset := &pipeline.Asset{
+		Name: "mart.revenue",
+		Materialization: pipeline.Materialization{
+			Type:                   pipeline.MaterializationTypeMaterializedView,
+			EnableRefresh:          &enableRefresh,
+			RefreshIntervalMinutes: 60,
+		},
+	}
+
+	tests := []struct {
+		name        string
+		table       *bigquery2.Table
+		wantDeleted bool
+		wantQueries []string
+		wantErr     string
+	}{
+		{
+			name: "missing view",
+		},
+		{
+			name: "unchanged view is kept",
+			table: &bigquery2.Table{Type: "MATERIALIZED_VIEW", MaterializedView: &bigquery2.MaterializedViewDefinition{
+				Query: "SELECT dt, SUM(revenue) AS revenue FROM raw.orders GROUP BY dt;", EnableRefresh: true, RefreshIntervalMs: 3600000,
+			}},
+		},
+		{
+			name: "changed definition drops the view",
+			table: &bigquery2.Table{Type: "MATERIALIZED_VIEW", MaterializedView: &bigquery2.MaterializedViewDefinition{
+				Query: "SELECT dt, SUM(amount) AS revenue FROM raw.orders GROUP BY dt", EnableRefresh: true, RefreshIntervalMs: 3600000,
+			}},
+			wantDeleted: true,
+		},
+		{
+			name: "changed refresh options are updated in place",
+			table: &bigquery2.Table{Type: "MATERIALIZED_VIEW", MaterializedView: &bigquery2.MaterializedViewDefinition{
+				Query: definition, EnableRefresh: true, RefreshIntervalMs: 1800000,
+			}},
+			wantQueries: []string{"ALTER MATERIALIZED VIEW mart.revenue SET OPTIONS(refresh_interval_minutes=60)"},
+		},
+		{
+			name:    "table in its place",
+			table:   &bigquery2.Table{Type: "TABLE"},
+			wantErr: "table 'mart.revenue' exists as a TABLE, run a full refresh to replace it with the materialized view",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			deleted := false
+			handler := &recordingQueryHandler{
+				fallback: func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != fmt.Sprintf("/projects/%s/datasets/mart/tables/revenue", testProjectID) {
+						http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+						return
+					}
+					switch {
+					case r.Method == http.MethodGet && tt.table == nil:
+						w.WriteHeader(http.StatusNotFound)
+						_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table test-project:mart.revenue"}}`))
+					case r.Method == http.MethodGet:
+						_ = json.NewEncoder(w).Encode(tt.table)
+					case r.Method == http.MethodDelete:
+						mu.Lock()
+						deleted = true
+						mu.Unlock()
+					default:
+						http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+					}
+				},
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			err := d.SyncMaterializedView(context.Background(), asset, definition)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tt.wantDeleted, deleted)
+			if tt.wantQueries == nil {
+				assert.Empty(t, handler.recordedQueries())
+			} else {
+				assert.Equal(t, tt.wantQueries, handler.recordedQueries())
+			}
+		})
+	}
+}
+
+func TestClient_TableReference(t *testing.T) {
+	t.Parallel()
+
+	billing := &Client{config: &Config{ProjectID: "billing-project"}}
+	data := &Client{config: &Config{ProjectID: "billing-project", DataProjectID: "data-project", ProjectResolution: ProjectResolutionData}}
+
+	tests := []struct {
+		name      string
+		client    *Client
+		tableName string
+		want      string
+	}{
+		{name: "two-part name in the billing project", client: billing, tableName: "dataset.table", want: "dataset.table"},
+		{name: "two-part name in the data project", client: data, tableName: "dataset.table", want: "`data-project.dataset.table`"},
+		{name: "three-part name", client: data, tableName: "other.dataset.table", want: "other.dataset.table"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.client.TableReference(tt.tableName)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := data.TableReference("table")
+	require.Error(t, err)
+}
+
 func TestBuildTableExistsQuery(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
 		name        string
 		client      *Client
 		tableName   string
-		wantQuery   string
+		wantQuery   *query.Query
 		wantErr     bool
 		errContains string
 	}{
@@ -1323,15 +2168,43 @@ func TestBuildTableExistsQuery(t *testing.T) {
 			name:      "valid dataset.table format",
 			client:    &Client{config: &Config{ProjectID: "test-project"}},
 			tableName: "dataset.table",
-			wantQuery: "SELECT EXISTS (SELECT 1 FROM test-project.dataset.INFORMATION_SCHEMA.TABLES WHERE table_name = 'table')",
-			wantErr:   false,
+			wantQuery: &query.Query{
+				Query:      "SELECT EXISTS (SELECT 1 FROM `test-project.dataset`.INFORMATION_SCHEMA.TABLES WHERE table_name = @table_name)",
+				Parameters: []query.Parameter{{Name: "table_name", Value: "table"}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "dataset.table in a separate data project",
+			client: &Client{config: &Config{
+				ProjectID:         "billing-project",
+				DataProjectID:     "data-project",
+				ProjectResolution: ProjectResolutionData,
+			}},
+			tableName: "dataset.table",
+			wantQuery: &query.Query{
+				Query:      "SELECT EXISTS (SELECT 1 FROM `data-project.dataset`.INFORMATION_SCHEMA.TABLES WHERE table_name = @table_name)",
+				Parameters: []query.Parameter{{Name: "table_name", Value: "table"}},
+			},
 		},
 		{
 			name:      "valid project.dataset.table format",
 			client:    &Client{config: &Config{ProjectID: "test-project"}},
 			tableName: "other-project.dataset.table",
-			wantQuery: "SELECT EXISTS (SELECT 1 FROM other-project.dataset.INFORMATION_SCHEMA.TABLES WHERE table_name = 'table')",
-			wantErr:   false,
+			wantQuery: &query.Query{
+				Query:      "SELECT EXISTS (SELECT 1 FROM `other-project.dataset`.INFORMATION_SCHEMA.TABLES WHERE table_name = @table_name)",
+				Parameters: []query.Parameter{{Name: "table_name", Value: "table"}},
+			},
+			wantErr: false,
+		},
+		{
+			name:      "quotes in the table name are kept out of the SQL",
+			client:    &Client{config: &Config{ProjectID: "test-project"}},
+			tableName: "dataset.x') OR TRUE --",
+			wantQuery: &query.Query{
+				Query:      "SELECT EXISTS (SELECT 1 FROM `test-project.dataset`.INFORMATION_SCHEMA.TABLES WHERE table_name = @table_name)",
+				Parameters: []query.Parameter{{Name: "table_name", Value: "x') OR TRUE --"}},
+			},
 		},
 		{
 			name:        "invalid empty component",
@@ -1372,3 +2245,541 @@ func TestBuildTableExistsQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestClient_ResolveTable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		tableName   string
+		want        *ResolvedTable
+		errContains string
+	}{
+		{
+			name:      "two-part name uses the configured project",
+			tableName: "dataset.table",
+			want: &ResolvedTable{
+				ProjectID: "test-project",
+				DatasetID: "dataset",
+				TableID:   "table",
+			},
+		},
+		{
+			name:      "three-part name keeps its own project",
+			tableName: "other-project.dataset.table",
+			want: &ResolvedTable{
+				ProjectID: "other-project",
+				DatasetID: "dataset",
+				TableID:   "table",
+			},
+		},
+		{
+			name:        "empty component",
+			tableName:   "dataset..table",
+			errContains: "table name must be in dataset.table or project.dataset.table format",
+		},
+		{
+			name:        "too many components",
+			tableName:   "a.b.c.d",
+			errContains: "table name must be in dataset.table or project.dataset.table format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := &Client{config: &Config{ProjectID: "test-project", Location: "EU"}}
+			got, err := d.ResolveTable(tt.tableName)
+			if tt.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errContains)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.want.ProjectID+"."+tt.want.DatasetID+"."+tt.want.TableID, got.String())
+		})
+	}
+}
+
+func TestClient_ResolveTable_ProjectResolution(t *testing.T) {
+	t.Parallel()
+
+	d := &Client{config: &Config{ProjectID: "billing-project", DataProjectID: "data-project", ProjectResolution: ProjectResolutionData}}
+
+	got, err := d.ResolveTable("dataset.table")
+	require.NoError(t, err)
+	assert.Equal(t, "data-project.dataset.table", got.String())
+
+	// explicit projects always take precedence over the resolution strategy
+	got, err = d.ResolveTable("other-project.dataset.table")
+	require.NoError(t, err)
+	assert.Equal(t, "other-project.dataset.table", got.String())
+
+	d.config.ProjectResolution = "unknown"
+	_, err = d.ResolveTable("dataset.table")
+	require.EqualError(t, err, "invalid project resolution 'unknown', must be one of billing or data")
+}
+
+func newTestClient(t *testing.T, serverURL string) *Client {
+	client, err := bigquery.NewClient(
+		context.Background(),
+		testProjectID,
+		option.WithEndpoint(serverURL),
+		option.WithCredentials(&google.Credentials{
+			ProjectID: testProjectID,
+			TokenSource: oauth2.StaticTokenSource(&oauth2.Token{
+				AccessToken: "some-token",
+			}),
+		}),
+	)
+	require.NoError(t, err)
+	client.Location = "US"
+
+	return &Client{
+		client: client,
+		config: &Config{
+			ProjectID: testProjectID,
+		},
+	}
+}
+
+func successfulJobResponse(jobID string) jobSubmitResponse {
+	return jobSubmitResponse{
+		response: &bigquery2.Job{
+			JobReference: &bigquery2.JobReference{
+				JobId:     jobID,
+				ProjectId: testProjectID,
+			},
+			Status: &bigquery2.JobStatus{
+				State: "DONE",
+			},
+		},
+		statusCode: http.StatusOK,
+	}
+}
+
+func singleValueQueryResponse(fieldType, value string) queryResultResponse {
+	return queryResultResponse{
+		response: &bigquery2.GetQueryResultsResponse{
+			JobReference: &bigquery2.JobReference{
+				JobId: "job-id",
+			},
+			JobComplete: true,
+			Schema: &bigquery2.TableSchema{
+				Fields: []*bigquery2.TableFieldSchema{
+					{Name: "f0_", Type: fieldType},
+				},
+			},
+			Rows: []*bigquery2.TableRow{
+				{F: []*bigquery2.TableCell{{V: value}}},
+			},
+			TotalRows: 1,
+		},
+		statusCode: http.StatusOK,
+	}
+}
+
+func TestDB_Select_ErrorOnNoRows(t *testing.T) {
+	t.Parallel()
+
+	emptyResult := queryResultResponse{
+		response: &bigquery2.GetQueryResultsResponse{
+			JobReference: &bigquery2.JobReference{
+				JobId: "job-id",
+			},
+			JobComplete: true,
+			Schema: &bigquery2.TableSchema{
+				Fields: []*bigquery2.TableFieldSchema{
+					{Name: "id", Type: "INTEGER"},
+				},
+			},
+		},
+		statusCode: http.StatusOK,
+	}
+
+	tests := []struct {
+		name          string
+		errorOnNoRows bool
+		wantErr       error
+	}{
+		{
+			name:          "empty result is returned without error by default",
+			errorOnNoRows: false,
+		},
+		{
+			name:          "empty result returns ErrNoRows when enabled",
+			errorOnNoRows: true,
+			wantErr:       ErrNoRows,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			jobID := "test-job"
+			server := httptest.NewServer(mockBqHandler(t, testProjectID, jobID, successfulJobResponse(jobID), emptyResult))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.ErrorOnNoRows = tt.errorOnNoRows
+
+			rows, err := d.Select(context.Background(), &query.Query{Query: "SELECT id FROM t WHERE false"})
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Empty(t, rows)
+			}
+
+			result, err := d.SelectWithSchema(context.Background(), &query.Query{Query: "SELECT id FROM t WHERE false"})
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Empty(t, result.Rows)
+				assert.Equal(t, []string{"id"}, result.Columns)
+			}
+		})
+	}
+}
+
+// recordingQueryHandler answers the synchronous query endpoint with the given response and records the
+// submitted queries, delegating every other request to the given fallback.
+type recordingQueryHandler struct {
+	mu       sync.Mutex
+	queries  []string
+	requests []*bigquery2.QueryRequest
+	response func(q string) *bigquery2.QueryResponse
+	fallback http.HandlerFunc
+}
+
+func (h *recordingQueryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/queries", testProjectID)) {
+		var req bigquery2.QueryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		h.mu.Lock()
+		h.queries = append(h.queries, req.Query)
+		h.requests = append(h.requests, &req)
+		h.mu.Unlock()
+
+		resp := &bigquery2.QueryResponse{
+			JobComplete:  true,
+			JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+		}
+		if h.response != nil {
+			if custom := h.response(req.Query); custom != nil {
+				resp = custom
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	if h.fallback != nil {
+		h.fallback(w, r)
+		return
+	}
+
+	http.Error(w, "there is no test definition found for the given request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+}
+
+func (h *recordingQueryHandler) recordedQueries() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]string{}, h.queries...)
+}
+
+func (h *recordingQueryHandler) recordedRequests() []*bigquery2.QueryRequest {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]*bigquery2.QueryRequest{}, h.requests...)
+}
+
+func stringQueryResponse(values ...string) *bigquery2.QueryResponse {
+	rows := make([]*bigquery2.TableRow, 0, len(values))
+	for _, v := range values {
+		rows = append(rows, &bigquery2.TableRow{F: []*bigquery2.TableCell{{V: v}}})
+	}
+
+	return &bigquery2.QueryResponse{
+		JobComplete:  true,
+		JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+		Schema: &bigquery2.TableSchema{
+			Fields: []*bigquery2.TableFieldSchema{{Name: "value", Type: "STRING"}},
+		},
+		Rows:      rows,
+		TotalRows: uint64(len(rows)),
+	}
+}
+
+func TestClient_QueryPriority(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	priorities := make([]string, 0)
+	ref := &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/jobs", testProjectID):
+			// batch queries cannot use jobs.query and are inserted as jobs instead
+			var job bigquery2.Job
+			_ = json.NewDecoder(r.Body).Decode(&job)
+			priorities = append(priorities, job.Configuration.Query.Priority)
+			_ = json.NewEncoder(w).Encode(&bigquery2.Job{JobReference: ref, Configuration: job.Configuration, Status: &bigquery2.JobStatus{State: "DONE"}})
+		case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/queries", testProjectID):
+			priorities = append(priorities, QueryPriorityInteractive)
+			_ = json.NewEncoder(w).Encode(&bigquery2.QueryResponse{JobComplete: true, JobReference: ref, Schema: &bigquery2.TableSchema{}})
+		case r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(&bigquery2.GetQueryResultsResponse{JobComplete: true, JobReference: ref, Schema: &bigquery2.TableSchema{}})
+		default:
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	d.config.QueryPriority = QueryPriorityBatch
+
+	ctx := context.Background()
+	require.NoError(t, d.RunQueryWithoutResult(ctx, &query.Query{Query: "SELECT 1"}))
+	require.NoError(t, d.RunQueryWithoutResult(ctx, &query.Query{Query: "SELECT 1", Priority: QueryPriorityInteractive}))
+	err := d.RunQueryWithoutResult(ctx, &query.Query{Query: "SELECT 1", Priority: "bacth"})
+	require.EqualError(t, err, "invalid query priority 'bacth', must be one of interactive or batch")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"BATCH", QueryPriorityInteractive}, priorities)
+
+	_, err = NewDB(&Config{QueryPriority: "urgent"})
+	require.EqualError(t, err, "invalid query priority 'urgent', must be one of interactive or batch")
+}
+
+func TestClient_QueryParameters(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			return rowsQueryResponse([]*bigquery2.TableFieldSchema{{Name: "f0_", Type: "BOOLEAN"}}, []interface{}{"true"})
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	q, err := d.BuildTableExistsQuery("dataset.it's")
+	require.NoError(t, err)
+
+	got, err := d.Select(context.Background(), q)
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{true}}, got)
+
+	requests := handler.recordedRequests()
+	require.Len(t, requests, 1)
+	require.Len(t, requests[0].QueryParameters, 1)
+	assert.Equal(t, "table_name", requests[0].QueryParameters[0].Name)
+	assert.Equal(t, "STRING", requests[0].QueryParameters[0].ParameterType.Type)
+	assert.Equal(t, "it's", requests[0].QueryParameters[0].ParameterValue.Value)
+}
+
+func TestClient_MaximumBytesBilled(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var limits []int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req bigquery2.QueryRequest
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			mu.Lock()
+			limits = append(limits, req.MaximumBytesBilled)
+			mu.Unlock()
+		}
+
+		// the results of the query are read with GET requests, which are answered with the same response
+		if req.MaximumBytesBilled == 1000 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"code": 400, "message": "Query exceeded limit for bytes billed: 1000. 10485760 or higher required.", "errors": [{"reason": "bytesBilledLimitExceeded", "message": "Query exceeded limit for bytes billed: 1000. 10485760 or higher required."}]}}`))
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(&bigquery2.QueryResponse{
+			JobComplete:  true,
+			JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+			Schema:       &bigquery2.TableSchema{Fields: []*bigquery2.TableFieldSchema{{Name: "one", Type: "INTEGER"}}},
+		})
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	d.config.MaximumBytesBilled = 1000
+
+	err := d.RunQueryWithoutResult(context.Background(), &query.Query{Query: "SELECT * FROM dataset.big_table"})
+	require.EqualError(t, err, "query would process 10485760 bytes, exceeding the configured limit of 1000")
+
+	var limitErr *BytesBilledLimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Equal(t, int64(10485760), limitErr.BytesRequired)
+
+	override := int64(20000000)
+	_, err = d.Select(context.Background(), &query.Query{Query: "SELECT * FROM dataset.big_table", MaxBytesBilled: &override})
+	require.NoError(t, err)
+
+	noLimit := int64(0)
+	_, err = d.Select(context.Background(), &query.Query{Query: "SELECT * FROM dataset.big_table", MaxBytesBilled: &noLimit})
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int64{1000, 20000000, 0}, limits)
+}
+
+func TestDB_SelectWithSchemaLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		limit    int
+		wantRows [][]interface{}
+		wantErr  string
+	}{
+		{
+			name:     "rows beyond the limit are not read",
+			limit:    2,
+			wantRows: [][]interface{}{{"a"}, {"b"}},
+		},
+		{
+			name:     "limit larger than the result",
+			limit:    10,
+			wantRows: [][]interface{}{{"a"}, {"b"}, {"c"}},
+		},
+		{
+			name:     "zero limit returns only the column metadata",
+			limit:    0,
+			wantRows: [][]interface{}{},
+		},
+		{
+			name:    "negative limit",
+			limit:   -1,
+			wantErr: "limit must not be negative, -1 given",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &recordingQueryHandler{
+				response: func(q string) *bigquery2.QueryResponse {
+					return stringQueryResponse("a", "b", "c")
+				},
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.ErrorOnNoRows = true
+
+			got, err := d.SelectWithSchemaLimit(context.Background(), &query.Query{Query: "SELECT * FROM dataset.facts"}, tt.limit)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, &query.QueryResult{
+				Columns:     []string{"value"},
+				Rows:        tt.wantRows,
+				ColumnTypes: []string{"STRING"},
+				ColumnModes: []string{"NULLABLE"},
+
+				CanonicalColumnTypes: []string{"string"},
+			}, got)
+		})
+	}
+}
+
+func TestClient_PingMetadata(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		statusCode int
+		datasets   []*bigquery2.DatasetListDatasets
+		wantErr    string
+	}{
+		{
+			name:       "listing a dataset succeeds",
+			statusCode: http.StatusOK,
+			datasets: []*bigquery2.DatasetListDatasets{
+				{DatasetReference: &bigquery2.DatasetReference{ProjectId: testProjectID, DatasetId: "raw"}},
+			},
+		},
+		{
+			name:       "a project without datasets is valid",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "missing permissions fail the ping",
+			statusCode: http.StatusForbidden,
+			wantErr:    "failed to list the datasets of the Bigquery connection",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			requests := make([]string, 0)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requests = append(requests, r.Method+" "+r.URL.Path+"?maxResults="+r.URL.Query().Get("maxResults"))
+				mu.Unlock()
+
+				w.Header().Set("Content-Type", "application/json")
+				if tt.statusCode != http.StatusOK {
+					w.WriteHeader(tt.statusCode)
+					_, _ = w.Write([]byte(`{"error": {"code": 403, "message": "Access Denied"}}`))
+					return
+				}
+				_ = json.NewEncoder(w).Encode(&bigquery2.DatasetList{Datasets: tt.datasets})
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.PingWithMetadata = true
+
+			err := d.Ping(context.Background())
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			// no query job is run, only the datasets are listed
+			assert.Equal(t, []string{fmt.Sprintf("GET /projects/%s/datasets?maxResults=1", testProjectID)}, requests)
+		})
+	}
+}
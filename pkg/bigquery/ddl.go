@@ -0,0 +1,42 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+)
+
+// BuildTableDDLQuery builds the query that reads the DDL of the table from INFORMATION_SCHEMA.TABLES.
+func BuildTableDDLQuery(table *ResolvedTable) string {
+	return fmt.Sprintf("SELECT ddl FROM %s.INFORMATION_SCHEMA.TABLES WHERE table_name = %s",
+		QuoteIdentifier(table.ProjectID+"."+table.DatasetID),
+		quoteStringLiteral(table.TableID),
+	)
+}
+
+// GetTableDDL returns the CREATE statement of the given table, view or materialized view, including its options,
+// partitioning and clustering, as BigQuery reports it.
+func (d *Client) GetTableDDL(ctx context.Context, tableName string) (string, error) {
+	table, err := d.ResolveTable(tableName)
+	if err != nil {
+		return "", err
+	}
+
+	rows, err := d.Select(ctx, &query.Query{Query: BuildTableDDLQuery(table)})
+	if err != nil && !errors.Is(err, ErrNoRows) {
+		return "", errors.Wrapf(err, "failed to read the DDL of table '%s'", tableName)
+	}
+
+	if len(rows) == 0 || len(rows[0]) == 0 || rows[0][0] == nil {
+		return "", fmt.Errorf("table '%s' does not exist", tableName)
+	}
+
+	ddl, ok := rows[0][0].(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected DDL value of type %T for table '%s'", rows[0][0], tableName)
+	}
+
+	return ddl, nil
+}
@@ -0,0 +1,87 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_GetTableDDL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		tableName string
+		rows      []string
+		want      string
+		wantQuery string
+		wantErr   string
+	}{
+		{
+			name:      "table",
+			tableName: "analytics.events",
+			rows:      []string{"CREATE TABLE `test-project.analytics.events`\n(\n  id INT64\n)\nPARTITION BY DATE(_PARTITIONTIME);"},
+			want:      "CREATE TABLE `test-project.analytics.events`\n(\n  id INT64\n)\nPARTITION BY DATE(_PARTITIONTIME);",
+			wantQuery: "SELECT ddl FROM `test-project.analytics`.INFORMATION_SCHEMA.TABLES WHERE table_name = 'events'",
+		},
+		{
+			name:      "materialized view in another project",
+			tableName: "other-project.analytics.daily_events",
+			rows:      []string{"CREATE MATERIALIZED VIEW `other-project.analytics.daily_events` AS SELECT 1;"},
+			want:      "CREATE MATERIALIZED VIEW `other-project.analytics.daily_events` AS SELECT 1;",
+			wantQuery: "SELECT ddl FROM `other-project.analytics`.INFORMATION_SCHEMA.TABLES WHERE table_name = 'daily_events'",
+		},
+		{
+			name:      "missing table",
+			tableName: "analytics.missing",
+			wantQuery: "SELECT ddl FROM `test-project.analytics`.INFORMATION_SCHEMA.TABLES WHERE table_name = 'missing'",
+			wantErr:   "table 'analytics.missing' does not exist",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &recordingQueryHandler{
+				response: func(q string) *bigquery2.QueryResponse {
+					return stringQueryResponse(tt.rows...)
+				},
+				fallback: func(w http.ResponseWriter, r *http.Request) {
+					// empty results are fetched again before the iteration ends
+					if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/queries/", testProjectID)) {
+						_ = json.NewEncoder(w).Encode(&bigquery2.GetQueryResultsResponse{
+							JobComplete:  true,
+							JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+							Schema:       stringQueryResponse().Schema,
+						})
+						return
+					}
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+				},
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			got, err := d.GetTableDDL(context.Background(), tt.tableName)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+
+			assert.Equal(t, []string{tt.wantQuery}, handler.recordedQueries())
+		})
+	}
+}
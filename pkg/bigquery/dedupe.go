@@ -0,0 +1,62 @@
+package bigquery
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+)
+
+// deduplicated wraps the materializer so that the query of the assets with DeduplicateOnIngest set is deduplicated
+// before being materialized, see DeduplicateQuery.
+func deduplicated(materializer pipeline.MaterializerFunc) pipeline.MaterializerFunc {
+	return func(asset *pipeline.Asset, query string) (string, error) {
+		if !asset.Materialization.DeduplicateOnIngest {
+			return materializer(asset, query)
+		}
+
+		deduplicatedQuery, err := DeduplicateQuery(asset, query)
+		if err != nil {
+			return "", err
+		}
+
+		return materializer(asset, deduplicatedQuery)
+	}
+}
+
+// DeduplicateQuery wraps the query so that it returns a single row per primary key of the asset, the one with the
+// highest value of the DeduplicateOrderBy column; ties are broken arbitrarily. The ordering column is required,
+// without it the row kept out of the duplicates could change from one run to the next.
+func DeduplicateQuery(asset *pipeline.Asset, query string) (string, error) {
+	mat := asset.Materialization
+	problems := make([]string, 0)
+	if mat.Type != pipeline.MaterializationTypeTable {
+		problems = append(problems, "deduplicate_on_ingest is only supported for tables")
+	}
+
+	primaryKeys := asset.ColumnNamesWithPrimaryKey()
+	if len(primaryKeys) == 0 {
+		problems = append(problems, "deduplicate_on_ingest requires the asset to declare primary key columns to deduplicate the rows on")
+	}
+	orderBy := strings.TrimSpace(mat.DeduplicateOrderBy)
+	if orderBy == "" {
+		problems = append(problems, "deduplicate_on_ingest requires deduplicate_order_by to name the column whose highest value decides which duplicate is kept, e.g. an updated_at column, otherwise the row kept is arbitrary")
+	} else if !columnNameRegex.MatchString(orderBy) {
+		problems = append(problems, fmt.Sprintf("deduplicate_order_by must be a single column name, '%s' given", orderBy))
+	}
+	if len(problems) > 0 {
+		return "", &MaterializationValidationError{Asset: asset.Name, Problems: problems}
+	}
+
+	partitionBy := make([]string, len(primaryKeys))
+	for i, key := range primaryKeys {
+		partitionBy[i] = QuoteColumn(key)
+	}
+
+	return fmt.Sprintf(
+		"WITH __bruin_deduplication_source AS (\n%s\n)\nSELECT * FROM __bruin_deduplication_source\nWHERE TRUE\nQUALIFY ROW_NUMBER() OVER (PARTITION BY %s ORDER BY %s DESC) = 1",
+		strings.TrimSuffix(strings.TrimSpace(query), ";"),
+		strings.Join(partitionBy, ", "),
+		QuoteColumn(orderBy),
+	), nil
+}
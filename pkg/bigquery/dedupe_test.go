@@ -0,0 +1,127 @@
+package bigquery
+
+import (
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeduplicateQuery(t *testing.T) {
+	t.Parallel()
+
+	columns := []pipeline.Column{
+		{Name: "id", PrimaryKey: true},
+		{Name: "region", PrimaryKey: true},
+		{Name: "updated_at"},
+	}
+
+	tests := []struct {
+		name    string
+		asset   *pipeline.Asset
+		query   string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "create+replace keeps the latest row per primary key",
+			asset: &pipeline.Asset{
+				Name:    "my.users",
+				Columns: columns,
+				Materialization: pipeline.Materialization{
+					Type:                pipeline.MaterializationTypeTable,
+					DeduplicateOnIngest: true,
+					DeduplicateOrderBy:  "updated_at",
+				},
+			},
+			query: "SELECT * FROM raw.users;",
+			want: "CREATE OR REPLACE TABLE my.users   AS\n" +
+				"WITH __bruin_deduplication_source AS (\nSELECT * FROM raw.users\n)\n" +
+				"SELECT * FROM __bruin_deduplication_source\nWHERE TRUE\n" +
+				"QUALIFY ROW_NUMBER() OVER (PARTITION BY `id`, `region` ORDER BY `updated_at` DESC) = 1",
+		},
+		{
+			name: "append inserts the deduplicated rows",
+			asset: &pipeline.Asset{
+				Name:    "my.users",
+				Columns: columns,
+				Materialization: pipeline.Materialization{
+					Type:                pipeline.MaterializationTypeTable,
+					Strategy:            pipeline.MaterializationStrategyAppend,
+					DeduplicateOnIngest: true,
+					DeduplicateOrderBy:  "`updated_at`",
+				},
+			},
+			query: "SELECT * FROM raw.users",
+			want: "INSERT INTO my.users WITH __bruin_deduplication_source AS (\nSELECT * FROM raw.users\n)\n" +
+				"SELECT * FROM __bruin_deduplication_source\nWHERE TRUE\n" +
+				"QUALIFY ROW_NUMBER() OVER (PARTITION BY `id`, `region` ORDER BY `updated_at` DESC) = 1",
+		},
+		{
+			name: "queries are left alone without the option",
+			asset: &pipeline.Asset{
+				Name:            "my.users",
+				Columns:         columns,
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, DeduplicateOrderBy: "updated_at"},
+			},
+			query: "SELECT * FROM raw.users",
+			want:  "CREATE OR REPLACE TABLE my.users   AS\nSELECT * FROM raw.users",
+		},
+		{
+			name: "the ordering column is required",
+			asset: &pipeline.Asset{
+				Name:    "my.users",
+				Columns: columns,
+				Materialization: pipeline.Materialization{
+					Type:                pipeline.MaterializationTypeTable,
+					DeduplicateOnIngest: true,
+				},
+			},
+			query:   "SELECT * FROM raw.users",
+			wantErr: "invalid materialization for asset 'my.users': deduplicate_on_ingest requires deduplicate_order_by to name the column whose highest value decides which duplicate is kept, e.g. an updated_at column, otherwise the row kept is arbitrary",
+		},
+		{
+			name: "the primary key is required",
+			asset: &pipeline.Asset{
+				Name:    "my.users",
+				Columns: []pipeline.Column{{Name: "id"}},
+				Materialization: pipeline.Materialization{
+					Type:                pipeline.MaterializationTypeTable,
+					DeduplicateOnIngest: true,
+					DeduplicateOrderBy:  "updated_at DESC",
+				},
+			},
+			query:   "SELECT * FROM raw.users",
+			wantErr: "invalid materialization for asset 'my.users': deduplicate_on_ingest requires the asset to declare primary key columns to deduplicate the rows on; deduplicate_order_by must be a single column name, 'updated_at DESC' given",
+		},
+		{
+			name: "views are not deduplicated",
+			asset: &pipeline.Asset{
+				Name:    "my.users",
+				Columns: columns,
+				Materialization: pipeline.Materialization{
+					Type:                pipeline.MaterializationTypeView,
+					DeduplicateOnIngest: true,
+					DeduplicateOrderBy:  "updated_at",
+				},
+			},
+			query:   "SELECT * FROM raw.users",
+			wantErr: "invalid materialization for asset 'my.users': deduplicate_on_ingest is only supported for tables",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NewMaterializer(false).Render(tt.asset, tt.query)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
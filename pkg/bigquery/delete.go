@@ -0,0 +1,57 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+// partitionIDRegex matches the IDs BigQuery gives to partitions: the YYYY, YYYYMM, YYYYMMDD or YYYYMMDDHH form of
+// time-unit partitions, the start of the range of integer-range partitions, and the special NULL and unpartitioned
+// partitions.
+var partitionIDRegex = regexp.MustCompile(`^(-?\d+|__NULL__|__UNPARTITIONED__)$`)
+
+// DeleteTable deletes the given table. Deleting a table that does not exist is not an error, which makes it safe to
+// call from teardown steps that may run more than once.
+func (d *Client) DeleteTable(ctx context.Context, tableName string) error {
+	tableRef, err := d.getTableRef(tableName)
+	if err != nil {
+		return err
+	}
+
+	return deleteTable(ctx, tableRef, tableName)
+}
+
+// DeletePartition deletes a single partition of the given table through the partition decorator, e.g. `20240101`
+// for a daily partition, which is free unlike a DELETE statement scanning the partition. Deleting a partition or a
+// table that does not exist is not an error.
+func (d *Client) DeletePartition(ctx context.Context, tableName, partitionID string) error {
+	if !partitionIDRegex.MatchString(partitionID) {
+		return fmt.Errorf("invalid partition ID '%s' for table '%s', expected a partition ID such as 20240101, 2024010112 or __NULL__", partitionID, tableName)
+	}
+
+	resolved, err := d.ResolveTable(tableName)
+	if err != nil {
+		return err
+	}
+	tableRef := d.client.DatasetInProject(resolved.ProjectID, resolved.DatasetID).Table(resolved.TableID + "$" + partitionID)
+
+	return deleteTable(ctx, tableRef, fmt.Sprintf("%s$%s", tableName, partitionID))
+}
+
+func deleteTable(ctx context.Context, tableRef *bigquery.Table, tableName string) error {
+	if err := tableRef.Delete(ctx); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil
+		}
+		return errors.Wrapf(formatError(err), "failed to delete table '%s'", tableName)
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_DeleteTable(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+			return
+		}
+
+		switch r.URL.Path {
+		case fmt.Sprintf("/projects/%s/datasets/dataset/tables/users", testProjectID),
+			"/projects/other-project/datasets/dataset/tables/users":
+			mu.Lock()
+			deleted = append(deleted, r.URL.Path)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		case fmt.Sprintf("/projects/%s/datasets/dataset/tables/locked", testProjectID):
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error": {"code": 403, "message": "Access Denied: Table test-project:dataset.locked"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table test-project:dataset.missing"}}`))
+		}
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	require.NoError(t, d.DeleteTable(context.Background(), "dataset.users"))
+	require.NoError(t, d.DeleteTable(context.Background(), "other-project.dataset.users"))
+	require.NoError(t, d.DeleteTable(context.Background(), "dataset.missing"), "deleting a missing table is not an error")
+
+	err := d.DeleteTable(context.Background(), "dataset.locked")
+	require.EqualError(t, err, "failed to delete table 'dataset.locked': googleapi: Error 403: Access Denied: Table test-project:dataset.locked")
+
+	err = d.DeleteTable(context.Background(), "users")
+	require.Error(t, err)
+
+	assert.Equal(t, []string{
+		fmt.Sprintf("/projects/%s/datasets/dataset/tables/users", testProjectID),
+		"/projects/other-project/datasets/dataset/tables/users",
+	}, deleted)
+}
+
+func TestClient_DeletePartition(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		tableName   string
+		partitionID string
+		wantPath    string
+		wantErr     string
+	}{
+		{
+			name:        "daily partition",
+			tableName:   "dataset.events",
+			partitionID: "20240101",
+			wantPath:    fmt.Sprintf("/projects/%s/datasets/dataset/tables/events$20240101", testProjectID),
+		},
+		{
+			name:        "hourly partition of a table in another project",
+			tableName:   "other-project.dataset.events",
+			partitionID: "2024010112",
+			wantPath:    "/projects/other-project/datasets/dataset/tables/events$2024010112",
+		},
+		{
+			name:        "null partition",
+			tableName:   "dataset.events",
+			partitionID: "__NULL__",
+			wantPath:    fmt.Sprintf("/projects/%s/datasets/dataset/tables/events$__NULL__", testProjectID),
+		},
+		{
+			name:        "missing partitions are not an error",
+			tableName:   "dataset.missing",
+			partitionID: "20240101",
+			wantPath:    fmt.Sprintf("/projects/%s/datasets/dataset/tables/missing$20240101", testProjectID),
+		},
+		{
+			name:        "invalid partition ID",
+			tableName:   "dataset.events",
+			partitionID: "2024-01-01",
+			wantErr:     "invalid partition ID '2024-01-01' for table 'dataset.events', expected a partition ID such as 20240101, 2024010112 or __NULL__",
+		},
+		{
+			name:        "decorators cannot be injected through the partition ID",
+			tableName:   "dataset.events",
+			partitionID: "20240101/../../other",
+			wantErr:     "invalid partition ID '20240101/../../other' for table 'dataset.events', expected a partition ID such as 20240101, 2024010112 or __NULL__",
+		},
+		{
+			name:        "invalid table name",
+			tableName:   "events",
+			partitionID: "20240101",
+			wantErr:     "table name must be in dataset.table or project.dataset.table format, 'events' given",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var deletedPath string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete {
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+					return
+				}
+				deletedPath = r.URL.Path
+				if tt.tableName == "dataset.missing" {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table test-project:dataset.missing"}}`))
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			err := d.DeletePartition(context.Background(), tt.tableName, tt.partitionID)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				assert.Empty(t, deletedPath)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPath, deletedPath)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package bigquery
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+)
+
+// UpstreamColumnDescriptions collects the column descriptions of the upstream assets of the given asset in the
+// pipeline, keyed by the lower-cased column name. When several upstreams describe the same column, the first one in
+// the order of the upstreams wins.
+func UpstreamColumnDescriptions(p *pipeline.Pipeline, asset *pipeline.Asset) map[string]string {
+	descriptions := make(map[string]string)
+	if p == nil {
+		return descriptions
+	}
+
+	for _, upstream := range asset.Upstreams {
+		if upstream.Type != "" && upstream.Type != "asset" {
+			continue
+		}
+
+		upstreamAsset := p.GetAssetByName(upstream.Value)
+		if upstreamAsset == nil {
+			continue
+		}
+
+		for _, col := range upstreamAsset.Columns {
+			key := strings.ToLower(col.Name)
+			if _, exists := descriptions[key]; exists || col.Description == "" {
+				continue
+			}
+			descriptions[key] = col.Description
+		}
+	}
+
+	return descriptions
+}
+
+// InheritColumnDescriptions returns a copy of the asset where the columns without a description of their own take
+// the description of the upstream column with the same name, matched case-insensitively, along with the names of the
+// columns that inherited one. Explicit descriptions are never overridden.
+func InheritColumnDescriptions(asset *pipeline.Asset, upstreamDescriptions map[string]string) (*pipeline.Asset, []string) {
+	inherited := make([]string, 0)
+	if len(upstreamDescriptions) == 0 {
+		return asset, inherited
+	}
+
+	lowered := make(map[string]string, len(upstreamDescriptions))
+	for name, description := range upstreamDescriptions {
+		lowered[strings.ToLower(name)] = description
+	}
+
+	withInherited := *asset
+	withInherited.Columns = make([]pipeline.Column, len(asset.Columns))
+	for i, col := range asset.Columns {
+		if description := lowered[strings.ToLower(col.Name)]; col.Description == "" && description != "" {
+			col.Description = description
+			inherited = append(inherited, col.Name)
+		}
+		withInherited.Columns[i] = col
+	}
+
+	return &withInherited, inherited
+}
+
+// UpdateTableMetadataWithUpstreamDescriptions is like UpdateTableMetadataIfNotExist, with the columns of the asset
+// that have no description inheriting the ones of the upstream columns, see InheritColumnDescriptions. The names of
+// the columns that inherited a description are returned.
+func (d *Client) UpdateTableMetadataWithUpstreamDescriptions(ctx context.Context, asset *pipeline.Asset, upstreamDescriptions map[string]string) ([]string, error) {
+	withInherited, inherited := InheritColumnDescriptions(asset, upstreamDescriptions)
+	if err := d.UpdateTableMetadataIfNotExist(ctx, withInherited); err != nil {
+		return nil, err
+	}
+
+	return inherited, nil
+}
+
+func (d *Client) inheritsColumnDescriptions() bool {
+	return d.config != nil && d.config.InheritColumnDescriptions
+}
@@ -0,0 +1,132 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestUpstreamColumnDescriptions(t *testing.T) {
+	t.Parallel()
+
+	p := &pipeline.Pipeline{
+		Assets: []*pipeline.Asset{
+			{
+				Name: "raw.users",
+				Columns: []pipeline.Column{
+					{Name: "id", Description: "The user identifier"},
+					{Name: "email"},
+				},
+			},
+			{
+				Name: "raw.orders",
+				Columns: []pipeline.Column{
+					{Name: "ID", Description: "The order identifier"},
+					{Name: "amount", Description: "The order amount in EUR"},
+				},
+			},
+		},
+	}
+	asset := &pipeline.Asset{
+		Name: "mart.user_orders",
+		Upstreams: []pipeline.Upstream{
+			{Type: "asset", Value: "raw.users"},
+			{Type: "uri", Value: "raw.orders"},
+			{Value: "raw.orders"},
+			{Value: "raw.missing"},
+		},
+	}
+
+	assert.Equal(t, map[string]string{
+		"id":     "The user identifier",
+		"amount": "The order amount in EUR",
+	}, UpstreamColumnDescriptions(p, asset))
+}
+
+func TestInheritColumnDescriptions(t *testing.T) {
+	t.Parallel()
+
+	asset := &pipeline.Asset{
+		Name: "mart.user_orders",
+		Columns: []pipeline.Column{
+			{Name: "ID"},
+			{Name: "amount", Description: "The amount including taxes"},
+			{Name: "country"},
+		},
+	}
+
+	got, inherited := InheritColumnDescriptions(asset, map[string]string{
+		"id":     "The user identifier",
+		"amount": "The order amount in EUR",
+	})
+
+	assert.Equal(t, []string{"ID"}, inherited)
+	assert.Equal(t, []pipeline.Column{
+		{Name: "ID", Description: "The user identifier"},
+		{Name: "amount", Description: "The amount including taxes"},
+		{Name: "country"},
+	}, got.Columns)
+
+	// the asset itself is left as it is
+	assert.Empty(t, asset.Columns[0].Description)
+}
+
+func TestClient_UpdateTableMetadataWithUpstreamDescriptions(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var patched *bigquery2.Table
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != fmt.Sprintf("/projects/%s/datasets/mart/tables/user_orders", testProjectID) {
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+			return
+		}
+
+		if r.Method == http.MethodPatch {
+			var table bigquery2.Table
+			_ = json.NewDecoder(r.Body).Decode(&table)
+			mu.Lock()
+			patched = &table
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(&table)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(&bigquery2.Table{
+			Schema: &bigquery2.TableSchema{Fields: []*bigquery2.TableFieldSchema{
+				{Name: "id", Type: "INTEGER"},
+				{Name: "amount", Type: "FLOAT"},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	asset := &pipeline.Asset{
+		Name:    "mart.user_orders",
+		Columns: []pipeline.Column{{Name: "id"}, {Name: "amount"}},
+	}
+
+	inherited, err := d.UpdateTableMetadataWithUpstreamDescriptions(context.Background(), asset, map[string]string{"id": "The user identifier"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id"}, inherited)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotNil(t, patched)
+	assert.Equal(t, "The user identifier", patched.Schema.Fields[0].Description)
+
+	// without anything to inherit there is nothing to push
+	_, err = d.UpdateTableMetadataWithUpstreamDescriptions(context.Background(), asset, nil)
+	require.ErrorIs(t, err, NoMetadataUpdatedError{})
+}
@@ -0,0 +1,143 @@
+package bigquery
+
+import (
+	"regexp"
+	"strings"
+)
+
+// nonDeterministicFunctions are the functions whose result changes between runs of the same query. The CURRENT_*
+// functions can be called without parentheses, the others need them.
+var nonDeterministicFunctions = regexp.MustCompile(
+	`(?i)\b(?:(CURRENT_(?:TIMESTAMP|DATETIME|DATE|TIME))\b|(RAND|GENERATE_UUID|SESSION_USER|CURRENT_USER)\s*\()`,
+)
+
+// IsDeterministic reports whether the query returns the same result for the same data, i.e. it does not call any
+// function such as CURRENT_TIMESTAMP or RAND whose result changes between runs. The non-deterministic functions the
+// query calls are returned in upper case, in the order they first appear. Cached results and checksums of queries
+// that are not deterministic cannot be compared across runs.
+//
+// The check is lexical: string literals, quoted identifiers and comments are ignored, but functions called through
+// views or UDFs are not detected.
+func IsDeterministic(query string) (bool, []string) {
+	found := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, match := range nonDeterministicFunctions.FindAllStringSubmatch(stripLiteralsAndComments(query), -1) {
+		name := strings.ToUpper(match[1] + match[2])
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		found = append(found, name)
+	}
+
+	return len(found) == 0, found
+}
+
+// stripLiteralsAndComments replaces the string literals, quoted identifiers and comments of the query with spaces,
+// so that only the SQL tokens are left to match against.
+func stripLiteralsAndComments(query string) string {
+	return maskLiteralsAndComments(query, false)
+}
+
+// maskLiteralsAndComments replaces the string literals and comments of the query, and the quoted identifiers unless
+// keepIdentifiers is set, with spaces. The result has the same length as the query, so that the positions of the
+// tokens matched in it are the positions in the query as well.
+func maskLiteralsAndComments(query string, keepIdentifiers bool) string {
+	masked := []byte(query)
+	forEachLiteralAndComment(query, func(kind sqlSpanKind, start, end int) {
+		if kind == sqlSpanQuotedIdentifier && keepIdentifiers {
+			return
+		}
+		for i := start; i < end; i++ {
+			if masked[i] != '\n' {
+				masked[i] = ' '
+			}
+		}
+	})
+
+	return string(masked)
+}
+
+// sqlSpanKind is the kind of a span of a query that is not made of SQL tokens, see forEachLiteralAndComment.
+type sqlSpanKind int
+
+const (
+	sqlSpanLiteral sqlSpanKind = iota
+	sqlSpanQuotedIdentifier
+	sqlSpanComment
+)
+
+// forEachLiteralAndComment calls fn with the kind and the byte range of every string literal, quoted identifier and
+// comment of the query, in order. Literals may be triple-quoted, and backslashes only escape the quote in literals
+// that are not raw, e.g. `r'\'` ends at its second quote. Unterminated spans run to the end of the query.
+func forEachLiteralAndComment(query string, fn func(kind sqlSpanKind, start, end int)) {
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			start := i
+			kind := sqlSpanLiteral
+			if c == '`' {
+				kind = sqlSpanQuotedIdentifier
+			}
+			raw := c != '`' && isRawLiteralPrefix(query[:i])
+
+			quote := query[i : i+1]
+			if c != '`' && strings.HasPrefix(query[i:], strings.Repeat(quote, 3)) {
+				quote = strings.Repeat(quote, 3)
+			}
+			i += len(quote)
+			for i < len(query) && !strings.HasPrefix(query[i:], quote) {
+				if query[i] == '\\' && !raw {
+					i++
+				}
+				i++
+			}
+			i = min(i+len(quote), len(query))
+			fn(kind, start, i)
+			i--
+		case c == '#' || (c == '-' && i+1 < len(query) && query[i+1] == '-'):
+			start := i
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			fn(sqlSpanComment, start, i)
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			start := i
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 4
+			}
+			fn(sqlSpanComment, start, i)
+			i--
+		}
+	}
+}
+
+// isRawLiteralPrefix reports whether the text before a quote ends with the prefix of a raw literal, i.e. `r`, `rb`
+// or `br` in any case, rather than with an identifier that happens to end in r.
+func isRawLiteralPrefix(before string) bool {
+	prefix := ""
+	for i := len(before) - 1; i >= 0 && len(before)-i <= 2; i-- {
+		c := before[i] | 0x20
+		if c != 'r' && c != 'b' {
+			break
+		}
+		prefix = string(c) + prefix
+	}
+	if !strings.Contains(prefix, "r") {
+		return false
+	}
+
+	// the prefix must not be the end of a longer identifier
+	if rest := before[:len(before)-len(prefix)]; rest != "" {
+		last := rest[len(rest)-1]
+		if last == '_' || last >= '0' && last <= '9' || last|0x20 >= 'a' && last|0x20 <= 'z' {
+			return false
+		}
+	}
+
+	return prefix == "r" || prefix == "rb" || prefix == "br"
+}
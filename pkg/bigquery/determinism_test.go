@@ -0,0 +1,64 @@
+package bigquery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsDeterministic(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		query     string
+		want      bool
+		wantFuncs []string
+	}{
+		{
+			name:      "deterministic query",
+			query:     "SELECT id, DATE(created_at) FROM orders WHERE created_at > '2024-01-01'",
+			want:      true,
+			wantFuncs: []string{},
+		},
+		{
+			name:      "functions with and without parentheses",
+			query:     "SELECT current_date, CURRENT_TIMESTAMP(), rand () AS r, RAND() FROM t",
+			wantFuncs: []string{"CURRENT_DATE", "CURRENT_TIMESTAMP", "RAND"},
+		},
+		{
+			name:      "session functions",
+			query:     "SELECT GENERATE_UUID() AS id, SESSION_USER() AS who",
+			wantFuncs: []string{"GENERATE_UUID", "SESSION_USER"},
+		},
+		{
+			name: "literals, quoted identifiers and comments are ignored",
+			query: "SELECT 'RAND()' AS a, \"it\\'s CURRENT_DATE\" AS b, `current_time` -- CURRENT_TIMESTAMP\n" +
+				"/* GENERATE_UUID() */ FROM t # SESSION_USER()",
+			want:      true,
+			wantFuncs: []string{},
+		},
+		{
+			name:      "raw and triple-quoted literals end where they close",
+			query:     "SELECT REGEXP_REPLACE(path, r'\\', '/'), \"\"\"RAND() \"quoted\" \"\"\" AS doc, RAND() AS r FROM t",
+			want:      false,
+			wantFuncs: []string{"RAND"},
+		},
+		{
+			name:      "similarly named columns",
+			query:     "SELECT rand_bucket, current_date_utc, my_rand(x) FROM t",
+			want:      true,
+			wantFuncs: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, funcs := IsDeterministic(tt.query)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantFuncs, funcs)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// GenerateMarkdownDocs builds a data dictionary of the dataset as a Markdown document from the live metadata: a
+// section per table with its description, followed by its columns with their types, modes and descriptions. The
+// fields of RECORD columns are listed as nested items under them. Tables and columns are sorted by name so that the
+// document only changes when the metadata does.
+func (d *Client) GenerateMarkdownDocs(ctx context.Context, datasetName string) (string, error) {
+	projectID, datasetID, err := d.resolveDataset(datasetName)
+	if err != nil {
+		return "", err
+	}
+
+	tableNames := make([]string, 0)
+	it := d.client.DatasetInProject(projectID, datasetID).Tables(ctx)
+	for {
+		table, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return "", errors.Wrapf(formatError(err), "failed to list the tables of dataset '%s'", datasetName)
+		}
+		tableNames = append(tableNames, table.TableID)
+	}
+	sort.Strings(tableNames)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# `%s.%s`\n", projectID, datasetID)
+	if len(tableNames) == 0 {
+		b.WriteString("\n_The dataset has no tables._\n")
+	}
+
+	for _, tableName := range tableNames {
+		table, err := d.DescribeTable(ctx, fmt.Sprintf("%s.%s.%s", projectID, datasetID, tableName))
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&b, "\n## `%s`", tableName)
+		if table.Type != "" && table.Type != "TABLE" {
+			fmt.Fprintf(&b, " (%s)", strings.ReplaceAll(strings.ToLower(table.Type), "_", " "))
+		}
+		b.WriteString("\n")
+		if table.Description != "" {
+			fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(table.Description))
+		}
+
+		if len(table.Columns) > 0 {
+			b.WriteString("\n")
+			writeMarkdownColumns(&b, table.Columns, 0)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// writeMarkdownColumns writes the columns as list items, sorted by name, with the fields of RECORD columns indented
+// under them.
+func writeMarkdownColumns(b *strings.Builder, columns []ColumnSchema, depth int) {
+	sorted := append([]ColumnSchema{}, columns...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return strings.ToLower(sorted[i].Name) < strings.ToLower(sorted[j].Name)
+	})
+
+	indent := strings.Repeat("  ", depth)
+	for _, column := range sorted {
+		kind := column.Type
+		if column.Mode != "" && column.Mode != "NULLABLE" {
+			kind += ", " + column.Mode
+		}
+		fmt.Fprintf(b, "%s- `%s` (%s)", indent, column.Name, kind)
+		if column.Description != "" {
+			// list items cannot span multiple lines without breaking the nesting
+			fmt.Fprintf(b, ": %s", strings.Join(strings.Fields(column.Description), " "))
+		}
+		b.WriteString("\n")
+
+		writeMarkdownColumns(b, column.Fields, depth+1)
+	}
+}
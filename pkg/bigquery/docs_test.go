@@ -0,0 +1,72 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_GenerateMarkdownDocs(t *testing.T) {
+	t.Parallel()
+
+	tables := map[string]*bigquery2.Table{
+		"users": {
+			Type:        "TABLE",
+			Description: "All the users that signed up.",
+			Schema: &bigquery2.TableSchema{Fields: []*bigquery2.TableFieldSchema{
+				{Name: "name", Type: "STRING", Description: "The full name\nof the user"},
+				{Name: "id", Type: "INTEGER", Mode: "REQUIRED", Description: "The user identifier"},
+				{Name: "address", Type: "RECORD", Fields: []*bigquery2.TableFieldSchema{
+					{Name: "zip", Type: "STRING"},
+					{Name: "city", Type: "STRING", Description: "The city"},
+				}},
+			}},
+		},
+		"active_users": {
+			Type:   "VIEW",
+			Schema: &bigquery2.TableSchema{Fields: []*bigquery2.TableFieldSchema{{Name: "id", Type: "INTEGER"}}},
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tablesPath := fmt.Sprintf("/projects/%s/datasets/analytics/tables", testProjectID)
+		if r.URL.Path == tablesPath {
+			_ = json.NewEncoder(w).Encode(&bigquery2.TableList{Tables: []*bigquery2.TableListTables{
+				{TableReference: &bigquery2.TableReference{ProjectId: testProjectID, DatasetId: "analytics", TableId: "users"}},
+				{TableReference: &bigquery2.TableReference{ProjectId: testProjectID, DatasetId: "analytics", TableId: "active_users"}},
+			}})
+			return
+		}
+
+		for name, table := range tables {
+			if r.URL.Path == tablesPath+"/"+name {
+				_ = json.NewEncoder(w).Encode(table)
+				return
+			}
+		}
+		http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	docs, err := d.GenerateMarkdownDocs(context.Background(), "analytics")
+	require.NoError(t, err)
+	assert.Equal(t, "# `test-project.analytics`\n"+
+		"\n## `active_users` (view)\n"+
+		"\n- `id` (INTEGER)\n"+
+		"\n## `users`\n"+
+		"\nAll the users that signed up.\n"+
+		"\n- `address` (RECORD)\n"+
+		"  - `city` (STRING): The city\n"+
+		"  - `zip` (STRING)\n"+
+		"- `id` (INTEGER, REQUIRED): The user identifier\n"+
+		"- `name` (STRING): The full name of the user\n", docs)
+}
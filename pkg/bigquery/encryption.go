@@ -0,0 +1,114 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+// KMSKeyParameter is the asset parameter that sets the Cloud KMS key the table of the asset is encrypted with,
+// e.g. `projects/p/locations/eu/keyRings/r/cryptoKeys/k`, overriding Config.DefaultEncryptionKey.
+const KMSKeyParameter = "kms_key_name"
+
+// EncryptionKeyMismatchError is returned when an existing table is not encrypted with the KMS key its asset expects.
+// The key of a table is never changed implicitly, since re-encrypting a table is a compliance decision.
+type EncryptionKeyMismatchError struct {
+	Table    string
+	Expected string
+	Actual   string
+}
+
+func (e *EncryptionKeyMismatchError) Error() string {
+	actual := "Google-managed keys"
+	if e.Actual != "" {
+		actual = fmt.Sprintf("the KMS key '%s'", e.Actual)
+	}
+
+	return fmt.Sprintf(
+		"table '%s' is encrypted with %s, but the asset expects the KMS key '%s'; re-encrypt the table with `ALTER TABLE %s SET OPTIONS(kms_key_name='%s')` or recreate it with a full refresh",
+		e.Table, actual, e.Expected, e.Table, e.Expected,
+	)
+}
+
+// assetEncryptionKey returns the KMS key given on the asset through KMSKeyParameter, which the statements creating
+// the asset table set explicitly.
+func assetEncryptionKey(asset *pipeline.Asset) string {
+	return strings.TrimSpace(asset.Parameters[KMSKeyParameter])
+}
+
+// EncryptionKey returns the KMS key the table of the asset is expected to be encrypted with: the one given on the
+// asset, Config.DefaultEncryptionKey otherwise. An empty key leaves the encryption to Google-managed keys.
+func (d *Client) EncryptionKey(asset *pipeline.Asset) string {
+	if key := assetEncryptionKey(asset); key != "" {
+		return key
+	}
+	if d.config != nil {
+		return strings.TrimSpace(d.config.DefaultEncryptionKey)
+	}
+
+	return ""
+}
+
+// CheckEncryptionKey fails with an EncryptionKeyMismatchError if the table of the asset exists but is not encrypted
+// with the KMS key the asset expects, see KMSKeyParameter and Config.DefaultEncryptionKey. Nothing is checked if no
+// key is expected or if the table does not exist yet.
+func (d *Client) CheckEncryptionKey(ctx context.Context, asset *pipeline.Asset) error {
+	expected := d.EncryptionKey(asset)
+	if expected == "" {
+		return nil
+	}
+
+	tableRef, err := d.getTableRef(asset.Name)
+	if err != nil {
+		return err
+	}
+	meta, err := tableRef.Metadata(ctx)
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil
+		}
+		return errors.Wrapf(formatError(err), "failed to fetch metadata for table '%s'", asset.Name)
+	}
+
+	actual := ""
+	if meta.EncryptionConfig != nil {
+		actual = meta.EncryptionConfig.KMSKeyName
+	}
+	if isSameKMSKey(actual, expected) {
+		return nil
+	}
+
+	return &EncryptionKeyMismatchError{Table: asset.Name, Expected: expected, Actual: actual}
+}
+
+// withEncryptionKey returns the asset with the given KMS key set through KMSKeyParameter, so that the statements
+// rendered for it create its table encrypted with the key. The asset itself is left untouched.
+func withEncryptionKey(asset *pipeline.Asset, key string) *pipeline.Asset {
+	if key == "" || assetEncryptionKey(asset) == key {
+		return asset
+	}
+
+	withKey := *asset
+	withKey.Parameters = make(pipeline.EmptyStringMap, len(asset.Parameters)+1)
+	for name, value := range asset.Parameters {
+		withKey.Parameters[name] = value
+	}
+	withKey.Parameters[KMSKeyParameter] = key
+
+	return &withKey
+}
+
+// isSameKMSKey compares KMS key names regardless of the key version BigQuery may report along with the key.
+func isSameKMSKey(actual, expected string) bool {
+	if i := strings.Index(actual, "/cryptoKeyVersions/"); i >= 0 {
+		actual = actual[:i]
+	}
+
+	return actual == expected
+}
@@ -0,0 +1,198 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+const (
+	testKMSKey      = "projects/p/locations/eu/keyRings/r/cryptoKeys/k"
+	testOtherKMSKey = "projects/p/locations/eu/keyRings/r/cryptoKeys/other"
+)
+
+func TestClient_CheckEncryptionKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		defaultKey  string
+		assetKey    string
+		notFound    bool
+		tableKey    *bigquery2.EncryptionConfiguration
+		wantChecked bool
+		wantErr     string
+	}{
+		{
+			name:     "nothing is checked without an expected key",
+			tableKey: &bigquery2.EncryptionConfiguration{KmsKeyName: testOtherKMSKey},
+		},
+		{
+			name:        "tables that do not exist yet have no key to check",
+			defaultKey:  testKMSKey,
+			notFound:    true,
+			wantChecked: true,
+		},
+		{
+			name:        "table encrypted with the default key",
+			defaultKey:  testKMSKey,
+			tableKey:    &bigquery2.EncryptionConfiguration{KmsKeyName: testKMSKey},
+			wantChecked: true,
+		},
+		{
+			name:        "the key version reported by BigQuery is ignored",
+			defaultKey:  testKMSKey,
+			tableKey:    &bigquery2.EncryptionConfiguration{KmsKeyName: testKMSKey + "/cryptoKeyVersions/3"},
+			wantChecked: true,
+		},
+		{
+			name:        "table encrypted with another key",
+			defaultKey:  testKMSKey,
+			tableKey:    &bigquery2.EncryptionConfiguration{KmsKeyName: testOtherKMSKey},
+			wantChecked: true,
+			wantErr:     fmt.Sprintf("table 'myschema.mytable' is encrypted with the KMS key '%s', but the asset expects the KMS key '%s'", testOtherKMSKey, testKMSKey),
+		},
+		{
+			name:        "table encrypted with Google-managed keys",
+			defaultKey:  testKMSKey,
+			wantChecked: true,
+			wantErr:     fmt.Sprintf("table 'myschema.mytable' is encrypted with Google-managed keys, but the asset expects the KMS key '%s'", testKMSKey),
+		},
+		{
+			name:        "the key of the asset overrides the default one",
+			defaultKey:  testOtherKMSKey,
+			assetKey:    testKMSKey,
+			tableKey:    &bigquery2.EncryptionConfiguration{KmsKeyName: testKMSKey},
+			wantChecked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			checked := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != fmt.Sprintf("/projects/%s/datasets/myschema/tables/mytable", testProjectID) {
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+					return
+				}
+				checked = true
+				if tt.notFound {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "not found"}}`))
+					return
+				}
+				_ = json.NewEncoder(w).Encode(&bigquery2.Table{EncryptionConfiguration: tt.tableKey})
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.DefaultEncryptionKey = tt.defaultKey
+
+			asset := &pipeline.Asset{Name: "myschema.mytable"}
+			if tt.assetKey != "" {
+				asset.Parameters = map[string]string{KMSKeyParameter: tt.assetKey}
+			}
+
+			err := d.CheckEncryptionKey(context.Background(), asset)
+			assert.Equal(t, tt.wantChecked, checked)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			var mismatch *EncryptionKeyMismatchError
+			require.ErrorAs(t, err, &mismatch)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestClient_CreateDataSetIfNotExist_EncryptionKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		dataset string
+		asset   *pipeline.Asset
+		wantKey string
+	}{
+		{
+			name:    "the default key is set on the dataset",
+			dataset: "fresh_encrypted",
+			asset:   &pipeline.Asset{Name: "fresh_encrypted.mytable"},
+			wantKey: testOtherKMSKey,
+		},
+		{
+			name:    "the key of the asset overrides the default one",
+			dataset: "fresh_encrypted_override",
+			asset: &pipeline.Asset{
+				Name:       "fresh_encrypted_override.mytable",
+				Parameters: map[string]string{KMSKeyParameter: testKMSKey},
+			},
+			wantKey: testKMSKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var created *bigquery2.Dataset
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/datasets/%s", testProjectID, tt.dataset):
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "not found"}}`))
+				case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/datasets", testProjectID):
+					var ds bigquery2.Dataset
+					_ = json.NewDecoder(r.Body).Decode(&ds)
+					mu.Lock()
+					created = &ds
+					mu.Unlock()
+					_ = json.NewEncoder(w).Encode(&ds)
+				default:
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.DefaultEncryptionKey = testOtherKMSKey
+
+			require.NoError(t, d.CreateDataSetIfNotExist(tt.asset, context.Background()))
+
+			mu.Lock()
+			defer mu.Unlock()
+			require.NotNil(t, created)
+			require.NotNil(t, created.DefaultEncryptionConfiguration)
+			assert.Equal(t, tt.wantKey, created.DefaultEncryptionConfiguration.KmsKeyName)
+		})
+	}
+}
+
+func TestWithEncryptionKey(t *testing.T) {
+	t.Parallel()
+
+	asset := &pipeline.Asset{Name: "my.asset", Parameters: map[string]string{"other": "value"}}
+
+	assert.Same(t, asset, withEncryptionKey(asset, ""))
+
+	withKey := withEncryptionKey(asset, testKMSKey)
+	assert.Equal(t, testKMSKey, withKey.Parameters[KMSKeyParameter])
+	assert.Equal(t, "value", withKey.Parameters["other"])
+	assert.NotContains(t, asset.Parameters, KMSKeyParameter, "the original asset must be left untouched")
+
+	assert.Same(t, withKey, withEncryptionKey(withKey, testKMSKey))
+}
@@ -0,0 +1,312 @@
+package bigquery
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+// The error reasons BigQuery reports most often, see https://cloud.google.com/bigquery/docs/error-messages.
+const (
+	ReasonNotFound     = "notFound"
+	ReasonInvalidQuery = "invalidQuery"
+	ReasonAccessDenied = "accessDenied"
+)
+
+// jobErrorCodes are the HTTP status codes that correspond to the reasons of the errors BigQuery reports in the
+// status of a job, which come without a status code of their own.
+var jobErrorCodes = map[string]int{
+	ReasonNotFound:     http.StatusNotFound,
+	ReasonInvalidQuery: http.StatusBadRequest,
+	ReasonAccessDenied: http.StatusForbidden,
+}
+
+// BigQueryError is a failed API call or job, keeping the HTTP status code and the BigQuery reason of the error so
+// that callers can tell e.g. a missing table from a denied access. The original googleapi.Error, or bigquery.Error
+// for errors reported in the status of a job, is available through errors.As.
+type BigQueryError struct {
+	Code    int
+	Reason  string
+	Message string
+	err     error
+}
+
+func newBigQueryError(err *googleapi.Error) *BigQueryError {
+	bqErr := &BigQueryError{Code: err.Code, Message: err.Message, err: err}
+	for _, item := range err.Errors {
+		if item.Reason != "" {
+			bqErr.Reason = item.Reason
+			break
+		}
+	}
+
+	return bqErr
+}
+
+// newBigQueryJobError maps an error from the status of a job, e.g. a table the query reads that does not exist, the
+// status code is derived from its reason.
+func newBigQueryJobError(err *bigquery.Error) *BigQueryError {
+	return &BigQueryError{Code: jobErrorCodes[err.Reason], Reason: err.Reason, Message: err.Message, err: err}
+}
+
+// Error returns only the message for invalid queries and missing resources, which is the part relevant to the
+// user, and the full description of the error otherwise.
+func (e *BigQueryError) Error() string {
+	if e.Code == http.StatusNotFound || e.Code == http.StatusBadRequest {
+		return e.Message
+	}
+
+	return e.err.Error()
+}
+
+func (e *BigQueryError) Unwrap() error {
+	return e.err
+}
+
+// IsNotFound reports whether the error was caused by a missing table, dataset, job or other resource.
+func IsNotFound(err error) bool {
+	var bqErr *BigQueryError
+	if errors.As(err, &bqErr) {
+		return bqErr.Code == http.StatusNotFound || bqErr.Reason == ReasonNotFound
+	}
+
+	var googleError *googleapi.Error
+	if errors.As(err, &googleError) {
+		return googleError.Code == http.StatusNotFound
+	}
+
+	var jobError *bigquery.Error
+	return errors.As(err, &jobError) && jobError.Reason == ReasonNotFound
+}
+
+// IsAccessDenied reports whether the error was caused by missing permissions.
+func IsAccessDenied(err error) bool {
+	var bqErr *BigQueryError
+	if errors.As(err, &bqErr) {
+		return bqErr.Code == http.StatusForbidden || bqErr.Reason == ReasonAccessDenied
+	}
+
+	var googleError *googleapi.Error
+	if errors.As(err, &googleError) {
+		return googleError.Code == http.StatusForbidden
+	}
+
+	var jobError *bigquery.Error
+	return errors.As(err, &jobError) && jobError.Reason == ReasonAccessDenied
+}
+
+// concurrentModificationMarkers are the message fragments BigQuery uses when a job conflicts with another job
+// writing to the same table.
+var concurrentModificationMarkers = []string{
+	"due to concurrent update",
+	"concurrent update against table",
+	"is being modified by another job",
+	"too many table update operations for this table",
+	"concurrent policy changes",
+}
+
+// ConcurrentModificationError is returned when a job failed because another job was modifying the same table.
+// These failures are transient, the job can be retried once the conflicting write is complete.
+type ConcurrentModificationError struct {
+	Message string
+	err     error
+}
+
+func (e *ConcurrentModificationError) Error() string {
+	return e.Message
+}
+
+func (e *ConcurrentModificationError) Unwrap() error {
+	return e.err
+}
+
+func (e *ConcurrentModificationError) IsConcurrentModification() bool {
+	return true
+}
+
+// IsConcurrentModification reports whether the error was caused by a conflicting write to the same resource,
+// either another job modifying the table or an update that was rejected because the resource changed since it
+// was read, e.g. an ETag-guarded metadata or IAM policy update.
+func IsConcurrentModification(err error) bool {
+	var target interface{ IsConcurrentModification() bool }
+	if errors.As(err, &target) && target.IsConcurrentModification() {
+		return true
+	}
+
+	return asConcurrentModificationError(err) != nil
+}
+
+// asConcurrentModificationError maps the API and job errors that signal a concurrent modification to
+// ConcurrentModificationError, returning nil for every other error.
+func asConcurrentModificationError(err error) *ConcurrentModificationError {
+	var message string
+
+	var googleError *googleapi.Error
+	var jobError *bigquery.Error
+	switch {
+	case errors.As(err, &googleError):
+		if googleError.Code == http.StatusPreconditionFailed {
+			return &ConcurrentModificationError{Message: googleError.Message, err: err}
+		}
+
+		message = googleError.Message
+		for _, item := range googleError.Errors {
+			if isConcurrentModificationMessage(item.Message) {
+				message = item.Message
+			}
+		}
+	case errors.As(err, &jobError):
+		message = jobError.Message
+	default:
+		return nil
+	}
+
+	if !isConcurrentModificationMessage(message) {
+		return nil
+	}
+
+	return &ConcurrentModificationError{Message: message, err: err}
+}
+
+func isConcurrentModificationMessage(message string) bool {
+	lower := strings.ToLower(message)
+	for _, marker := range concurrentModificationMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// bytesBilledLimitReason is the error reason BigQuery uses when a query would bill more than its maximum bytes billed.
+const bytesBilledLimitReason = "bytesBilledLimitExceeded"
+
+// bytesBilledLimitMessage matches messages such as "Query exceeded limit for bytes billed: 1000. 10485760 or higher required.".
+var bytesBilledLimitMessage = regexp.MustCompile(`(?i)limit for bytes billed: (\d+)\. (\d+) or higher required`)
+
+// BytesBilledLimitError is returned when a query was not run because it would bill more bytes than the configured
+// maximum bytes billed. BytesRequired and Limit are zero if BigQuery did not report them.
+type BytesBilledLimitError struct {
+	BytesRequired int64
+	Limit         int64
+	Message       string
+	err           error
+}
+
+func (e *BytesBilledLimitError) Error() string {
+	if e.BytesRequired == 0 && e.Limit == 0 {
+		return "query exceeded the configured maximum bytes billed: " + e.Message
+	}
+
+	return fmt.Sprintf("query would process %d bytes, exceeding the configured limit of %d", e.BytesRequired, e.Limit)
+}
+
+func (e *BytesBilledLimitError) Unwrap() error {
+	return e.err
+}
+
+// asBytesBilledLimitError maps the API and job errors that signal an exceeded maximum bytes billed to
+// BytesBilledLimitError, returning nil for every other error.
+func asBytesBilledLimitError(err error) *BytesBilledLimitError {
+	var message string
+	found := false
+
+	var googleError *googleapi.Error
+	var jobError *bigquery.Error
+	switch {
+	case errors.As(err, &googleError):
+		message = googleError.Message
+		for _, item := range googleError.Errors {
+			if item.Reason == bytesBilledLimitReason {
+				message = item.Message
+				found = true
+			}
+		}
+	case errors.As(err, &jobError):
+		message = jobError.Message
+		found = jobError.Reason == bytesBilledLimitReason
+	default:
+		return nil
+	}
+
+	matches := bytesBilledLimitMessage.FindStringSubmatch(message)
+	if !found && matches == nil {
+		return nil
+	}
+
+	limitErr := &BytesBilledLimitError{Message: message, err: err}
+	if matches != nil {
+		limitErr.Limit, _ = strconv.ParseInt(matches[1], 10, 64)
+		limitErr.BytesRequired, _ = strconv.ParseInt(matches[2], 10, 64)
+	}
+
+	return limitErr
+}
+
+// partitionFilterRequiredMessage matches messages such as "Cannot query over table 'project.dataset.table' without
+// a filter over column(s) 'dt' that can be used for partition elimination".
+var partitionFilterRequiredMessage = regexp.MustCompile(`(?i)cannot query over table '([^']+)' without a filter over column\(s\) (.+?) that can be used for partition elimination`)
+
+// PartitionFilterRequiredError is returned when a query reads a table that has require_partition_filter set without
+// filtering on its partition column, which BigQuery rejects to prevent scanning every partition of the table.
+type PartitionFilterRequiredError struct {
+	Table   string
+	Columns []string
+	Message string
+	err     error
+}
+
+func (e *PartitionFilterRequiredError) Error() string {
+	return fmt.Sprintf(
+		"this query must include a filter on partition column '%s', table '%s' requires a partition filter to avoid scanning all of its partitions",
+		strings.Join(e.Columns, "' or '"),
+		e.Table,
+	)
+}
+
+func (e *PartitionFilterRequiredError) Unwrap() error {
+	return e.err
+}
+
+// asPartitionFilterRequiredError maps the API and job errors that signal a missing partition filter to
+// PartitionFilterRequiredError, returning nil for every other error.
+func asPartitionFilterRequiredError(err error) *PartitionFilterRequiredError {
+	var messages []string
+
+	var googleError *googleapi.Error
+	var jobError *bigquery.Error
+	switch {
+	case errors.As(err, &googleError):
+		messages = append(messages, googleError.Message)
+		for _, item := range googleError.Errors {
+			messages = append(messages, item.Message)
+		}
+	case errors.As(err, &jobError):
+		messages = append(messages, jobError.Message)
+	default:
+		return nil
+	}
+
+	for _, message := range messages {
+		matches := partitionFilterRequiredMessage.FindStringSubmatch(message)
+		if matches == nil {
+			continue
+		}
+
+		columns := strings.Split(matches[2], ",")
+		for i, column := range columns {
+			columns[i] = strings.Trim(strings.TrimSpace(column), "'`")
+		}
+
+		return &PartitionFilterRequiredError{Table: matches[1], Columns: columns, Message: message, err: err}
+	}
+
+	return nil
+}
@@ -0,0 +1,330 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+)
+
+func TestFormatError_ConcurrentModification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		err        error
+		concurrent bool
+		wantMsg    string
+	}{
+		{
+			name: "concurrent DML update",
+			err: &googleapi.Error{
+				Code:    400,
+				Message: "Could not serialize access to table my-project:dataset.table due to concurrent update",
+			},
+			concurrent: true,
+			wantMsg:    "Could not serialize access to table my-project:dataset.table due to concurrent update",
+		},
+		{
+			name: "conflict reported in the error items",
+			err: &googleapi.Error{
+				Code:    400,
+				Message: "Query error",
+				Errors:  []googleapi.ErrorItem{{Reason: "invalidQuery", Message: "Transaction is aborted due to concurrent update against table dataset.table"}},
+			},
+			concurrent: true,
+			wantMsg:    "Transaction is aborted due to concurrent update against table dataset.table",
+		},
+		{
+			name:       "failed job",
+			err:        fmt.Errorf("job failed: %w", &bigquery.Error{Reason: "invalidQuery", Message: "Table dataset.table is being modified by another job"}),
+			concurrent: true,
+			wantMsg:    "Table dataset.table is being modified by another job",
+		},
+		{
+			name:       "resource changed since it was read",
+			err:        &googleapi.Error{Code: 412, Message: "Precondition check failed."},
+			concurrent: true,
+			wantMsg:    "Precondition check failed.",
+		},
+		{
+			name:       "concurrent policy changes",
+			err:        &googleapi.Error{Code: 409, Message: "There were concurrent policy changes. Please retry the whole read-modify-write with exponential backoff."},
+			concurrent: true,
+			wantMsg:    "There were concurrent policy changes. Please retry the whole read-modify-write with exponential backoff.",
+		},
+		{
+			name:    "unrelated bad request",
+			err:     &googleapi.Error{Code: 400, Message: "Syntax error: Unexpected end of script"},
+			wantMsg: "Syntax error: Unexpected end of script",
+		},
+		{
+			name:    "non-API error",
+			err:     errors.New("some error"),
+			wantMsg: "some error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := formatError(tt.err)
+			assert.Equal(t, tt.concurrent, IsConcurrentModification(got))
+			assert.EqualError(t, got, tt.wantMsg)
+		})
+	}
+}
+
+func TestFormatError_BytesBilledLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		err     error
+		wantMsg string
+	}{
+		{
+			name: "limit reported by the API",
+			err: &googleapi.Error{
+				Code:    400,
+				Message: "Query exceeded limit for bytes billed: 1000. 10485760 or higher required.",
+				Errors:  []googleapi.ErrorItem{{Reason: "bytesBilledLimitExceeded", Message: "Query exceeded limit for bytes billed: 1000. 10485760 or higher required."}},
+			},
+			wantMsg: "query would process 10485760 bytes, exceeding the configured limit of 1000",
+		},
+		{
+			name:    "failed job",
+			err:     fmt.Errorf("job failed: %w", &bigquery.Error{Reason: "bytesBilledLimitExceeded", Message: "Query exceeded limit for bytes billed: 500. 2048 or higher required."}),
+			wantMsg: "query would process 2048 bytes, exceeding the configured limit of 500",
+		},
+		{
+			name:    "message without the byte counts",
+			err:     &bigquery.Error{Reason: "bytesBilledLimitExceeded", Message: "Query exceeded limit for bytes billed."},
+			wantMsg: "query exceeded the configured maximum bytes billed: Query exceeded limit for bytes billed.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := formatError(tt.err)
+
+			var limitErr *BytesBilledLimitError
+			require.ErrorAs(t, got, &limitErr)
+			assert.EqualError(t, got, tt.wantMsg)
+		})
+	}
+}
+
+func TestFormatError_PartitionFilterRequired(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		err         error
+		wantTable   string
+		wantColumns []string
+	}{
+		{
+			name: "rejected by the API",
+			err: &googleapi.Error{
+				Code:    400,
+				Message: "Cannot query over table 'test-project.raw.events' without a filter over column(s) 'event_date' that can be used for partition elimination",
+				Errors:  []googleapi.ErrorItem{{Reason: "invalidQuery"}},
+			},
+			wantTable:   "test-project.raw.events",
+			wantColumns: []string{"event_date"},
+		},
+		{
+			name:        "failed job",
+			err:         fmt.Errorf("job failed: %w", &bigquery.Error{Reason: "invalidQuery", Message: "Cannot query over table 'raw.events' without a filter over column(s) 'dt', 'hour' that can be used for partition elimination"}),
+			wantTable:   "raw.events",
+			wantColumns: []string{"dt", "hour"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := formatError(tt.err)
+
+			var filterErr *PartitionFilterRequiredError
+			require.ErrorAs(t, got, &filterErr)
+			assert.Equal(t, tt.wantTable, filterErr.Table)
+			assert.Equal(t, tt.wantColumns, filterErr.Columns)
+			assert.Contains(t, got.Error(), "this query must include a filter on partition column '"+tt.wantColumns[0]+"'")
+		})
+	}
+
+	var filterErr *PartitionFilterRequiredError
+	assert.False(t, errors.As(formatError(&googleapi.Error{Code: 400, Message: "Syntax error: Unexpected end of script"}), &filterErr))
+}
+
+func TestClient_RunQueryWithoutResult_ConcurrentModification(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": {"code": 400, "message": "Could not serialize access to table test-project:dataset.table due to concurrent update", "errors": [{"reason": "invalidQuery"}]}}`))
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	err := d.RunQueryWithoutResult(context.Background(), &query.Query{Query: "UPDATE dataset.table SET a = 1 WHERE true"})
+	require.Error(t, err)
+	assert.True(t, IsConcurrentModification(err))
+
+	var concurrentErr *ConcurrentModificationError
+	require.ErrorAs(t, err, &concurrentErr)
+
+	var googleError *googleapi.Error
+	require.ErrorAs(t, err, &googleError)
+	assert.Equal(t, http.StatusBadRequest, googleError.Code)
+}
+
+func TestFormatError_BigQueryError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		err              error
+		wantCode         int
+		wantReason       string
+		wantMsg          string
+		wantNotFound     bool
+		wantAccessDenied bool
+	}{
+		{
+			name: "missing table",
+			err: &googleapi.Error{
+				Code:    404,
+				Message: "Not found: Table test-project:dataset.users",
+				Errors:  []googleapi.ErrorItem{{Reason: "notFound", Message: "Not found: Table test-project:dataset.users"}},
+			},
+			wantCode:     404,
+			wantReason:   ReasonNotFound,
+			wantMsg:      "Not found: Table test-project:dataset.users",
+			wantNotFound: true,
+		},
+		{
+			name: "invalid query",
+			err: fmt.Errorf("query failed: %w", &googleapi.Error{
+				Code:    400,
+				Message: "Syntax error: Unexpected end of script",
+				Errors:  []googleapi.ErrorItem{{Reason: "invalidQuery"}},
+			}),
+			wantCode:   400,
+			wantReason: ReasonInvalidQuery,
+			wantMsg:    "Syntax error: Unexpected end of script",
+		},
+		{
+			name: "denied access",
+			err: &googleapi.Error{
+				Code:    403,
+				Message: "Access Denied: Table test-project:dataset.users",
+				Errors:  []googleapi.ErrorItem{{Reason: "accessDenied"}},
+			},
+			wantCode:         403,
+			wantReason:       ReasonAccessDenied,
+			wantMsg:          "googleapi: Error 403: Access Denied: Table test-project:dataset.users\nMore details:\nReason: accessDenied, Message: \n",
+			wantAccessDenied: true,
+		},
+		{
+			name:     "error without a reason",
+			err:      &googleapi.Error{Code: 500, Message: "Backend error"},
+			wantCode: 500,
+			wantMsg:  "googleapi: Error 500: Backend error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := formatError(tt.err)
+
+			var bqErr *BigQueryError
+			require.ErrorAs(t, got, &bqErr)
+			assert.Equal(t, tt.wantCode, bqErr.Code)
+			assert.Equal(t, tt.wantReason, bqErr.Reason)
+			assert.EqualError(t, got, tt.wantMsg)
+			assert.Equal(t, tt.wantNotFound, IsNotFound(got))
+			assert.Equal(t, tt.wantAccessDenied, IsAccessDenied(got))
+
+			var googleError *googleapi.Error
+			require.ErrorAs(t, got, &googleError, "the original error must remain reachable")
+			assert.Equal(t, tt.wantCode, googleError.Code)
+		})
+	}
+
+	assert.False(t, IsNotFound(formatError(errors.New("some other error"))))
+}
+
+func TestFormatError_BigQueryJobError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name             string
+		err              *bigquery.Error
+		wantCode         int
+		wantMsg          string
+		wantNotFound     bool
+		wantAccessDenied bool
+	}{
+		{
+			name:         "table not found in query",
+			err:          &bigquery.Error{Reason: "notFound", Message: "Not found: Table test-project:dataset.users was not found in location US"},
+			wantCode:     404,
+			wantMsg:      "Not found: Table test-project:dataset.users was not found in location US",
+			wantNotFound: true,
+		},
+		{
+			name:     "invalid query",
+			err:      &bigquery.Error{Reason: "invalidQuery", Message: "Unrecognized name: nme at [1:8]", Location: "query"},
+			wantCode: 400,
+			wantMsg:  "Unrecognized name: nme at [1:8]",
+		},
+		{
+			name:             "denied access",
+			err:              &bigquery.Error{Reason: "accessDenied", Message: "Access Denied: Table test-project:dataset.users"},
+			wantCode:         403,
+			wantMsg:          "{Location: \"\"; Message: \"Access Denied: Table test-project:dataset.users\"; Reason: \"accessDenied\"}",
+			wantAccessDenied: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// the raw job errors are classified as well
+			assert.Equal(t, tt.wantNotFound, IsNotFound(tt.err))
+			assert.Equal(t, tt.wantAccessDenied, IsAccessDenied(tt.err))
+
+			got := formatError(fmt.Errorf("query failed: %w", tt.err))
+
+			var bqErr *BigQueryError
+			require.ErrorAs(t, got, &bqErr)
+			assert.Equal(t, tt.wantCode, bqErr.Code)
+			assert.Equal(t, tt.err.Reason, bqErr.Reason)
+			assert.EqualError(t, got, tt.wantMsg)
+			assert.Equal(t, tt.wantNotFound, IsNotFound(got))
+			assert.Equal(t, tt.wantAccessDenied, IsAccessDenied(got))
+
+			var jobError *bigquery.Error
+			require.ErrorAs(t, got, &jobError, "the original error must remain reachable")
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+)
+
+// hivePartitioningFormats are the source formats BigQuery supports hive partitioning for.
+var hivePartitioningFormats = map[bigquery.DataFormat]bool{
+	bigquery.Avro:    true,
+	bigquery.CSV:     true,
+	bigquery.JSON:    true,
+	bigquery.ORC:     true,
+	bigquery.Parquet: true,
+}
+
+// customPartitionKeyRegex matches a partition key encoded in the source URI prefix of the CUSTOM hive partitioning
+// mode, e.g. `{dt:DATE}`.
+var customPartitionKeyRegex = regexp.MustCompile(`^\{\w+:(STRING|INTEGER|DATE|TIMESTAMP)\}$`)
+
+// ExternalTableOptions configures an external table over files in Google Cloud Storage.
+type ExternalTableOptions struct {
+	SourceFormat bigquery.DataFormat
+	SourceURIs   []string
+
+	// Schema is the explicit schema of the table, it is auto-detected by BigQuery when empty. The hive partition
+	// columns are never part of it, they are derived from the file paths.
+	Schema bigquery.Schema
+
+	// HivePartitioning makes the table partitioned by the `key=value` directories of the file paths.
+	HivePartitioning *HivePartitioningOptions
+}
+
+// HivePartitioningOptions configures the hive partitioning of an external table.
+type HivePartitioningOptions struct {
+	// Mode is how the partition keys are typed: bigquery.AutoHivePartitioningMode detects the partition columns
+	// and their types from the paths, bigquery.StringHivePartitioningMode reads them all as strings and
+	// bigquery.CustomHivePartitioningMode reads them with the types encoded in SourceURIPrefix. It defaults to AUTO.
+	Mode bigquery.HivePartitioningMode
+
+	// SourceURIPrefix is the path all the source URIs share before the partition keys begin, e.g.
+	// `gs://bucket/events/`. In CUSTOM mode it is followed by the partition keys and their types, e.g.
+	// `gs://bucket/events/{dt:DATE}/{country:STRING}`.
+	SourceURIPrefix string
+
+	// RequirePartitionFilter rejects the queries on the table that do not filter on a partition column.
+	RequirePartitionFilter bool
+}
+
+// CreateExternalTable creates an external table with the given name over the files in Google Cloud Storage.
+func (d *Client) CreateExternalTable(ctx context.Context, tableName string, opts ExternalTableOptions) error {
+	if len(opts.SourceURIs) == 0 {
+		return errors.New("at least one source URI is required to create an external table")
+	}
+
+	tableRef, err := d.getTableRef(tableName)
+	if err != nil {
+		return err
+	}
+
+	config := &bigquery.ExternalDataConfig{
+		SourceFormat: opts.SourceFormat,
+		SourceURIs:   opts.SourceURIs,
+	}
+	if len(opts.Schema) > 0 {
+		if err := ValidateSchema(opts.Schema); err != nil {
+			return errors.Wrapf(err, "invalid schema for external table '%s'", tableName)
+		}
+		config.Schema = opts.Schema
+	} else {
+		config.AutoDetect = true
+	}
+
+	if hive := opts.HivePartitioning; hive != nil {
+		if err := validateHivePartitioning(opts.SourceFormat, opts.SourceURIs, hive); err != nil {
+			return errors.Wrapf(err, "invalid hive partitioning for external table '%s'", tableName)
+		}
+
+		mode := hive.Mode
+		if mode == "" {
+			mode = bigquery.AutoHivePartitioningMode
+		}
+		config.HivePartitioningOptions = &bigquery.HivePartitioningOptions{
+			Mode:                   mode,
+			SourceURIPrefix:        hive.SourceURIPrefix,
+			RequirePartitionFilter: hive.RequirePartitionFilter,
+		}
+	}
+
+	if err := tableRef.Create(ctx, &bigquery.TableMetadata{ExternalDataConfig: config}); err != nil {
+		return fmt.Errorf("failed to create external table '%s': %w", tableName, formatError(err))
+	}
+
+	return nil
+}
+
+// validateHivePartitioning checks that the source URI prefix is a GCS path shared by all the source URIs and, in
+// CUSTOM mode, that it encodes the partition keys with supported types.
+func validateHivePartitioning(format bigquery.DataFormat, sourceURIs []string, hive *HivePartitioningOptions) error {
+	if !hivePartitioningFormats[format] {
+		return fmt.Errorf("hive partitioning is not supported for the source format '%s'", format)
+	}
+
+	switch hive.Mode {
+	case "", bigquery.AutoHivePartitioningMode, bigquery.StringHivePartitioningMode, bigquery.CustomHivePartitioningMode:
+	default:
+		return fmt.Errorf("unknown hive partitioning mode '%s'", hive.Mode)
+	}
+
+	prefix := hive.SourceURIPrefix
+	bucket, _, _ := strings.Cut(strings.TrimPrefix(prefix, "gs://"), "/")
+	if !strings.HasPrefix(prefix, "gs://") || bucket == "" {
+		return fmt.Errorf("source URI prefix must be a GCS path such as 'gs://bucket/path/', '%s' given", prefix)
+	}
+	if strings.Contains(prefix, "*") {
+		return fmt.Errorf("source URI prefix must not contain wildcards, '%s' given", prefix)
+	}
+
+	// in CUSTOM mode the common path ends before the first partition key
+	common := prefix
+	if hive.Mode == bigquery.CustomHivePartitioningMode {
+		start := strings.Index(prefix, "{")
+		if start < 0 {
+			return fmt.Errorf("source URI prefix must encode the partition keys in CUSTOM mode, e.g. '%s{dt:DATE}', '%s' given", prefix, prefix)
+		}
+		common = prefix[:start]
+		for _, key := range strings.Split(strings.Trim(prefix[start:], "/"), "/") {
+			if !customPartitionKeyRegex.MatchString(key) {
+				return fmt.Errorf("invalid partition key '%s' in the source URI prefix, expected '{name:TYPE}' with one of the types STRING, INTEGER, DATE or TIMESTAMP", key)
+			}
+		}
+	} else if strings.ContainsAny(prefix, "{}") {
+		return fmt.Errorf("partition keys can only be encoded in the source URI prefix in CUSTOM mode, '%s' given", prefix)
+	}
+
+	// the trailing slash of the prefix is optional
+	common = strings.TrimSuffix(common, "/") + "/"
+	for _, uri := range sourceURIs {
+		if !strings.HasPrefix(uri, common) {
+			return fmt.Errorf("source URI '%s' does not start with the source URI prefix '%s'", uri, common)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestValidateHivePartitioning(t *testing.T) {
+	t.Parallel()
+
+	uris := []string{"gs://lake/events/*"}
+
+	tests := []struct {
+		name    string
+		format  bigquery.DataFormat
+		hive    HivePartitioningOptions
+		wantErr string
+	}{
+		{
+			name:   "auto mode",
+			format: bigquery.Parquet,
+			hive:   HivePartitioningOptions{SourceURIPrefix: "gs://lake/events"},
+		},
+		{
+			name:   "custom mode",
+			format: bigquery.ORC,
+			hive:   HivePartitioningOptions{Mode: bigquery.CustomHivePartitioningMode, SourceURIPrefix: "gs://lake/events/{dt:DATE}/{country:STRING}"},
+		},
+		{
+			name:    "unsupported format",
+			format:  bigquery.GoogleSheets,
+			hive:    HivePartitioningOptions{SourceURIPrefix: "gs://lake/events/"},
+			wantErr: "hive partitioning is not supported for the source format 'GOOGLE_SHEETS'",
+		},
+		{
+			name:    "not a GCS path",
+			format:  bigquery.Parquet,
+			hive:    HivePartitioningOptions{SourceURIPrefix: "s3://lake/events/"},
+			wantErr: "source URI prefix must be a GCS path such as 'gs://bucket/path/', 's3://lake/events/' given",
+		},
+		{
+			name:    "wildcard in the prefix",
+			format:  bigquery.Parquet,
+			hive:    HivePartitioningOptions{SourceURIPrefix: "gs://lake/*/"},
+			wantErr: "source URI prefix must not contain wildcards, 'gs://lake/*/' given",
+		},
+		{
+			name:    "source URIs outside the prefix",
+			format:  bigquery.Parquet,
+			hive:    HivePartitioningOptions{SourceURIPrefix: "gs://lake/orders/"},
+			wantErr: "source URI 'gs://lake/events/*' does not start with the source URI prefix 'gs://lake/orders/'",
+		},
+		{
+			name:    "custom mode without partition keys",
+			format:  bigquery.Parquet,
+			hive:    HivePartitioningOptions{Mode: bigquery.CustomHivePartitioningMode, SourceURIPrefix: "gs://lake/events/"},
+			wantErr: "source URI prefix must encode the partition keys in CUSTOM mode, e.g. 'gs://lake/events/{dt:DATE}', 'gs://lake/events/' given",
+		},
+		{
+			name:    "custom mode with an unsupported type",
+			format:  bigquery.Parquet,
+			hive:    HivePartitioningOptions{Mode: bigquery.CustomHivePartitioningMode, SourceURIPrefix: "gs://lake/events/{dt:DATETIME}"},
+			wantErr: "invalid partition key '{dt:DATETIME}' in the source URI prefix, expected '{name:TYPE}' with one of the types STRING, INTEGER, DATE or TIMESTAMP",
+		},
+		{
+			name:    "partition keys outside custom mode",
+			format:  bigquery.Parquet,
+			hive:    HivePartitioningOptions{Mode: bigquery.StringHivePartitioningMode, SourceURIPrefix: "gs://lake/events/{dt:DATE}"},
+			wantErr: "partition keys can only be encoded in the source URI prefix in CUSTOM mode, 'gs://lake/events/{dt:DATE}' given",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateHivePartitioning(tt.format, uris, &tt.hive)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestClient_CreateExternalTable(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var created *bigquery2.Table
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != fmt.Sprintf("/projects/%s/datasets/lake/tables", testProjectID) {
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+			return
+		}
+
+		var table bigquery2.Table
+		_ = json.NewDecoder(r.Body).Decode(&table)
+		mu.Lock()
+		created = &table
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(&table)
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	err := d.CreateExternalTable(context.Background(), "lake.events", ExternalTableOptions{
+		SourceFormat: bigquery.Parquet,
+		SourceURIs:   []string{"gs://lake/events/*"},
+		HivePartitioning: &HivePartitioningOptions{
+			SourceURIPrefix:        "gs://lake/events/",
+			RequirePartitionFilter: true,
+		},
+	})
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotNil(t, created)
+	config := created.ExternalDataConfiguration
+	assert.Equal(t, "PARQUET", config.SourceFormat)
+	assert.True(t, config.Autodetect)
+	assert.Equal(t, &bigquery2.HivePartitioningOptions{Mode: "AUTO", SourceUriPrefix: "gs://lake/events/", RequirePartitionFilter: true}, config.HivePartitioningOptions)
+
+	err = d.CreateExternalTable(context.Background(), "lake.events", ExternalTableOptions{
+		SourceFormat:     bigquery.Parquet,
+		SourceURIs:       []string{"gs://lake/events/*"},
+		HivePartitioning: &HivePartitioningOptions{SourceURIPrefix: "lake/events"},
+	})
+	require.EqualError(t, err, "invalid hive partitioning for external table 'lake.events': source URI prefix must be a GCS path such as 'gs://bucket/path/', 'lake/events' given")
+}
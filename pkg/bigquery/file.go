@@ -0,0 +1,326 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/decimal128"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/parquet"
+	"github.com/apache/arrow/go/v17/parquet/compress"
+	"github.com/apache/arrow/go/v17/parquet/pqarrow"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+)
+
+// FileFormat is the format SelectToFile writes the results in.
+type FileFormat string
+
+const (
+	FileFormatCSV     FileFormat = "csv"
+	FileFormatParquet FileFormat = "parquet"
+)
+
+// parquetRowGroupSize is the number of rows buffered in memory before they are written out as a row group.
+const parquetRowGroupSize = 10_000
+
+// numericPrecision and numericScale are the precision and scale of the NUMERIC type of BigQuery.
+const (
+	numericPrecision = 38
+	numericScale     = 9
+)
+
+var civilEpoch = civil.Date{Year: 1970, Month: time.January, Day: 1}
+
+// rowFileWriter writes the rows of the results to a file as they are read.
+type rowFileWriter interface {
+	WriteSchema(schema bigquery.Schema) error
+	WriteRow(row []interface{}) error
+	Flush() error
+}
+
+// SelectToFile runs the query and writes its results to the file at the given path as they are read, without
+// holding all the rows in memory. The file is overwritten if it exists. If it cannot be written, the query stops
+// being read and the partially written file is removed.
+//
+// CSV files start with a header row of the column names. Parquet files have the column types of the schema of the
+// results; RECORD and repeated columns, along with the types Parquet has no equivalent for, e.g. BIGNUMERIC or
+// GEOGRAPHY, are written as strings.
+func (d *Client) SelectToFile(ctx context.Context, queryObj *query.Query, path string, format FileFormat) (err error) {
+	var newWriter func(w io.Writer) rowFileWriter
+	switch format {
+	case FileFormatCSV:
+		newWriter = newCSVFileWriter
+	case FileFormatParquet:
+		newWriter = newParquetFileWriter
+	default:
+		return fmt.Errorf("unsupported file format '%s', supported formats are '%s' and '%s'", format, FileFormatCSV, FileFormatParquet)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to create the output file")
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = errors.Wrap(closeErr, "failed to close the output file")
+		}
+		if err != nil {
+			_ = os.Remove(path)
+		}
+	}()
+
+	writer := newWriter(file)
+	err = d.SelectStreamWithSchema(ctx, queryObj, writer.WriteSchema, writer.WriteRow)
+	if err != nil {
+		return err
+	}
+
+	if err := writer.Flush(); err != nil {
+		return errors.Wrapf(err, "failed to write the output file '%s'", path)
+	}
+
+	return nil
+}
+
+type csvFileWriter struct {
+	w *csv.Writer
+}
+
+func newCSVFileWriter(w io.Writer) rowFileWriter {
+	return &csvFileWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvFileWriter) WriteSchema(schema bigquery.Schema) error {
+	header := make([]string, len(schema))
+	for i, field := range schema {
+		header[i] = field.Name
+	}
+
+	return c.write(header)
+}
+
+func (c *csvFileWriter) WriteRow(row []interface{}) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		formatted, err := query.FormatValue(value)
+		if err != nil {
+			return err
+		}
+		record[i] = formatted
+	}
+
+	return c.write(record)
+}
+
+func (c *csvFileWriter) write(record []string) error {
+	if err := c.w.Write(record); err != nil {
+		return errors.Wrap(err, "failed to write to the output file")
+	}
+
+	return nil
+}
+
+func (c *csvFileWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type parquetFileWriter struct {
+	out     io.Writer
+	schema  bigquery.Schema
+	builder *array.RecordBuilder
+	writer  *pqarrow.FileWriter
+	rows    int
+}
+
+func newParquetFileWriter(w io.Writer) rowFileWriter {
+	return &parquetFileWriter{out: w}
+}
+
+func (p *parquetFileWriter) WriteSchema(schema bigquery.Schema) error {
+	fields := make([]arrow.Field, len(schema))
+	for i, field := range schema {
+		fields[i] = arrow.Field{
+			Name:     field.Name,
+			Type:     arrowType(field),
+			Nullable: !field.Required || field.Repeated,
+		}
+	}
+	arrowSchema := arrow.NewSchema(fields, nil)
+
+	// the file is closed by SelectToFile, therefore it is hidden from the writer that would close it otherwise
+	props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+	writer, err := pqarrow.NewFileWriter(arrowSchema, struct{ io.Writer }{p.out}, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return errors.Wrap(err, "failed to create the Parquet writer")
+	}
+
+	p.schema = schema
+	p.writer = writer
+	p.builder = array.NewRecordBuilder(memory.DefaultAllocator, arrowSchema)
+
+	return nil
+}
+
+// arrowType maps the type of a column to the Arrow type it is written to Parquet as.
+func arrowType(field *bigquery.FieldSchema) arrow.DataType {
+	if field.Repeated {
+		return arrow.BinaryTypes.String
+	}
+
+	switch field.Type {
+	case bigquery.IntegerFieldType:
+		return arrow.PrimitiveTypes.Int64
+	case bigquery.FloatFieldType:
+		return arrow.PrimitiveTypes.Float64
+	case bigquery.BooleanFieldType:
+		return arrow.FixedWidthTypes.Boolean
+	case bigquery.BytesFieldType:
+		return arrow.BinaryTypes.Binary
+	case bigquery.NumericFieldType:
+		return &arrow.Decimal128Type{Precision: numericPrecision, Scale: numericScale}
+	case bigquery.TimestampFieldType:
+		return arrow.FixedWidthTypes.Timestamp_us
+	case bigquery.DateTimeFieldType:
+		// DATETIME values are wall-clock times, therefore they have no time zone
+		return &arrow.TimestampType{Unit: arrow.Microsecond}
+	case bigquery.DateFieldType:
+		return arrow.FixedWidthTypes.Date32
+	case bigquery.TimeFieldType:
+		return arrow.FixedWidthTypes.Time64us
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+func (p *parquetFileWriter) WriteRow(row []interface{}) error {
+	for i, value := range row {
+		if err := appendArrowValue(p.builder.Field(i), p.schema[i], value); err != nil {
+			return errors.Wrapf(err, "failed to convert the value of column '%s'", p.schema[i].Name)
+		}
+	}
+
+	p.rows++
+	if p.rows < parquetRowGroupSize {
+		return nil
+	}
+
+	return p.writeRowGroup()
+}
+
+func (p *parquetFileWriter) writeRowGroup() error {
+	record := p.builder.NewRecord()
+	defer record.Release()
+	p.rows = 0
+
+	if err := p.writer.Write(record); err != nil {
+		return errors.Wrap(err, "failed to write to the output file")
+	}
+
+	return nil
+}
+
+func (p *parquetFileWriter) Flush() error {
+	if p.writer == nil {
+		return errors.New("the schema of the results is not available")
+	}
+	defer p.builder.Release()
+
+	if p.rows > 0 {
+		if err := p.writeRowGroup(); err != nil {
+			return err
+		}
+	}
+
+	return p.writer.Close()
+}
+
+func appendArrowValue(builder array.Builder, field *bigquery.FieldSchema, value interface{}) error {
+	if value == nil {
+		builder.AppendNull()
+		return nil
+	}
+
+	switch b := builder.(type) {
+	case *array.Int64Builder:
+		v, ok := value.(int64)
+		if !ok {
+			return unexpectedValueError(value)
+		}
+		b.Append(v)
+	case *array.Float64Builder:
+		v, ok := value.(float64)
+		if !ok {
+			return unexpectedValueError(value)
+		}
+		b.Append(v)
+	case *array.BooleanBuilder:
+		v, ok := value.(bool)
+		if !ok {
+			return unexpectedValueError(value)
+		}
+		b.Append(v)
+	case *array.BinaryBuilder:
+		v, ok := value.([]byte)
+		if !ok {
+			return unexpectedValueError(value)
+		}
+		b.Append(v)
+	case *array.Decimal128Builder:
+		v, ok := value.(*big.Rat)
+		if !ok {
+			return unexpectedValueError(value)
+		}
+		n, err := decimal128.FromString(bigquery.NumericString(v), numericPrecision, numericScale)
+		if err != nil {
+			return err
+		}
+		b.Append(n)
+	case *array.TimestampBuilder:
+		switch v := value.(type) {
+		case time.Time:
+			b.Append(arrow.Timestamp(v.UnixMicro()))
+		case civil.DateTime:
+			b.Append(arrow.Timestamp(v.In(time.UTC).UnixMicro()))
+		default:
+			return unexpectedValueError(value)
+		}
+	case *array.Date32Builder:
+		v, ok := value.(civil.Date)
+		if !ok {
+			return unexpectedValueError(value)
+		}
+		b.Append(arrow.Date32(v.DaysSince(civilEpoch)))
+	case *array.Time64Builder:
+		v, ok := value.(civil.Time)
+		if !ok {
+			return unexpectedValueError(value)
+		}
+		micros := (int64(v.Hour)*3600+int64(v.Minute)*60+int64(v.Second))*1_000_000 + int64(v.Nanosecond)/1_000
+		b.Append(arrow.Time64(micros))
+	case *array.StringBuilder:
+		formatted, err := query.FormatValue(value)
+		if err != nil {
+			return err
+		}
+		b.Append(formatted)
+	default:
+		return fmt.Errorf("unsupported column type '%s'", field.Type)
+	}
+
+	return nil
+}
+
+func unexpectedValueError(value interface{}) error {
+	return fmt.Errorf("unexpected value of type %T", value)
+}
@@ -0,0 +1,153 @@
+package bigquery
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+	"github.com/apache/arrow/go/v17/parquet/file"
+	"github.com/apache/arrow/go/v17/parquet/pqarrow"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func fileQueryResponse() *bigquery2.QueryResponse {
+	return &bigquery2.QueryResponse{
+		JobComplete:  true,
+		JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+		Schema: &bigquery2.TableSchema{
+			Fields: []*bigquery2.TableFieldSchema{
+				{Name: "id", Type: "INTEGER", Mode: "REQUIRED"},
+				{Name: "name", Type: "STRING"},
+				{Name: "amount", Type: "NUMERIC"},
+				{Name: "created_at", Type: "TIMESTAMP"},
+				{Name: "day", Type: "DATE"},
+				{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+			},
+		},
+		Rows: []*bigquery2.TableRow{
+			{F: []*bigquery2.TableCell{
+				{V: "1"},
+				{V: "alice"},
+				{V: "1.5"},
+				{V: "1714557600000000"},
+				{V: "2024-05-01"},
+				{V: []interface{}{map[string]interface{}{"v": "a"}, map[string]interface{}{"v": "b"}}},
+			}},
+			{F: []*bigquery2.TableCell{
+				{V: "2"}, {V: nil}, {V: nil}, {V: nil}, {V: nil}, {V: []interface{}{}},
+			}},
+		},
+		TotalRows: 2,
+	}
+}
+
+func TestClient_SelectToFile_CSV(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			return fileQueryResponse()
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	path := filepath.Join(t.TempDir(), "out.csv")
+
+	require.NoError(t, d.SelectToFile(context.Background(), &query.Query{Query: "SELECT * FROM dataset.users"}, path, FileFormatCSV))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "id,name,amount,created_at,day,tags\n"+
+		"1,alice,1.5,2024-05-01T10:00:00Z,2024-05-01,\"[\"\"a\"\",\"\"b\"\"]\"\n"+
+		"2,,,,,[]\n", string(content))
+}
+
+func TestClient_SelectToFile_Parquet(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			return fileQueryResponse()
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	path := filepath.Join(t.TempDir(), "out.parquet")
+
+	require.NoError(t, d.SelectToFile(context.Background(), &query.Query{Query: "SELECT * FROM dataset.users"}, path, FileFormatParquet))
+
+	reader, err := file.OpenParquetFile(path, false)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	arrowReader, err := pqarrow.NewFileReader(reader, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	require.NoError(t, err)
+	table, err := arrowReader.ReadTable(context.Background())
+	require.NoError(t, err)
+	defer table.Release()
+
+	require.Equal(t, int64(2), table.NumRows())
+	schema := table.Schema()
+	assert.Equal(t, arrow.PrimitiveTypes.Int64, schema.Field(0).Type)
+	assert.False(t, schema.Field(0).Nullable)
+	assert.Equal(t, arrow.BinaryTypes.String, schema.Field(1).Type)
+	assert.Equal(t, &arrow.Decimal128Type{Precision: 38, Scale: 9}, schema.Field(2).Type)
+	assert.Equal(t, arrow.TIMESTAMP, schema.Field(3).Type.ID())
+	assert.Equal(t, arrow.FixedWidthTypes.Date32, schema.Field(4).Type)
+	assert.Equal(t, arrow.BinaryTypes.String, schema.Field(5).Type)
+
+	ids := table.Column(0).Data().Chunk(0).(*array.Int64)
+	assert.Equal(t, []int64{1, 2}, ids.Int64Values())
+
+	names := table.Column(1).Data().Chunk(0).(*array.String)
+	assert.Equal(t, "alice", names.Value(0))
+	assert.True(t, names.IsNull(1))
+
+	amounts := table.Column(2).Data().Chunk(0).(*array.Decimal128)
+	assert.Equal(t, "1.5", amounts.ValueStr(0))
+
+	createdAt := table.Column(3).Data().Chunk(0).(*array.Timestamp)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC).UnixMicro(), int64(createdAt.Value(0)))
+
+	days := table.Column(4).Data().Chunk(0).(*array.Date32)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), days.Value(0).ToTime())
+
+	tags := table.Column(5).Data().Chunk(0).(*array.String)
+	assert.Equal(t, `["a","b"]`, tags.Value(0))
+}
+
+func TestClient_SelectToFile_Errors(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			return fileQueryResponse()
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	dir := t.TempDir()
+
+	err := d.SelectToFile(context.Background(), &query.Query{Query: "SELECT 1"}, filepath.Join(dir, "out.xlsx"), FileFormat("xlsx"))
+	require.EqualError(t, err, "unsupported file format 'xlsx', supported formats are 'csv' and 'parquet'")
+
+	err = d.SelectToFile(context.Background(), &query.Query{Query: "SELECT 1"}, filepath.Join(dir, "missing", "out.csv"), FileFormatCSV)
+	require.ErrorContains(t, err, "failed to create the output file")
+
+	assert.Empty(t, handler.recordedQueries())
+}
@@ -0,0 +1,87 @@
+package bigquery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bruin-data/bruin/pkg/query"
+)
+
+// FingerprintQuery returns a stable fingerprint of the query and its parameters, to be used as a cache key or as the
+// seed of idempotent job IDs. Queries that only differ in comments, in the amount of whitespace between tokens or
+// in trailing semicolons share the same fingerprint, see NormalizeQuery. Named parameters are fingerprinted
+// regardless of their order, positional ones in the order they are given.
+func FingerprintQuery(query string, params ...query.Parameter) string {
+	h := sha256.New()
+	// every part is prefixed with its length so that the boundaries between the query and the parameters are kept
+	writePart := func(part string) {
+		_, _ = fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+	writePart(NormalizeQuery(query))
+
+	encoded := make([]string, 0, len(params))
+	named := true
+	for _, param := range params {
+		named = named && param.Name != ""
+		encoded = append(encoded, fmt.Sprintf("%s=%T:%v", param.Name, param.Value, param.Value))
+	}
+	if named {
+		sort.Strings(encoded)
+	}
+	for _, param := range encoded {
+		writePart(param)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NormalizeQuery removes the comments of the query, collapses the whitespace between tokens into single spaces and
+// drops the trailing semicolons. The normalization is conservative: string literals and quoted identifiers are kept
+// as they are, and neither the case of the query nor the whitespace around operators is changed, so that queries
+// that differ in meaning are never normalized into the same one.
+func NormalizeQuery(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	pendingSpace := false
+	write := func(s string) {
+		if pendingSpace && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		pendingSpace = false
+		b.WriteString(s)
+	}
+	writeTokens := func(tokens string) {
+		for i, field := range strings.Fields(tokens) {
+			if i > 0 || isSpace(tokens[0]) {
+				pendingSpace = true
+			}
+			write(field)
+		}
+		if tokens != "" && isSpace(tokens[len(tokens)-1]) {
+			pendingSpace = true
+		}
+	}
+
+	last := 0
+	forEachLiteralAndComment(query, func(kind sqlSpanKind, start, end int) {
+		writeTokens(query[last:start])
+		last = end
+		if kind == sqlSpanComment {
+			pendingSpace = true
+			return
+		}
+		// literals and quoted identifiers are kept verbatim
+		write(query[start:end])
+	})
+	writeTokens(query[last:])
+
+	return strings.TrimRight(b.String(), "; ")
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
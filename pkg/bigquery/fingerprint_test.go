@@ -0,0 +1,94 @@
+package bigquery
+
+import (
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "whitespace is collapsed",
+			query: "\n  SELECT id,\n\tname\n  FROM   dataset.users  \n",
+			want:  "SELECT id, name FROM dataset.users",
+		},
+		{
+			name:  "comments are removed",
+			query: "-- the users\nSELECT id /* the key */ FROM dataset.users # all of them\nWHERE true",
+			want:  "SELECT id FROM dataset.users WHERE true",
+		},
+		{
+			name:  "comments between tokens keep them apart",
+			query: "SELECT/**/1",
+			want:  "SELECT 1",
+		},
+		{
+			name:  "trailing semicolons are dropped",
+			query: "SELECT 1 ;\n",
+			want:  "SELECT 1",
+		},
+		{
+			name:  "literals and quoted identifiers are kept verbatim",
+			query: "SELECT 'a  -- b',  `my   column`, \"it\\\"s  /* here */\" FROM t",
+			want:  "SELECT 'a  -- b', `my   column`, \"it\\\"s  /* here */\" FROM t",
+		},
+		{
+			name:  "raw and triple-quoted literals",
+			query: "SELECT r'\\'  ,  '''a  -- 'b' '''  -- c\nFROM t",
+			want:  "SELECT r'\\' , '''a  -- 'b' ''' FROM t",
+		},
+		{
+			name:  "case is kept",
+			query: "select Name from T",
+			want:  "select Name from T",
+		},
+		{
+			name:  "non-ASCII characters are kept",
+			query: "SELECT  größe FROM t",
+			want:  "SELECT größe FROM t",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, NormalizeQuery(tt.query))
+		})
+	}
+}
+
+func TestFingerprintQuery(t *testing.T) {
+	t.Parallel()
+
+	base := FingerprintQuery("SELECT * FROM dataset.users WHERE id = @id")
+	assert.Len(t, base, 64)
+	assert.Equal(t, base, FingerprintQuery("SELECT *\nFROM dataset.users -- by id\nWHERE id = @id;"))
+
+	// semantically different queries never share a fingerprint
+	assert.NotEqual(t, base, FingerprintQuery("select * from dataset.users where id = @id"))
+	assert.NotEqual(t, FingerprintQuery("SELECT 'a b'"), FingerprintQuery("SELECT 'a  b'"))
+
+	// parameters are part of the fingerprint
+	withID := FingerprintQuery("SELECT * FROM dataset.users WHERE id = @id", query.Parameter{Name: "id", Value: 1})
+	assert.NotEqual(t, base, withID)
+	assert.NotEqual(t, withID, FingerprintQuery("SELECT * FROM dataset.users WHERE id = @id", query.Parameter{Name: "id", Value: "1"}))
+
+	// named parameters are order-independent, positional ones are not
+	assert.Equal(t,
+		FingerprintQuery("SELECT @a, @b", query.Parameter{Name: "a", Value: 1}, query.Parameter{Name: "b", Value: 2}),
+		FingerprintQuery("SELECT @a, @b", query.Parameter{Name: "b", Value: 2}, query.Parameter{Name: "a", Value: 1}),
+	)
+	assert.NotEqual(t,
+		FingerprintQuery("SELECT ?, ?", query.Parameter{Value: 1}, query.Parameter{Value: 2}),
+		FingerprintQuery("SELECT ?, ?", query.Parameter{Value: 2}, query.Parameter{Value: 1}),
+	)
+}
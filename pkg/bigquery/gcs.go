@@ -0,0 +1,221 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+// loadFormats are the source formats LoadFromGCS supports.
+var loadFormats = map[bigquery.DataFormat]bool{
+	bigquery.CSV:     true,
+	bigquery.JSON:    true,
+	bigquery.Avro:    true,
+	bigquery.Parquet: true,
+}
+
+// loadErrorSampleSize is the maximum number of row errors reported when a load job fails.
+const loadErrorSampleSize = 5
+
+// LoadOptions configures a load job from Google Cloud Storage.
+type LoadOptions struct {
+	// SourceFormat is one of CSV, JSON (newline delimited), AVRO or PARQUET, it defaults to CSV.
+	SourceFormat bigquery.DataFormat
+
+	// WriteDisposition is what happens to the rows already in the destination table, they are kept by default.
+	WriteDisposition bigquery.TableWriteDisposition
+
+	// Schema is the explicit schema of the destination table, see SchemaFromAsset. When it is empty the schema
+	// is auto-detected by BigQuery, which may infer the wrong types, especially for CSV files.
+	Schema bigquery.Schema
+
+	// AutoDetect makes BigQuery infer the schema from the files even when an explicit schema is given, it is
+	// implied when Schema is empty.
+	AutoDetect bool
+
+	// SanitizeColumnNames replaces the characters BigQuery rejects in the column names of the explicit schema,
+	// e.g. spaces in CSV headers, with underscores. It requires an explicit schema.
+	SanitizeColumnNames bool
+
+	// MaxBadRecords is the number of unreadable rows that are skipped before the load job fails.
+	MaxBadRecords int64
+}
+
+// LoadResult describes the outcome of a load job.
+type LoadResult struct {
+	// ColumnRenames lists the columns renamed by SanitizeColumnNames.
+	ColumnRenames []ColumnRename
+}
+
+// LoadFromGCS loads the files at the given GCS URIs into the destination table and waits for the job to finish.
+// The dataset of the destination table is created if it does not exist yet.
+func (d *Client) LoadFromGCS(ctx context.Context, gcsURIs []string, destination string, opts LoadOptions) (*LoadResult, error) {
+	if len(gcsURIs) == 0 {
+		return nil, errors.New("at least one GCS URI is required to load data")
+	}
+	if opts.SourceFormat != "" && !loadFormats[opts.SourceFormat] {
+		return nil, errors.Errorf("unsupported source format '%s', supported formats are CSV, NEWLINE_DELIMITED_JSON, AVRO and PARQUET", opts.SourceFormat)
+	}
+
+	tableRef, err := d.getTableRef(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LoadResult{ColumnRenames: []ColumnRename{}}
+	schema := opts.Schema
+	if opts.SanitizeColumnNames {
+		if len(schema) == 0 {
+			return nil, errors.New("sanitizing column names requires an explicit schema")
+		}
+		schema, result.ColumnRenames = SanitizeSchema(schema)
+	}
+
+	gcsRef := bigquery.NewGCSReference(gcsURIs...)
+	gcsRef.SourceFormat = opts.SourceFormat
+	gcsRef.MaxBadRecords = opts.MaxBadRecords
+	gcsRef.AutoDetect = opts.AutoDetect || len(schema) == 0
+	if len(schema) > 0 {
+		if err := ValidateSchema(schema); err != nil {
+			return nil, errors.Wrapf(err, "invalid schema for loading into '%s'", destination)
+		}
+		gcsRef.Schema = schema
+	}
+
+	if err := d.CreateDataSetIfNotExist(&pipeline.Asset{Name: destination}, ctx); err != nil {
+		return nil, err
+	}
+
+	loader := tableRef.LoaderFrom(gcsRef)
+	loader.WriteDisposition = opts.WriteDisposition
+
+	job, err := loader.Run(ctx)
+	if err != nil {
+		return nil, formatError(err)
+	}
+
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return nil, formatError(err)
+	}
+
+	if err := status.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load data into '%s': %w", destination, loadError(err, status.Errors))
+	}
+
+	return result, nil
+}
+
+// loadError adds the errors of the individual rows, which point at the file and the position of the bad rows, to
+// the error of a failed load job.
+func loadError(err error, rowErrors []*bigquery.Error) error {
+	jobMessage := ""
+	var jobErr *bigquery.Error
+	if errors.As(err, &jobErr) {
+		jobMessage = jobErr.Message
+	}
+
+	details := make([]string, 0, len(rowErrors))
+	for _, rowErr := range rowErrors {
+		// the error of the job itself is usually repeated among the row errors
+		if rowErr != nil && rowErr.Message != "" && rowErr.Message != jobMessage {
+			details = append(details, rowErr.Message)
+		}
+	}
+
+	err = formatError(err)
+	if len(details) == 0 {
+		return err
+	}
+	if len(details) > loadErrorSampleSize {
+		details = append(details[:loadErrorSampleSize], fmt.Sprintf("and %d more", len(details)-loadErrorSampleSize))
+	}
+
+	return fmt.Errorf("%w: %s", err, strings.Join(details, "; "))
+}
+
+// exportFormats are the destination formats ExportToGCS supports, together with the compressions they allow.
+var exportFormats = map[bigquery.DataFormat][]bigquery.Compression{
+	bigquery.CSV:     {bigquery.None, bigquery.Gzip},
+	bigquery.JSON:    {bigquery.None, bigquery.Gzip},
+	bigquery.Avro:    {bigquery.None, bigquery.Deflate, bigquery.Snappy},
+	bigquery.Parquet: {bigquery.None, bigquery.Gzip, bigquery.Snappy},
+}
+
+// ExportOptions configures an extract job to Google Cloud Storage.
+type ExportOptions struct {
+	// DestinationFormat is one of CSV, JSON (newline delimited), AVRO or PARQUET, it defaults to CSV.
+	DestinationFormat bigquery.DataFormat
+
+	// Compression is applied to the exported files, they are not compressed by default. GZIP is supported for all
+	// formats but Avro, SNAPPY for Avro and Parquet only.
+	Compression bigquery.Compression
+
+	// FieldDelimiter separates the fields of CSV files, it defaults to a comma.
+	FieldDelimiter string
+}
+
+// ExportToGCS exports the source table to the given GCS URI and waits for the job to finish. Tables larger than 1 GB
+// are exported into multiple files, which requires a single `*` wildcard in the URI, e.g. `gs://bucket/export-*.parquet`.
+func (d *Client) ExportToGCS(ctx context.Context, sourceTable string, gcsURI string, opts ExportOptions) error {
+	if !strings.HasPrefix(gcsURI, "gs://") {
+		return errors.Errorf("invalid GCS URI '%s', it must start with gs://", gcsURI)
+	}
+
+	format := opts.DestinationFormat
+	if format == "" {
+		format = bigquery.CSV
+	}
+	compressions, ok := exportFormats[format]
+	if !ok {
+		return errors.Errorf("unsupported destination format '%s', supported formats are CSV, NEWLINE_DELIMITED_JSON, AVRO and PARQUET", format)
+	}
+	if opts.Compression != "" && !slices.Contains(compressions, opts.Compression) {
+		return errors.Errorf("compression '%s' is not supported for the %s format", opts.Compression, format)
+	}
+	if opts.FieldDelimiter != "" && format != bigquery.CSV {
+		return errors.Errorf("a field delimiter can only be set for the CSV format, not for %s", format)
+	}
+
+	tableRef, err := d.getTableRef(sourceTable)
+	if err != nil {
+		return err
+	}
+
+	// the extract job would only fail once it runs, a missing table is reported before submitting it
+	if _, err := tableRef.Metadata(ctx); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return errors.Wrapf(ErrTableNotFound, "cannot export table '%s'", sourceTable)
+		}
+		return errors.Wrapf(formatError(err), "failed to fetch metadata for table '%s'", sourceTable)
+	}
+
+	gcsRef := bigquery.NewGCSReference(gcsURI)
+	gcsRef.DestinationFormat = format
+	gcsRef.Compression = opts.Compression
+	gcsRef.FieldDelimiter = opts.FieldDelimiter
+
+	job, err := tableRef.ExtractorTo(gcsRef).Run(ctx)
+	if err != nil {
+		return formatError(err)
+	}
+
+	status, err := job.Wait(ctx)
+	if err != nil {
+		return formatError(err)
+	}
+
+	if err := status.Err(); err != nil {
+		return fmt.Errorf("failed to export table '%s' to '%s': %w", sourceTable, gcsURI, formatError(err))
+	}
+
+	return nil
+}
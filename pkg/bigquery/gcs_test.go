@@ -0,0 +1,350 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+// jobsHandler accepts job insertions, records their configuration and reports them as done with the given error.
+type jobsHandler struct {
+	mu        sync.Mutex
+	jobs      []*bigquery2.JobConfiguration
+	jobError  *bigquery2.ErrorProto
+	jobStatus func(job *bigquery2.Job)
+}
+
+func (h *jobsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	jobsPath := fmt.Sprintf("/projects/%s/jobs", testProjectID)
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, jobsPath):
+		var job bigquery2.Job
+		if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		h.mu.Lock()
+		h.jobs = append(h.jobs, job.Configuration)
+		h.mu.Unlock()
+
+		h.writeJob(w, job.JobReference)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, jobsPath+"/"):
+		h.writeJob(w, &bigquery2.JobReference{ProjectId: testProjectID, JobId: strings.TrimPrefix(r.URL.Path, jobsPath+"/")})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/datasets/", testProjectID)):
+		// the datasets of the destination tables exist
+		_ = json.NewEncoder(w).Encode(&bigquery2.Dataset{Location: "US"})
+	default:
+		http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+	}
+}
+
+func (h *jobsHandler) writeJob(w http.ResponseWriter, ref *bigquery2.JobReference) {
+	job := &bigquery2.Job{
+		JobReference: ref,
+		Status:       &bigquery2.JobStatus{State: "DONE", ErrorResult: h.jobError},
+	}
+	if h.jobStatus != nil {
+		h.jobStatus(job)
+	}
+
+	_ = json.NewEncoder(w).Encode(job)
+}
+
+func (h *jobsHandler) recordedJobs() []*bigquery2.JobConfiguration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return append([]*bigquery2.JobConfiguration{}, h.jobs...)
+}
+
+func TestClient_LoadFromGCS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		uris           []string
+		destination    string
+		opts           LoadOptions
+		jobError       *bigquery2.ErrorProto
+		rowErrors      []*bigquery2.ErrorProto
+		wantErr        string
+		wantAutodetect bool
+		wantWrite      string
+		wantFields     []string
+		wantRenames    []ColumnRename
+	}{
+		{
+			name:    "no uris",
+			wantErr: "at least one GCS URI is required to load data",
+		},
+		{
+			name:        "invalid destination",
+			uris:        []string{"gs://bucket/file.parquet"},
+			destination: "table",
+			wantErr:     "table name must be in dataset.table or project.dataset.table format, 'table' given",
+		},
+		{
+			name:        "invalid schema is rejected before the job is submitted",
+			uris:        []string{"gs://bucket/file.csv"},
+			destination: "dataset.table",
+			opts:        LoadOptions{SourceFormat: bigquery.CSV, Schema: bigquery.Schema{{Name: "id"}}},
+			wantErr:     "invalid schema for loading into 'dataset.table': field 'id' has no type",
+		},
+		{
+			name:           "schema is auto-detected without an explicit schema",
+			uris:           []string{"gs://bucket/file.parquet"},
+			destination:    "dataset.table",
+			opts:           LoadOptions{SourceFormat: bigquery.Parquet},
+			wantAutodetect: true,
+		},
+		{
+			name:        "explicit schema is used",
+			uris:        []string{"gs://bucket/file.csv"},
+			destination: "dataset.table",
+			opts: LoadOptions{
+				SourceFormat: bigquery.CSV,
+				Schema: bigquery.Schema{
+					{Name: "id", Type: bigquery.IntegerFieldType},
+					{Name: "name", Type: bigquery.StringFieldType},
+				},
+			},
+			wantFields:  []string{"id", "name"},
+			wantRenames: []ColumnRename{},
+		},
+		{
+			name:        "column names are sanitized",
+			uris:        []string{"gs://bucket/file.csv"},
+			destination: "dataset.table",
+			opts: LoadOptions{
+				SourceFormat:        bigquery.CSV,
+				SanitizeColumnNames: true,
+				Schema: bigquery.Schema{
+					{Name: "order id", Type: bigquery.IntegerFieldType},
+					{Name: "order_id", Type: bigquery.IntegerFieldType},
+					{Name: "total ($)", Type: bigquery.FloatFieldType},
+				},
+			},
+			wantFields: []string{"order_id_2", "order_id", "total____"},
+			wantRenames: []ColumnRename{
+				{From: "order id", To: "order_id_2"},
+				{From: "total ($)", To: "total____"},
+			},
+		},
+		{
+			name:        "sanitizing requires a schema",
+			uris:        []string{"gs://bucket/file.csv"},
+			destination: "dataset.table",
+			opts:        LoadOptions{SourceFormat: bigquery.CSV, SanitizeColumnNames: true},
+			wantErr:     "sanitizing column names requires an explicit schema",
+		},
+		{
+			name:        "job errors are surfaced",
+			uris:        []string{"gs://bucket/file.csv"},
+			destination: "dataset.table",
+			opts:        LoadOptions{SourceFormat: bigquery.CSV},
+			jobError:    &bigquery2.ErrorProto{Reason: "invalid", Message: "Error while reading data"},
+			wantErr:     "Error while reading data",
+		},
+		{
+			name:        "unsupported source format",
+			uris:        []string{"gs://bucket/file.orc"},
+			destination: "dataset.table",
+			opts:        LoadOptions{SourceFormat: bigquery.ORC},
+			wantErr:     "unsupported source format 'ORC', supported formats are CSV, NEWLINE_DELIMITED_JSON, AVRO and PARQUET",
+		},
+		{
+			name:        "write disposition and auto-detection on top of a schema",
+			uris:        []string{"gs://bucket/file.json"},
+			destination: "dataset.table",
+			opts: LoadOptions{
+				SourceFormat:     bigquery.JSON,
+				WriteDisposition: bigquery.WriteTruncate,
+				Schema:           bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}},
+				AutoDetect:       true,
+			},
+			wantAutodetect: true,
+			wantWrite:      "WRITE_TRUNCATE",
+			wantFields:     []string{"id"},
+			wantRenames:    []ColumnRename{},
+		},
+		{
+			name:        "bad rows are listed",
+			uris:        []string{"gs://bucket/file.csv"},
+			destination: "dataset.table",
+			opts:        LoadOptions{SourceFormat: bigquery.CSV},
+			jobError:    &bigquery2.ErrorProto{Reason: "invalid", Message: "Error while reading data"},
+			rowErrors: []*bigquery2.ErrorProto{
+				{Reason: "invalid", Message: "Error while reading data"},
+				{Reason: "invalid", Message: "Could not parse 'abc' as INT64 for field id (position 0) starting at location 12"},
+				{Reason: "invalid", Message: "Could not parse 'def' as INT64 for field id (position 0) starting at location 40"},
+			},
+			wantErr: "failed to load data into 'dataset.table': {Location: \"\"; Message: \"Error while reading data\"; Reason: \"invalid\"}: " +
+				"Could not parse 'abc' as INT64 for field id (position 0) starting at location 12; " +
+				"Could not parse 'def' as INT64 for field id (position 0) starting at location 40",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &jobsHandler{jobError: tt.jobError, jobStatus: func(job *bigquery2.Job) {
+				job.Status.Errors = tt.rowErrors
+			}}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			result, err := d.LoadFromGCS(context.Background(), tt.uris, tt.destination, tt.opts)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			jobs := handler.recordedJobs()
+			require.Len(t, jobs, 1)
+			load := jobs[0].Load
+			require.NotNil(t, load)
+			assert.Equal(t, tt.uris, load.SourceUris)
+			assert.Equal(t, string(tt.opts.SourceFormat), load.SourceFormat)
+			assert.Equal(t, tt.wantAutodetect, load.Autodetect)
+			assert.Equal(t, tt.wantWrite, load.WriteDisposition)
+			if len(tt.wantFields) > 0 {
+				require.NotNil(t, load.Schema)
+				names := make([]string, 0, len(load.Schema.Fields))
+				for _, f := range load.Schema.Fields {
+					names = append(names, f.Name)
+				}
+				assert.Equal(t, tt.wantFields, names)
+				assert.Equal(t, tt.wantRenames, result.ColumnRenames)
+			}
+		})
+	}
+}
+
+func TestClient_ExportToGCS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		source          string
+		uri             string
+		opts            ExportOptions
+		jobError        *bigquery2.ErrorProto
+		wantErr         string
+		wantFormat      string
+		wantCompression string
+		wantDelimiter   string
+	}{
+		{
+			name:       "csv by default",
+			source:     "dataset.events",
+			uri:        "gs://bucket/events-*.csv",
+			wantFormat: "CSV",
+		},
+		{
+			name:            "compressed parquet",
+			source:          "dataset.events",
+			uri:             "gs://bucket/events-*.parquet",
+			opts:            ExportOptions{DestinationFormat: bigquery.Parquet, Compression: bigquery.Snappy},
+			wantFormat:      "PARQUET",
+			wantCompression: "SNAPPY",
+		},
+		{
+			name:            "csv with a custom delimiter",
+			source:          "dataset.events",
+			uri:             "gs://bucket/events.csv.gz",
+			opts:            ExportOptions{DestinationFormat: bigquery.CSV, Compression: bigquery.Gzip, FieldDelimiter: "|"},
+			wantFormat:      "CSV",
+			wantCompression: "GZIP",
+			wantDelimiter:   "|",
+		},
+		{
+			name:    "invalid uri",
+			source:  "dataset.events",
+			uri:     "/tmp/events.csv",
+			wantErr: "invalid GCS URI '/tmp/events.csv', it must start with gs://",
+		},
+		{
+			name:    "snappy is not supported for csv",
+			source:  "dataset.events",
+			uri:     "gs://bucket/events.csv",
+			opts:    ExportOptions{Compression: bigquery.Snappy},
+			wantErr: "compression 'SNAPPY' is not supported for the CSV format",
+		},
+		{
+			name:    "delimiter is only supported for csv",
+			source:  "dataset.events",
+			uri:     "gs://bucket/events.json",
+			opts:    ExportOptions{DestinationFormat: bigquery.JSON, FieldDelimiter: ";"},
+			wantErr: "a field delimiter can only be set for the CSV format, not for NEWLINE_DELIMITED_JSON",
+		},
+		{
+			name:    "missing table",
+			source:  "dataset.missing",
+			uri:     "gs://bucket/missing.csv",
+			wantErr: "cannot export table 'dataset.missing': table does not exist",
+		},
+		{
+			name:     "job errors are surfaced",
+			source:   "dataset.events",
+			uri:      "gs://bucket/events.csv",
+			jobError: &bigquery2.ErrorProto{Reason: "accessDenied", Message: "Access Denied: BigQuery BigQuery: Permission denied while writing data."},
+			wantErr:  "failed to export table 'dataset.events' to 'gs://bucket/events.csv'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			jobs := &jobsHandler{jobError: tt.jobError}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case fmt.Sprintf("/projects/%s/datasets/dataset/tables/events", testProjectID):
+					_ = json.NewEncoder(w).Encode(&bigquery2.Table{Type: "TABLE"})
+				case fmt.Sprintf("/projects/%s/datasets/dataset/tables/missing", testProjectID):
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table test-project:dataset.missing"}}`))
+				default:
+					jobs.ServeHTTP(w, r)
+				}
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			err := d.ExportToGCS(context.Background(), tt.source, tt.uri, tt.opts)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			recorded := jobs.recordedJobs()
+			require.Len(t, recorded, 1)
+			extract := recorded[0].Extract
+			require.NotNil(t, extract)
+			assert.Equal(t, []string{tt.uri}, extract.DestinationUris)
+			assert.Equal(t, "events", extract.SourceTable.TableId)
+			assert.Equal(t, tt.wantFormat, extract.DestinationFormat)
+			assert.Equal(t, tt.wantCompression, extract.Compression)
+			assert.Equal(t, tt.wantDelimiter, extract.FieldDelimiter)
+		})
+	}
+}
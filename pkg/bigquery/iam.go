@@ -0,0 +1,208 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/iam"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// maxPolicyConflictRetries is the number of times an IAM policy update is retried after another update changed the
+// policy in the meantime.
+const maxPolicyConflictRetries = 3
+
+// IAMBinding grants a role, e.g. "roles/bigquery.dataViewer", to the given members, e.g. "user:jane@example.com"
+// or "group:analysts@example.com".
+type IAMBinding struct {
+	Role    string
+	Members []string
+}
+
+// SetTableIAM makes the members of each given role on the table exactly the given members, adding the missing ones
+// and removing the others; a binding without members removes the role altogether. Roles that are not given are left
+// as they are. The policy is only written if it changes. Policy updates are guarded by the policy etag, the
+// update is retried on a fresh copy of the policy if another update got in between.
+func (d *Client) SetTableIAM(ctx context.Context, tableName string, bindings []IAMBinding) error {
+	seen := make(map[string]bool, len(bindings))
+	for _, binding := range bindings {
+		if binding.Role == "" {
+			return errors.New("IAM bindings must have a role")
+		}
+		if seen[binding.Role] {
+			return errors.Errorf("role '%s' is given more than once", binding.Role)
+		}
+		seen[binding.Role] = true
+	}
+
+	tableRef, err := d.getTableRef(tableName)
+	if err != nil {
+		return err
+	}
+	handle := tableRef.IAM()
+
+	for attempt := 0; ; attempt++ {
+		policy, err := handle.Policy(ctx)
+		if err != nil {
+			return errors.Wrapf(formatError(err), "failed to read the IAM policy of table '%s'", tableName)
+		}
+
+		if !applyIAMBindings(policy, bindings) {
+			return nil
+		}
+
+		err = handle.SetPolicy(ctx, policy)
+		if err == nil {
+			return nil
+		}
+		if !IsConcurrentModification(err) || attempt >= maxPolicyConflictRetries {
+			return errors.Wrapf(formatError(err), "failed to set the IAM policy of table '%s'", tableName)
+		}
+	}
+}
+
+// applyIAMBindings updates the policy with the given bindings and reports whether anything changed.
+func applyIAMBindings(policy *iam.Policy, bindings []IAMBinding) bool {
+	changed := false
+	for _, binding := range bindings {
+		role := iam.RoleName(binding.Role)
+
+		desired := make(map[string]bool, len(binding.Members))
+		for _, member := range binding.Members {
+			desired[member] = true
+		}
+
+		// Members returns the slice of the policy itself, which Remove modifies
+		current := append([]string{}, policy.Members(role)...)
+		for _, member := range current {
+			if !desired[member] {
+				policy.Remove(member, role)
+				changed = true
+			}
+		}
+
+		for _, member := range binding.Members {
+			if !policy.HasRole(member, role) {
+				policy.Add(member, role)
+				changed = true
+			}
+		}
+	}
+
+	return changed
+}
+
+// CheckPermissions reports which of the given permissions, e.g. "bigquery.tables.getData", the credentials hold on
+// each of the given resources, so that missing permissions surface before a run rather than in the middle of it.
+// The resources are tables, e.g. `dataset.table`, or datasets, named by the dataset alone or as `project:dataset`.
+// The result maps every resource to its granted permissions, in the order they were requested.
+//
+// BigQuery only supports testing permissions on tables and views. Grants on a dataset or project are inherited by
+// its tables, the permissions on a dataset, and on the tables that do not exist yet, are therefore tested on an
+// existing table of the dataset. Every resource is checked with a single request for all the permissions.
+// Resources that cannot be checked, e.g. datasets without any table, are left out of the result and listed in the
+// error, the other resources are checked nonetheless.
+func (d *Client) CheckPermissions(ctx context.Context, resources []string, permissions []string) (map[string][]string, error) {
+	if len(permissions) == 0 {
+		return nil, errors.New("at least one permission must be given")
+	}
+
+	granted := make(map[string][]string, len(resources))
+	checked := make(map[string]bool, len(resources))
+	failures := make([]string, 0)
+	for _, resource := range resources {
+		if checked[resource] {
+			continue
+		}
+		checked[resource] = true
+
+		allowed, err := d.testPermissions(ctx, resource, permissions)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("'%s': %s", resource, err))
+			continue
+		}
+
+		allowedSet := make(map[string]bool, len(allowed))
+		for _, permission := range allowed {
+			allowedSet[permission] = true
+		}
+
+		resourcePermissions := make([]string, 0, len(allowed))
+		for _, permission := range permissions {
+			if allowedSet[permission] {
+				resourcePermissions = append(resourcePermissions, permission)
+			}
+		}
+		granted[resource] = resourcePermissions
+	}
+
+	if len(failures) > 0 {
+		return granted, fmt.Errorf("failed to test the permissions on %s", strings.Join(failures, "; "))
+	}
+
+	return granted, nil
+}
+
+// testPermissions tests the permissions on the table, or on a table of the dataset for datasets and for the tables
+// that do not exist.
+func (d *Client) testPermissions(ctx context.Context, resource string, permissions []string) ([]string, error) {
+	projectID, datasetID, isDataset, err := d.datasetResource(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isDataset {
+		resolved, err := d.ResolveTable(resource)
+		if err != nil {
+			return nil, err
+		}
+
+		table := d.client.DatasetInProject(resolved.ProjectID, resolved.DatasetID).Table(resolved.TableID)
+		allowed, err := table.IAM().TestPermissions(ctx, permissions)
+		if err == nil {
+			return allowed, nil
+		}
+		if !IsNotFound(err) {
+			return nil, formatError(err)
+		}
+		projectID, datasetID = resolved.ProjectID, resolved.DatasetID
+	}
+
+	table, err := d.client.DatasetInProject(projectID, datasetID).Tables(ctx).Next()
+	if errors.Is(err, iterator.Done) {
+		return nil, fmt.Errorf("dataset '%s.%s' has no table to test the permissions on", projectID, datasetID)
+	}
+	if err != nil {
+		return nil, formatError(err)
+	}
+
+	allowed, err := table.IAM().TestPermissions(ctx, permissions)
+	if err != nil {
+		return nil, formatError(err)
+	}
+
+	return allowed, nil
+}
+
+// datasetResource parses the resource as a dataset, reporting whether it names one: `dataset` in the project tables
+// are resolved to, or `project:dataset`.
+func (d *Client) datasetResource(resource string) (string, string, bool, error) {
+	if projectID, datasetID, ok := strings.Cut(resource, ":"); ok {
+		if projectID == "" || datasetID == "" || strings.Contains(datasetID, ".") {
+			return "", "", false, fmt.Errorf("dataset must be in dataset or project:dataset format, '%s' given", resource)
+		}
+		return projectID, datasetID, true, nil
+	}
+	if resource == "" || strings.Contains(resource, ".") {
+		return "", "", false, nil
+	}
+
+	projectID, err := d.config.DefaultTableProject()
+	if err != nil {
+		return "", "", false, err
+	}
+
+	return projectID, resource, true, nil
+}
@@ -0,0 +1,258 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+// iamPolicyHandler serves the IAM policy of a single table, rejecting the first conflicts updates as if another
+// update changed the policy in the meantime.
+type iamPolicyHandler struct {
+	mu        sync.Mutex
+	policy    *bigquery2.Policy
+	conflicts int
+	updates   []*bigquery2.Policy
+}
+
+func (h *iamPolicyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	resource := fmt.Sprintf("/projects/%s/datasets/dataset/tables/orders", testProjectID)
+	switch r.URL.Path {
+	case resource + ":getIamPolicy":
+		_ = json.NewEncoder(w).Encode(h.policy)
+	case resource + ":setIamPolicy":
+		var req bigquery2.SetIamPolicyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.updates = append(h.updates, req.Policy)
+
+		if h.conflicts > 0 {
+			h.conflicts--
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error": {"code": 409, "message": "There were concurrent policy changes."}}`))
+			return
+		}
+
+		h.policy = req.Policy
+		_ = json.NewEncoder(w).Encode(h.policy)
+	default:
+		http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusBadRequest)
+	}
+}
+
+func (h *iamPolicyHandler) bindings() map[string][]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	bindings := make(map[string][]string)
+	for _, binding := range h.policy.Bindings {
+		members := append([]string{}, binding.Members...)
+		sort.Strings(members)
+		bindings[binding.Role] = members
+	}
+
+	return bindings
+}
+
+func TestClient_SetTableIAM(t *testing.T) {
+	t.Parallel()
+
+	initialPolicy := func() *bigquery2.Policy {
+		return &bigquery2.Policy{
+			Etag: "BwYA",
+			Bindings: []*bigquery2.Binding{
+				{Role: "roles/bigquery.dataViewer", Members: []string{"user:jane@example.com", "user:john@example.com"}},
+				{Role: "roles/bigquery.dataOwner", Members: []string{"group:admins@example.com"}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		bindings     []IAMBinding
+		conflicts    int
+		wantBindings map[string][]string
+		wantUpdates  int
+		wantErr      string
+	}{
+		{
+			name: "members are added and removed",
+			bindings: []IAMBinding{
+				{Role: "roles/bigquery.dataViewer", Members: []string{"user:jane@example.com", "group:analysts@example.com"}},
+				{Role: "roles/bigquery.dataEditor", Members: []string{"serviceAccount:etl@test-project.iam.gserviceaccount.com"}},
+			},
+			wantBindings: map[string][]string{
+				"roles/bigquery.dataViewer": {"group:analysts@example.com", "user:jane@example.com"},
+				"roles/bigquery.dataEditor": {"serviceAccount:etl@test-project.iam.gserviceaccount.com"},
+				"roles/bigquery.dataOwner":  {"group:admins@example.com"},
+			},
+			wantUpdates: 1,
+		},
+		{
+			name:     "role without members is removed",
+			bindings: []IAMBinding{{Role: "roles/bigquery.dataViewer"}},
+			wantBindings: map[string][]string{
+				"roles/bigquery.dataOwner": {"group:admins@example.com"},
+			},
+			wantUpdates: 1,
+		},
+		{
+			name: "unchanged policy is not written",
+			bindings: []IAMBinding{
+				{Role: "roles/bigquery.dataViewer", Members: []string{"user:john@example.com", "user:jane@example.com"}},
+			},
+			wantBindings: map[string][]string{
+				"roles/bigquery.dataViewer": {"user:jane@example.com", "user:john@example.com"},
+				"roles/bigquery.dataOwner":  {"group:admins@example.com"},
+			},
+		},
+		{
+			name:      "concurrent policy changes are retried",
+			bindings:  []IAMBinding{{Role: "roles/bigquery.dataViewer", Members: []string{"user:jane@example.com"}}},
+			conflicts: 2,
+			wantBindings: map[string][]string{
+				"roles/bigquery.dataViewer": {"user:jane@example.com"},
+				"roles/bigquery.dataOwner":  {"group:admins@example.com"},
+			},
+			wantUpdates: 3,
+		},
+		{
+			name:        "retries are bounded",
+			bindings:    []IAMBinding{{Role: "roles/bigquery.dataViewer", Members: []string{"user:jane@example.com"}}},
+			conflicts:   10,
+			wantUpdates: maxPolicyConflictRetries + 1,
+			wantErr:     "failed to set the IAM policy of table 'dataset.orders'",
+		},
+		{
+			name:     "duplicate roles",
+			bindings: []IAMBinding{{Role: "roles/bigquery.dataViewer"}, {Role: "roles/bigquery.dataViewer"}},
+			wantErr:  "role 'roles/bigquery.dataViewer' is given more than once",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &iamPolicyHandler{policy: initialPolicy(), conflicts: tt.conflicts}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			err := d.SetTableIAM(context.Background(), "dataset.orders", tt.bindings)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantBindings, handler.bindings())
+			}
+
+			require.Len(t, handler.updates, tt.wantUpdates)
+			for _, update := range handler.updates {
+				assert.Equal(t, "BwYA", update.Etag)
+			}
+		})
+	}
+}
+
+func TestClient_CheckPermissions(t *testing.T) {
+	t.Parallel()
+
+	granted := map[string][]string{
+		"/projects/test-project/datasets/analytics/tables/events:testIamPermissions": {"bigquery.tables.getData", "bigquery.tables.updateData"},
+		"/projects/other-project/datasets/raw/tables/users:testIamPermissions":       {"bigquery.tables.getData"},
+	}
+	tables := map[string][]string{
+		"/projects/test-project/datasets/analytics/tables": {"events"},
+		"/projects/other-project/datasets/raw/tables":      {"users"},
+		"/projects/test-project/datasets/empty/tables":     {},
+	}
+
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.Path)
+		mu.Unlock()
+
+		if ids, ok := tables[r.URL.Path]; ok && r.Method == http.MethodGet {
+			list := &bigquery2.TableList{}
+			for _, id := range ids {
+				ref := strings.Split(strings.TrimPrefix(r.URL.Path, "/projects/"), "/")
+				list.Tables = append(list.Tables, &bigquery2.TableListTables{
+					TableReference: &bigquery2.TableReference{ProjectId: ref[0], DatasetId: ref[2], TableId: id},
+				})
+			}
+			_ = json.NewEncoder(w).Encode(list)
+			return
+		}
+
+		var req bigquery2.TestIamPermissionsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		allowed, ok := granted[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table"}}`))
+			return
+		}
+
+		// the API only returns the requested permissions that are granted
+		response := &bigquery2.TestIamPermissionsResponse{}
+		for _, permission := range req.Permissions {
+			for _, a := range allowed {
+				if permission == a {
+					response.Permissions = append(response.Permissions, permission)
+				}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	permissions := []string{"bigquery.tables.updateData", "bigquery.tables.getData", "bigquery.tables.delete"}
+	got, err := d.CheckPermissions(context.Background(), []string{"analytics.events", "other-project.raw.users", "analytics.events"}, permissions)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"analytics.events":        {"bigquery.tables.updateData", "bigquery.tables.getData"},
+		"other-project.raw.users": {"bigquery.tables.getData"},
+	}, got)
+	assert.Len(t, requests, 2)
+
+	// datasets and tables that do not exist yet are tested on a table of the dataset, the resources that cannot be
+	// tested do not stop the others
+	got, err = d.CheckPermissions(context.Background(), []string{"analytics.missing", "empty", "other-project:raw", "analytics"}, permissions)
+	require.EqualError(t, err, "failed to test the permissions on 'empty': dataset 'test-project.empty' has no table to test the permissions on")
+	assert.Equal(t, map[string][]string{
+		"analytics.missing": {"bigquery.tables.updateData", "bigquery.tables.getData"},
+		"other-project:raw": {"bigquery.tables.getData"},
+		"analytics":         {"bigquery.tables.updateData", "bigquery.tables.getData"},
+	}, got)
+
+	_, err = d.CheckPermissions(context.Background(), []string{"other-project:raw.users"}, permissions)
+	require.EqualError(t, err, "failed to test the permissions on 'other-project:raw.users': dataset must be in dataset or project:dataset format, 'other-project:raw.users' given")
+
+	_, err = d.CheckPermissions(context.Background(), []string{"analytics.events"}, nil)
+	require.EqualError(t, err, "at least one permission must be given")
+}
@@ -0,0 +1,230 @@
+package bigquery
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/iterator"
+)
+
+// JobInfo identifies the BigQuery job that ran a query together with its final statistics, e.g. to look the job
+// up in the console or in `INFORMATION_SCHEMA.JOBS`.
+type JobInfo struct {
+	JobID     string
+	ProjectID string
+
+	// Location is where BigQuery actually ran the job as reported back by it, which is not necessarily the
+	// location configured on the client, e.g. when the client does not set one and the job follows its tables.
+	Location string
+
+	BytesProcessed int64
+	BytesBilled    int64
+	SlotMillis     int64
+	CacheHit       bool
+
+	// RowsAffected is the number of rows inserted, updated or deleted by a DML statement, it is zero otherwise. For
+	// scripts, e.g. the queries run in a reservation, it is the total of their DML statements.
+	RowsAffected int64
+
+	CreationTime time.Time
+	StartTime    time.Time
+	EndTime      time.Time
+
+	// Insights are the hints BigQuery gives on the performance of the job, for scripts the ones of their last
+	// statement. They are only read if Config.FetchPerformanceInsights is set, and are nil if BigQuery has none.
+	Insights *PerformanceInsights
+}
+
+// PerformanceInsights are the hints BigQuery gives on the performance of a query job, e.g. to find out why an asset
+// got slower or what to optimize in an expensive one.
+type PerformanceInsights struct {
+	// AvgPreviousExecution is the average duration of the previous runs of the same query, zero if unknown.
+	AvgPreviousExecution time.Duration
+	Stages               []StageInsight
+}
+
+// StageInsight is what BigQuery found about a single stage of the execution of a query.
+type StageInsight struct {
+	StageID int64
+
+	// SlotContention is set if the stage waited for slots used by other jobs, InsufficientShuffleQuota if it was
+	// slowed down by the shuffle quota of the project.
+	SlotContention           bool
+	InsufficientShuffleQuota bool
+
+	// PartitionSkew is set if the data the stage processed was unevenly spread across its workers, e.g. because of
+	// a few very frequent join keys.
+	PartitionSkew bool
+
+	// HighCardinalityJoins is the number of joins of the stage that produced far more rows than they read.
+	HighCardinalityJoins int
+
+	// InputDataChangePercent is how much the number of rows read by the stage changed compared to the previous runs
+	// of the same query, zero if it did not change or is unknown.
+	InputDataChangePercent float64
+}
+
+// RanIn reports whether the job ran in the given location. Locations are compared case-insensitively, as BigQuery
+// accepts both `EU` and `eu`.
+func (i *JobInfo) RanIn(location string) bool {
+	return strings.EqualFold(i.Location, location)
+}
+
+// RunQueryWithJobInfo runs the query like RunQueryWithoutResult and returns the job that ran it. Once the job is
+// started, the returned JobInfo identifies it even if the query fails, so that the job ID can be logged along with
+// the error; the statistics are only set for successful queries. The times are in UTC.
+func (d *Client) RunQueryWithJobInfo(ctx context.Context, queryObj *query.Query) (*JobInfo, error) {
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer cancel()
+
+	q, err := d.newQuery(ctx, queryObj)
+	if err != nil {
+		return nil, err
+	}
+	job, err := d.runWithRetry(ctx, q)
+	if err != nil {
+		return nil, formatError(err)
+	}
+
+	info := &JobInfo{
+		JobID:     job.ID(),
+		ProjectID: job.ProjectID(),
+		Location:  job.Location(),
+	}
+
+	status, err := d.waitJob(ctx, job)
+	if err != nil {
+		return info, formatError(timeoutCause(ctx, err))
+	}
+	if err := status.Err(); err != nil {
+		return info, formatError(err)
+	}
+
+	insightsJob := info
+	if stats := status.Statistics; stats != nil {
+		info.BytesProcessed = stats.TotalBytesProcessed
+		info.CreationTime = stats.CreationTime.UTC()
+		info.StartTime = stats.StartTime.UTC()
+		info.EndTime = stats.EndTime.UTC()
+		if details, ok := stats.Details.(*bigquery.QueryStatistics); ok {
+			info.BytesBilled = details.TotalBytesBilled
+			info.SlotMillis = details.SlotMillis
+			info.CacheHit = details.CacheHit
+			info.RowsAffected = details.NumDMLAffectedRows
+		}
+		if stats.NumChildJobs > 0 {
+			if last := scriptStatistics(ctx, job, info); last != nil {
+				insightsJob = last
+			}
+		}
+	}
+
+	info.Insights = d.performanceInsights(ctx, insightsJob)
+	return info, nil
+}
+
+// scriptStatistics adds up the rows affected by the child jobs of the script, which BigQuery does not report on the
+// script itself, and returns the last of them, the one of the final statement. Failing to list them only logs a
+// warning, the statistics of the script are kept then.
+func scriptStatistics(ctx context.Context, script *bigquery.Job, info *JobInfo) *JobInfo {
+	var rowsAffected int64
+	var last *JobInfo
+	var lastCreated time.Time
+
+	it := script.Children(ctx)
+	for {
+		child, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			contextLogger(ctx).Warnf("failed to read the child jobs of script '%s': %s", script.ID(), err)
+			return nil
+		}
+
+		stats := child.LastStatus().Statistics
+		if stats == nil {
+			continue
+		}
+		if details, ok := stats.Details.(*bigquery.QueryStatistics); ok {
+			rowsAffected += details.NumDMLAffectedRows
+		}
+		if last == nil || stats.CreationTime.After(lastCreated) {
+			last = &JobInfo{JobID: child.ID(), ProjectID: child.ProjectID(), Location: child.Location()}
+			lastCreated = stats.CreationTime
+		}
+	}
+
+	info.RowsAffected = rowsAffected
+	return last
+}
+
+// performanceInsights reads the performance insights of the job. The insights are advisory, therefore failing to
+// read them only logs a warning instead of failing the query that already succeeded.
+func (d *Client) performanceInsights(ctx context.Context, info *JobInfo) *PerformanceInsights {
+	if d.service == nil || info.JobID == "" {
+		return nil
+	}
+
+	job, err := d.service.Jobs.Get(info.ProjectID, info.JobID).Location(info.Location).Context(ctx).Do()
+	if err != nil {
+		contextLogger(ctx).Warnf("failed to read the performance insights of job '%s': %s", info.JobID, err)
+		return nil
+	}
+	if job.Statistics == nil || job.Statistics.Query == nil {
+		return nil
+	}
+
+	return convertPerformanceInsights(job.Statistics.Query.PerformanceInsights)
+}
+
+// convertPerformanceInsights merges the standalone and the change insights of the stages, returning nil if there
+// is nothing to report.
+func convertPerformanceInsights(raw *bigquery2.PerformanceInsights) *PerformanceInsights {
+	if raw == nil {
+		return nil
+	}
+
+	insights := &PerformanceInsights{
+		AvgPreviousExecution: time.Duration(raw.AvgPreviousExecutionMs) * time.Millisecond,
+		Stages:               make([]StageInsight, 0),
+	}
+	stages := make(map[int64]int)
+	stage := func(id int64) *StageInsight {
+		i, ok := stages[id]
+		if !ok {
+			i = len(insights.Stages)
+			stages[id] = i
+			insights.Stages = append(insights.Stages, StageInsight{StageID: id})
+		}
+		return &insights.Stages[i]
+	}
+
+	for _, standalone := range raw.StagePerformanceStandaloneInsights {
+		if standalone == nil {
+			continue
+		}
+		s := stage(standalone.StageId)
+		s.SlotContention = standalone.SlotContention
+		s.InsufficientShuffleQuota = standalone.InsufficientShuffleQuota
+		s.PartitionSkew = standalone.PartitionSkew != nil && len(standalone.PartitionSkew.SkewSources) > 0
+		s.HighCardinalityJoins = len(standalone.HighCardinalityJoins)
+	}
+	for _, change := range raw.StagePerformanceChangeInsights {
+		if change == nil || change.InputDataChange == nil {
+			continue
+		}
+		stage(change.StageId).InputDataChangePercent = change.InputDataChange.RecordsReadDiffPercentage
+	}
+
+	if insights.AvgPreviousExecution == 0 && len(insights.Stages) == 0 {
+		return nil
+	}
+
+	return insights
+}
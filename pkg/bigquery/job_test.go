@@ -0,0 +1,243 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/option"
+)
+
+func TestClient_RunQueryWithJobInfo(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	insights := &bigquery2.PerformanceInsights{
+		AvgPreviousExecutionMs: 1200,
+		StagePerformanceStandaloneInsights: []*bigquery2.StagePerformanceStandaloneInsight{
+			{StageId: 1, SlotContention: true},
+			{StageId: 3, PartitionSkew: &bigquery2.PartitionSkew{SkewSources: []*bigquery2.SkewSource{{StageId: 2}}}, HighCardinalityJoins: []*bigquery2.HighCardinalityJoin{{StepIndex: 1}}},
+		},
+		StagePerformanceChangeInsights: []*bigquery2.StagePerformanceChangeInsight{
+			{StageId: 3, InputDataChange: &bigquery2.InputDataChange{RecordsReadDiffPercentage: 250.5}},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		failJob       bool
+		fetchInsights bool
+		insights      *bigquery2.PerformanceInsights
+		wantInfo      *JobInfo
+		wantErr       string
+	}{
+		{
+			name: "successful query",
+			wantInfo: &JobInfo{
+				JobID:          "job-1",
+				ProjectID:      testProjectID,
+				Location:       "EU",
+				BytesProcessed: 2048,
+				BytesBilled:    10485760,
+				SlotMillis:     1500,
+				CreationTime:   created,
+				StartTime:      created.Add(time.Second),
+				EndTime:        created.Add(3 * time.Second),
+			},
+		},
+		{
+			name:          "performance insights are read when enabled",
+			fetchInsights: true,
+			insights:      insights,
+			wantInfo: &JobInfo{
+				JobID:          "job-1",
+				ProjectID:      testProjectID,
+				Location:       "EU",
+				BytesProcessed: 2048,
+				BytesBilled:    10485760,
+				SlotMillis:     1500,
+				CreationTime:   created,
+				StartTime:      created.Add(time.Second),
+				EndTime:        created.Add(3 * time.Second),
+				Insights: &PerformanceInsights{
+					AvgPreviousExecution: 1200 * time.Millisecond,
+					Stages: []StageInsight{
+						{StageID: 1, SlotContention: true},
+						{StageID: 3, PartitionSkew: true, HighCardinalityJoins: 1, InputDataChangePercent: 250.5},
+					},
+				},
+			},
+		},
+		{
+			name:          "jobs without insights have none",
+			fetchInsights: true,
+			wantInfo: &JobInfo{
+				JobID:          "job-1",
+				ProjectID:      testProjectID,
+				Location:       "EU",
+				BytesProcessed: 2048,
+				BytesBilled:    10485760,
+				SlotMillis:     1500,
+				CreationTime:   created,
+				StartTime:      created.Add(time.Second),
+				EndTime:        created.Add(3 * time.Second),
+			},
+		},
+		{
+			name:     "failed query still identifies the job",
+			failJob:  true,
+			wantInfo: &JobInfo{JobID: "job-1", ProjectID: testProjectID, Location: "EU"},
+			wantErr:  "Syntax error: Unexpected end of script",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ref := &bigquery2.JobReference{ProjectId: testProjectID, JobId: "job-1", Location: "EU"}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/jobs", testProjectID):
+					_ = json.NewEncoder(w).Encode(&bigquery2.Job{
+						JobReference:  ref,
+						Configuration: &bigquery2.JobConfiguration{Query: &bigquery2.JobConfigurationQuery{Query: "SELECT 1"}},
+						Status:        &bigquery2.JobStatus{State: "RUNNING"},
+					})
+				case strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/queries/job-1", testProjectID)):
+					if tt.failJob {
+						w.WriteHeader(http.StatusBadRequest)
+						_, _ = w.Write([]byte(`{"error": {"code": 400, "message": "Syntax error: Unexpected end of script"}}`))
+						return
+					}
+					_ = json.NewEncoder(w).Encode(&bigquery2.GetQueryResultsResponse{JobComplete: true, JobReference: ref})
+				case strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/jobs/job-1", testProjectID)):
+					_ = json.NewEncoder(w).Encode(&bigquery2.Job{
+						JobReference:  ref,
+						Configuration: &bigquery2.JobConfiguration{Query: &bigquery2.JobConfigurationQuery{Query: "SELECT 1"}},
+						Status:        &bigquery2.JobStatus{State: "DONE"},
+						Statistics: &bigquery2.JobStatistics{
+							CreationTime:        created.UnixMilli(),
+							StartTime:           created.Add(time.Second).UnixMilli(),
+							EndTime:             created.Add(3 * time.Second).UnixMilli(),
+							TotalBytesProcessed: 2048,
+							Query: &bigquery2.JobStatistics2{
+								TotalBytesBilled:    10485760,
+								TotalSlotMs:         1500,
+								PerformanceInsights: tt.insights,
+							},
+						},
+					})
+				default:
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			if tt.fetchInsights {
+				service, err := bigquery2.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+				require.NoError(t, err)
+				d.service = service
+			}
+
+			info, err := d.RunQueryWithJobInfo(context.Background(), &query.Query{Query: "SELECT 1"})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantInfo, info)
+
+			// the job ran in the location it reported rather than the one of the client
+			assert.True(t, info.RanIn("eu"))
+			assert.False(t, info.RanIn(d.client.Location))
+		})
+	}
+}
+
+func TestClient_RunQueryWithJobInfo_Script(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	ref := func(id string) *bigquery2.JobReference {
+		return &bigquery2.JobReference{ProjectId: testProjectID, JobId: id, Location: "EU"}
+	}
+	child := func(id string, offset time.Duration, rowsAffected int64) *bigquery2.JobListJobs {
+		return &bigquery2.JobListJobs{
+			JobReference: ref(id),
+			Status:       &bigquery2.JobStatus{State: "DONE"},
+			Statistics: &bigquery2.JobStatistics{
+				CreationTime: created.Add(offset).UnixMilli(),
+				ParentJobId:  "job-1",
+				Query:        &bigquery2.JobStatistics2{NumDmlAffectedRows: rowsAffected},
+			},
+		}
+	}
+
+	var requestedInsights []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/jobs", testProjectID):
+			_ = json.NewEncoder(w).Encode(&bigquery2.Job{JobReference: ref("job-1"), Status: &bigquery2.JobStatus{State: "RUNNING"}})
+		case r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/jobs", testProjectID):
+			assert.Equal(t, "job-1", r.URL.Query().Get("parentJobId"))
+			// BigQuery lists the latest jobs first
+			_ = json.NewEncoder(w).Encode(&bigquery2.JobList{Jobs: []*bigquery2.JobListJobs{
+				child("job-1_2", 2*time.Second, 0),
+				child("job-1_1", time.Second, 12),
+				child("job-1_0", 0, 0),
+			}})
+		case strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/queries/job-1", testProjectID)):
+			_ = json.NewEncoder(w).Encode(&bigquery2.GetQueryResultsResponse{JobComplete: true, JobReference: ref("job-1")})
+		case r.URL.Path == fmt.Sprintf("/projects/%s/jobs/job-1", testProjectID):
+			requestedInsights = append(requestedInsights, "job-1")
+			_ = json.NewEncoder(w).Encode(&bigquery2.Job{
+				JobReference: ref("job-1"),
+				Status:       &bigquery2.JobStatus{State: "DONE"},
+				Statistics: &bigquery2.JobStatistics{
+					CreationTime: created.UnixMilli(),
+					NumChildJobs: 3,
+					Query:        &bigquery2.JobStatistics2{StatementType: "SCRIPT"},
+				},
+			})
+		case r.URL.Path == fmt.Sprintf("/projects/%s/jobs/job-1_2", testProjectID):
+			requestedInsights = append(requestedInsights, "job-1_2")
+			_ = json.NewEncoder(w).Encode(&bigquery2.Job{
+				JobReference: ref("job-1_2"),
+				Status:       &bigquery2.JobStatus{State: "DONE"},
+				Statistics: &bigquery2.JobStatistics{Query: &bigquery2.JobStatistics2{
+					PerformanceInsights: &bigquery2.PerformanceInsights{AvgPreviousExecutionMs: 800},
+				}},
+			})
+		default:
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	d.config.ReservationID = "projects/test-project/locations/EU/reservations/etl"
+	service, err := bigquery2.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+	require.NoError(t, err)
+	d.service = service
+
+	info, err := d.RunQueryWithJobInfo(context.Background(), &query.Query{Query: "UPDATE dataset.users SET active = TRUE WHERE TRUE"})
+	require.NoError(t, err)
+
+	// the statistics come from the children of the script the reservation turns the query into
+	assert.Equal(t, "job-1", info.JobID)
+	assert.Equal(t, int64(12), info.RowsAffected)
+	assert.Equal(t, &PerformanceInsights{AvgPreviousExecution: 800 * time.Millisecond, Stages: []StageInsight{}}, info.Insights)
+	assert.Equal(t, []string{"job-1", "job-1_2"}, requestedInsights)
+}
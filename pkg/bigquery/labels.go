@@ -0,0 +1,156 @@
+package bigquery
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/pkg/errors"
+)
+
+// The job labels injected by WithAssetJobLabels, which allow breaking down the costs per asset and pipeline in
+// the billing export.
+const (
+	AssetLabel    = "bruin_asset"
+	PipelineLabel = "bruin_pipeline"
+)
+
+// maxLabelLength is the maximum length of both label keys and label values.
+const maxLabelLength = 63
+
+// maxLabelsPerResource is the maximum number of labels BigQuery allows on a single table or dataset.
+const maxLabelsPerResource = 64
+
+// sanitizeLabelValue turns the value into a valid BigQuery label value: lowercase letters, digits, underscores
+// and dashes, at most 63 characters. Every other character is replaced with an underscore.
+func sanitizeLabelValue(value string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(value) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+
+	sanitized := b.String()
+	if len(sanitized) > maxLabelLength {
+		sanitized = sanitized[:maxLabelLength]
+	}
+
+	return sanitized
+}
+
+// sanitizeLabelKey turns the key into a valid BigQuery label key, which follows the rules of the values but
+// must also start with a lowercase letter and cannot be empty.
+func sanitizeLabelKey(key string) string {
+	sanitized := sanitizeLabelValue(key)
+	if sanitized == "" || sanitized[0] < 'a' || sanitized[0] > 'z' {
+		sanitized = sanitizeLabelValue("l_" + sanitized)
+	}
+
+	return sanitized
+}
+
+type jobLabelsKey struct{}
+
+// WithJobLabels returns a context that makes the queries run with it carry the given job labels, on top of the
+// labels already set on the context. Keys and values are sanitized to meet the BigQuery label constraints.
+func WithJobLabels(ctx context.Context, labels map[string]string) context.Context {
+	merged := make(map[string]string)
+	if existing, ok := ctx.Value(jobLabelsKey{}).(map[string]string); ok {
+		for key, value := range existing {
+			merged[key] = value
+		}
+	}
+	for key, value := range labels {
+		merged[key] = value
+	}
+
+	return context.WithValue(ctx, jobLabelsKey{}, merged)
+}
+
+// WithAssetJobLabels is like WithJobLabels, with the name of the asset and its pipeline as the labels.
+func WithAssetJobLabels(ctx context.Context, p *pipeline.Pipeline, asset *pipeline.Asset) context.Context {
+	labels := make(map[string]string)
+	if asset != nil && asset.Name != "" {
+		labels[AssetLabel] = asset.Name
+	}
+	if p != nil && p.Name != "" {
+		labels[PipelineLabel] = p.Name
+	}
+
+	return WithJobLabels(ctx, labels)
+}
+
+// jobLabels returns the sanitized job labels of the context, including the query tag, or nil if there are none.
+func (d *Client) jobLabels(ctx context.Context) map[string]string {
+	var labels map[string]string
+	if contextLabels, ok := ctx.Value(jobLabelsKey{}).(map[string]string); ok && len(contextLabels) > 0 {
+		labels = make(map[string]string, len(contextLabels)+1)
+		for key, value := range contextLabels {
+			labels[sanitizeLabelKey(key)] = sanitizeLabelValue(value)
+		}
+	}
+
+	if key, value, ok := d.queryTagLabel(ctx); ok {
+		if labels == nil {
+			labels = make(map[string]string, 1)
+		}
+		labels[key] = value
+	}
+
+	return labels
+}
+
+// sanitizeTableLabels sanitizes the keys and values of the labels of an asset, failing if they do not fit on a
+// table or if different keys are sanitized into the same one.
+func sanitizeTableLabels(labels map[string]string) (map[string]string, error) {
+	if len(labels) > maxLabelsPerResource {
+		return nil, errors.Errorf("tables can have at most %d labels, got %d", maxLabelsPerResource, len(labels))
+	}
+
+	sanitized := make(map[string]string, len(labels))
+	original := make(map[string]string, len(labels))
+	for key, value := range labels {
+		sanitizedKey := sanitizeLabelKey(key)
+		if other, ok := original[sanitizedKey]; ok {
+			return nil, errors.Errorf("labels '%s' and '%s' both turn into the label key '%s'", min(key, other), max(key, other), sanitizedKey)
+		}
+
+		original[sanitizedKey] = key
+		sanitized[sanitizedKey] = sanitizeLabelValue(value)
+	}
+
+	return sanitized, nil
+}
+
+// applyTableLabels adds the changes that make the current labels of a table match the desired ones to the update,
+// reporting whether there were any. The labels in keep are left on the table even though they are not desired.
+func applyTableLabels(update *bigquery.TableMetadataToUpdate, current, desired map[string]string, keep ...string) bool {
+	if maps.Equal(current, desired) {
+		return false
+	}
+
+	changed := false
+	for key, value := range desired {
+		if existing, ok := current[key]; !ok || existing != value {
+			update.SetLabel(key, value)
+			changed = true
+		}
+	}
+	for key := range current {
+		if _, ok := desired[key]; ok || slices.Contains(keep, key) {
+			continue
+		}
+
+		update.DeleteLabel(key)
+		changed = true
+	}
+
+	return changed
+}
@@ -0,0 +1,96 @@
+package bigquery
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestSanitizeLabelValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "valid value", value: "team-data_1", want: "team-data_1"},
+		{name: "uppercase letters", value: "Team Data", want: "team_data"},
+		{name: "special characters", value: "data@example.com", want: "data_example_com"},
+		{name: "non-ascii characters", value: "équipe", want: "_quipe"},
+		{name: "long value", value: strings.Repeat("a", 70), want: strings.Repeat("a", 63)},
+		{name: "empty value", value: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, sanitizeLabelValue(tt.value))
+		})
+	}
+}
+
+func TestSanitizeLabelKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{name: "valid key", key: "pipeline", want: "pipeline"},
+		{name: "dotted key", key: "Asset.Name", want: "asset_name"},
+		{name: "leading digit", key: "1st", want: "l_1st"},
+		{name: "empty key", key: "", want: "l_"},
+		{name: "long key with leading digit", key: "1" + strings.Repeat("a", 70), want: "l_1" + strings.Repeat("a", 60)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, sanitizeLabelKey(tt.key))
+		})
+	}
+}
+
+func TestClient_JobLabels(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			return stringQueryResponse("a")
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	ctx := WithJobLabels(context.Background(), map[string]string{"Team": "Data Platform", "pipeline": "overridden"})
+	ctx = WithAssetJobLabels(ctx, &pipeline.Pipeline{Name: "Daily Pipeline"}, &pipeline.Asset{Name: "dataset.orders"})
+	ctx = WithQueryTag(ctx, "finance")
+
+	require.NoError(t, d.RunQueryWithoutResult(ctx, &query.Query{Query: "SELECT 1"}))
+	_, err := d.Select(context.Background(), &query.Query{Query: "SELECT 1"})
+	require.NoError(t, err)
+
+	requests := handler.recordedRequests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, map[string]string{
+		"team":               "data_platform",
+		"pipeline":           "overridden",
+		AssetLabel:           "dataset_orders",
+		PipelineLabel:        "daily_pipeline",
+		DefaultQueryTagLabel: "finance",
+	}, requests[0].Labels)
+	assert.Nil(t, requests[1].Labels)
+}
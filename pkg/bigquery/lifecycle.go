@@ -0,0 +1,50 @@
+package bigquery
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Lifecycle holds the timestamps of a table relevant for retention reporting.
+type Lifecycle struct {
+	CreationTime     time.Time
+	LastModifiedTime time.Time
+
+	// ExpirationTime is the zero time if the table never expires, see NeverExpires.
+	ExpirationTime time.Time
+
+	// RemainingTTL is the time left until the table expires at the time it was read, zero for tables that already
+	// expired or never expire.
+	RemainingTTL time.Duration
+}
+
+// NeverExpires reports whether the table has no expiration time set.
+func (l *Lifecycle) NeverExpires() bool {
+	return l.ExpirationTime.IsZero()
+}
+
+// GetTableLifecycle reads the creation, last modification and expiration times of the given table.
+func (d *Client) GetTableLifecycle(ctx context.Context, tableName string) (*Lifecycle, error) {
+	tableRef, err := d.getTableRef(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := tableRef.Metadata(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(formatError(err), "failed to fetch metadata for table '%s'", tableName)
+	}
+
+	lifecycle := &Lifecycle{
+		CreationTime:     meta.CreationTime,
+		LastModifiedTime: meta.LastModifiedTime,
+		ExpirationTime:   meta.ExpirationTime,
+	}
+	if !lifecycle.NeverExpires() {
+		lifecycle.RemainingTTL = max(time.Until(meta.ExpirationTime), 0)
+	}
+
+	return lifecycle, nil
+}
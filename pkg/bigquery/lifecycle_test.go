@@ -0,0 +1,68 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_GetTableLifecycle(t *testing.T) {
+	t.Parallel()
+
+	created := time.Now().Add(-48 * time.Hour).Truncate(time.Millisecond)
+	modified := created.Add(time.Hour)
+	expires := time.Now().Add(24 * time.Hour).Truncate(time.Millisecond)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		table := &bigquery2.Table{
+			CreationTime:     created.UnixMilli(),
+			LastModifiedTime: uint64(modified.UnixMilli()),
+		}
+
+		switch r.URL.Path {
+		case fmt.Sprintf("/projects/%s/datasets/dataset/tables/expiring", testProjectID):
+			table.ExpirationTime = expires.UnixMilli()
+		case fmt.Sprintf("/projects/%s/datasets/dataset/tables/expired", testProjectID):
+			table.ExpirationTime = created.Add(time.Hour).UnixMilli()
+		case fmt.Sprintf("/projects/%s/datasets/dataset/tables/permanent", testProjectID):
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table test-project:dataset.missing"}}`))
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(table)
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	lifecycle, err := d.GetTableLifecycle(context.Background(), "dataset.expiring")
+	require.NoError(t, err)
+	assert.True(t, created.Equal(lifecycle.CreationTime))
+	assert.True(t, modified.Equal(lifecycle.LastModifiedTime))
+	assert.True(t, expires.Equal(lifecycle.ExpirationTime))
+	assert.False(t, lifecycle.NeverExpires())
+	assert.InDelta(t, 24*time.Hour, lifecycle.RemainingTTL, float64(time.Minute))
+
+	lifecycle, err = d.GetTableLifecycle(context.Background(), "dataset.expired")
+	require.NoError(t, err)
+	assert.False(t, lifecycle.NeverExpires())
+	assert.Zero(t, lifecycle.RemainingTTL)
+
+	lifecycle, err = d.GetTableLifecycle(context.Background(), "dataset.permanent")
+	require.NoError(t, err)
+	assert.True(t, lifecycle.NeverExpires())
+	assert.Zero(t, lifecycle.RemainingTTL)
+
+	_, err = d.GetTableLifecycle(context.Background(), "dataset.missing")
+	require.EqualError(t, err, "failed to fetch metadata for table 'dataset.missing': Not found: Table test-project:dataset.missing")
+}
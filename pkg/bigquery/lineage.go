@@ -0,0 +1,180 @@
+package bigquery
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// The OpenLineage events are plain structs that marshal into the OpenLineage JSON format, which keeps the
+// lineage export free of any OpenLineage client dependency. See https://openlineage.io/spec for the spec.
+const (
+	LineageProducer  = "https://github.com/bruin-data/bruin"
+	LineageNamespace = "bigquery"
+	LineageJobSpace  = "bruin"
+
+	LineageEventComplete = "COMPLETE"
+
+	lineageRunEventSchemaURL   = "https://openlineage.io/spec/2-0-2/OpenLineage.json#/$defs/RunEvent"
+	lineageSchemaFacetURL      = "https://openlineage.io/spec/facets/1-1-1/SchemaDatasetFacet.json#/$defs/SchemaDatasetFacet"
+	lineageDocumentationURL    = "https://openlineage.io/spec/facets/1-0-1/DocumentationDatasetFacet.json#/$defs/DocumentationDatasetFacet"
+	lineageOutputStatisticsURL = "https://openlineage.io/spec/facets/1-0-2/OutputStatisticsOutputDatasetFacet.json#/$defs/OutputStatisticsOutputDatasetFacet"
+)
+
+// LineageEvent is an OpenLineage run event describing the materialization of an asset.
+type LineageEvent struct {
+	EventType string           `json:"eventType"`
+	EventTime time.Time        `json:"eventTime"`
+	Producer  string           `json:"producer"`
+	SchemaURL string           `json:"schemaURL"`
+	Run       LineageRun       `json:"run"`
+	Job       LineageJob       `json:"job"`
+	Inputs    []LineageDataset `json:"inputs"`
+	Outputs   []LineageDataset `json:"outputs"`
+}
+
+type LineageRun struct {
+	RunID string `json:"runId"`
+}
+
+type LineageJob struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// LineageDataset is a table read or written by the run, named `project.dataset.table` in the LineageNamespace.
+type LineageDataset struct {
+	Namespace    string                `json:"namespace"`
+	Name         string                `json:"name"`
+	Facets       *LineageDatasetFacets `json:"facets,omitempty"`
+	OutputFacets *LineageOutputFacets  `json:"outputFacets,omitempty"`
+}
+
+type LineageDatasetFacets struct {
+	Schema        *LineageSchemaFacet        `json:"schema,omitempty"`
+	Documentation *LineageDocumentationFacet `json:"documentation,omitempty"`
+}
+
+type LineageOutputFacets struct {
+	OutputStatistics *LineageOutputStatisticsFacet `json:"outputStatistics,omitempty"`
+}
+
+// LineageFacet holds the fields every OpenLineage facet carries.
+type LineageFacet struct {
+	Producer  string `json:"_producer"`
+	SchemaURL string `json:"_schemaURL"`
+}
+
+type LineageSchemaFacet struct {
+	LineageFacet
+	Fields []LineageSchemaField `json:"fields"`
+}
+
+// LineageSchemaField is a column of the dataset, the fields of records are nested under their parent.
+type LineageSchemaField struct {
+	Name        string               `json:"name"`
+	Type        string               `json:"type"`
+	Description string               `json:"description,omitempty"`
+	Fields      []LineageSchemaField `json:"fields,omitempty"`
+}
+
+type LineageDocumentationFacet struct {
+	LineageFacet
+	Description string `json:"description"`
+}
+
+type LineageOutputStatisticsFacet struct {
+	LineageFacet
+	RowCount uint64 `json:"rowCount"`
+	Size     int64  `json:"size"`
+}
+
+// BuildLineageEvent builds a COMPLETE OpenLineage event for the materialized asset. The output dataset carries the
+// schema, description and size of the asset table as read from its metadata; the inputs are the upstream assets,
+// which are only named, their metadata is not read. Upstreams that are not assets, e.g. URIs, are left out.
+func (d *Client) BuildLineageEvent(ctx context.Context, asset *pipeline.Asset) (*LineageEvent, error) {
+	output, err := d.ResolveTable(asset.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	tableRef, err := d.getTableRef(asset.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := tableRef.Metadata(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(formatError(err), "failed to fetch metadata for table '%s'", asset.Name)
+	}
+
+	outputDataset := LineageDataset{
+		Namespace: LineageNamespace,
+		Name:      output.String(),
+		Facets: &LineageDatasetFacets{
+			Schema: &LineageSchemaFacet{
+				LineageFacet: LineageFacet{Producer: LineageProducer, SchemaURL: lineageSchemaFacetURL},
+				Fields:       lineageSchemaFields(meta.Schema),
+			},
+		},
+		OutputFacets: &LineageOutputFacets{
+			OutputStatistics: &LineageOutputStatisticsFacet{
+				LineageFacet: LineageFacet{Producer: LineageProducer, SchemaURL: lineageOutputStatisticsURL},
+				RowCount:     meta.NumRows,
+				Size:         meta.NumBytes,
+			},
+		},
+	}
+	if meta.Description != "" {
+		outputDataset.Facets.Documentation = &LineageDocumentationFacet{
+			LineageFacet: LineageFacet{Producer: LineageProducer, SchemaURL: lineageDocumentationURL},
+			Description:  meta.Description,
+		}
+	}
+
+	inputs := make([]LineageDataset, 0, len(asset.Upstreams))
+	for _, upstream := range asset.Upstreams {
+		if upstream.Type != "asset" {
+			continue
+		}
+
+		input, err := d.ResolveTable(upstream.Value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve the upstream '%s'", upstream.Value)
+		}
+		inputs = append(inputs, LineageDataset{Namespace: LineageNamespace, Name: input.String()})
+	}
+
+	return &LineageEvent{
+		EventType: LineageEventComplete,
+		EventTime: time.Now().UTC(),
+		Producer:  LineageProducer,
+		SchemaURL: lineageRunEventSchemaURL,
+		Run:       LineageRun{RunID: uuid.NewString()},
+		Job:       LineageJob{Namespace: LineageJobSpace, Name: asset.Name},
+		Inputs:    inputs,
+		Outputs:   []LineageDataset{outputDataset},
+	}, nil
+}
+
+func lineageSchemaFields(schema bigquery.Schema) []LineageSchemaField {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	fields := make([]LineageSchemaField, 0, len(schema))
+	for _, field := range schema {
+		fields = append(fields, LineageSchemaField{
+			Name:        field.Name,
+			Type:        string(field.Type),
+			Description: field.Description,
+			Fields:      lineageSchemaFields(field.Schema),
+		})
+	}
+
+	return fields
+}
@@ -0,0 +1,80 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_BuildLineageEvent(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != fmt.Sprintf("/projects/%s/datasets/analytics/tables/orders", testProjectID) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table test-project:analytics.missing"}}`))
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(&bigquery2.Table{
+			Description: "all the orders",
+			NumRows:     42,
+			NumBytes:    1024,
+			Schema: &bigquery2.TableSchema{
+				Fields: []*bigquery2.TableFieldSchema{
+					{Name: "id", Type: "INTEGER", Description: "the order id"},
+					{Name: "customer", Type: "RECORD", Fields: []*bigquery2.TableFieldSchema{
+						{Name: "name", Type: "STRING"},
+					}},
+				},
+			},
+		})
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	event, err := d.BuildLineageEvent(context.Background(), &pipeline.Asset{
+		Name: "analytics.orders",
+		Upstreams: []pipeline.Upstream{
+			{Type: "asset", Value: "raw.orders"},
+			{Type: "asset", Value: "other-project.raw.customers"},
+			{Type: "uri", Value: "s3://bucket/orders"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, LineageEventComplete, event.EventType)
+	assert.Equal(t, LineageJob{Namespace: LineageJobSpace, Name: "analytics.orders"}, event.Job)
+	assert.NotEmpty(t, event.Run.RunID)
+	assert.Equal(t, []LineageDataset{
+		{Namespace: LineageNamespace, Name: "test-project.raw.orders"},
+		{Namespace: LineageNamespace, Name: "other-project.raw.customers"},
+	}, event.Inputs)
+
+	require.Len(t, event.Outputs, 1)
+	output := event.Outputs[0]
+	assert.Equal(t, "test-project.analytics.orders", output.Name)
+	assert.Equal(t, []LineageSchemaField{
+		{Name: "id", Type: "INTEGER", Description: "the order id"},
+		{Name: "customer", Type: "RECORD", Fields: []LineageSchemaField{{Name: "name", Type: "STRING"}}},
+	}, output.Facets.Schema.Fields)
+	assert.Equal(t, "all the orders", output.Facets.Documentation.Description)
+	assert.Equal(t, uint64(42), output.OutputFacets.OutputStatistics.RowCount)
+	assert.Equal(t, int64(1024), output.OutputFacets.OutputStatistics.Size)
+
+	encoded, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.Contains(t, string(encoded), `"outputStatistics":{"_producer":"https://github.com/bruin-data/bruin"`)
+
+	_, err = d.BuildLineageEvent(context.Background(), &pipeline.Asset{Name: "analytics.missing"})
+	require.EqualError(t, err, "failed to fetch metadata for table 'analytics.missing': Not found: Table test-project:analytics.missing")
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"cloud.google.com/go/bigquery"
 	"github.com/bruin-data/bruin/pkg/helpers"
 	"github.com/bruin-data/bruin/pkg/pipeline"
 	"github.com/pkg/errors"
@@ -11,18 +12,26 @@ import (
 
 var matMap = pipeline.AssetMaterializationMap{
 	pipeline.MaterializationTypeView: {
-		pipeline.MaterializationStrategyNone:          viewMaterializer,
+		pipeline.MaterializationStrategyNone:          deduplicated(viewMaterializer),
 		pipeline.MaterializationStrategyAppend:        errorMaterializer,
 		pipeline.MaterializationStrategyCreateReplace: errorMaterializer,
 		pipeline.MaterializationStrategyDeleteInsert:  errorMaterializer,
 	},
 	pipeline.MaterializationTypeTable: {
-		pipeline.MaterializationStrategyNone:          buildCreateReplaceQuery,
-		pipeline.MaterializationStrategyAppend:        buildAppendQuery,
-		pipeline.MaterializationStrategyCreateReplace: buildCreateReplaceQuery,
-		pipeline.MaterializationStrategyDeleteInsert:  buildIncrementalQuery,
-		pipeline.MaterializationStrategyMerge:         mergeMaterializer,
-		pipeline.MaterializationStrategyTimeInterval:  buildTimeIntervalQuery,
+		pipeline.MaterializationStrategyNone:          deduplicated(buildCreateReplaceQuery),
+		pipeline.MaterializationStrategyAppend:        deduplicated(buildAppendQuery),
+		pipeline.MaterializationStrategyCreateReplace: deduplicated(buildCreateReplaceQuery),
+		pipeline.MaterializationStrategyDeleteInsert:  deduplicated(buildIncrementalQuery),
+		pipeline.MaterializationStrategyMerge:         deduplicated(mergeMaterializer),
+		pipeline.MaterializationStrategyTimeInterval:  deduplicated(buildTimeIntervalQuery),
+	},
+	pipeline.MaterializationTypeMaterializedView: {
+		pipeline.MaterializationStrategyNone:          deduplicated(materializedViewMaterializer),
+		pipeline.MaterializationStrategyAppend:        errorMaterializer,
+		pipeline.MaterializationStrategyCreateReplace: errorMaterializer,
+		pipeline.MaterializationStrategyDeleteInsert:  errorMaterializer,
+		pipeline.MaterializationStrategyMerge:         errorMaterializer,
+		pipeline.MaterializationStrategyTimeInterval:  errorMaterializer,
 	},
 }
 
@@ -41,14 +50,67 @@ func viewMaterializer(asset *pipeline.Asset, query string) (string, error) {
 	return fmt.Sprintf("CREATE OR REPLACE VIEW %s AS\n%s", asset.Name, query), nil
 }
 
+// materializedViewMaterializer creates the materialized view unless it exists, partitioned and clustered the way
+// tables are; a changed definition is applied by dropping the view first, see Client.SyncMaterializedView. BigQuery
+// refreshes materialized views by itself, which is why their definition must be deterministic.
+func materializedViewMaterializer(asset *pipeline.Asset, query string) (string, error) {
+	if err := ValidateMaterialization(asset); err != nil {
+		return "", err
+	}
+
+	if deterministic, functions := IsDeterministic(query); !deterministic {
+		return "", &MaterializationValidationError{
+			Asset:    asset.Name,
+			Problems: []string{"materialized views cannot call non-deterministic functions, the query calls " + strings.Join(functions, ", ")},
+		}
+	}
+
+	mat := asset.Materialization
+
+	partitionClause := ""
+	if mat.PartitionBy != "" {
+		partitionClause = "PARTITION BY " + partitionExpression(asset)
+	}
+
+	clusterByClause := ""
+	if len(mat.ClusterBy) > 0 {
+		clusterByClause = "CLUSTER BY " + clusterByColumns(asset)
+	}
+
+	options := make([]string, 0, 3)
+	if mat.EnableRefresh != nil {
+		options = append(options, fmt.Sprintf("enable_refresh=%t", *mat.EnableRefresh))
+	}
+	if mat.RefreshIntervalMinutes > 0 {
+		options = append(options, fmt.Sprintf("refresh_interval_minutes=%d", mat.RefreshIntervalMinutes))
+	}
+	if key := assetEncryptionKey(asset); key != "" {
+		options = append(options, "kms_key_name="+quoteStringLiteral(key))
+	}
+
+	optionsClause := ""
+	if len(options) > 0 {
+		optionsClause = "\nOPTIONS(" + strings.Join(options, ", ") + ")"
+	}
+
+	return fmt.Sprintf("CREATE MATERIALIZED VIEW IF NOT EXISTS %s %s %s%s AS\n%s", asset.Name, partitionClause, clusterByClause, optionsClause, query), nil
+}
+
 func mergeMaterializer(asset *pipeline.Asset, query string) (string, error) {
+	// the rows are matched on the primary key, without one the MERGE statement cannot be built
 	if len(asset.Columns) == 0 {
-		return "", fmt.Errorf("materialization strategy %s requires the `columns` field to be set", asset.Materialization.Strategy)
+		return "", &MaterializationValidationError{
+			Asset:    asset.Name,
+			Problems: []string{fmt.Sprintf("materialization strategy %s requires the `columns` field to be set", asset.Materialization.Strategy)},
+		}
 	}
 
 	primaryKeys := asset.ColumnNamesWithPrimaryKey()
 	if len(primaryKeys) == 0 {
-		return "", fmt.Errorf("materialization strategy %s requires the `primary_key` field to be set on at least one column", asset.Materialization.Strategy)
+		return "", &MaterializationValidationError{
+			Asset:    asset.Name,
+			Problems: []string{fmt.Sprintf("materialization strategy %s requires the `primary_key` field to be set on at least one column", asset.Materialization.Strategy)},
+		}
 	}
 
 	nonPrimaryKeys := asset.ColumnNamesWithUpdateOnMerge()
@@ -56,18 +118,22 @@ func mergeMaterializer(asset *pipeline.Asset, query string) (string, error) {
 
 	on := make([]string, 0, len(primaryKeys))
 	for _, key := range primaryKeys {
-		on = append(on, fmt.Sprintf("target.%s = source.%s", key, key))
+		on = append(on, fmt.Sprintf("target.%s = source.%s", QuoteColumn(key), QuoteColumn(key)))
 	}
 	onQuery := strings.Join(on, " AND ")
 
-	allColumnValues := strings.Join(columnNames, ", ")
+	quotedColumnNames := make([]string, 0, len(columnNames))
+	for _, name := range columnNames {
+		quotedColumnNames = append(quotedColumnNames, QuoteColumn(name))
+	}
+	allColumnValues := strings.Join(quotedColumnNames, ", ")
 
 	whenMatchedThenQuery := ""
 
 	if len(nonPrimaryKeys) > 0 {
 		matchedUpdateStatements := make([]string, 0, len(nonPrimaryKeys))
 		for _, col := range nonPrimaryKeys {
-			matchedUpdateStatements = append(matchedUpdateStatements, fmt.Sprintf("target.%s = source.%s", col, col))
+			matchedUpdateStatements = append(matchedUpdateStatements, fmt.Sprintf("target.%s = source.%s", QuoteColumn(col), QuoteColumn(col)))
 		}
 
 		matchedUpdateQuery := strings.Join(matchedUpdateStatements, ", ")
@@ -103,12 +169,13 @@ func buildIncrementalQuery(asset *pipeline.Asset, query string) (string, error)
 	tempTableName := "__bruin_tmp_" + randPrefix
 
 	declaredVarName := "distinct_keys_" + randPrefix
+	key := QuoteColumn(mat.IncrementalKey)
 	queries := []string{
 		fmt.Sprintf("DECLARE %s array<%s>", declaredVarName, foundCol.Type),
 		"BEGIN TRANSACTION",
 		fmt.Sprintf("CREATE TEMP TABLE %s AS %s\n", tempTableName, query),
-		fmt.Sprintf("SET %s = (SELECT array_agg(distinct %s) FROM %s)", declaredVarName, mat.IncrementalKey, tempTableName),
-		fmt.Sprintf("DELETE FROM %s WHERE %s in unnest(%s)", asset.Name, mat.IncrementalKey, declaredVarName),
+		fmt.Sprintf("SET %s = (SELECT array_agg(distinct %s) FROM %s)", declaredVarName, key, tempTableName),
+		fmt.Sprintf("DELETE FROM %s WHERE %s in unnest(%s)", asset.Name, key, declaredVarName),
 		fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", asset.Name, tempTableName),
 		"COMMIT TRANSACTION",
 	}
@@ -119,11 +186,12 @@ func buildIncrementalQuery(asset *pipeline.Asset, query string) (string, error)
 func buildIncrementalQueryWithoutTempVariable(asset *pipeline.Asset, query string) (string, error) {
 	mat := asset.Materialization
 	tempTableName := "__bruin_tmp_" + helpers.PrefixGenerator()
+	key := QuoteColumn(mat.IncrementalKey)
 
 	queries := []string{
 		"BEGIN TRANSACTION",
 		fmt.Sprintf("CREATE TEMP TABLE %s AS %s\n", tempTableName, query),
-		fmt.Sprintf("DELETE FROM %s WHERE %s in (SELECT DISTINCT %s FROM %s)", asset.Name, mat.IncrementalKey, mat.IncrementalKey, tempTableName),
+		fmt.Sprintf("DELETE FROM %s WHERE %s in (SELECT DISTINCT %s FROM %s)", asset.Name, key, key, tempTableName),
 		fmt.Sprintf("INSERT INTO %s SELECT * FROM %s", asset.Name, tempTableName),
 		"COMMIT TRANSACTION",
 	}
@@ -132,19 +200,28 @@ func buildIncrementalQueryWithoutTempVariable(asset *pipeline.Asset, query strin
 }
 
 func buildCreateReplaceQuery(asset *pipeline.Asset, query string) (string, error) {
+	if err := ValidateMaterialization(asset); err != nil {
+		return "", err
+	}
+
 	mat := asset.Materialization
 
 	partitionClause := ""
 	if mat.PartitionBy != "" {
-		partitionClause = "PARTITION BY " + mat.PartitionBy
+		partitionClause = "PARTITION BY " + partitionExpression(asset)
 	}
 
 	clusterByClause := ""
 	if len(mat.ClusterBy) > 0 {
-		clusterByClause = "CLUSTER BY " + strings.Join(mat.ClusterBy, ", ")
+		clusterByClause = "CLUSTER BY " + clusterByColumns(asset)
 	}
 
-	return fmt.Sprintf("CREATE OR REPLACE TABLE %s %s %s AS\n%s", asset.Name, partitionClause, clusterByClause, query), nil
+	optionsClause := ""
+	if options := tableOptions(asset); options != "" {
+		optionsClause = "\n" + options
+	}
+
+	return fmt.Sprintf("CREATE OR REPLACE TABLE %s %s %s%s AS\n%s", asset.Name, partitionClause, clusterByClause, optionsClause, query), nil
 }
 
 func buildTimeIntervalQuery(asset *pipeline.Asset, query string) (string, error) {
@@ -170,7 +247,7 @@ func buildTimeIntervalQuery(asset *pipeline.Asset, query string) (string, error)
 		"BEGIN TRANSACTION",
 		fmt.Sprintf(`DELETE FROM %s WHERE %s BETWEEN '%s' AND '%s'`,
 			asset.Name,
-			asset.Materialization.IncrementalKey,
+			QuoteColumn(asset.Materialization.IncrementalKey),
 			startVar,
 			endVar),
 		fmt.Sprintf(`INSERT INTO %s %s`,
@@ -183,20 +260,127 @@ func buildTimeIntervalQuery(asset *pipeline.Asset, query string) (string, error)
 }
 
 func BuildCreateTableQuery(asset *pipeline.Asset, query string) (string, error) {
+	// the table is created from the declared columns, they are all of its columns
+	if err := validateMaterialization(asset, true); err != nil {
+		return "", err
+	}
+
 	columnDefs := make([]string, 0, len(asset.Columns))
 	for _, column := range asset.Columns {
-		columnDefs = append(columnDefs, fmt.Sprintf("`%s` %s", column.Name, column.Type))
+		columnDefs = append(columnDefs, fmt.Sprintf("%s %s", QuoteIdentifier(column.Name), column.Type))
 	}
 	q := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n  %s\n)",
 		asset.Name,
 		strings.Join(columnDefs, ",\n  "))
 
 	if asset.Materialization.PartitionBy != "" {
-		q += "\nPARTITION BY " + asset.Materialization.PartitionBy
+		q += "\nPARTITION BY " + partitionExpression(asset)
 	}
 	if len(asset.Materialization.ClusterBy) > 0 {
-		q += "\nCLUSTER BY " + strings.Join(asset.Materialization.ClusterBy, ", ")
+		q += "\nCLUSTER BY " + clusterByColumns(asset)
+	}
+	if options := tableOptions(asset); options != "" {
+		q += "\n" + options
 	}
 
 	return q, nil
 }
+
+// partitionExpression returns the PARTITION BY expression of the asset table. A partition_granularity given with a
+// plain partitioning column truncates the column with the function matching its declared type, e.g.
+// `DATE_TRUNC(created_at, MONTH)`. Daily partitions use the column as it is, which is the only granularity that
+// does not require the type of the column to be declared, see validatePartitionGranularity.
+func partitionExpression(asset *pipeline.Asset) string {
+	mat := asset.Materialization
+	if mat.PartitionRange != nil {
+		return fmt.Sprintf("RANGE_BUCKET(%s, GENERATE_ARRAY(%d, %d, %d))", QuoteColumn(mat.PartitionBy), mat.PartitionRange.Start, mat.PartitionRange.End, mat.PartitionRange.Interval)
+	}
+	if mat.PartitionGranularity == pipeline.PartitionGranularityNone || !columnNameRegex.MatchString(mat.PartitionBy) {
+		return quotePartitionColumn(mat.PartitionBy)
+	}
+
+	column := quotePartitionColumn(mat.PartitionBy)
+	granularity := strings.ToUpper(string(mat.PartitionGranularity))
+	switch partitionColumnType(asset) {
+	case bigquery.TimestampFieldType:
+		return fmt.Sprintf("TIMESTAMP_TRUNC(%s, %s)", column, granularity)
+	case bigquery.DateTimeFieldType:
+		return fmt.Sprintf("DATETIME_TRUNC(%s, %s)", column, granularity)
+	}
+
+	// DATE columns, and the columns of any time type, are partitioned by day as they are
+	if mat.PartitionGranularity == pipeline.PartitionGranularityDay {
+		return column
+	}
+
+	return fmt.Sprintf("DATE_TRUNC(%s, %s)", column, granularity)
+}
+
+// partitionColumnType returns the declared type of the partitioning column of the asset, or an empty type if the
+// column is not declared.
+func partitionColumnType(asset *pipeline.Asset) bigquery.FieldType {
+	name := partitionColumn(asset.Materialization.PartitionBy)
+	for _, column := range asset.Columns {
+		if !strings.EqualFold(column.Name, name) {
+			continue
+		}
+		field, err := parseFieldSchema(column.Name, column.Type)
+		if err != nil {
+			return ""
+		}
+		return field.Type
+	}
+
+	return ""
+}
+
+// quotePartitionColumn quotes partition_by if it is a plain column, expressions such as `DATE(created_at)` are kept
+// as they are.
+func quotePartitionColumn(partitionBy string) string {
+	if !columnNameRegex.MatchString(partitionBy) {
+		return partitionBy
+	}
+
+	return QuoteColumn(strings.Trim(partitionBy, "`"))
+}
+
+func clusterByColumns(asset *pipeline.Asset) string {
+	columns := make([]string, 0, len(asset.Materialization.ClusterBy))
+	for _, column := range asset.Materialization.ClusterBy {
+		columns = append(columns, QuoteColumn(column))
+	}
+
+	return strings.Join(columns, ", ")
+}
+
+// tableOptions returns the OPTIONS clause that sets the partition expiration, the partition filter requirement and the
+// KMS key of the asset table, or an empty string if the asset sets none of them.
+func tableOptions(asset *pipeline.Asset) string {
+	mat := asset.Materialization
+	options := make([]string, 0, 3)
+	if mat.PartitionExpirationDays > 0 {
+		options = append(options, fmt.Sprintf("partition_expiration_days=%d", mat.PartitionExpirationDays))
+	}
+	if mat.RequirePartitionFilter {
+		options = append(options, "require_partition_filter=true")
+	}
+	if key := assetEncryptionKey(asset); key != "" {
+		options = append(options, "kms_key_name="+quoteStringLiteral(key))
+	}
+	if len(options) == 0 {
+		return ""
+	}
+
+	return "OPTIONS(" + strings.Join(options, ", ") + ")"
+}
+
+// neverExpireUpdate adds the removal of the expiration of the table to the update if the asset never expires but
+// the table does, reporting whether it did.
+func neverExpireUpdate(update *bigquery.TableMetadataToUpdate, meta *bigquery.TableMetadata, asset *pipeline.Asset) bool {
+	if !asset.Materialization.NeverExpire || meta.ExpirationTime.IsZero() {
+		return false
+	}
+
+	update.ExpirationTime = bigquery.NeverExpire
+	return true
+}
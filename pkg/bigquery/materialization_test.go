@@ -10,6 +10,8 @@ import (
 
 func TestMaterializer_Render(t *testing.T) {
 	t.Parallel()
+
+	enableRefresh := true
 	tests := []struct {
 		name        string
 		task        *pipeline.Asset
@@ -35,6 +37,57 @@ func TestMaterializer_Render(t *testing.T) {
 			query: "SELECT 1",
 			want:  "CREATE OR REPLACE VIEW my.asset AS\nSELECT 1",
 		},
+		{
+			name: "materialize to a materialized view",
+			task: &pipeline.Asset{
+				Name: "my.asset",
+				Materialization: pipeline.Materialization{
+					Type:                   pipeline.MaterializationTypeMaterializedView,
+					PartitionBy:            "dt",
+					ClusterBy:              []string{"country"},
+					EnableRefresh:          &enableRefresh,
+					RefreshIntervalMinutes: 30,
+				},
+			},
+			query: "SELECT dt, country, SUM(revenue) AS revenue FROM raw.orders GROUP BY dt, country",
+			want: "^CREATE MATERIALIZED VIEW IF NOT EXISTS my\\.asset PARTITION BY `dt` CLUSTER BY `country`\n" +
+				"OPTIONS\\(enable_refresh=true, refresh_interval_minutes=30\\) AS\n" +
+				"SELECT dt, country, SUM\\(revenue\\) AS revenue FROM raw\\.orders GROUP BY dt, country$",
+		},
+		{
+			name: "materialized view without options",
+			task: &pipeline.Asset{
+				Name: "my.asset",
+				Materialization: pipeline.Materialization{
+					Type: pipeline.MaterializationTypeMaterializedView,
+				},
+			},
+			query: "SELECT 1",
+			want:  "^CREATE MATERIALIZED VIEW IF NOT EXISTS my\\.asset   AS\nSELECT 1$",
+		},
+		{
+			name: "materialized view with a non-deterministic definition",
+			task: &pipeline.Asset{
+				Name: "my.asset",
+				Materialization: pipeline.Materialization{
+					Type: pipeline.MaterializationTypeMaterializedView,
+				},
+			},
+			query:   "SELECT * FROM raw.orders WHERE dt = CURRENT_DATE()",
+			wantErr: true,
+		},
+		{
+			name: "materialized view with a strategy",
+			task: &pipeline.Asset{
+				Name: "my.asset",
+				Materialization: pipeline.Materialization{
+					Type:     pipeline.MaterializationTypeMaterializedView,
+					Strategy: pipeline.MaterializationStrategyMerge,
+				},
+			},
+			query:   "SELECT 1",
+			wantErr: true,
+		},
 		{
 			name: "materialize to a table, no partition or cluster, default to create+replace",
 			task: &pipeline.Asset{
@@ -46,6 +99,101 @@ func TestMaterializer_Render(t *testing.T) {
 			query: "SELECT 1",
 			want:  "CREATE OR REPLACE TABLE my.asset   AS\nSELECT 1",
 		},
+		{
+			name: "materialize to a range partitioned table",
+			task: &pipeline.Asset{
+				Name: "my.asset",
+				Materialization: pipeline.Materialization{
+					Type:           pipeline.MaterializationTypeTable,
+					PartitionBy:    "customer_id",
+					PartitionRange: &pipeline.PartitionRange{Start: 0, End: 1000, Interval: 10},
+				},
+			},
+			query: "SELECT 1",
+			want:  "^CREATE OR REPLACE TABLE my.asset PARTITION BY RANGE_BUCKET\\(`customer_id`, GENERATE_ARRAY\\(0, 1000, 10\\)\\)  AS\nSELECT 1$",
+		},
+		{
+			name: "materialize to a monthly partitioned table",
+			task: &pipeline.Asset{
+				Name:    "my.asset",
+				Columns: []pipeline.Column{{Name: "dt", Type: "DATE"}},
+				Materialization: pipeline.Materialization{
+					Type:                 pipeline.MaterializationTypeTable,
+					PartitionBy:          "dt",
+					PartitionGranularity: pipeline.PartitionGranularityMonth,
+				},
+			},
+			query: "SELECT 1",
+			want:  "^CREATE OR REPLACE TABLE my.asset PARTITION BY DATE_TRUNC\\(`dt`, MONTH\\)  AS\nSELECT 1$",
+		},
+		{
+			name: "materialize to an hourly partitioned table",
+			task: &pipeline.Asset{
+				Name:    "my.asset",
+				Columns: []pipeline.Column{{Name: "created_at", Type: "DATETIME"}},
+				Materialization: pipeline.Materialization{
+					Type:                 pipeline.MaterializationTypeTable,
+					PartitionBy:          "created_at",
+					PartitionGranularity: pipeline.PartitionGranularityHour,
+				},
+			},
+			query: "SELECT 1",
+			want:  "^CREATE OR REPLACE TABLE my.asset PARTITION BY DATETIME_TRUNC\\(`created_at`, HOUR\\)  AS\nSELECT 1$",
+		},
+		{
+			name: "materialize to a daily partitioned table",
+			task: &pipeline.Asset{
+				Name:    "my.asset",
+				Columns: []pipeline.Column{{Name: "dt", Type: "DATE"}},
+				Materialization: pipeline.Materialization{
+					Type:                 pipeline.MaterializationTypeTable,
+					PartitionBy:          "dt",
+					PartitionGranularity: pipeline.PartitionGranularityDay,
+				},
+			},
+			query: "SELECT 1",
+			want:  "^CREATE OR REPLACE TABLE my.asset PARTITION BY `dt`  AS\nSELECT 1$",
+		},
+		{
+			name: "materialize to a table with partition options",
+			task: &pipeline.Asset{
+				Name: "my.asset",
+				Materialization: pipeline.Materialization{
+					Type:                    pipeline.MaterializationTypeTable,
+					PartitionBy:             "dt",
+					PartitionExpirationDays: 30,
+					RequirePartitionFilter:  true,
+				},
+			},
+			query: "SELECT 1",
+			want:  "^CREATE OR REPLACE TABLE my.asset PARTITION BY `dt` \nOPTIONS\\(partition_expiration_days=30, require_partition_filter=true\\) AS\nSELECT 1$",
+		},
+		{
+			name: "materialize to a table encrypted with a KMS key",
+			task: &pipeline.Asset{
+				Name:       "my.asset",
+				Parameters: map[string]string{KMSKeyParameter: "projects/p/locations/eu/keyRings/r/cryptoKeys/k"},
+				Materialization: pipeline.Materialization{
+					Type:                    pipeline.MaterializationTypeTable,
+					PartitionBy:             "dt",
+					PartitionExpirationDays: 30,
+				},
+			},
+			query: "SELECT 1",
+			want:  "^CREATE OR REPLACE TABLE my.asset PARTITION BY `dt` \nOPTIONS\\(partition_expiration_days=30, kms_key_name='projects/p/locations/eu/keyRings/r/cryptoKeys/k'\\) AS\nSELECT 1$",
+		},
+		{
+			name: "materialize to a table that never expires, the expiration is cleared after the statement",
+			task: &pipeline.Asset{
+				Name: "my.asset",
+				Materialization: pipeline.Materialization{
+					Type:        pipeline.MaterializationTypeTable,
+					NeverExpire: true,
+				},
+			},
+			query: "SELECT 1 -- the last line",
+			want:  "^CREATE OR REPLACE TABLE my.asset   AS\nSELECT 1 -- the last line$",
+		},
 		{
 			name: "materialize to a table, no partition or cluster, full refresh results in create+replace",
 			task: &pipeline.Asset{
@@ -70,7 +218,7 @@ func TestMaterializer_Render(t *testing.T) {
 				},
 			},
 			query: "SELECT 1",
-			want:  "CREATE OR REPLACE TABLE my.asset PARTITION BY dt  AS\nSELECT 1",
+			want:  "CREATE OR REPLACE TABLE my.asset PARTITION BY `dt`  AS\nSELECT 1",
 		},
 		{
 			name: "materialize to a table with partition and cluster, single field to cluster",
@@ -84,7 +232,7 @@ func TestMaterializer_Render(t *testing.T) {
 				},
 			},
 			query: "SELECT 1",
-			want:  "CREATE OR REPLACE TABLE my.asset PARTITION BY dt CLUSTER BY event_type AS\nSELECT 1",
+			want:  "CREATE OR REPLACE TABLE my.asset PARTITION BY `dt` CLUSTER BY `event_type` AS\nSELECT 1",
 		},
 		{
 			name: "materialize to a table with partition and cluster, multiple fields to cluster",
@@ -98,7 +246,7 @@ func TestMaterializer_Render(t *testing.T) {
 				},
 			},
 			query: "SELECT 1",
-			want:  "CREATE OR REPLACE TABLE my.asset PARTITION BY dt CLUSTER BY event_type, event_name AS\nSELECT 1",
+			want:  "CREATE OR REPLACE TABLE my.asset PARTITION BY `dt` CLUSTER BY `event_type`, `event_name` AS\nSELECT 1",
 		},
 		{
 			name: "materialize to a table with append",
@@ -149,7 +297,7 @@ func TestMaterializer_Render(t *testing.T) {
 			query: "SELECT 1",
 			want: "^BEGIN TRANSACTION;\n" +
 				"CREATE TEMP TABLE __bruin_tmp_.+ AS SELECT 1\n;\n" +
-				"DELETE FROM my\\.asset WHERE dt in \\(SELECT DISTINCT dt FROM __bruin_tmp_.+\\);\n" +
+				"DELETE FROM my\\.asset WHERE `dt` in \\(SELECT DISTINCT `dt` FROM __bruin_tmp_.+\\);\n" +
 				"INSERT INTO my\\.asset SELECT \\* FROM __bruin_tmp.+;\n" +
 				"COMMIT TRANSACTION;$",
 		},
@@ -170,8 +318,8 @@ func TestMaterializer_Render(t *testing.T) {
 			want: "^DECLARE distinct_keys.+ array<date>;\n" +
 				"BEGIN TRANSACTION;\n" +
 				"CREATE TEMP TABLE __bruin_tmp_.+ AS SELECT 1\n;\n" +
-				"SET distinct_keys_.+ = \\(SELECT array_agg\\(distinct somekey\\) FROM __bruin_tmp_.+\\);\n" +
-				"DELETE FROM my\\.asset WHERE somekey in unnest\\(distinct_keys.+\\);\n" +
+				"SET distinct_keys_.+ = \\(SELECT array_agg\\(distinct `somekey`\\) FROM __bruin_tmp_.+\\);\n" +
+				"DELETE FROM my\\.asset WHERE `somekey` in unnest\\(distinct_keys.+\\);\n" +
 				"INSERT INTO my\\.asset SELECT \\* FROM __bruin_tmp.+;\n" +
 				"COMMIT TRANSACTION;$",
 		},
@@ -188,7 +336,7 @@ func TestMaterializer_Render(t *testing.T) {
 			query: "SELECT 1\n -- This is a comment",
 			want: "^BEGIN TRANSACTION;\n" +
 				"CREATE TEMP TABLE __bruin_tmp_.+ AS SELECT 1\n -- This is a comment\n;\n" +
-				"DELETE FROM my\\.asset WHERE dt in \\(SELECT DISTINCT dt FROM __bruin_tmp_.+\\);\n" +
+				"DELETE FROM my\\.asset WHERE `dt` in \\(SELECT DISTINCT `dt` FROM __bruin_tmp_.+\\);\n" +
 				"INSERT INTO my\\.asset SELECT \\* FROM __bruin_tmp.+;\n" +
 				"COMMIT TRANSACTION;$",
 		},
@@ -240,9 +388,9 @@ func TestMaterializer_Render(t *testing.T) {
 			},
 			query: "SELECT 1",
 			want: "MERGE my\\.asset target\n" +
-				"USING \\(SELECT 1\\) source ON target\\.dt = source.dt AND target\\.event_type = source\\.event_type\n" +
+				"USING \\(SELECT 1\\) source ON target\\.`dt` = source.`dt` AND target\\.`event_type` = source\\.`event_type`\n" +
 				"\n" +
-				"WHEN NOT MATCHED THEN INSERT\\(dt, event_type, value, value2\\) VALUES\\(dt, event_type, value, value2\\);",
+				"WHEN NOT MATCHED THEN INSERT\\(`dt`, `event_type`, `value`, `value2`\\) VALUES\\(`dt`, `event_type`, `value`, `value2`\\);",
 		},
 		{
 			name: "merge with some columns to update",
@@ -261,9 +409,28 @@ func TestMaterializer_Render(t *testing.T) {
 			},
 			query: "SELECT 1;",
 			want: "MERGE my\\.asset target\n" +
-				"USING \\(SELECT 1\\) source ON target\\.dt = source\\.dt AND target\\.event_type = source\\.event_type\n" +
-				"WHEN MATCHED THEN UPDATE SET target\\.value = source\\.value\n" +
-				"WHEN NOT MATCHED THEN INSERT\\(dt, event_type, value, value2\\) VALUES\\(dt, event_type, value, value2\\);",
+				"USING \\(SELECT 1\\) source ON target\\.`dt` = source\\.`dt` AND target\\.`event_type` = source\\.`event_type`\n" +
+				"WHEN MATCHED THEN UPDATE SET target\\.`value` = source\\.`value`\n" +
+				"WHEN NOT MATCHED THEN INSERT\\(`dt`, `event_type`, `value`, `value2`\\) VALUES\\(`dt`, `event_type`, `value`, `value2`\\);",
+		},
+		{
+			name: "merge with reserved word columns",
+			task: &pipeline.Asset{
+				Name: "my.asset",
+				Columns: []pipeline.Column{
+					{Name: "order", PrimaryKey: true},
+					{Name: "select", UpdateOnMerge: true},
+				},
+				Materialization: pipeline.Materialization{
+					Type:     pipeline.MaterializationTypeTable,
+					Strategy: pipeline.MaterializationStrategyMerge,
+				},
+			},
+			query: "SELECT 1",
+			want: "MERGE my\\.asset target\n" +
+				"USING \\(SELECT 1\\) source ON target\\.`order` = source\\.`order`\n" +
+				"WHEN MATCHED THEN UPDATE SET target\\.`select` = source\\.`select`\n" +
+				"WHEN NOT MATCHED THEN INSERT\\(`order`, `select`\\) VALUES\\(`order`, `select`\\);",
 		},
 		{
 			name: "time_interval_no_incremental_key",
@@ -292,7 +459,7 @@ func TestMaterializer_Render(t *testing.T) {
 			},
 			query: "SELECT ts, event_name from source_table where ts between '{{start_timestamp}}' AND '{{end_timestamp}}'",
 			want: "^BEGIN TRANSACTION;\n" +
-				"DELETE FROM my\\.asset WHERE ts BETWEEN '{{start_timestamp}}' AND '{{end_timestamp}}';\n" +
+				"DELETE FROM my\\.asset WHERE `ts` BETWEEN '{{start_timestamp}}' AND '{{end_timestamp}}';\n" +
 				"INSERT INTO my\\.asset SELECT ts, event_name from source_table where ts between '{{start_timestamp}}' AND '{{end_timestamp}}';\n" +
 				"COMMIT TRANSACTION;$",
 		},
@@ -309,7 +476,7 @@ func TestMaterializer_Render(t *testing.T) {
 			},
 			query: "SELECT dt, event_name from source_table where dt between '{{start_date}}' and '{{end_date}}'",
 			want: "^BEGIN TRANSACTION;\n" +
-				"DELETE FROM my\\.asset WHERE dt BETWEEN '{{start_date}}' AND '{{end_date}}';\n" +
+				"DELETE FROM my\\.asset WHERE `dt` BETWEEN '{{start_date}}' AND '{{end_date}}';\n" +
 				"INSERT INTO my\\.asset SELECT dt, event_name from source_table where dt between '{{start_date}}' and '{{end_date}}';\n" +
 				"COMMIT TRANSACTION;$",
 		},
@@ -331,3 +498,23 @@ func TestMaterializer_Render(t *testing.T) {
 		})
 	}
 }
+
+func TestMaterializer_Render_MergeWithoutPrimaryKey(t *testing.T) {
+	t.Parallel()
+
+	asset := &pipeline.Asset{
+		Name:    "my.asset",
+		Columns: []pipeline.Column{{Name: "id"}, {Name: "value"}},
+		Materialization: pipeline.Materialization{
+			Type:     pipeline.MaterializationTypeTable,
+			Strategy: pipeline.MaterializationStrategyMerge,
+		},
+	}
+
+	_, err := NewMaterializer(false).Render(asset, "SELECT 1")
+
+	var validationErr *MaterializationValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, "my.asset", validationErr.Asset)
+	assert.EqualError(t, err, "invalid materialization for asset 'my.asset': materialization strategy merge requires the `primary_key` field to be set on at least one column")
+}
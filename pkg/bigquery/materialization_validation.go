@@ -0,0 +1,316 @@
+package bigquery
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/pipeline"
+)
+
+// MaxClusteringColumns is the maximum number of columns BigQuery allows a table to be clustered by.
+const MaxClusteringColumns = 4
+
+// MaxRefreshIntervalMinutes is the longest refresh interval BigQuery allows for materialized views, 7 days.
+const MaxRefreshIntervalMinutes = 7 * 24 * 60
+
+// partitionFunctionTypes lists the functions allowed in a partitioning expression together with the column types
+// they accept. The empty function name stands for partitioning by the column itself.
+var partitionFunctionTypes = map[string][]bigquery.FieldType{
+	"":                {bigquery.DateFieldType, bigquery.TimestampFieldType, bigquery.DateTimeFieldType},
+	"DATE":            {bigquery.TimestampFieldType, bigquery.DateTimeFieldType},
+	"DATE_TRUNC":      {bigquery.DateFieldType},
+	"DATETIME_TRUNC":  {bigquery.DateTimeFieldType},
+	"TIMESTAMP_TRUNC": {bigquery.TimestampFieldType},
+	"RANGE_BUCKET":    {bigquery.IntegerFieldType},
+}
+
+// clusteringFieldTypes are the column types BigQuery allows a table to be clustered by.
+var clusteringFieldTypes = map[bigquery.FieldType]bool{
+	bigquery.StringFieldType:     true,
+	bigquery.BytesFieldType:      true,
+	bigquery.IntegerFieldType:    true,
+	bigquery.NumericFieldType:    true,
+	bigquery.BigNumericFieldType: true,
+	bigquery.BooleanFieldType:    true,
+	bigquery.DateFieldType:       true,
+	bigquery.DateTimeFieldType:   true,
+	bigquery.TimestampFieldType:  true,
+	bigquery.GeographyFieldType:  true,
+	bigquery.RangeFieldType:      true,
+}
+
+// partitionExpressionRegex matches partitioning expressions such as `created_at`, `DATE(created_at)` or
+// `RANGE_BUCKET(id, GENERATE_ARRAY(0, 100, 10))`, capturing the function name and the partitioning column.
+var partitionExpressionRegex = regexp.MustCompile(`^(?:(\w+)\s*\(\s*)?` + "`?" + `(\w+)` + "`?" + `\s*(?:[,)].*)?$`)
+
+var columnNameRegex = regexp.MustCompile("^`?\\w+`?$")
+
+// MaterializationValidationError lists all the problems found in the materialization config of an asset.
+type MaterializationValidationError struct {
+	Asset    string
+	Problems []string
+}
+
+func (e *MaterializationValidationError) Error() string {
+	return fmt.Sprintf("invalid materialization for asset '%s': %s", e.Asset, strings.Join(e.Problems, "; "))
+}
+
+// ValidateMaterialization checks the partitioning and clustering config of the asset against its declared columns,
+// without calling BigQuery. All the problems are reported at once through a MaterializationValidationError.
+// Assets often declare only some of the columns of their query, so only the types of the declared columns are
+// checked, a partitioning or clustering column that is not declared is left to BigQuery.
+func ValidateMaterialization(asset *pipeline.Asset) error {
+	return validateMaterialization(asset, false)
+}
+
+// validateMaterialization is ValidateMaterialization, with completeColumns set for the assets whose declared
+// columns are all the columns of the table, e.g. the ones a table is created from, so that the partitioning and
+// clustering columns must be among them.
+func validateMaterialization(asset *pipeline.Asset, completeColumns bool) error {
+	mat := asset.Materialization
+	if mat.PartitionBy == "" && len(mat.ClusterBy) == 0 && mat.PartitionRange == nil &&
+		mat.PartitionType == pipeline.PartitionTypeNone && mat.PartitionGranularity == pipeline.PartitionGranularityNone &&
+		mat.PartitionExpirationDays == 0 && !mat.RequirePartitionFilter &&
+		mat.Type != pipeline.MaterializationTypeMaterializedView && mat.EnableRefresh == nil && mat.RefreshIntervalMinutes == 0 {
+		return nil
+	}
+
+	problems := make([]string, 0)
+	isPartitionedOrClustered := mat.PartitionBy != "" || len(mat.ClusterBy) > 0 || mat.PartitionRange != nil ||
+		mat.PartitionType != pipeline.PartitionTypeNone || mat.PartitionGranularity != pipeline.PartitionGranularityNone ||
+		mat.PartitionExpirationDays != 0 || mat.RequirePartitionFilter
+	if mat.Type == pipeline.MaterializationTypeView && isPartitionedOrClustered {
+		problems = append(problems, "views cannot be partitioned or clustered")
+	}
+	problems = append(problems, validateRefreshOptions(mat)...)
+
+	columns := make(map[string]*pipeline.Column, len(asset.Columns))
+	for i := range asset.Columns {
+		// column names are case-insensitive in BigQuery
+		columns[strings.ToLower(asset.Columns[i].Name)] = &asset.Columns[i]
+	}
+
+	if mat.PartitionRange != nil {
+		problems = append(problems, validatePartitionRange(mat.PartitionBy, mat.PartitionRange, columns, completeColumns)...)
+	} else if mat.PartitionBy != "" && mat.PartitionType != pipeline.PartitionTypeRange {
+		problems = append(problems, validatePartitionBy(mat.PartitionBy, columns, completeColumns)...)
+	}
+	problems = append(problems, validatePartitionGranularity(mat, columns)...)
+
+	if mat.PartitionExpirationDays < 0 {
+		problems = append(problems, fmt.Sprintf("partition expiration must not be negative, %d days given", mat.PartitionExpirationDays))
+	}
+	if mat.PartitionExpirationDays > 0 && (mat.PartitionBy == "" || mat.PartitionRange != nil) {
+		problems = append(problems, "partition expiration requires the table to be partitioned by time")
+	}
+	if mat.RequirePartitionFilter && mat.PartitionBy == "" {
+		problems = append(problems, "require_partition_filter requires partition_by to be set")
+	}
+
+	if len(mat.ClusterBy) > MaxClusteringColumns {
+		problems = append(problems, fmt.Sprintf("a table can be clustered by at most %d columns, %d given", MaxClusteringColumns, len(mat.ClusterBy)))
+	}
+
+	seen := make(map[string]bool, len(mat.ClusterBy))
+	for _, name := range mat.ClusterBy {
+		key := strings.ToLower(name)
+		if seen[key] {
+			problems = append(problems, fmt.Sprintf("clustering column '%s' is given more than once", name))
+			continue
+		}
+		seen[key] = true
+
+		if len(columns) == 0 {
+			continue
+		}
+
+		column, ok := columns[key]
+		if !ok {
+			if completeColumns {
+				problems = append(problems, fmt.Sprintf("clustering column '%s' is not one of the asset columns", name))
+			}
+			continue
+		}
+
+		field, err := parseFieldSchema(column.Name, column.Type)
+		if err != nil {
+			// invalid column types are reported by the schema validation
+			continue
+		}
+		if field.Repeated || !clusteringFieldTypes[field.Type] {
+			problems = append(problems, fmt.Sprintf("clustering column '%s' has type '%s', which cannot be clustered by", name, column.Type))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &MaterializationValidationError{Asset: asset.Name, Problems: problems}
+	}
+
+	return nil
+}
+
+// validateRefreshOptions checks the options that only apply to materialized views, which are refreshed by BigQuery
+// rather than by the materialization strategies, and the partition options they do not support.
+func validateRefreshOptions(mat pipeline.Materialization) []string {
+	if mat.Type != pipeline.MaterializationTypeMaterializedView {
+		if mat.EnableRefresh != nil || mat.RefreshIntervalMinutes != 0 {
+			return []string{"enable_refresh and refresh_interval_minutes are only supported for materialized views"}
+		}
+		return nil
+	}
+
+	problems := make([]string, 0)
+	if mat.Strategy != pipeline.MaterializationStrategyNone {
+		problems = append(problems, fmt.Sprintf("materialized views are refreshed by BigQuery and cannot use the materialization strategy '%s'", mat.Strategy))
+	}
+	if mat.IncrementalKey != "" {
+		problems = append(problems, "materialized views do not support incremental_key")
+	}
+	if mat.PartitionExpirationDays != 0 || mat.RequirePartitionFilter {
+		problems = append(problems, "materialized views inherit the partition expiration and the partition filter requirement of their base table")
+	}
+
+	if mat.RefreshIntervalMinutes < 0 || mat.RefreshIntervalMinutes > MaxRefreshIntervalMinutes {
+		problems = append(problems, fmt.Sprintf("refresh_interval_minutes must be between 1 and %d when set, %d given", MaxRefreshIntervalMinutes, mat.RefreshIntervalMinutes))
+	}
+	if mat.RefreshIntervalMinutes > 0 && mat.EnableRefresh != nil && !*mat.EnableRefresh {
+		problems = append(problems, "refresh_interval_minutes cannot be set when enable_refresh is false")
+	}
+
+	return problems
+}
+
+func validatePartitionBy(partitionBy string, columns map[string]*pipeline.Column, completeColumns bool) []string {
+	matches := partitionExpressionRegex.FindStringSubmatch(strings.TrimSpace(partitionBy))
+	if matches == nil {
+		return []string{fmt.Sprintf("partitioning expression '%s' is not supported", partitionBy)}
+	}
+
+	function := strings.ToUpper(matches[1])
+	name := matches[2]
+
+	allowedTypes, ok := partitionFunctionTypes[function]
+	if !ok {
+		return []string{fmt.Sprintf("function '%s' cannot be used in the partitioning expression '%s'", matches[1], partitionBy)}
+	}
+
+	// the ingestion-time pseudo columns are not part of the declared columns
+	if function == "" && strings.HasPrefix(strings.ToUpper(name), "_PARTITION") {
+		return nil
+	}
+
+	if len(columns) == 0 {
+		return nil
+	}
+
+	column, ok := columns[strings.ToLower(name)]
+	if !ok {
+		if !completeColumns {
+			return nil
+		}
+		return []string{fmt.Sprintf("partitioning column '%s' is not one of the asset columns", name)}
+	}
+
+	field, err := parseFieldSchema(column.Name, column.Type)
+	if err != nil {
+		// invalid column types are reported by the schema validation
+		return nil
+	}
+
+	for _, allowed := range allowedTypes {
+		if field.Type == allowed && !field.Repeated {
+			return nil
+		}
+	}
+
+	return []string{fmt.Sprintf("partitioning column '%s' has type '%s', which cannot be used in the partitioning expression '%s'", name, column.Type, partitionBy)}
+}
+
+// validatePartitionGranularity checks the partition type and granularity against the rest of the partitioning
+// config: the granularity only applies to time partitioning, and must agree with the truncating partitioning
+// expression if one is given instead of a plain column.
+func validatePartitionGranularity(mat pipeline.Materialization, columns map[string]*pipeline.Column) []string {
+	problems := make([]string, 0)
+	switch mat.PartitionType {
+	case pipeline.PartitionTypeNone, pipeline.PartitionTypeTime:
+		if mat.PartitionType == pipeline.PartitionTypeTime && mat.PartitionRange != nil {
+			problems = append(problems, "partition_type time cannot be used with a partition range")
+		}
+	case pipeline.PartitionTypeRange:
+		if mat.PartitionRange == nil {
+			problems = append(problems, "partition_type range requires partition_range to set the start, end and interval of the ranges")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("partition_type must be one of time or range, '%s' given", mat.PartitionType))
+	}
+	if mat.PartitionType != pipeline.PartitionTypeNone && mat.PartitionBy == "" {
+		problems = append(problems, "partition_type requires partition_by to be set")
+	}
+
+	switch mat.PartitionGranularity {
+	case pipeline.PartitionGranularityNone:
+		return problems
+	case pipeline.PartitionGranularityHour, pipeline.PartitionGranularityDay, pipeline.PartitionGranularityMonth, pipeline.PartitionGranularityYear:
+	default:
+		return append(problems, fmt.Sprintf("partition_granularity must be one of hour, day, month or year, '%s' given", mat.PartitionGranularity))
+	}
+
+	if mat.PartitionBy == "" || mat.PartitionRange != nil || mat.PartitionType == pipeline.PartitionTypeRange {
+		return append(problems, "partition_granularity requires the table to be partitioned by time")
+	}
+
+	granularity := strings.ToUpper(string(mat.PartitionGranularity))
+	if !columnNameRegex.MatchString(mat.PartitionBy) {
+		// expressions such as DATE(created_at) partition by day
+		inferred := string(bigquery.DayPartitioningType)
+		if matches := partitionGranularityRegex.FindStringSubmatch(mat.PartitionBy); matches != nil {
+			inferred = strings.ToUpper(matches[1])
+		}
+		if inferred != granularity {
+			problems = append(problems, fmt.Sprintf("partition_granularity '%s' conflicts with the partitioning expression '%s', which partitions by %s", mat.PartitionGranularity, mat.PartitionBy, strings.ToLower(inferred)))
+		}
+		return problems
+	}
+
+	// the truncating function depends on the type of the column, only daily partitions use the column as it is
+	column, ok := columns[strings.ToLower(partitionColumn(mat.PartitionBy))]
+	if !ok || strings.TrimSpace(column.Type) == "" {
+		if mat.PartitionGranularity != pipeline.PartitionGranularityDay {
+			problems = append(problems, fmt.Sprintf("partition_granularity '%s' requires the type of partitioning column '%s' to be declared", mat.PartitionGranularity, mat.PartitionBy))
+		}
+		return problems
+	}
+	if mat.PartitionGranularity != pipeline.PartitionGranularityHour {
+		return problems
+	}
+	field, err := parseFieldSchema(column.Name, column.Type)
+	if err == nil && field.Type == bigquery.DateFieldType {
+		problems = append(problems, fmt.Sprintf("partitioning column '%s' has type DATE, which cannot be partitioned by hour", column.Name))
+	}
+
+	return problems
+}
+
+func validatePartitionRange(partitionBy string, r *pipeline.PartitionRange, columns map[string]*pipeline.Column, completeColumns bool) []string {
+	problems := make([]string, 0)
+	if r.Interval <= 0 {
+		problems = append(problems, fmt.Sprintf("partition range interval must be positive, %d given", r.Interval))
+	}
+	if r.End <= r.Start {
+		problems = append(problems, fmt.Sprintf("partition range end (%d) must be greater than its start (%d)", r.End, r.Start))
+	}
+
+	if partitionBy == "" {
+		return append(problems, "partition range requires partition_by to name the column to partition by")
+	}
+	if !columnNameRegex.MatchString(partitionBy) {
+		return append(problems, fmt.Sprintf("partition_by must be a column name when a partition range is given, '%s' given", partitionBy))
+	}
+
+	// the range is applied to the column, the column name is wrapped in RANGE_BUCKET
+	problems = append(problems, validatePartitionBy(fmt.Sprintf("RANGE_BUCKET(%s)", partitionBy), columns, completeColumns)...)
+
+	return problems
+}
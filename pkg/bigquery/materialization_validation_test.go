@@ -0,0 +1,280 @@
+package bigquery
+
+import (
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMaterialization(t *testing.T) {
+	t.Parallel()
+
+	enableRefresh, disableRefresh := true, false
+
+	columns := []pipeline.Column{
+		{Name: "id", Type: "INT64"},
+		{Name: "name", Type: "STRING"},
+		{Name: "score", Type: "FLOAT64"},
+		{Name: "tags", Type: "ARRAY<STRING>"},
+		{Name: "created_at", Type: "TIMESTAMP"},
+		{Name: "updated_on", Type: "DATE"},
+	}
+
+	tests := []struct {
+		name         string
+		columns      []pipeline.Column
+		mat          pipeline.Materialization
+		wantProblems []string
+	}{
+		{
+			name:    "no partitioning or clustering",
+			columns: columns,
+			mat:     pipeline.Materialization{Type: pipeline.MaterializationTypeTable},
+		},
+		{
+			name:    "valid config",
+			columns: columns,
+			mat: pipeline.Materialization{
+				Type:        pipeline.MaterializationTypeTable,
+				PartitionBy: "DATE(created_at)",
+				ClusterBy:   []string{"name", "ID"},
+			},
+		},
+		{
+			name:    "supported partitioning expressions",
+			columns: columns,
+			mat:     pipeline.Materialization{PartitionBy: "RANGE_BUCKET(id, GENERATE_ARRAY(0, 100, 10))"},
+		},
+		{
+			name:    "ingestion time partitioning",
+			columns: columns,
+			mat:     pipeline.Materialization{PartitionBy: "_PARTITIONDATE"},
+		},
+		{
+			name: "columns are not checked when none are declared",
+			mat: pipeline.Materialization{
+				PartitionBy: "DATE_TRUNC(updated_on, MONTH)",
+				ClusterBy:   []string{"name"},
+			},
+		},
+		{
+			name:    "all problems are reported",
+			columns: columns,
+			mat: pipeline.Materialization{
+				Type:        pipeline.MaterializationTypeView,
+				PartitionBy: "missing",
+				ClusterBy:   []string{"name", "score", "tags", "unknown", "name"},
+			},
+			wantProblems: []string{
+				"views cannot be partitioned or clustered",
+				"a table can be clustered by at most 4 columns, 5 given",
+				"clustering column 'score' has type 'FLOAT64', which cannot be clustered by",
+				"clustering column 'tags' has type 'ARRAY<STRING>', which cannot be clustered by",
+				"clustering column 'name' is given more than once",
+			},
+		},
+		{
+			name:    "partitioning and clustering columns that are not declared",
+			columns: columns[:2],
+			mat: pipeline.Materialization{
+				Type:        pipeline.MaterializationTypeTable,
+				PartitionBy: "DATE(created_at)",
+				ClusterBy:   []string{"name", "country"},
+			},
+		},
+		{
+			name:    "partitioning column with the wrong type",
+			columns: columns,
+			mat:     pipeline.Materialization{PartitionBy: "TIMESTAMP_TRUNC(updated_on, DAY)"},
+			wantProblems: []string{
+				"partitioning column 'updated_on' has type 'DATE', which cannot be used in the partitioning expression 'TIMESTAMP_TRUNC(updated_on, DAY)'",
+			},
+		},
+		{
+			name:    "valid partition range",
+			columns: columns,
+			mat:     pipeline.Materialization{PartitionBy: "id", PartitionRange: &pipeline.PartitionRange{Start: 0, End: 100, Interval: 10}},
+		},
+		{
+			name:    "invalid partition range",
+			columns: columns,
+			mat:     pipeline.Materialization{PartitionBy: "name", PartitionRange: &pipeline.PartitionRange{Start: 100, End: 100}},
+			wantProblems: []string{
+				"partition range interval must be positive, 0 given",
+				"partition range end (100) must be greater than its start (100)",
+				"partitioning column 'name' has type 'STRING', which cannot be used in the partitioning expression 'RANGE_BUCKET(name)'",
+			},
+		},
+		{
+			name:         "partition range on an expression",
+			columns:      columns,
+			mat:          pipeline.Materialization{PartitionBy: "DATE(created_at)", PartitionRange: &pipeline.PartitionRange{Start: 0, End: 100, Interval: 10}},
+			wantProblems: []string{"partition_by must be a column name when a partition range is given, 'DATE(created_at)' given"},
+		},
+		{
+			name:    "partition granularity",
+			columns: columns,
+			mat:     pipeline.Materialization{PartitionBy: "created_at", PartitionType: pipeline.PartitionTypeTime, PartitionGranularity: pipeline.PartitionGranularityHour},
+		},
+		{
+			name:         "invalid partition type and granularity",
+			columns:      columns,
+			mat:          pipeline.Materialization{PartitionBy: "created_at", PartitionType: "list", PartitionGranularity: "week"},
+			wantProblems: []string{"partition_type must be one of time or range, 'list' given", "partition_granularity must be one of hour, day, month or year, 'week' given"},
+		},
+		{
+			name:    "range partition type without a range",
+			columns: columns,
+			mat:     pipeline.Materialization{PartitionBy: "id", PartitionType: pipeline.PartitionTypeRange, PartitionGranularity: pipeline.PartitionGranularityDay},
+			wantProblems: []string{
+				"partition_type range requires partition_range to set the start, end and interval of the ranges",
+				"partition_granularity requires the table to be partitioned by time",
+			},
+		},
+		{
+			name:         "partition granularity conflicting with the expression",
+			columns:      columns,
+			mat:          pipeline.Materialization{PartitionBy: "DATE(created_at)", PartitionGranularity: pipeline.PartitionGranularityMonth},
+			wantProblems: []string{"partition_granularity 'month' conflicts with the partitioning expression 'DATE(created_at)', which partitions by day"},
+		},
+		{
+			name:         "hourly partitions of a date column",
+			columns:      columns,
+			mat:          pipeline.Materialization{PartitionBy: "updated_on", PartitionGranularity: pipeline.PartitionGranularityHour},
+			wantProblems: []string{"partitioning column 'updated_on' has type DATE, which cannot be partitioned by hour"},
+		},
+		{
+			name:         "partition granularity of an undeclared column",
+			columns:      columns,
+			mat:          pipeline.Materialization{PartitionBy: "loaded_at", PartitionGranularity: pipeline.PartitionGranularityMonth},
+			wantProblems: []string{"partition_granularity 'month' requires the type of partitioning column 'loaded_at' to be declared"},
+		},
+		{
+			name:    "daily partitions of an undeclared column",
+			columns: columns,
+			mat:     pipeline.Materialization{PartitionBy: "loaded_at", PartitionGranularity: pipeline.PartitionGranularityDay},
+		},
+		{
+			name:    "partition options",
+			columns: columns,
+			mat:     pipeline.Materialization{PartitionBy: "created_at", PartitionExpirationDays: 30, RequirePartitionFilter: true},
+		},
+		{
+			name:    "partition options without time partitioning",
+			columns: columns,
+			mat: pipeline.Materialization{
+				PartitionBy:             "id",
+				PartitionRange:          &pipeline.PartitionRange{Start: 0, End: 100, Interval: 10},
+				PartitionExpirationDays: 30,
+			},
+			wantProblems: []string{"partition expiration requires the table to be partitioned by time"},
+		},
+		{
+			name:    "partition options on an unpartitioned table",
+			columns: columns,
+			mat:     pipeline.Materialization{PartitionExpirationDays: -1, RequirePartitionFilter: true},
+			wantProblems: []string{
+				"partition expiration must not be negative, -1 days given",
+				"require_partition_filter requires partition_by to be set",
+			},
+		},
+		{
+			name:    "materialized view with refresh options",
+			columns: columns,
+			mat: pipeline.Materialization{
+				Type:                   pipeline.MaterializationTypeMaterializedView,
+				PartitionBy:            "DATE(created_at)",
+				EnableRefresh:          &enableRefresh,
+				RefreshIntervalMinutes: 60,
+			},
+		},
+		{
+			name: "materialized view with unsupported options",
+			mat: pipeline.Materialization{
+				Type:                   pipeline.MaterializationTypeMaterializedView,
+				Strategy:               pipeline.MaterializationStrategyDeleteInsert,
+				IncrementalKey:         "id",
+				PartitionBy:            "DATE(created_at)",
+				RequirePartitionFilter: true,
+				EnableRefresh:          &disableRefresh,
+				RefreshIntervalMinutes: MaxRefreshIntervalMinutes + 1,
+			},
+			wantProblems: []string{
+				"materialized views are refreshed by BigQuery and cannot use the materialization strategy 'delete+insert'",
+				"materialized views do not support incremental_key",
+				"materialized views inherit the partition expiration and the partition filter requirement of their base table",
+				"refresh_interval_minutes must be between 1 and 10080 when set, 10081 given",
+				"refresh_interval_minutes cannot be set when enable_refresh is false",
+			},
+		},
+		{
+			name:         "refresh options on a table",
+			mat:          pipeline.Materialization{Type: pipeline.MaterializationTypeTable, RefreshIntervalMinutes: 30},
+			wantProblems: []string{"enable_refresh and refresh_interval_minutes are only supported for materialized views"},
+		},
+		{
+			name:         "unsupported partitioning function",
+			columns:      columns,
+			mat:          pipeline.Materialization{PartitionBy: "EXTRACT(created_at)"},
+			wantProblems: []string{"function 'EXTRACT' cannot be used in the partitioning expression 'EXTRACT(created_at)'"},
+		},
+		{
+			name:         "unsupported partitioning expression",
+			columns:      columns,
+			mat:          pipeline.Materialization{PartitionBy: "id + 1"},
+			wantProblems: []string{"partitioning expression 'id + 1' is not supported"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			asset := &pipeline.Asset{Name: "dataset.table", Columns: tt.columns, Materialization: tt.mat}
+
+			err := ValidateMaterialization(asset)
+			if tt.wantProblems == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var validationErr *MaterializationValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, "dataset.table", validationErr.Asset)
+			assert.Equal(t, tt.wantProblems, validationErr.Problems)
+		})
+	}
+}
+
+func TestBuildCreateTableQuery_InvalidMaterialization(t *testing.T) {
+	t.Parallel()
+
+	asset := &pipeline.Asset{
+		Name:    "dataset.table",
+		Columns: []pipeline.Column{{Name: "id", Type: "INT64"}},
+		Materialization: pipeline.Materialization{
+			Type:        pipeline.MaterializationTypeTable,
+			PartitionBy: "created_at",
+			ClusterBy:   []string{"name"},
+		},
+	}
+
+	_, err := BuildCreateTableQuery(asset, "")
+	require.EqualError(t, err, "invalid materialization for asset 'dataset.table': partitioning column 'created_at' is not one of the asset columns; clustering column 'name' is not one of the asset columns")
+}
+
+func TestBuildCreateTableQuery_ColumnTypes(t *testing.T) {
+	t.Parallel()
+
+	// the declared types are passed on as they are, BigQuery reports the ones it does not support
+	asset := &pipeline.Asset{
+		Name:    "dataset.table",
+		Columns: []pipeline.Column{{Name: "id", Type: "INT64"}, {Name: "email", Type: "VARCHAR"}, {Name: "note"}},
+	}
+
+	got, err := BuildCreateTableQuery(asset, "")
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS dataset.table (\n  `id` INT64,\n  `email` VARCHAR,\n  `note` \n)", got)
+}
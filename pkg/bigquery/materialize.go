@@ -0,0 +1,285 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+// MaterializationStep is the step of MaterializeWithMetadata that failed.
+type MaterializationStep string
+
+const (
+	MaterializationStepBackup   MaterializationStep = "backup"
+	MaterializationStepData     MaterializationStep = "data"
+	MaterializationStepMetadata MaterializationStep = "metadata"
+)
+
+// backupTableSuffix is appended to the name of the table of an asset to name the clone MaterializeWithMetadata
+// keeps while the asset is updated.
+const backupTableSuffix = "__bruin_backup"
+
+// MaterializationStepError is returned by MaterializeWithMetadata, reporting which step failed and whether the
+// table of the asset was rolled back to its state before the update. If the rollback failed too, RollbackErr holds
+// the reason and the previous state of the table is kept in Backup.
+type MaterializationStepError struct {
+	Asset       string
+	Step        MaterializationStep
+	Err         error
+	RolledBack  bool
+	RollbackErr error
+	Backup      string
+}
+
+func (e *MaterializationStepError) Error() string {
+	msg := fmt.Sprintf("failed to update asset '%s' at the %s step: %s", e.Asset, e.Step, e.Err)
+	switch {
+	case e.RolledBack:
+		return msg + "; the table was rolled back to its previous state"
+	case e.RollbackErr != nil:
+		return fmt.Sprintf("%s; rolling back the table failed as well: %s; its previous state is kept in '%s'", msg, e.RollbackErr, e.Backup)
+	default:
+		return msg
+	}
+}
+
+func (e *MaterializationStepError) Unwrap() error {
+	return e.Err
+}
+
+// Materialize writes the rows of the temporary table, typically holding the results of the query of the asset, into
+// the table of the asset following its materialization strategy:
+//   - create+replace, the default, replaces the table with the rows
+//   - append inserts the rows
+//   - merge updates the rows matching on the primary key and inserts the others
+//   - delete+insert replaces the rows that share an incremental_key value with the new ones
+//   - time_interval replaces the rows whose incremental_key is within the range covered by the new ones
+//
+// The prerequisites of the strategy are validated before anything runs, reporting the missing ones through a
+// MaterializationValidationError. New columns are added to the table first if Config.AllowSchemaEvolution is set, see
+// EvolveSchema. The replaced table is encrypted with the KMS key of the asset, see EncryptionKey, while the other
+// strategies fail with an EncryptionKeyMismatchError if the existing table is encrypted with another one. With
+// DeduplicateOnIngest set, the rows are deduplicated before they are written, see DeduplicateQuery.
+//
+// The table is returned fully qualified, with the location of its dataset, so that the following steps can reference
+// it exactly. The number of affected rows is returned for the strategies that run a single DML statement, i.e. append
+// and merge, and is -1 for the others.
+func (d *Client) Materialize(ctx context.Context, asset *pipeline.Asset, tempTable string) (*ResolvedTable, int64, error) {
+	if asset.Materialization.Type != pipeline.MaterializationTypeTable {
+		return nil, -1, errors.Errorf("cannot materialize asset '%s' from a temporary table, only table materializations are supported", asset.Name)
+	}
+	if err := validateStrategyPrerequisites(asset); err != nil {
+		return nil, -1, err
+	}
+
+	source, err := d.ResolveTable(tempTable)
+	if err != nil {
+		return nil, -1, errors.Wrap(err, "invalid temporary table")
+	}
+	sourceQuery := "SELECT * FROM " + QuoteIdentifier(source.String())
+	if asset.Materialization.DeduplicateOnIngest {
+		// the strategies are built directly rather than through matMap, the deduplication is applied here instead
+		sourceQuery, err = DeduplicateQuery(asset, sourceQuery)
+		if err != nil {
+			return nil, -1, err
+		}
+	}
+	reference, err := d.TableReference(asset.Name)
+	if err != nil {
+		return nil, -1, err
+	}
+	target := withTableName(asset, reference)
+
+	var statement string
+	mat := asset.Materialization
+	switch mat.Strategy {
+	case pipeline.MaterializationStrategyNone, pipeline.MaterializationStrategyCreateReplace:
+		statement, err = buildCreateReplaceQuery(withEncryptionKey(target, d.EncryptionKey(asset)), sourceQuery)
+	case pipeline.MaterializationStrategyAppend:
+		statement, err = buildAppendQuery(target, sourceQuery)
+	case pipeline.MaterializationStrategyMerge:
+		statement, err = mergeMaterializer(target, sourceQuery)
+	case pipeline.MaterializationStrategyDeleteInsert:
+		statement, err = buildIncrementalQuery(target, sourceQuery)
+	case pipeline.MaterializationStrategyTimeInterval:
+		statement = buildTimeIntervalQueryFromTable(target, source, sourceQuery)
+	default:
+		return nil, -1, errors.Errorf("materialization strategy %s is not supported for asset '%s'", mat.Strategy, asset.Name)
+	}
+	if err != nil {
+		return nil, -1, err
+	}
+
+	// the dataset exists before the table is written, looking it up first leaves nothing half done on failures
+	table, err := d.resolveTableLocation(ctx, asset.Name)
+	if err != nil {
+		return nil, -1, err
+	}
+
+	if mat.Strategy != pipeline.MaterializationStrategyNone && mat.Strategy != pipeline.MaterializationStrategyCreateReplace {
+		if err := d.CheckEncryptionKey(ctx, asset); err != nil {
+			return nil, -1, err
+		}
+	}
+	if _, err := d.EvolveSchema(ctx, asset); err != nil {
+		return nil, -1, err
+	}
+
+	info, err := d.RunQueryWithJobInfo(ctx, &query.Query{Query: statement})
+	if err != nil {
+		return nil, -1, errors.Wrapf(err, "failed to materialize asset '%s' with strategy %s", asset.Name, strategyName(mat.Strategy))
+	}
+	if err := d.ClearTableExpiration(ctx, asset); err != nil {
+		return nil, -1, err
+	}
+
+	if mat.Strategy == pipeline.MaterializationStrategyAppend || mat.Strategy == pipeline.MaterializationStrategyMerge {
+		return table, info.RowsAffected, nil
+	}
+
+	return table, -1, nil
+}
+
+// MaterializeWithMetadata materializes the asset from the temporary table like Materialize and then updates the
+// metadata of its table like UpdateTableMetadataIfNotExist, as a single unit: either both steps succeed, or the
+// table is rolled back to its state before the update.
+//
+// Before anything changes, the existing table is cloned into a backup table next to it, which is cheap since
+// clones share the storage of their source. If either step fails, the table is replaced with a clone of the backup,
+// restoring both its rows and its metadata, or dropped if it did not exist before. The backup is dropped once the
+// update is done. Failures are reported through a MaterializationStepError naming the step that failed.
+//
+// The guarantees are limited to what BigQuery allows: the rollback itself is a separate statement, therefore other
+// writers see the intermediate state until it completes, and writes made by them in the meantime are lost with it.
+// If the rollback fails, the backup is kept so that the table can be restored manually. The table is returned like
+// Materialize does.
+func (d *Client) MaterializeWithMetadata(ctx context.Context, asset *pipeline.Asset, tempTable string) (*ResolvedTable, error) {
+	target, err := d.ResolveTable(asset.Name)
+	if err != nil {
+		return nil, err
+	}
+	backup := &ResolvedTable{ProjectID: target.ProjectID, DatasetID: target.DatasetID, TableID: target.TableID + backupTableSuffix}
+
+	existed := true
+	if _, err := d.client.DatasetInProject(target.ProjectID, target.DatasetID).Table(target.TableID).Metadata(ctx); err != nil {
+		var apiErr *googleapi.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+			return nil, &MaterializationStepError{Asset: asset.Name, Step: MaterializationStepBackup, Err: formatError(err)}
+		}
+		existed = false
+	}
+
+	if existed {
+		statement := fmt.Sprintf("CREATE OR REPLACE TABLE %s CLONE %s", QuoteIdentifier(backup.String()), QuoteIdentifier(target.String()))
+		if err := d.RunQueryWithoutResult(ctx, &query.Query{Query: statement}); err != nil {
+			return nil, &MaterializationStepError{Asset: asset.Name, Step: MaterializationStepBackup, Err: err}
+		}
+	}
+
+	step := MaterializationStepData
+	table, _, err := d.Materialize(ctx, asset, tempTable)
+	if err == nil {
+		step = MaterializationStepMetadata
+		var noMetadata NoMetadataUpdatedError
+		if err = d.UpdateTableMetadataIfNotExist(ctx, asset); errors.As(err, &noMetadata) {
+			err = nil
+		}
+	}
+
+	if err != nil {
+		stepErr := &MaterializationStepError{Asset: asset.Name, Step: step, Err: err, Backup: backup.String()}
+		if stepErr.RollbackErr = d.rollbackMaterialization(ctx, target, backup, existed); stepErr.RollbackErr == nil {
+			stepErr.RolledBack = true
+		}
+		return nil, stepErr
+	}
+
+	if existed {
+		if err := d.client.DatasetInProject(backup.ProjectID, backup.DatasetID).Table(backup.TableID).Delete(ctx); err != nil {
+			contextLogger(ctx).Warnf("failed to drop the backup table '%s' of asset '%s': %s", backup, asset.Name, err)
+		}
+	}
+
+	return table, nil
+}
+
+// rollbackMaterialization restores the table from its backup and drops the backup, or drops the table if it did
+// not exist before the update.
+func (d *Client) rollbackMaterialization(ctx context.Context, target, backup *ResolvedTable, existed bool) error {
+	if !existed {
+		err := d.client.DatasetInProject(target.ProjectID, target.DatasetID).Table(target.TableID).Delete(ctx)
+		var apiErr *googleapi.Error
+		if err != nil && (!errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound) {
+			return errors.Wrapf(formatError(err), "failed to drop table '%s'", target)
+		}
+		return nil
+	}
+
+	statement := fmt.Sprintf("CREATE OR REPLACE TABLE %s CLONE %s", QuoteIdentifier(target.String()), QuoteIdentifier(backup.String()))
+	if err := d.RunQueryWithoutResult(ctx, &query.Query{Query: statement}); err != nil {
+		return errors.Wrapf(err, "failed to restore table '%s' from '%s'", target, backup)
+	}
+
+	if err := d.client.DatasetInProject(backup.ProjectID, backup.DatasetID).Table(backup.TableID).Delete(ctx); err != nil {
+		contextLogger(ctx).Warnf("failed to drop the backup table '%s': %s", backup, err)
+	}
+
+	return nil
+}
+
+// validateStrategyPrerequisites checks that the asset declares what its materialization strategy relies on.
+func validateStrategyPrerequisites(asset *pipeline.Asset) error {
+	mat := asset.Materialization
+	problems := make([]string, 0)
+
+	switch mat.Strategy { //nolint:exhaustive
+	case pipeline.MaterializationStrategyMerge:
+		if len(asset.ColumnNamesWithPrimaryKey()) == 0 {
+			problems = append(problems, fmt.Sprintf("materialization strategy %s requires the `primary_key` field to be set on at least one column", mat.Strategy))
+		}
+	case pipeline.MaterializationStrategyDeleteInsert, pipeline.MaterializationStrategyTimeInterval:
+		if mat.IncrementalKey == "" {
+			problems = append(problems, fmt.Sprintf("materialization strategy %s requires the `incremental_key` field to be set", mat.Strategy))
+		} else if len(asset.Columns) > 0 && asset.GetColumnWithName(mat.IncrementalKey) == nil {
+			problems = append(problems, fmt.Sprintf("incremental_key '%s' is not one of the columns of the asset", mat.IncrementalKey))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &MaterializationValidationError{Asset: asset.Name, Problems: problems}
+	}
+
+	return ValidateMaterialization(asset)
+}
+
+// buildTimeIntervalQueryFromTable is the time_interval strategy for rows that are already in a table: without the
+// interval of the run at hand, the replaced interval is the one between the lowest and the highest incremental_key
+// of the new rows. The rows are inserted through the source query, which deduplicates them for DeduplicateOnIngest.
+func buildTimeIntervalQueryFromTable(asset *pipeline.Asset, source *ResolvedTable, sourceQuery string) string {
+	key := QuoteColumn(asset.Materialization.IncrementalKey)
+	sourceName := QuoteIdentifier(source.String())
+
+	queries := []string{
+		"BEGIN TRANSACTION",
+		fmt.Sprintf("DELETE FROM %s WHERE %s BETWEEN (SELECT MIN(%s) FROM %s) AND (SELECT MAX(%s) FROM %s)",
+			asset.Name, key, key, sourceName, key, sourceName),
+		fmt.Sprintf("INSERT INTO %s %s", asset.Name, sourceQuery),
+		"COMMIT TRANSACTION",
+	}
+
+	return strings.Join(queries, ";\n") + ";"
+}
+
+func strategyName(strategy pipeline.MaterializationStrategy) string {
+	if strategy == pipeline.MaterializationStrategyNone {
+		return string(pipeline.MaterializationStrategyCreateReplace)
+	}
+
+	return string(strategy)
+}
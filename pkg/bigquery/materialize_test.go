@@ -0,0 +1,496 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_Materialize(t *testing.T) {
+	t.Parallel()
+
+	columns := []pipeline.Column{
+		{Name: "id", Type: "INT64", PrimaryKey: true},
+		{Name: "name", Type: "STRING", UpdateOnMerge: true},
+		{Name: "dt", Type: "DATE"},
+	}
+
+	tests := []struct {
+		name         string
+		asset        *pipeline.Asset
+		dataProject  string
+		wantQuery    []string
+		wantRows     int64
+		wantErr      string
+		wantValidErr bool
+	}{
+		{
+			name: "create+replace is the default",
+			asset: &pipeline.Asset{
+				Name:            "mart.users",
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable},
+			},
+			wantQuery: []string{"CREATE OR REPLACE TABLE mart.users", "SELECT * FROM `test-project.tmp.users_123`"},
+			wantRows:  -1,
+		},
+		{
+			name: "append reports the inserted rows",
+			asset: &pipeline.Asset{
+				Name:            "mart.users",
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, Strategy: pipeline.MaterializationStrategyAppend},
+			},
+			wantQuery: []string{"INSERT INTO mart.users SELECT * FROM `test-project.tmp.users_123`"},
+			wantRows:  42,
+		},
+		{
+			name: "merge reports the affected rows",
+			asset: &pipeline.Asset{
+				Name:            "mart.users",
+				Columns:         columns,
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, Strategy: pipeline.MaterializationStrategyMerge},
+			},
+			wantQuery: []string{"MERGE mart.users target", "USING (SELECT * FROM `test-project.tmp.users_123`) source ON target.`id` = source.`id`"},
+			wantRows:  42,
+		},
+		{
+			name: "delete+insert",
+			asset: &pipeline.Asset{
+				Name:            "mart.users",
+				Columns:         columns,
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, Strategy: pipeline.MaterializationStrategyDeleteInsert, IncrementalKey: "dt"},
+			},
+			wantQuery: []string{"DELETE FROM mart.users WHERE `dt` in unnest(", "AS SELECT * FROM `test-project.tmp.users_123`"},
+			wantRows:  -1,
+		},
+		{
+			name: "time_interval replaces the range of the new rows",
+			asset: &pipeline.Asset{
+				Name:            "mart.users",
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, Strategy: pipeline.MaterializationStrategyTimeInterval, IncrementalKey: "dt"},
+			},
+			wantQuery: []string{
+				"DELETE FROM mart.users WHERE `dt` BETWEEN (SELECT MIN(`dt`) FROM `test-project.tmp.users_123`) AND (SELECT MAX(`dt`) FROM `test-project.tmp.users_123`)",
+				"INSERT INTO mart.users SELECT * FROM `test-project.tmp.users_123`",
+			},
+			wantRows: -1,
+		},
+		{
+			name: "rows are deduplicated on ingest",
+			asset: &pipeline.Asset{
+				Name:    "mart.users",
+				Columns: columns,
+				Materialization: pipeline.Materialization{
+					Type:                pipeline.MaterializationTypeTable,
+					Strategy:            pipeline.MaterializationStrategyMerge,
+					DeduplicateOnIngest: true,
+					DeduplicateOrderBy:  "dt",
+				},
+			},
+			wantQuery: []string{
+				"USING (WITH __bruin_deduplication_source AS (\nSELECT * FROM `test-project.tmp.users_123`\n)",
+				"QUALIFY ROW_NUMBER() OVER (PARTITION BY `id` ORDER BY `dt` DESC) = 1) source",
+			},
+			wantRows: 42,
+		},
+		{
+			name: "time_interval inserts the deduplicated rows",
+			asset: &pipeline.Asset{
+				Name:    "mart.users",
+				Columns: columns,
+				Materialization: pipeline.Materialization{
+					Type:                pipeline.MaterializationTypeTable,
+					Strategy:            pipeline.MaterializationStrategyTimeInterval,
+					IncrementalKey:      "dt",
+					DeduplicateOnIngest: true,
+					DeduplicateOrderBy:  "dt",
+				},
+			},
+			wantQuery: []string{
+				"INSERT INTO mart.users WITH __bruin_deduplication_source AS (\nSELECT * FROM `test-project.tmp.users_123`\n)",
+				"QUALIFY ROW_NUMBER() OVER (PARTITION BY `id` ORDER BY `dt` DESC) = 1",
+			},
+			wantRows: -1,
+		},
+		{
+			name: "deduplication requires an ordering column",
+			asset: &pipeline.Asset{
+				Name:            "mart.users",
+				Columns:         columns,
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, DeduplicateOnIngest: true},
+			},
+			wantErr:      "invalid materialization for asset 'mart.users': deduplicate_on_ingest requires deduplicate_order_by to name the column whose highest value decides which duplicate is kept, e.g. an updated_at column, otherwise the row kept is arbitrary",
+			wantValidErr: true,
+		},
+		{
+			name: "tables of a separate data project are qualified",
+			asset: &pipeline.Asset{
+				Name:            "mart.users",
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, Strategy: pipeline.MaterializationStrategyAppend},
+			},
+			dataProject: "data-project",
+			wantQuery:   []string{"INSERT INTO `data-project.mart.users` SELECT * FROM `data-project.tmp.users_123`"},
+			wantRows:    42,
+		},
+		{
+			name: "merge requires a primary key",
+			asset: &pipeline.Asset{
+				Name:            "mart.users",
+				Columns:         []pipeline.Column{{Name: "id"}},
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, Strategy: pipeline.MaterializationStrategyMerge},
+			},
+			wantErr:      "invalid materialization for asset 'mart.users': materialization strategy merge requires the `primary_key` field to be set on at least one column",
+			wantValidErr: true,
+		},
+		{
+			name: "delete+insert requires a known incremental key",
+			asset: &pipeline.Asset{
+				Name:            "mart.users",
+				Columns:         columns,
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, Strategy: pipeline.MaterializationStrategyDeleteInsert, IncrementalKey: "updated_at"},
+			},
+			wantErr:      "invalid materialization for asset 'mart.users': incremental_key 'updated_at' is not one of the columns of the asset",
+			wantValidErr: true,
+		},
+		{
+			name: "time_interval requires an incremental key",
+			asset: &pipeline.Asset{
+				Name:            "mart.users",
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, Strategy: pipeline.MaterializationStrategyTimeInterval},
+			},
+			wantErr:      "invalid materialization for asset 'mart.users': materialization strategy time_interval requires the `incremental_key` field to be set",
+			wantValidErr: true,
+		},
+		{
+			name: "views are not materialized from tables",
+			asset: &pipeline.Asset{
+				Name:            "mart.users",
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeView},
+			},
+			wantErr: "cannot materialize asset 'mart.users' from a temporary table, only table materializations are supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var submitted []string
+			ref := &bigquery2.JobReference{ProjectId: testProjectID, JobId: "job-1", Location: "US"}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/jobs", testProjectID):
+					var job bigquery2.Job
+					_ = json.NewDecoder(r.Body).Decode(&job)
+					mu.Lock()
+					submitted = append(submitted, job.Configuration.Query.Query)
+					mu.Unlock()
+					_ = json.NewEncoder(w).Encode(&bigquery2.Job{
+						JobReference:  ref,
+						Configuration: job.Configuration,
+						Status:        &bigquery2.JobStatus{State: "RUNNING"},
+					})
+				case strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/queries/job-1", testProjectID)):
+					_ = json.NewEncoder(w).Encode(&bigquery2.GetQueryResultsResponse{JobComplete: true, JobReference: ref})
+				case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/datasets/mart"):
+					_ = json.NewEncoder(w).Encode(&bigquery2.Dataset{Location: "EU"})
+				case strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/jobs/job-1", testProjectID)):
+					_ = json.NewEncoder(w).Encode(&bigquery2.Job{
+						JobReference:  ref,
+						Configuration: &bigquery2.JobConfiguration{Query: &bigquery2.JobConfigurationQuery{Query: "SELECT 1"}},
+						Status:        &bigquery2.JobStatus{State: "DONE"},
+						Statistics: &bigquery2.JobStatistics{
+							Query: &bigquery2.JobStatistics2{NumDmlAffectedRows: 42},
+						},
+					})
+				default:
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.DatasetCacheTTL = -1
+			if tt.dataProject != "" {
+				d.config.DataProjectID = tt.dataProject
+				d.config.ProjectResolution = ProjectResolutionData
+			}
+
+			table, rows, err := d.Materialize(context.Background(), tt.asset, "tmp.users_123")
+
+			mu.Lock()
+			defer mu.Unlock()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				if tt.wantValidErr {
+					var validationErr *MaterializationValidationError
+					require.ErrorAs(t, err, &validationErr)
+				}
+				assert.Empty(t, submitted)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRows, rows)
+			wantProject := testProjectID
+			if tt.dataProject != "" {
+				wantProject = tt.dataProject
+			}
+			assert.Equal(t, &ResolvedTable{ProjectID: wantProject, DatasetID: "mart", TableID: "users", Location: "EU"}, table)
+			require.Len(t, submitted, 1)
+			for _, want := range tt.wantQuery {
+				assert.Contains(t, submitted[0], want)
+			}
+		})
+	}
+}
+
+func TestClient_Materialize_NeverExpire(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var requests []string
+	var patched map[string]json.RawMessage
+	ref := &bigquery2.JobReference{ProjectId: testProjectID, JobId: "job-1", Location: "EU"}
+	tablePath := fmt.Sprintf("/projects/%s/datasets/mart/tables/users", testProjectID)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/jobs", testProjectID):
+			requests = append(requests, "create")
+			var job bigquery2.Job
+			_ = json.NewDecoder(r.Body).Decode(&job)
+			_ = json.NewEncoder(w).Encode(&bigquery2.Job{
+				JobReference:  ref,
+				Configuration: job.Configuration,
+				Status:        &bigquery2.JobStatus{State: "RUNNING"},
+			})
+		case strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/queries/job-1", testProjectID)):
+			_ = json.NewEncoder(w).Encode(&bigquery2.GetQueryResultsResponse{JobComplete: true, JobReference: ref})
+		case strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/jobs/job-1", testProjectID)):
+			_ = json.NewEncoder(w).Encode(&bigquery2.Job{
+				JobReference:  ref,
+				Configuration: &bigquery2.JobConfiguration{Query: &bigquery2.JobConfigurationQuery{Query: "SELECT 1"}},
+				Status:        &bigquery2.JobStatus{State: "DONE"},
+			})
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/datasets/mart"):
+			// the dataset expires its tables after a week by default
+			_ = json.NewEncoder(w).Encode(&bigquery2.Dataset{Location: "EU", DefaultTableExpirationMs: 7 * 24 * 3600 * 1000})
+		case r.Method == http.MethodGet && r.URL.Path == tablePath:
+			requests = append(requests, "metadata")
+			// the table created by the statement inherited the default expiration of the dataset
+			_ = json.NewEncoder(w).Encode(&bigquery2.Table{Type: "TABLE", Etag: "etag-1", ExpirationTime: 1717200000000})
+		case r.Method == http.MethodPatch && r.URL.Path == tablePath:
+			requests = append(requests, "update")
+			_ = json.NewDecoder(r.Body).Decode(&patched)
+			_ = json.NewEncoder(w).Encode(&bigquery2.Table{})
+		default:
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	d.config.DatasetCacheTTL = -1
+
+	_, _, err := d.Materialize(context.Background(), &pipeline.Asset{
+		Name:            "mart.users",
+		Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, NeverExpire: true},
+	}, "tmp.users_123")
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"create", "metadata", "update"}, requests)
+	require.Contains(t, patched, "expirationTime")
+	assert.Equal(t, "null", string(patched["expirationTime"]))
+}
+
+func TestClient_MaterializeWithMetadata(t *testing.T) {
+	t.Parallel()
+
+	backupQuery := "QUERY CREATE OR REPLACE TABLE `test-project.mart.users__bruin_backup` CLONE `test-project.mart.users`"
+	dataJob := "JOB CREATE OR REPLACE TABLE mart.users AS"
+	restoreQuery := "QUERY CREATE OR REPLACE TABLE `test-project.mart.users` CLONE `test-project.mart.users__bruin_backup`"
+
+	tests := []struct {
+		name           string
+		tableMissing   bool
+		failData       bool
+		failMetadata   bool
+		failRestore    bool
+		wantOps        []string
+		wantStep       MaterializationStep
+		wantRolledBack bool
+		wantErr        string
+	}{
+		{
+			name:    "data and metadata are updated and the backup is dropped",
+			wantOps: []string{backupQuery, dataJob, "PATCH users", "DELETE users__bruin_backup"},
+		},
+		{
+			name:         "new tables are not backed up",
+			tableMissing: true,
+			wantOps:      []string{dataJob, "PATCH users"},
+		},
+		{
+			name:           "a failed data step restores the table",
+			failData:       true,
+			wantOps:        []string{backupQuery, dataJob, restoreQuery, "DELETE users__bruin_backup"},
+			wantStep:       MaterializationStepData,
+			wantRolledBack: true,
+			wantErr:        "failed to update asset 'mart.users' at the data step",
+		},
+		{
+			name:           "a failed metadata step restores the table",
+			failMetadata:   true,
+			wantOps:        []string{backupQuery, dataJob, "PATCH users", restoreQuery, "DELETE users__bruin_backup"},
+			wantStep:       MaterializationStepMetadata,
+			wantRolledBack: true,
+			wantErr:        "the table was rolled back to its previous state",
+		},
+		{
+			name:           "a failed metadata step drops tables that did not exist",
+			tableMissing:   true,
+			failMetadata:   true,
+			wantOps:        []string{dataJob, "PATCH users", "DELETE users"},
+			wantStep:       MaterializationStepMetadata,
+			wantRolledBack: true,
+		},
+		{
+			name:         "a failed rollback keeps the backup",
+			failMetadata: true,
+			failRestore:  true,
+			wantOps:      []string{backupQuery, dataJob, "PATCH users", restoreQuery},
+			wantStep:     MaterializationStepMetadata,
+			wantErr:      "its previous state is kept in 'test-project.mart.users__bruin_backup'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			ops := make([]string, 0)
+			created := !tt.tableMissing
+			record := func(op string) {
+				mu.Lock()
+				defer mu.Unlock()
+				ops = append(ops, op)
+			}
+			firstLine := func(q string) string {
+				return strings.Join(strings.Fields(strings.SplitN(q, "\n", 2)[0]), " ")
+			}
+
+			ref := &bigquery2.JobReference{ProjectId: testProjectID, JobId: "job-1", Location: "US"}
+			jobStatus := func() *bigquery2.JobStatus {
+				if tt.failData {
+					return &bigquery2.JobStatus{State: "DONE", ErrorResult: &bigquery2.ErrorProto{Reason: "invalidQuery", Message: "the data is invalid"}}
+				}
+				return &bigquery2.JobStatus{State: "DONE"}
+			}
+			tablesPath := fmt.Sprintf("/projects/%s/datasets/mart/tables/", testProjectID)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/queries", testProjectID):
+					var req bigquery2.QueryRequest
+					_ = json.NewDecoder(r.Body).Decode(&req)
+					record("QUERY " + firstLine(req.Query))
+					if tt.failRestore && strings.HasPrefix(req.Query, strings.TrimPrefix(restoreQuery, "QUERY ")) {
+						w.WriteHeader(http.StatusBadRequest)
+						_, _ = w.Write([]byte(`{"error": {"code": 400, "message": "the backup is gone"}}`))
+						return
+					}
+					_ = json.NewEncoder(w).Encode(&bigquery2.QueryResponse{JobComplete: true, JobReference: ref})
+				case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/jobs", testProjectID):
+					var job bigquery2.Job
+					_ = json.NewDecoder(r.Body).Decode(&job)
+					record("JOB " + firstLine(job.Configuration.Query.Query))
+					if !tt.failData {
+						mu.Lock()
+						created = true
+						mu.Unlock()
+					}
+					_ = json.NewEncoder(w).Encode(&bigquery2.Job{JobReference: ref, Configuration: job.Configuration, Status: jobStatus()})
+				case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/queries/job-1", testProjectID)):
+					_ = json.NewEncoder(w).Encode(&bigquery2.GetQueryResultsResponse{JobComplete: true, JobReference: ref})
+				case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/jobs/job-1", testProjectID)):
+					_ = json.NewEncoder(w).Encode(&bigquery2.Job{JobReference: ref, Status: jobStatus()})
+				case r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/datasets/mart", testProjectID):
+					_ = json.NewEncoder(w).Encode(&bigquery2.Dataset{Location: "EU"})
+				case r.Method == http.MethodGet && r.URL.Path == tablesPath+"users":
+					mu.Lock()
+					exists := created
+					mu.Unlock()
+					if !exists {
+						w.WriteHeader(http.StatusNotFound)
+						_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table"}}`))
+						return
+					}
+					_ = json.NewEncoder(w).Encode(&bigquery2.Table{
+						Etag:   "etag-1",
+						Schema: &bigquery2.TableSchema{Fields: []*bigquery2.TableFieldSchema{{Name: "id", Type: "INTEGER"}}},
+					})
+				case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, tablesPath):
+					record("PATCH " + strings.TrimPrefix(r.URL.Path, tablesPath))
+					if tt.failMetadata {
+						w.WriteHeader(http.StatusForbidden)
+						_, _ = w.Write([]byte(`{"error": {"code": 403, "message": "Access Denied"}}`))
+						return
+					}
+					var table bigquery2.Table
+					_ = json.NewDecoder(r.Body).Decode(&table)
+					_ = json.NewEncoder(w).Encode(&table)
+				case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, tablesPath):
+					record("DELETE " + strings.TrimPrefix(r.URL.Path, tablesPath))
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.DatasetCacheTTL = -1
+
+			table, err := d.MaterializeWithMetadata(context.Background(), &pipeline.Asset{
+				Name:            "mart.users",
+				Description:     "the users",
+				Columns:         []pipeline.Column{{Name: "id", Type: "INT64"}},
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable},
+			}, "tmp.users_123")
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tt.wantOps, ops)
+
+			if tt.wantStep == "" {
+				require.NoError(t, err)
+				assert.Equal(t, &ResolvedTable{ProjectID: testProjectID, DatasetID: "mart", TableID: "users", Location: "EU"}, table)
+				return
+			}
+			assert.Nil(t, table)
+
+			var stepErr *MaterializationStepError
+			require.ErrorAs(t, err, &stepErr)
+			assert.Equal(t, tt.wantStep, stepErr.Step)
+			assert.Equal(t, tt.wantRolledBack, stepErr.RolledBack)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package bigquery
+
+import (
+	"context"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// metadataUpdateConcurrency bounds the number of tables whose metadata UpdateTableMetadataBatch updates in
+// parallel, so that large pipelines do not immediately run into the BigQuery metadata update rate limits.
+const metadataUpdateConcurrency = 8
+
+// UpdateTableMetadataBatch updates the metadata of the tables of the assets like UpdateTableMetadataIfNotExist,
+// a bounded number of tables at a time, each with its own ETag check and retries. Updates rejected with
+// rateLimitExceeded are retried by the BigQuery client with exponential backoff until the context is done, so the
+// context should carry a deadline. The errors are returned in the order of the assets, nil for the tables that were
+// updated, had nothing to update or do not exist.
+func (d *Client) UpdateTableMetadataBatch(ctx context.Context, assets []*pipeline.Asset) []error {
+	errs := make([]error, len(assets))
+
+	var wg errgroup.Group
+	wg.SetLimit(metadataUpdateConcurrency)
+	for i, asset := range assets {
+		wg.Go(func() error {
+			err := d.UpdateTableMetadataIfNotExist(ctx, asset)
+			if err != nil && !errors.As(err, &NoMetadataUpdatedError{}) {
+				errs[i] = errors.Wrapf(err, "failed to update the metadata of asset '%s'", asset.Name)
+			}
+
+			// errors are reported per asset, a failed update must not stop the others
+			return nil
+		})
+	}
+	_ = wg.Wait()
+
+	return errs
+}
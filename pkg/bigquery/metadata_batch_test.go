@@ -0,0 +1,87 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_UpdateTableMetadataBatch(t *testing.T) {
+	t.Parallel()
+
+	const rateLimitError = `{"error": {"code": 403, "message": "Exceeded rate limits: too many table update operations for this table.", "errors": [{"reason": "rateLimitExceeded"}]}}`
+
+	var mu sync.Mutex
+	patches := make(map[string][]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		table := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case table == "missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table"}}`))
+		case r.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(&bigquery2.Table{Etag: "etag-" + table})
+		case r.Method == http.MethodPatch:
+			patches[table] = append(patches[table], r.Header.Get("If-Match"))
+			switch {
+			case table == "throttled" && len(patches[table]) == 1:
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(rateLimitError))
+			case table == "invalid":
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"error": {"code": 400, "message": "Invalid description"}}`))
+			default:
+				_ = json.NewEncoder(w).Encode(&bigquery2.Table{})
+			}
+		default:
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	names := []string{"first", "throttled", "missing", "invalid", "no_metadata"}
+	assets := make([]*pipeline.Asset, len(names))
+	for i, name := range names {
+		assets[i] = &pipeline.Asset{Name: "myschema." + name, Description: "description of " + name}
+	}
+	assets[4].Description = ""
+	for i := range 20 {
+		assets = append(assets, &pipeline.Asset{Name: fmt.Sprintf("myschema.table_%d", i), Description: "test"})
+	}
+
+	errs := d.UpdateTableMetadataBatch(context.Background(), assets)
+	require.Len(t, errs, len(assets))
+
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1], "rate limited updates must be retried")
+	require.NoError(t, errs[2], "missing tables are skipped")
+	require.ErrorContains(t, errs[3], "failed to update the metadata of asset 'myschema.invalid'")
+	require.ErrorContains(t, errs[3], "Invalid description")
+	require.NoError(t, errs[4], "assets without metadata are not an error")
+	for _, err := range errs[5:] {
+		require.NoError(t, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// the ETag of the metadata read is kept on the retries
+	assert.Equal(t, []string{"etag-throttled", "etag-throttled"}, patches["throttled"])
+	assert.NotContains(t, patches, "no_metadata")
+	assert.Len(t, patches, 23)
+}
@@ -0,0 +1,237 @@
+package bigquery
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultMigrationsDataset is the dataset the migrations table is created in unless configured otherwise.
+	DefaultMigrationsDataset = "bruin"
+	migrationsTableName      = "_bruin_migrations"
+)
+
+// transactionalStatements are the statements BigQuery allows in multi-statement transactions. DDL on regular
+// tables is not allowed, migrations containing it are applied without a transaction.
+var transactionalStatements = map[string]bool{
+	"SELECT":   true,
+	"INSERT":   true,
+	"UPDATE":   true,
+	"DELETE":   true,
+	"MERGE":    true,
+	"TRUNCATE": true,
+}
+
+// Migration is a versioned SQL script that is applied to the warehouse exactly once.
+type Migration struct {
+	Version string
+	Name    string
+	SQL     string
+}
+
+// Checksum identifies the contents of the migration, it is used to detect changes to applied migrations.
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(m.SQL)))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadMigrations reads the `.sql` files in the given directory as migrations. Files are expected to be named
+// `<version>_<name>.sql`, e.g. `0001_create_users.sql`, and are ordered by version.
+func LoadMigrations(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read migrations directory '%s'", dir)
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read migration '%s'", entry.Name())
+		}
+
+		version, name, _ := strings.Cut(strings.TrimSuffix(entry.Name(), ".sql"), "_")
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    name,
+			SQL:     string(content),
+		})
+	}
+
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrationVersionLess(migrations[i].Version, migrations[j].Version)
+	})
+
+	return migrations, nil
+}
+
+// ApplyMigrations applies the given migrations in version order, skipping the ones that were applied before.
+// Applied migrations are tracked in the `_bruin_migrations` table together with their checksums, and the run
+// is refused if a previously applied migration was modified. Migrations that only contain DML are applied in
+// a transaction together with their bookkeeping row.
+func (d *Client) ApplyMigrations(ctx context.Context, migrations []Migration) error {
+	ordered, err := sortMigrations(migrations)
+	if err != nil {
+		return err
+	}
+
+	table, err := d.ensureMigrationsTable(ctx)
+	if err != nil {
+		return err
+	}
+
+	applied, err := d.appliedMigrations(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	pending := make([]Migration, 0, len(ordered))
+	for _, m := range ordered {
+		checksum, ok := applied[m.Version]
+		if !ok {
+			pending = append(pending, m)
+			continue
+		}
+
+		if checksum != m.Checksum() {
+			return fmt.Errorf("migration '%s' was modified after it was applied, refusing to run migrations", m.Version)
+		}
+	}
+
+	for _, m := range pending {
+		if err := d.RunQueryWithoutResult(ctx, &query.Query{Query: buildMigrationScript(table, m)}); err != nil {
+			return errors.Wrapf(err, "failed to apply migration '%s'", m.Version)
+		}
+	}
+
+	return nil
+}
+
+func sortMigrations(migrations []Migration) ([]Migration, error) {
+	seen := make(map[string]bool, len(migrations))
+	for _, m := range migrations {
+		if m.Version == "" {
+			return nil, errors.New("migrations must have a version")
+		}
+		if seen[m.Version] {
+			return nil, fmt.Errorf("duplicate migration version '%s'", m.Version)
+		}
+		seen[m.Version] = true
+	}
+
+	ordered := append([]Migration{}, migrations...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return migrationVersionLess(ordered[i].Version, ordered[j].Version)
+	})
+
+	return ordered, nil
+}
+
+// migrationVersionLess orders numeric versions by their value, so that `10` comes after `9` whether or not the
+// versions are zero-padded. Other versions are ordered as strings.
+func migrationVersionLess(a, b string) bool {
+	x, errA := strconv.ParseUint(a, 10, 64)
+	y, errB := strconv.ParseUint(b, 10, 64)
+	if errA != nil || errB != nil || x == y {
+		return a < b
+	}
+
+	return x < y
+}
+
+func (d *Client) ensureMigrationsTable(ctx context.Context) (string, error) {
+	dataset := DefaultMigrationsDataset
+	if d.config != nil && d.config.MigrationsDataset != "" {
+		dataset = d.config.MigrationsDataset
+	}
+
+	resolved, err := d.ResolveTable(dataset + "." + migrationsTableName)
+	if err != nil {
+		return "", err
+	}
+
+	if err := d.CreateDataSetIfNotExist(&pipeline.Asset{Name: resolved.String()}, ctx); err != nil {
+		return "", err
+	}
+
+	table := QuoteIdentifier(resolved.String())
+	qq := fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (version STRING NOT NULL, name STRING, checksum STRING NOT NULL, applied_at TIMESTAMP NOT NULL)",
+		table,
+	)
+	if err := d.RunQueryWithoutResult(ctx, &query.Query{Query: qq}); err != nil {
+		return "", errors.Wrap(err, "failed to create the migrations table")
+	}
+
+	return table, nil
+}
+
+func (d *Client) appliedMigrations(ctx context.Context, table string) (map[string]string, error) {
+	rows, err := d.Select(ctx, &query.Query{Query: fmt.Sprintf("SELECT version, checksum FROM %s", table)})
+	if err != nil && !errors.Is(err, ErrNoRows) {
+		return nil, errors.Wrap(err, "failed to read the applied migrations")
+	}
+
+	applied := make(map[string]string, len(rows))
+	for _, row := range rows {
+		if len(row) != 2 {
+			return nil, fmt.Errorf("unexpected row in the migrations table: %v", row)
+		}
+		applied[fmt.Sprint(row[0])] = fmt.Sprint(row[1])
+	}
+
+	return applied, nil
+}
+
+func buildMigrationScript(table string, m Migration) string {
+	body := strings.TrimSuffix(strings.TrimSpace(m.SQL), ";")
+	record := fmt.Sprintf(
+		"INSERT INTO %s (version, name, checksum, applied_at) VALUES (%s, %s, '%s', CURRENT_TIMESTAMP())",
+		table,
+		quoteStringLiteral(m.Version),
+		quoteStringLiteral(m.Name),
+		m.Checksum(),
+	)
+
+	if !isTransactional(body) {
+		return body + ";\n" + record + ";"
+	}
+
+	return "BEGIN TRANSACTION;\n" + body + ";\n" + record + ";\nCOMMIT TRANSACTION;"
+}
+
+// isTransactional reports whether every statement in the script can run inside a transaction. Semicolons in
+// literals and comments do not end a statement.
+func isTransactional(script string) bool {
+	for _, statement := range strings.Split(maskLiteralsAndComments(script, false), ";") {
+		fields := strings.Fields(statement)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if !transactionalStatements[strings.ToUpper(fields[0])] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func quoteStringLiteral(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,185 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func appliedMigrationsResponse(applied ...Migration) *bigquery2.QueryResponse {
+	rows := make([]*bigquery2.TableRow, 0, len(applied))
+	for _, m := range applied {
+		rows = append(rows, &bigquery2.TableRow{F: []*bigquery2.TableCell{{V: m.Version}, {V: m.Checksum()}}})
+	}
+
+	return &bigquery2.QueryResponse{
+		JobComplete:  true,
+		JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+		Schema: &bigquery2.TableSchema{
+			Fields: []*bigquery2.TableFieldSchema{
+				{Name: "version", Type: "STRING"},
+				{Name: "checksum", Type: "STRING"},
+			},
+		},
+		Rows:      rows,
+		TotalRows: uint64(len(rows)),
+	}
+}
+
+func existingDatasetHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/datasets/", testProjectID)) {
+		_ = json.NewEncoder(w).Encode(&bigquery2.Dataset{
+			DatasetReference: &bigquery2.DatasetReference{ProjectId: testProjectID, DatasetId: strings.TrimPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/datasets/", testProjectID))},
+		})
+		return
+	}
+
+	http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+}
+
+func TestClient_ApplyMigrations(t *testing.T) {
+	t.Parallel()
+
+	createUsers := Migration{Version: "0001", Name: "create_users", SQL: "CREATE TABLE dataset.users (id INT64)"}
+	seedUsers := Migration{Version: "0002", Name: "seed_users", SQL: "INSERT INTO dataset.users VALUES (1);\nUPDATE dataset.users SET id = 2 WHERE id = 1;"}
+	addEmail := Migration{Version: "0003", Name: "add_email", SQL: "ALTER TABLE dataset.users ADD COLUMN email STRING"}
+
+	const table = "`test-project.migrations_test._bruin_migrations`"
+
+	tests := []struct {
+		name        string
+		migrations  []Migration
+		applied     []Migration
+		wantQueries []string
+		wantErr     string
+	}{
+		{
+			name:       "only pending migrations are applied in order",
+			migrations: []Migration{addEmail, seedUsers, createUsers},
+			applied:    []Migration{createUsers},
+			wantQueries: []string{
+				"BEGIN TRANSACTION;\nINSERT INTO dataset.users VALUES (1);\nUPDATE dataset.users SET id = 2 WHERE id = 1;\n" +
+					"INSERT INTO " + table + " (version, name, checksum, applied_at) VALUES ('0002', 'seed_users', '" + seedUsers.Checksum() + "', CURRENT_TIMESTAMP());\nCOMMIT TRANSACTION;",
+				"ALTER TABLE dataset.users ADD COLUMN email STRING;\n" +
+					"INSERT INTO " + table + " (version, name, checksum, applied_at) VALUES ('0003', 'add_email', '" + addEmail.Checksum() + "', CURRENT_TIMESTAMP());",
+			},
+		},
+		{
+			name:        "nothing to apply",
+			migrations:  []Migration{createUsers},
+			applied:     []Migration{createUsers},
+			wantQueries: []string{},
+		},
+		{
+			name:       "modified migrations are refused",
+			migrations: []Migration{createUsers, addEmail},
+			applied:    []Migration{{Version: "0001", SQL: "CREATE TABLE dataset.users (id STRING)"}},
+			wantErr:    "migration '0001' was modified after it was applied, refusing to run migrations",
+		},
+		{
+			name:       "duplicate versions",
+			migrations: []Migration{createUsers, {Version: "0001", SQL: "SELECT 1"}},
+			wantErr:    "duplicate migration version '0001'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &recordingQueryHandler{
+				response: func(q string) *bigquery2.QueryResponse {
+					if strings.HasPrefix(q, "SELECT version, checksum FROM") {
+						return appliedMigrationsResponse(tt.applied...)
+					}
+					return nil
+				},
+				fallback: existingDatasetHandler,
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.MigrationsDataset = "migrations_test"
+
+			err := d.ApplyMigrations(context.Background(), tt.migrations)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				// nothing beyond the bookkeeping queries must have run
+				assert.LessOrEqual(t, len(handler.recordedQueries()), 2)
+				return
+			}
+			require.NoError(t, err)
+
+			queries := handler.recordedQueries()
+			require.GreaterOrEqual(t, len(queries), 2)
+			assert.Equal(t, "CREATE TABLE IF NOT EXISTS "+table+" (version STRING NOT NULL, name STRING, checksum STRING NOT NULL, applied_at TIMESTAMP NOT NULL)", queries[0])
+			assert.Equal(t, "SELECT version, checksum FROM "+table, queries[1])
+			assert.Equal(t, tt.wantQueries, queries[2:])
+		})
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0002_seed_users.sql"), []byte("INSERT INTO dataset.users VALUES (1)"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_create_users.sql"), []byte("CREATE TABLE dataset.users (id INT64)"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a migration"), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested.sql"), 0o700))
+
+	got, err := LoadMigrations(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{Version: "0001", Name: "create_users", SQL: "CREATE TABLE dataset.users (id INT64)"},
+		{Version: "0002", Name: "seed_users", SQL: "INSERT INTO dataset.users VALUES (1)"},
+	}, got)
+
+	_, err = LoadMigrations(filepath.Join(dir, "missing"))
+	require.Error(t, err)
+
+	unpadded := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(unpadded, "10_add_email.sql"), []byte("SELECT 10"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(unpadded, "9_create_users.sql"), []byte("SELECT 9"), 0o600))
+
+	got, err = LoadMigrations(unpadded)
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "9", got[0].Version)
+	assert.Equal(t, "10", got[1].Version)
+}
+
+func TestIsTransactional(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		script string
+		want   bool
+	}{
+		{name: "dml only", script: "INSERT INTO dataset.users VALUES (1);\nDELETE FROM dataset.users WHERE id = 2", want: true},
+		{name: "ddl", script: "CREATE TABLE dataset.users (id INT64)", want: false},
+		{name: "semicolon in a literal", script: "INSERT INTO dataset.users VALUES ('a; CREATE TABLE x')", want: true},
+		{name: "semicolon in a comment", script: "-- rename; drop later\nUPDATE dataset.users SET id = 2", want: true},
+		{name: "ddl after a comment", script: "/* setup */ CREATE TABLE dataset.users (id INT64)", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, isTransactional(tt.script))
+		})
+	}
+}
@@ -4,9 +4,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
-	"github.com/bruin-data/bruin/pkg/ansisql"
 	"github.com/bruin-data/bruin/pkg/executor"
 	"github.com/bruin-data/bruin/pkg/helpers"
 	"github.com/bruin-data/bruin/pkg/pipeline"
@@ -30,6 +30,45 @@ type connectionFetcher interface {
 	GetConnection(name string) (interface{}, error)
 }
 
+type partitionLimitChecker interface {
+	CheckPartitionLimit(ctx context.Context, asset *pipeline.Asset, assetQuery string) (*PartitionLimitWarning, error)
+}
+
+type schemaEvolver interface {
+	EvolveSchema(ctx context.Context, asset *pipeline.Asset) ([]SchemaChange, error)
+}
+
+type encryptionKeyChecker interface {
+	EncryptionKey(asset *pipeline.Asset) string
+	CheckEncryptionKey(ctx context.Context, asset *pipeline.Asset) error
+}
+
+type materializedViewSyncer interface {
+	SyncMaterializedView(ctx context.Context, asset *pipeline.Asset, definition string) error
+}
+
+type tableReferencer interface {
+	TableReference(tableName string) (string, error)
+}
+
+type columnValidator interface {
+	ValidateColumnsExist(ctx context.Context, tableName string, columns []string) error
+	validatesCheckColumns() bool
+}
+
+type columnDescriptionInheritor interface {
+	UpdateTableMetadataWithUpstreamDescriptions(ctx context.Context, asset *pipeline.Asset, upstreamDescriptions map[string]string) ([]string, error)
+	inheritsColumnDescriptions() bool
+}
+
+type expirationClearer interface {
+	ClearTableExpiration(ctx context.Context, asset *pipeline.Asset) error
+}
+
+type datasetPreparer interface {
+	PrepareDatasets(ctx context.Context, assets []*pipeline.Asset) error
+}
+
 type BasicOperator struct {
 	connection   connectionFetcher
 	extractor    queryExtractor
@@ -44,11 +83,46 @@ func NewBasicOperator(conn connectionFetcher, extractor queryExtractor, material
 	}
 }
 
+// PrepareDatasets creates the datasets of the given assets of the pipeline up front, once per dataset, so that the
+// assets sharing a dataset do not race to create it when they run concurrently. The assets are grouped by their
+// connection, connections that cannot prepare datasets are skipped since RunTask creates the datasets as well.
+func (o BasicOperator) PrepareDatasets(ctx context.Context, p *pipeline.Pipeline, assets []*pipeline.Asset) error {
+	byConnection := make(map[string][]*pipeline.Asset)
+	connNames := make([]string, 0)
+	for _, asset := range assets {
+		connName, err := p.GetConnectionNameForAsset(asset)
+		if err != nil {
+			return err
+		}
+		if _, ok := byConnection[connName]; !ok {
+			connNames = append(connNames, connName)
+		}
+		byConnection[connName] = append(byConnection[connName], asset)
+	}
+
+	for _, connName := range connNames {
+		conn, err := o.connection.GetBqConnection(connName)
+		if err != nil {
+			return err
+		}
+		preparer, ok := conn.(datasetPreparer)
+		if !ok {
+			continue
+		}
+		if err := preparer.PrepareDatasets(ctx, byConnection[connName]); err != nil {
+			return errors.Wrapf(err, "failed to prepare the datasets of connection '%s'", connName)
+		}
+	}
+
+	return nil
+}
+
 func (o BasicOperator) Run(ctx context.Context, ti scheduler.TaskInstance) error {
 	return o.RunTask(ctx, ti.GetPipeline(), ti.GetAsset())
 }
 
 func (o BasicOperator) RunTask(ctx context.Context, p *pipeline.Pipeline, t *pipeline.Asset) error {
+	ctx = WithAssetJobLabels(ctx, p, t)
 	extractor := o.extractor.CloneForAsset(ctx, t)
 	queries, err := extractor.ExtractQueriesFromString(t.ExecutableFile.Content)
 	if err != nil {
@@ -61,7 +135,32 @@ func (o BasicOperator) RunTask(ctx context.Context, p *pipeline.Pipeline, t *pip
 		return errors.New("cannot enable materialization for tasks with multiple queries")
 	}
 	q := queries[0]
-	materialized, err := o.materializer.Render(t, q.String())
+	assetQuery := q.String()
+
+	connName, err := p.GetConnectionNameForAsset(t)
+	if err != nil {
+		return err
+	}
+
+	conn, err := o.connection.GetBqConnection(connName)
+	if err != nil {
+		return err
+	}
+
+	// the table is created with the KMS key of the connection unless the asset sets its own
+	renderAsset := t
+	if referencer, ok := conn.(tableReferencer); ok {
+		reference, err := referencer.TableReference(t.Name)
+		if err != nil {
+			return err
+		}
+		renderAsset = withTableName(renderAsset, reference)
+	}
+	keyChecker, checksKeys := conn.(encryptionKeyChecker)
+	if checksKeys {
+		renderAsset = withEncryptionKey(renderAsset, keyChecker.EncryptionKey(t))
+	}
+	materialized, err := o.materializer.Render(renderAsset, assetQuery)
 	if err != nil {
 		return err
 	}
@@ -79,17 +178,18 @@ func (o BasicOperator) RunTask(ctx context.Context, p *pipeline.Pipeline, t *pip
 		q.Query = renderedQueries[0].Query
 	}
 
-	connName, err := p.GetConnectionNameForAsset(t)
-	if err != nil {
+	if err := conn.CreateDataSetIfNotExist(t, ctx); err != nil {
 		return err
 	}
 
-	conn, err := o.connection.GetBqConnection(connName)
-	if err != nil {
-		return err
-	}
-	if err := conn.CreateDataSetIfNotExist(t, ctx); err != nil {
-		return err
+	if checker, ok := conn.(partitionLimitChecker); ok {
+		warning, err := checker.CheckPartitionLimit(ctx, t, assetQuery)
+		if err != nil {
+			return err
+		}
+		if printer, ok := ctx.Value(executor.KeyPrinter).(io.Writer); ok && warning != nil {
+			fmt.Fprintln(printer, "Warning:", warning.String())
+		}
 	}
 
 	if o.materializer.IsFullRefresh() {
@@ -97,9 +197,42 @@ func (o BasicOperator) RunTask(ctx context.Context, p *pipeline.Pipeline, t *pip
 		if err != nil {
 			return errors.Wrapf(err, "failed to check for mismatches for table '%s'", t.Name)
 		}
+	} else {
+		// full refreshes rebuild the table, there is no existing key to check nor schema to evolve then; neither is
+		// there for views and the strategies that replace the table
+		if checksKeys && writesIntoExistingTable(t) {
+			if err := keyChecker.CheckEncryptionKey(ctx, t); err != nil {
+				return err
+			}
+		}
+		if evolver, ok := conn.(schemaEvolver); ok {
+			if _, err := evolver.EvolveSchema(ctx, t); err != nil {
+				return err
+			}
+		}
+	}
+
+	if syncer, ok := conn.(materializedViewSyncer); ok {
+		if err := syncer.SyncMaterializedView(ctx, t, assetQuery); err != nil {
+			return err
+		}
+	}
+
+	err = conn.RunQueryWithoutResult(ctx, q)
+	if err != nil && t.Materialization.Type == pipeline.MaterializationTypeMaterializedView {
+		// BigQuery rejects the definitions it cannot refresh incrementally, its error names the offending construct
+		return errors.Wrapf(err, "BigQuery rejected the materialized view '%s'", t.Name)
+	}
+	if err != nil {
+		return err
 	}
 
-	return conn.RunQueryWithoutResult(ctx, q)
+	// the tables created by the statement inherit the default expiration of their dataset
+	if clearer, ok := conn.(expirationClearer); ok {
+		return clearer.ClearTableExpiration(ctx, t)
+	}
+
+	return nil
 }
 
 type checkRunner interface {
@@ -107,17 +240,19 @@ type checkRunner interface {
 }
 
 type ColumnCheckOperator struct {
+	connection   connectionFetcher
 	checkRunners map[string]checkRunner
 }
 
 func NewColumnCheckOperator(manager connectionFetcher) (*ColumnCheckOperator, error) {
 	return &ColumnCheckOperator{
+		connection: manager,
 		checkRunners: map[string]checkRunner{
-			"not_null":        ansisql.NewNotNullCheck(manager),
-			"unique":          ansisql.NewUniqueCheck(manager),
-			"positive":        ansisql.NewPositiveCheck(manager),
-			"non_negative":    ansisql.NewNonNegativeCheck(manager),
-			"negative":        ansisql.NewNegativeCheck(manager),
+			"not_null":        newNotNullCheck(manager),
+			"unique":          newUniqueCheck(manager),
+			"positive":        newComparisonCheck(manager, "positive", "<= 0", "column '%s' has %d non-positive values"),
+			"non_negative":    newComparisonCheck(manager, "non_negative", "< 0", "column '%s' has %d negative values"),
+			"negative":        newComparisonCheck(manager, "negative", ">= 0", "column '%s' has %d non negative values"),
 			"accepted_values": &AcceptedValuesCheck{conn: manager},
 			"pattern":         &PatternCheck{conn: manager},
 		},
@@ -135,9 +270,39 @@ func (o ColumnCheckOperator) Run(ctx context.Context, ti scheduler.TaskInstance)
 		return errors.New("there is no executor configured for the check type, check cannot be run: " + test.Check.Name)
 	}
 
+	if err := o.validateColumn(ctx, test); err != nil {
+		return err
+	}
+
 	return executor.Check(ctx, test)
 }
 
+// validateColumn makes sure the checked column exists before the check query is sent, so that a typo in the
+// column name surfaces as a clear error instead of an SQL error. The connection decides whether to validate, a
+// connection that cannot be found is left to the check to report.
+func (o ColumnCheckOperator) validateColumn(ctx context.Context, ti *scheduler.ColumnCheckInstance) error {
+	if o.connection == nil {
+		return nil
+	}
+
+	connName, err := ti.GetPipeline().GetConnectionNameForAsset(ti.GetAsset())
+	if err != nil {
+		return nil //nolint:nilerr
+	}
+
+	conn, err := o.connection.GetConnection(connName)
+	if err != nil {
+		return nil //nolint:nilerr
+	}
+
+	validator, ok := conn.(columnValidator)
+	if !ok || !validator.validatesCheckColumns() {
+		return nil
+	}
+
+	return validator.ValidateColumnsExist(ctx, ti.GetAsset().Name, []string{ti.Column.Name})
+}
+
 type MetadataPushOperator struct {
 	connection connectionFetcher
 }
@@ -164,7 +329,13 @@ func (o *MetadataPushOperator) Run(ctx context.Context, ti scheduler.TaskInstanc
 		return errors.New("no writer found in context, please create an issue for this: https://github.com/bruin-data/bruin/issues")
 	}
 
-	err = client.UpdateTableMetadataIfNotExist(ctx, ti.GetAsset())
+	var inherited []string
+	if inheritor, ok := client.(columnDescriptionInheritor); ok && inheritor.inheritsColumnDescriptions() {
+		asset := ti.GetAsset()
+		inherited, err = inheritor.UpdateTableMetadataWithUpstreamDescriptions(ctx, asset, UpstreamColumnDescriptions(ti.GetPipeline(), asset))
+	} else {
+		err = client.UpdateTableMetadataIfNotExist(ctx, ti.GetAsset())
+	}
 	if err != nil {
 		var noMetadata NoMetadataUpdatedError
 		if errors.As(err, &noMetadata) {
@@ -175,6 +346,10 @@ func (o *MetadataPushOperator) Run(ctx context.Context, ti scheduler.TaskInstanc
 		return err
 	}
 
+	if len(inherited) > 0 {
+		_, _ = writer.Write([]byte(fmt.Sprintf("Inherited the descriptions of the columns %s from the upstream assets\n", strings.Join(inherited, ", "))))
+	}
+
 	return nil
 }
 
@@ -200,6 +375,7 @@ func (o *QuerySensor) RunTask(ctx context.Context, p *pipeline.Pipeline, t *pipe
 	if o.sensorMode == "skip" {
 		return nil
 	}
+	ctx = WithAssetJobLabels(ctx, p, t)
 	qq, ok := t.Parameters["query"]
 	if !ok {
 		return errors.New("query sensor requires a parameter named 'query'")
@@ -307,7 +483,7 @@ func (ts *TableSensor) RunTask(ctx context.Context, p *pipeline.Pipeline, t *pip
 		case <-timeout:
 			return errors.New("Sensor timed out after 24 hours")
 		default:
-			res, err := conn.Select(ctx, &query.Query{Query: qq})
+			res, err := conn.Select(ctx, qq)
 			if err != nil {
 				return err
 			}
@@ -2,7 +2,13 @@ package bigquery
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/bruin-data/bruin/pkg/executor"
@@ -13,6 +19,8 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
 )
 
 type mockExtractor struct {
@@ -430,3 +438,283 @@ func TestBasicOperator_RunTask_WithRenderer(t *testing.T) {
 		})
 	}
 }
+
+type mockValidatingQuerier struct {
+	mockQuerierWithResult
+	disabled bool
+}
+
+func (m *mockValidatingQuerier) ValidateColumnsExist(ctx context.Context, tableName string, columns []string) error {
+	args := m.Called(ctx, tableName, columns)
+	return args.Error(0)
+}
+
+func (m *mockValidatingQuerier) validatesCheckColumns() bool {
+	return !m.disabled
+}
+
+func TestColumnCheckOperator_Run_ValidatesColumn(t *testing.T) {
+	t.Parallel()
+
+	newInstance := func() *scheduler.ColumnCheckInstance {
+		return &scheduler.ColumnCheckInstance{
+			AssetInstance: &scheduler.AssetInstance{
+				Asset: &pipeline.Asset{
+					Name: "dataset.test_asset",
+					Type: pipeline.AssetTypeBigqueryQuery,
+				},
+				Pipeline: &pipeline.Pipeline{
+					Name: "test",
+					DefaultConnections: map[string]string{
+						"google_cloud_platform": "test",
+					},
+				},
+			},
+			Column: &pipeline.Column{Name: "emial"},
+			Check:  &pipeline.ColumnCheck{Name: "not_null"},
+		}
+	}
+
+	t.Run("missing column fails before the check query", func(t *testing.T) {
+		t.Parallel()
+
+		q := new(mockValidatingQuerier)
+		q.On("ValidateColumnsExist", mock.Anything, "dataset.test_asset", []string{"emial"}).
+			Return(errors.New("column emial does not exist on dataset.test_asset"))
+
+		conn := new(mockConnectionFetcher)
+		conn.On("GetConnection", "test").Return(q, nil)
+
+		o, err := NewColumnCheckOperator(conn)
+		require.NoError(t, err)
+
+		require.EqualError(t, o.Run(context.Background(), newInstance()), "column emial does not exist on dataset.test_asset")
+		q.AssertNotCalled(t, "Select", mock.Anything, mock.Anything)
+	})
+
+	t.Run("existing column runs the check", func(t *testing.T) {
+		t.Parallel()
+
+		q := new(mockValidatingQuerier)
+		q.On("ValidateColumnsExist", mock.Anything, "dataset.test_asset", []string{"emial"}).Return(nil)
+		q.On("Select", mock.Anything, mock.Anything).Return([][]interface{}{{int64(0)}}, nil)
+
+		conn := new(mockConnectionFetcher)
+		conn.On("GetConnection", "test").Return(q, nil)
+
+		o, err := NewColumnCheckOperator(conn)
+		require.NoError(t, err)
+
+		require.NoError(t, o.Run(context.Background(), newInstance()))
+		q.AssertExpectations(t)
+	})
+
+	t.Run("disabled validation does not look up the schema", func(t *testing.T) {
+		t.Parallel()
+
+		q := &mockValidatingQuerier{disabled: true}
+		q.On("Select", mock.Anything, mock.Anything).Return([][]interface{}{{int64(0)}}, nil)
+
+		conn := new(mockConnectionFetcher)
+		conn.On("GetConnection", "test").Return(q, nil)
+
+		o, err := NewColumnCheckOperator(conn)
+		require.NoError(t, err)
+
+		require.NoError(t, o.Run(context.Background(), newInstance()))
+		q.AssertNotCalled(t, "ValidateColumnsExist", mock.Anything, mock.Anything, mock.Anything)
+		conn.AssertNotCalled(t, "GetBqConnection", mock.Anything)
+	})
+
+	t.Run("missing connection is reported by the check", func(t *testing.T) {
+		t.Parallel()
+
+		conn := new(mockConnectionFetcher)
+		conn.On("GetConnection", "test").Return(nil, errors.New("connection 'test' not found"))
+
+		o, err := NewColumnCheckOperator(conn)
+		require.NoError(t, err)
+
+		require.ErrorContains(t, o.Run(context.Background(), newInstance()), "connection 'test' not found")
+	})
+}
+
+type mockEncryptingQuerier struct {
+	mockQuerierWithResult
+}
+
+func (m *mockEncryptingQuerier) EncryptionKey(asset *pipeline.Asset) string {
+	return "projects/p/locations/eu/keyRings/r/cryptoKeys/k"
+}
+
+func (m *mockEncryptingQuerier) CheckEncryptionKey(ctx context.Context, asset *pipeline.Asset) error {
+	args := m.Called(ctx, asset)
+	return args.Error(0)
+}
+
+func TestBasicOperator_RunTask_EncryptionKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		mat       pipeline.Materialization
+		wantCheck bool
+	}{
+		{name: "views have no key", mat: pipeline.Materialization{Type: pipeline.MaterializationTypeView}},
+		{name: "replaced tables take the key", mat: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, Strategy: pipeline.MaterializationStrategyCreateReplace}},
+		{name: "assets that are not materialized", mat: pipeline.Materialization{}},
+		{name: "existing tables are checked", mat: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, Strategy: pipeline.MaterializationStrategyAppend}, wantCheck: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			asset := &pipeline.Asset{
+				Name:            "dataset.users",
+				Type:            pipeline.AssetTypeBigqueryQuery,
+				ExecutableFile:  pipeline.ExecutableFile{Path: "users.sql", Content: "some content"},
+				Materialization: tt.mat,
+			}
+
+			client := new(mockEncryptingQuerier)
+			client.On("CreateDataSetIfNotExist", mock.Anything, mock.Anything).Return(nil)
+			client.On("RunQueryWithoutResult", mock.Anything, mock.Anything).Return(nil)
+			client.On("CheckEncryptionKey", mock.Anything, asset).Return(nil)
+			conn := new(mockConnectionFetcher)
+			conn.On("GetBqConnection", "gcp-default").Return(client, nil)
+
+			extractor := new(mockExtractor)
+			extractor.On("ExtractQueriesFromString", "some content").Return([]*query.Query{{Query: "select 1"}}, nil)
+			mat := new(mockMaterializer)
+			mat.On("IsFullRefresh").Return(false)
+			mat.On("Render", mock.Anything, "select 1").Return("select 1", nil)
+
+			o := BasicOperator{connection: conn, extractor: extractor, materializer: mat}
+			require.NoError(t, o.RunTask(context.Background(), &pipeline.Pipeline{}, asset))
+
+			if tt.wantCheck {
+				client.AssertCalled(t, "CheckEncryptionKey", mock.Anything, asset)
+			} else {
+				client.AssertNotCalled(t, "CheckEncryptionKey", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+type mockExpiringQuerier struct {
+	mockQuerierWithResult
+}
+
+func (m *mockExpiringQuerier) ClearTableExpiration(ctx context.Context, asset *pipeline.Asset) error {
+	args := m.Called(ctx, asset)
+	return args.Error(0)
+}
+
+func TestBasicOperator_RunTask_ClearsExpiration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		queryErr error
+	}{
+		{name: "the expiration is cleared after the table is created"},
+		{name: "nothing is cleared if the statement fails", queryErr: errors.New("statement failed")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			asset := &pipeline.Asset{
+				Name:            "dataset.users",
+				Type:            pipeline.AssetTypeBigqueryQuery,
+				ExecutableFile:  pipeline.ExecutableFile{Path: "users.sql", Content: "some content"},
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, NeverExpire: true},
+			}
+
+			var calls []string
+			client := new(mockExpiringQuerier)
+			client.On("CreateDataSetIfNotExist", mock.Anything, mock.Anything).Return(nil)
+			client.On("RunQueryWithoutResult", mock.Anything, mock.Anything).
+				Run(func(mock.Arguments) { calls = append(calls, "query") }).Return(tt.queryErr)
+			client.On("ClearTableExpiration", mock.Anything, asset).
+				Run(func(mock.Arguments) { calls = append(calls, "clear") }).Return(nil)
+			conn := new(mockConnectionFetcher)
+			conn.On("GetBqConnection", "gcp-default").Return(client, nil)
+
+			extractor := new(mockExtractor)
+			extractor.On("ExtractQueriesFromString", "some content").Return([]*query.Query{{Query: "select 1"}}, nil)
+			mat := new(mockMaterializer)
+			mat.On("IsFullRefresh").Return(false)
+			mat.On("Render", mock.Anything, "select 1").Return("select 1", nil)
+
+			o := BasicOperator{connection: conn, extractor: extractor, materializer: mat}
+			err := o.RunTask(context.Background(), &pipeline.Pipeline{}, asset)
+
+			if tt.queryErr != nil {
+				require.ErrorIs(t, err, tt.queryErr)
+				assert.Equal(t, []string{"query"}, calls)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []string{"query", "clear"}, calls)
+		})
+	}
+}
+
+func TestBasicOperator_PrepareDatasets(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	metadataCalls := make(map[string]int)
+	createCalls := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/datasets/", testProjectID)):
+			name := strings.TrimPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/datasets/", testProjectID))
+			metadataCalls[name]++
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "not found"}}`))
+		case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/datasets", testProjectID):
+			var ds bigquery2.Dataset
+			_ = json.NewDecoder(r.Body).Decode(&ds)
+			createCalls[ds.DatasetReference.DatasetId]++
+			_ = json.NewEncoder(w).Encode(&ds)
+		default:
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server.URL)
+	other := new(mockQuerierWithResult)
+	conn := new(mockConnectionFetcher)
+	conn.On("GetBqConnection", "gcp-default").Return(client, nil)
+	conn.On("GetBqConnection", "other").Return(other, nil)
+
+	assets := []*pipeline.Asset{
+		{Name: "mart.users", Type: pipeline.AssetTypeBigqueryQuery},
+		{Name: "mart.orders", Type: pipeline.AssetTypeBigqueryQuery},
+		{Name: "staging.events", Type: pipeline.AssetTypeBigqueryQuery},
+		{Name: "raw.events", Type: pipeline.AssetTypeBigqueryQuery, Connection: "other"},
+	}
+
+	o := BasicOperator{connection: conn}
+	require.NoError(t, o.PrepareDatasets(context.Background(), &pipeline.Pipeline{}, assets))
+
+	// the assets create their datasets when they run as well, which must not reach the API again
+	for _, asset := range assets[:3] {
+		require.NoError(t, client.CreateDataSetIfNotExist(asset, context.Background()))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]int{"mart": 1, "staging": 1}, metadataCalls)
+	assert.Equal(t, map[string]int{"mart": 1, "staging": 1}, createCalls)
+	conn.AssertNumberOfCalls(t, "GetBqConnection", 2)
+	other.AssertNotCalled(t, "CreateDataSetIfNotExist", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,126 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// pageCursor is the content of the page tokens returned by SelectPage: the job whose results are paged through and
+// the BigQuery page token within them.
+type pageCursor struct {
+	ProjectID string `json:"p"`
+	Location  string `json:"l"`
+	JobID     string `json:"j"`
+	Token     string `json:"t"`
+}
+
+func encodePageToken(job *bigquery.Job, token string) (string, error) {
+	encoded, err := json.Marshal(&pageCursor{ProjectID: job.ProjectID(), Location: job.Location(), JobID: job.ID(), Token: token})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+func decodePageToken(pageToken string) (*pageCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(pageToken)
+	if err != nil {
+		return nil, errors.New("invalid page token")
+	}
+
+	var cursor pageCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil || cursor.JobID == "" || cursor.Token == "" {
+		return nil, errors.New("invalid page token")
+	}
+
+	return &cursor, nil
+}
+
+// SelectPage runs the query and returns the first page of at most pageSize rows of its results, with the columns
+// set like SelectWithSchema, together with the token of the next page. Passing the token back returns the next page
+// from the results of the same job instead of running the query again, the query is ignored then; BigQuery keeps
+// the results for about a day. The token is empty after the last page.
+func (d *Client) SelectPage(ctx context.Context, queryObj *query.Query, pageToken string, pageSize int) (*query.QueryResult, string, error) {
+	if pageSize <= 0 {
+		return nil, "", fmt.Errorf("page size must be positive, %d given", pageSize)
+	}
+	loc, err := d.config.outputLocation()
+	if err != nil {
+		return nil, "", err
+	}
+
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer cancel()
+
+	var job *bigquery.Job
+	var token string
+	if pageToken == "" {
+		q, err := d.newQuery(ctx, queryObj)
+		if err != nil {
+			return nil, "", err
+		}
+		job, err = d.runWithRetry(ctx, q)
+		if err != nil {
+			return nil, "", formatError(err)
+		}
+	} else {
+		cursor, err := decodePageToken(pageToken)
+		if err != nil {
+			return nil, "", err
+		}
+		job, err = d.client.JobFromProject(ctx, cursor.ProjectID, cursor.JobID, cursor.Location)
+		if err != nil {
+			return nil, "", errors.Wrapf(formatError(err), "failed to find the job '%s' of the page token", cursor.JobID)
+		}
+		token = cursor.Token
+	}
+
+	// reading a query job waits for it to complete
+	rows, err := d.readJob(ctx, job)
+	if err != nil {
+		return nil, "", formatError(timeoutCause(ctx, err))
+	}
+
+	var page [][]bigquery.Value
+	next, err := iterator.NewPager(rows, pageSize, token).NextPage(&page)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read page: %w", formatError(timeoutCause(ctx, err)))
+	}
+
+	schema := rows.Schema
+	if schema == nil {
+		return nil, "", errors.New("schema information is not available")
+	}
+
+	result := &query.QueryResult{Rows: make([][]interface{}, 0, len(page))}
+	setResultColumns(result, schema)
+	for _, values := range page {
+		row := make([]interface{}, len(values))
+		for i, v := range values {
+			row[i] = v
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	if loc != nil {
+		normalizeDateTimes(result.Rows, schema, loc)
+	}
+
+	if next == "" {
+		return result, "", nil
+	}
+	nextPageToken, err := encodePageToken(job, next)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return result, nextPageToken, nil
+}
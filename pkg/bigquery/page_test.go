@@ -0,0 +1,99 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_SelectPage(t *testing.T) {
+	t.Parallel()
+
+	ref := &bigquery2.JobReference{ProjectId: testProjectID, JobId: "job-1", Location: "EU"}
+	job := &bigquery2.Job{
+		JobReference:  ref,
+		Configuration: &bigquery2.JobConfiguration{Query: &bigquery2.JobConfigurationQuery{Query: "SELECT name FROM users"}},
+		Status:        &bigquery2.JobStatus{State: "DONE"},
+	}
+	schema := &bigquery2.TableSchema{Fields: []*bigquery2.TableFieldSchema{{Name: "name", Type: "STRING", Mode: "REQUIRED"}}}
+	pages := map[string]struct {
+		rows []string
+		next string
+	}{
+		"":     {rows: []string{"a", "b"}, next: "bq-2"},
+		"bq-2": {rows: []string{"c", "d"}, next: "bq-3"},
+		"bq-3": {rows: []string{"e"}},
+	}
+
+	var submitted atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/jobs", testProjectID):
+			submitted.Add(1)
+			_ = json.NewEncoder(w).Encode(job)
+		case strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/queries/job-1", testProjectID)):
+			response := &bigquery2.GetQueryResultsResponse{JobComplete: true, JobReference: ref, Schema: schema, TotalRows: 5}
+			if r.URL.Query().Get("maxResults") != "0" {
+				if r.URL.Query().Get("maxResults") != "2" {
+					http.Error(w, "unexpected page size: "+r.URL.RawQuery, http.StatusBadRequest)
+					return
+				}
+				page := pages[r.URL.Query().Get("pageToken")]
+				for _, value := range page.rows {
+					response.Rows = append(response.Rows, &bigquery2.TableRow{F: []*bigquery2.TableCell{{V: value}}})
+				}
+				response.PageToken = page.next
+			}
+			_ = json.NewEncoder(w).Encode(response)
+		case r.Method == http.MethodGet && r.URL.Path == fmt.Sprintf("/projects/%s/jobs/job-1", testProjectID):
+			if r.URL.Query().Get("location") != "EU" {
+				http.Error(w, "the job must be looked up in its location", http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(job)
+		default:
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	q := &query.Query{Query: "SELECT name FROM users"}
+
+	var got [][]interface{}
+	token := ""
+	for i := 0; ; i++ {
+		require.Less(t, i, 3, "the pages must end")
+
+		result, next, err := d.SelectPage(context.Background(), q, token, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"name"}, result.Columns)
+		assert.Equal(t, []string{"STRING"}, result.ColumnTypes)
+		assert.Equal(t, []string{FieldModeRequired}, result.ColumnModes)
+		got = append(got, result.Rows...)
+
+		if next == "" {
+			break
+		}
+		token = next
+	}
+
+	assert.Equal(t, [][]interface{}{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}, got)
+	assert.Equal(t, int32(1), submitted.Load(), "the following pages must be read without running the query again")
+
+	_, _, err := d.SelectPage(context.Background(), q, "", 0)
+	require.EqualError(t, err, "page size must be positive, 0 given")
+
+	_, _, err = d.SelectPage(context.Background(), q, "not-a-token", 2)
+	require.EqualError(t, err, "invalid page token")
+}
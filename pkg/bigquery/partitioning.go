@@ -0,0 +1,332 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/helpers"
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+)
+
+// MaxPartitionsPerTable is the maximum number of partitions BigQuery allows on a single table.
+const MaxPartitionsPerTable = 10000
+
+const (
+	PartitioningTypeNone  = ""
+	PartitioningTypeTime  = "TIME"
+	PartitioningTypeRange = "RANGE"
+)
+
+// PartitioningSpec is a unified view of the partitioning and clustering configuration of a table, regardless
+// of whether it is partitioned by time or by an integer range.
+type PartitioningSpec struct {
+	Type  string
+	Field string
+
+	// Granularity is only set for time partitioning, e.g. DAY or MONTH.
+	Granularity bigquery.TimePartitioningType
+
+	// RangeStart, RangeEnd and RangeInterval are only set for integer range partitioning.
+	RangeStart    int64
+	RangeEnd      int64
+	RangeInterval int64
+
+	Expiration             time.Duration
+	RequirePartitionFilter bool
+
+	ClusterFields []string
+}
+
+// PartitioningSpecFromMetadata extracts the partitioning spec of the table with the given metadata.
+func PartitioningSpecFromMetadata(meta *bigquery.TableMetadata) *PartitioningSpec {
+	spec := &PartitioningSpec{
+		Type:          PartitioningTypeNone,
+		ClusterFields: []string{},
+	}
+	if meta == nil {
+		return spec
+	}
+
+	spec.RequirePartitionFilter = meta.RequirePartitionFilter
+	if meta.TimePartitioning != nil {
+		spec.Type = PartitioningTypeTime
+		spec.Field = meta.TimePartitioning.Field
+		spec.Granularity = meta.TimePartitioning.Type
+		spec.Expiration = meta.TimePartitioning.Expiration
+		// the deprecated table-level flag is still reported by older tables
+		spec.RequirePartitionFilter = spec.RequirePartitionFilter || meta.TimePartitioning.RequirePartitionFilter
+	}
+
+	if meta.RangePartitioning != nil {
+		spec.Type = PartitioningTypeRange
+		spec.Field = meta.RangePartitioning.Field
+		if meta.RangePartitioning.Range != nil {
+			spec.RangeStart = meta.RangePartitioning.Range.Start
+			spec.RangeEnd = meta.RangePartitioning.Range.End
+			spec.RangeInterval = meta.RangePartitioning.Range.Interval
+		}
+	}
+
+	if meta.Clustering != nil {
+		spec.ClusterFields = append(spec.ClusterFields, meta.Clustering.Fields...)
+	}
+
+	return spec
+}
+
+func (s *PartitioningSpec) IsPartitioned() bool {
+	return s.Type != PartitioningTypeNone
+}
+
+func (s *PartitioningSpec) IsClustered() bool {
+	return len(s.ClusterFields) > 0
+}
+
+// MatchesPartitioning reports whether the table is partitioned the way the asset expects. For assets with a
+// partition range the start, end and interval of the range have to match as well, and for time partitioned ones
+// the granularity, see expectedPartitionGranularity.
+func (s *PartitioningSpec) MatchesPartitioning(asset *pipeline.Asset) bool {
+	mat := asset.Materialization
+	if !s.IsPartitioned() {
+		return mat.PartitionBy == ""
+	}
+
+	if s.Field != partitionColumn(mat.PartitionBy) {
+		return false
+	}
+
+	if expectedPartitionType(asset) == PartitioningTypeRange {
+		r := mat.PartitionRange
+		return s.Type == PartitioningTypeRange && r != nil && s.RangeStart == r.Start && s.RangeEnd == r.End && s.RangeInterval == r.Interval
+	}
+
+	if s.Type != PartitioningTypeTime {
+		// the range is only known to differ if the asset asks for time partitioning explicitly
+		return mat.PartitionType == pipeline.PartitionTypeNone
+	}
+
+	// BigQuery partitions by day unless told otherwise
+	granularity := s.Granularity
+	if granularity == "" {
+		granularity = bigquery.DayPartitioningType
+	}
+
+	return granularity == expectedPartitionGranularity(asset)
+}
+
+// partitionGranularityRegex matches the granularity argument of truncating partitioning expressions, such as
+// MONTH in `DATE_TRUNC(created_at, MONTH)`.
+var partitionGranularityRegex = regexp.MustCompile(`(?i),\s*(HOUR|DAY|MONTH|YEAR)\s*\)\s*$`)
+
+// expectedPartitionType returns how the asset table is expected to be partitioned: by range if the asset gives a
+// partition range or asks for it, by time otherwise, and not at all without partition_by.
+func expectedPartitionType(asset *pipeline.Asset) string {
+	mat := asset.Materialization
+	switch {
+	case mat.PartitionBy == "":
+		return PartitioningTypeNone
+	case mat.PartitionRange != nil || mat.PartitionType == pipeline.PartitionTypeRange:
+		return PartitioningTypeRange
+	default:
+		return PartitioningTypeTime
+	}
+}
+
+// expectedPartitionGranularity returns the size of the time partitions of the asset table: the configured
+// granularity, the one of the truncating partitioning expression otherwise, and a day by default.
+func expectedPartitionGranularity(asset *pipeline.Asset) bigquery.TimePartitioningType {
+	mat := asset.Materialization
+	if mat.PartitionGranularity != pipeline.PartitionGranularityNone {
+		return bigquery.TimePartitioningType(strings.ToUpper(string(mat.PartitionGranularity)))
+	}
+	if matches := partitionGranularityRegex.FindStringSubmatch(mat.PartitionBy); matches != nil {
+		return bigquery.TimePartitioningType(strings.ToUpper(matches[1]))
+	}
+
+	return bigquery.DayPartitioningType
+}
+
+// partitionColumn returns the column of the partitioning expression, e.g. created_at for `DATE(created_at)`.
+func partitionColumn(partitionBy string) string {
+	matches := partitionExpressionRegex.FindStringSubmatch(strings.TrimSpace(partitionBy))
+	if matches == nil {
+		return partitionBy
+	}
+
+	return matches[2]
+}
+
+// MatchesClustering reports whether the table is clustered by the same fields, in the same order, as the asset.
+func (s *PartitioningSpec) MatchesClustering(asset *pipeline.Asset) bool {
+	userFields := asset.Materialization.ClusterBy
+	if len(s.ClusterFields) != len(userFields) {
+		return false
+	}
+
+	for i := range s.ClusterFields {
+		if s.ClusterFields[i] != userFields[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// MatchesPartitionOptions reports whether the partition expiration and the partition filter requirement of the
+// table are the ones the asset declares. Unlike the partitioning itself, both can be changed without recreating the
+// table, see PartitionOptionsUpdate. Options the asset does not declare, i.e. no expiration or no filter requirement,
+// are left to the table, like the expiration of the table is unless the asset sets never_expire.
+func (s *PartitioningSpec) MatchesPartitionOptions(asset *pipeline.Asset) bool {
+	if !s.IsPartitioned() {
+		return true
+	}
+
+	mat := asset.Materialization
+	if mat.RequirePartitionFilter && !s.RequirePartitionFilter {
+		return false
+	}
+
+	return s.Type != PartitioningTypeTime || mat.PartitionExpirationDays == 0 || s.Expiration == partitionExpiration(asset)
+}
+
+// PartitionOptionsUpdate returns the metadata update that sets the partition options the asset declares on the
+// table, or nil if they already match, see MatchesPartitionOptions. It assumes the table is partitioned the way the
+// asset expects, a different partitioning requires the table to be recreated instead.
+func PartitionOptionsUpdate(meta *bigquery.TableMetadata, asset *pipeline.Asset) *bigquery.TableMetadataToUpdate {
+	spec := PartitioningSpecFromMetadata(meta)
+	if spec.MatchesPartitionOptions(asset) {
+		return nil
+	}
+
+	mat := asset.Materialization
+	update := &bigquery.TableMetadataToUpdate{}
+	if mat.RequirePartitionFilter && !spec.RequirePartitionFilter {
+		update.RequirePartitionFilter = true
+	}
+
+	if spec.Type == PartitioningTypeTime && mat.PartitionExpirationDays != 0 && spec.Expiration != partitionExpiration(asset) {
+		update.TimePartitioning = &bigquery.TimePartitioning{
+			Type:                   meta.TimePartitioning.Type,
+			Field:                  meta.TimePartitioning.Field,
+			Expiration:             partitionExpiration(asset),
+			RequirePartitionFilter: spec.RequirePartitionFilter || mat.RequirePartitionFilter,
+		}
+	}
+
+	return update
+}
+
+// ClusteringUpdate returns the clustering to set on the table for it to match the asset when only the clustering
+// columns differ, which BigQuery allows changing in place: new rows are clustered by the new columns and the
+// existing ones are reclustered in the background. It returns nil if the clustering matches, and if the table must
+// be recreated instead, i.e. if the partitioning differs too, if the table is not a regular table, or if the asset
+// removes the clustering.
+func ClusteringUpdate(meta *bigquery.TableMetadata, asset *pipeline.Asset) *bigquery.Clustering {
+	spec := PartitioningSpecFromMetadata(meta)
+	if spec.MatchesClustering(asset) || !spec.MatchesPartitioning(asset) {
+		return nil
+	}
+	if meta.Type != bigquery.RegularTable || len(asset.Materialization.ClusterBy) == 0 {
+		return nil
+	}
+
+	return &bigquery.Clustering{Fields: slices.Clone(asset.Materialization.ClusterBy)}
+}
+
+func partitionExpiration(asset *pipeline.Asset) time.Duration {
+	return time.Duration(asset.Materialization.PartitionExpirationDays) * 24 * time.Hour
+}
+
+// GetPartitioningSpec reads the partitioning and clustering configuration of the given table.
+func (d *Client) GetPartitioningSpec(ctx context.Context, tableName string) (*PartitioningSpec, error) {
+	tableRef, err := d.getTableRef(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := tableRef.Metadata(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(formatError(err), "failed to fetch metadata for table '%s'", tableName)
+	}
+
+	return PartitioningSpecFromMetadata(meta), nil
+}
+
+// PartitionLimitWarning is an advisory result that signals a partitioned table is at risk of hitting the
+// partition count limit of BigQuery.
+type PartitionLimitWarning struct {
+	Table          string
+	PartitionBy    string
+	PartitionCount int64
+	Threshold      int64
+}
+
+func (w PartitionLimitWarning) String() string {
+	return fmt.Sprintf(
+		"table '%s' would have %d partitions on '%s', which is above the warning threshold of %d (BigQuery allows at most %d); consider partitioning by month, e.g. `DATE_TRUNC(%s, MONTH)`, or clustering instead",
+		w.Table, w.PartitionCount, w.PartitionBy, w.Threshold, MaxPartitionsPerTable, w.PartitionBy,
+	)
+}
+
+// BuildPartitionCountQuery builds a query that counts the distinct partitions the given query would produce.
+func BuildPartitionCountQuery(partitionBy, query string) string {
+	return fmt.Sprintf("SELECT COUNT(DISTINCT %s) FROM (\n%s\n)", quotePartitionColumn(partitionBy), strings.TrimSuffix(strings.TrimSpace(query), ";"))
+}
+
+// CheckPartitionLimit counts the partitions the asset query would produce and returns a warning if the count
+// is above the configured threshold. The check is disabled unless Config.PartitionCountWarningThreshold is set,
+// since it needs to run the asset query once more.
+func (d *Client) CheckPartitionLimit(ctx context.Context, asset *pipeline.Asset, assetQuery string) (*PartitionLimitWarning, error) {
+	if d.config == nil || d.config.PartitionCountWarningThreshold <= 0 {
+		return nil, nil
+	}
+
+	if asset.Materialization.Type != pipeline.MaterializationTypeTable || asset.Materialization.PartitionBy == "" {
+		return nil, nil
+	}
+
+	res, err := d.Select(ctx, &query.Query{Query: BuildPartitionCountQuery(partitionExpression(asset), assetQuery)})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to count the partitions of '%s'", asset.Name)
+	}
+
+	count, err := helpers.CastResultToInteger(res)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the partition count of '%s'", asset.Name)
+	}
+
+	if count <= d.config.PartitionCountWarningThreshold {
+		return nil, nil
+	}
+
+	return &PartitionLimitWarning{
+		Table:          asset.Name,
+		PartitionBy:    asset.Materialization.PartitionBy,
+		PartitionCount: count,
+		Threshold:      d.config.PartitionCountWarningThreshold,
+	}, nil
+}
+
+// CheckPartitionFilter dry-runs the asset query to check that it filters on the partition column of every table it
+// reads that has require_partition_filter set. It returns a PartitionFilterRequiredError naming the partition column
+// of the first table read without such a filter, instead of the generic error BigQuery reports for it.
+func (d *Client) CheckPartitionFilter(ctx context.Context, asset *pipeline.Asset, assetQuery string) error {
+	_, err := d.dryRun(ctx, &query.Query{Query: assetQuery})
+	if err == nil {
+		return nil
+	}
+
+	var filterErr *PartitionFilterRequiredError
+	if errors.As(err, &filterErr) {
+		return filterErr
+	}
+
+	return errors.Wrapf(err, "failed to dry run the query of '%s'", asset.Name)
+}
@@ -0,0 +1,572 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestBuildPartitionCountQuery(t *testing.T) {
+	t.Parallel()
+
+	got := BuildPartitionCountQuery("DATE(created_at)", "SELECT * FROM events;\n")
+	assert.Equal(t, "SELECT COUNT(DISTINCT DATE(created_at)) FROM (\nSELECT * FROM events\n)", got)
+}
+
+func TestClient_CheckPartitionLimit(t *testing.T) {
+	t.Parallel()
+
+	partitionedAsset := &pipeline.Asset{
+		Name: "dataset.events",
+		Materialization: pipeline.Materialization{
+			Type:        pipeline.MaterializationTypeTable,
+			PartitionBy: "event_date",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		threshold   int64
+		asset       *pipeline.Asset
+		count       string
+		wantWarning bool
+		wantQueries int
+	}{
+		{
+			name:        "check is disabled by default",
+			asset:       partitionedAsset,
+			count:       "20000",
+			wantQueries: 0,
+		},
+		{
+			name:        "unpartitioned tables are not checked",
+			threshold:   4000,
+			asset:       &pipeline.Asset{Name: "dataset.events", Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable}},
+			count:       "20000",
+			wantQueries: 0,
+		},
+		{
+			name:        "below the threshold",
+			threshold:   4000,
+			asset:       partitionedAsset,
+			count:       "3650",
+			wantQueries: 1,
+		},
+		{
+			name:        "above the threshold",
+			threshold:   4000,
+			asset:       partitionedAsset,
+			count:       "9000",
+			wantWarning: true,
+			wantQueries: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &recordingQueryHandler{
+				response: func(q string) *bigquery2.QueryResponse {
+					resp := stringQueryResponse(tt.count)
+					resp.Schema.Fields[0].Type = "INTEGER"
+					return resp
+				},
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.PartitionCountWarningThreshold = tt.threshold
+
+			warning, err := d.CheckPartitionLimit(context.Background(), tt.asset, "SELECT * FROM raw.events")
+			require.NoError(t, err)
+			assert.Len(t, handler.recordedQueries(), tt.wantQueries)
+
+			if !tt.wantWarning {
+				assert.Nil(t, warning)
+				return
+			}
+
+			require.NotNil(t, warning)
+			assert.Equal(t, int64(9000), warning.PartitionCount)
+			assert.Contains(t, warning.String(), "consider partitioning by month")
+		})
+	}
+}
+
+func TestPartitioningSpecFromMetadata(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		meta *bigquery.TableMetadata
+		want *PartitioningSpec
+	}{
+		{
+			name: "no partitioning",
+			meta: &bigquery.TableMetadata{},
+			want: &PartitioningSpec{Type: PartitioningTypeNone, ClusterFields: []string{}},
+		},
+		{
+			name: "time partitioning with clustering",
+			meta: &bigquery.TableMetadata{
+				TimePartitioning: &bigquery.TimePartitioning{
+					Type:       bigquery.MonthPartitioningType,
+					Field:      "created_at",
+					Expiration: 90 * 24 * time.Hour,
+				},
+				RequirePartitionFilter: true,
+				Clustering:             &bigquery.Clustering{Fields: []string{"country", "city"}},
+			},
+			want: &PartitioningSpec{
+				Type:                   PartitioningTypeTime,
+				Field:                  "created_at",
+				Granularity:            bigquery.MonthPartitioningType,
+				Expiration:             90 * 24 * time.Hour,
+				RequirePartitionFilter: true,
+				ClusterFields:          []string{"country", "city"},
+			},
+		},
+		{
+			name: "range partitioning",
+			meta: &bigquery.TableMetadata{
+				RangePartitioning: &bigquery.RangePartitioning{
+					Field: "customer_id",
+					Range: &bigquery.RangePartitioningRange{Start: 0, End: 1000, Interval: 10},
+				},
+			},
+			want: &PartitioningSpec{
+				Type:          PartitioningTypeRange,
+				Field:         "customer_id",
+				RangeStart:    0,
+				RangeEnd:      1000,
+				RangeInterval: 10,
+				ClusterFields: []string{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, PartitioningSpecFromMetadata(tt.meta))
+		})
+	}
+}
+
+func TestIsSamePartitioning_Range(t *testing.T) {
+	t.Parallel()
+
+	meta := &bigquery.TableMetadata{
+		RangePartitioning: &bigquery.RangePartitioning{
+			Field: "customer_id",
+			Range: &bigquery.RangePartitioningRange{Start: 0, End: 1000, Interval: 10},
+		},
+	}
+
+	tests := []struct {
+		name string
+		mat  pipeline.Materialization
+		want bool
+	}{
+		{
+			name: "same range",
+			mat:  pipeline.Materialization{PartitionBy: "customer_id", PartitionRange: &pipeline.PartitionRange{Start: 0, End: 1000, Interval: 10}},
+			want: true,
+		},
+		{
+			name: "changed interval",
+			mat:  pipeline.Materialization{PartitionBy: "customer_id", PartitionRange: &pipeline.PartitionRange{Start: 0, End: 1000, Interval: 100}},
+			want: false,
+		},
+		{
+			name: "different field",
+			mat:  pipeline.Materialization{PartitionBy: "order_id", PartitionRange: &pipeline.PartitionRange{Start: 0, End: 1000, Interval: 10}},
+			want: false,
+		},
+		{
+			name: "only the field is given",
+			mat:  pipeline.Materialization{PartitionBy: "customer_id"},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, IsSamePartitioning(meta, &pipeline.Asset{Materialization: tt.mat}))
+		})
+	}
+
+	timePartitioned := &bigquery.TableMetadata{TimePartitioning: &bigquery.TimePartitioning{Field: "customer_id"}}
+	assert.False(t, IsSamePartitioning(timePartitioned, &pipeline.Asset{Materialization: tests[0].mat}))
+}
+
+func TestPartitionOptionsUpdate(t *testing.T) {
+	t.Parallel()
+
+	meta := &bigquery.TableMetadata{
+		TimePartitioning: &bigquery.TimePartitioning{
+			Type:       bigquery.DayPartitioningType,
+			Field:      "created_at",
+			Expiration: 30 * 24 * time.Hour,
+		},
+		RequirePartitionFilter: true,
+	}
+
+	tests := []struct {
+		name string
+		mat  pipeline.Materialization
+		want *bigquery.TableMetadataToUpdate
+	}{
+		{
+			name: "options match",
+			mat:  pipeline.Materialization{PartitionBy: "created_at", PartitionExpirationDays: 30, RequirePartitionFilter: true},
+		},
+		{
+			name: "changed expiration",
+			mat:  pipeline.Materialization{PartitionBy: "created_at", PartitionExpirationDays: 90, RequirePartitionFilter: true},
+			want: &bigquery.TableMetadataToUpdate{
+				TimePartitioning: &bigquery.TimePartitioning{
+					Type:                   bigquery.DayPartitioningType,
+					Field:                  "created_at",
+					Expiration:             90 * 24 * time.Hour,
+					RequirePartitionFilter: true,
+				},
+			},
+		},
+		{
+			name: "options the asset does not declare are kept",
+			mat:  pipeline.Materialization{PartitionBy: "created_at"},
+		},
+		{
+			name: "the expiration is kept without the filter requirement",
+			mat:  pipeline.Materialization{PartitionBy: "created_at", PartitionExpirationDays: 30},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, PartitionOptionsUpdate(meta, &pipeline.Asset{Materialization: tt.mat}))
+		})
+	}
+
+	// only the declared filter requirement is set on a table without one, its expiration stays
+	withoutFilter := &bigquery.TableMetadata{TimePartitioning: meta.TimePartitioning}
+	assert.Equal(t, &bigquery.TableMetadataToUpdate{RequirePartitionFilter: true},
+		PartitionOptionsUpdate(withoutFilter, &pipeline.Asset{Materialization: pipeline.Materialization{PartitionBy: "created_at", RequirePartitionFilter: true}}))
+
+	// unpartitioned tables have no partition options to update
+	assert.Nil(t, PartitionOptionsUpdate(&bigquery.TableMetadata{}, &pipeline.Asset{Materialization: tests[1].mat}))
+}
+
+func TestClient_DropTableOnMismatch_PartitionExpiration(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var patched *bigquery2.Table
+	deleted := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(&bigquery2.Table{
+				Type:             "TABLE",
+				Etag:             "etag-1",
+				TimePartitioning: &bigquery2.TimePartitioning{Type: "DAY", Field: "created_at", ExpirationMs: 30 * 24 * 3600 * 1000},
+			})
+		case http.MethodPatch:
+			patched = &bigquery2.Table{}
+			_ = json.NewDecoder(r.Body).Decode(patched)
+			_ = json.NewEncoder(w).Encode(patched)
+		case http.MethodDelete:
+			deleted = true
+		default:
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	err := d.DropTableOnMismatch(context.Background(), "dataset.events", &pipeline.Asset{
+		Name: "dataset.events",
+		Materialization: pipeline.Materialization{
+			Type:                    pipeline.MaterializationTypeTable,
+			PartitionBy:             "created_at",
+			PartitionExpirationDays: 90,
+		},
+	})
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.False(t, deleted, "the table must not be recreated for an expiration change")
+	require.NotNil(t, patched)
+	assert.Equal(t, int64(90*24*3600*1000), patched.TimePartitioning.ExpirationMs)
+	assert.Equal(t, "created_at", patched.TimePartitioning.Field)
+}
+
+func TestClient_DropTableOnMismatch_NeverExpire(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		expiration  int64
+		neverExpire bool
+		wantPatch   bool
+	}{
+		{
+			name:        "the expiration of the table is removed",
+			expiration:  time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).UnixMilli(),
+			neverExpire: true,
+			wantPatch:   true,
+		},
+		{
+			name:        "tables without an expiration are left alone",
+			neverExpire: true,
+		},
+		{
+			name:       "the expiration is kept unless the asset opts out",
+			expiration: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).UnixMilli(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var patched map[string]json.RawMessage
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				switch r.Method {
+				case http.MethodGet:
+					_ = json.NewEncoder(w).Encode(&bigquery2.Table{Type: "TABLE", Etag: "etag-1", ExpirationTime: tt.expiration})
+				case http.MethodPatch:
+					_ = json.NewDecoder(r.Body).Decode(&patched)
+					_ = json.NewEncoder(w).Encode(&bigquery2.Table{})
+				default:
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			err := d.DropTableOnMismatch(context.Background(), "dataset.permanent", &pipeline.Asset{
+				Name:            "dataset.permanent",
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable, NeverExpire: tt.neverExpire},
+			})
+			require.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !tt.wantPatch {
+				assert.Nil(t, patched)
+				return
+			}
+			require.Contains(t, patched, "expirationTime")
+			assert.Equal(t, "null", string(patched["expirationTime"]))
+		})
+	}
+}
+
+func TestClient_GetPartitioningSpec(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/projects/%s/datasets/dataset/tables/events", testProjectID):
+			_ = json.NewEncoder(w).Encode(&bigquery2.Table{
+				TimePartitioning: &bigquery2.TimePartitioning{Type: "DAY", Field: "event_date"},
+				Clustering:       &bigquery2.Clustering{Fields: []string{"user_id"}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table test-project:dataset.missing"}}`))
+		}
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	spec, err := d.GetPartitioningSpec(context.Background(), "dataset.events")
+	require.NoError(t, err)
+	assert.Equal(t, PartitioningTypeTime, spec.Type)
+	assert.Equal(t, "event_date", spec.Field)
+	assert.Equal(t, bigquery.DayPartitioningType, spec.Granularity)
+	assert.True(t, spec.IsPartitioned())
+	assert.True(t, spec.IsClustered())
+
+	asset := &pipeline.Asset{Materialization: pipeline.Materialization{PartitionBy: "event_date", ClusterBy: []string{"user_id"}}}
+	assert.True(t, spec.MatchesPartitioning(asset))
+	assert.True(t, spec.MatchesClustering(asset))
+
+	_, err = d.GetPartitioningSpec(context.Background(), "dataset.missing")
+	require.EqualError(t, err, "failed to fetch metadata for table 'dataset.missing': Not found: Table test-project:dataset.missing")
+}
+
+func TestClient_CheckPartitionFilter(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var job bigquery2.Job
+		_ = json.NewDecoder(r.Body).Decode(&job)
+
+		switch {
+		case strings.Contains(job.Configuration.Query.Query, "WHERE event_date"):
+			job.JobReference = &bigquery2.JobReference{ProjectId: testProjectID, JobId: "job-1"}
+			job.Status = &bigquery2.JobStatus{State: "DONE"}
+			job.Statistics = &bigquery2.JobStatistics{TotalBytesProcessed: 1024}
+			_ = json.NewEncoder(w).Encode(&job)
+		case strings.Contains(job.Configuration.Query.Query, "raw.events"):
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"code": 400, "message": "Cannot query over table 'test-project.raw.events' without a filter over column(s) 'event_date' that can be used for partition elimination", "errors": [{"reason": "invalidQuery"}]}}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"code": 400, "message": "Syntax error: Unexpected end of script", "errors": [{"reason": "invalidQuery"}]}}`))
+		}
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	asset := &pipeline.Asset{Name: "mart.daily_events"}
+
+	require.NoError(t, d.CheckPartitionFilter(context.Background(), asset, "SELECT * FROM raw.events WHERE event_date = '2024-01-01'"))
+
+	err := d.CheckPartitionFilter(context.Background(), asset, "SELECT * FROM raw.events")
+	var filterErr *PartitionFilterRequiredError
+	require.ErrorAs(t, err, &filterErr)
+	assert.Equal(t, []string{"event_date"}, filterErr.Columns)
+	require.EqualError(t, err, "this query must include a filter on partition column 'event_date', table 'test-project.raw.events' requires a partition filter to avoid scanning all of its partitions")
+
+	err = d.CheckPartitionFilter(context.Background(), asset, "SELECT")
+	require.EqualError(t, err, "failed to dry run the query of 'mart.daily_events': Syntax error: Unexpected end of script")
+}
+
+func TestClient_DropTableOnMismatch_Clustering(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		tableType      string
+		partitionBy    string
+		clusterBy      []string
+		wantDeleted    bool
+		wantClustering []string
+	}{
+		{
+			name:           "clustering column order changed in place",
+			tableType:      "TABLE",
+			partitionBy:    "created_at",
+			clusterBy:      []string{"user_id", "country"},
+			wantClustering: []string{"user_id", "country"},
+		},
+		{
+			name:           "clustering columns added in place",
+			tableType:      "TABLE",
+			partitionBy:    "created_at",
+			clusterBy:      []string{"country", "user_id", "device"},
+			wantClustering: []string{"country", "user_id", "device"},
+		},
+		{
+			name:        "partitioning changed as well",
+			tableType:   "TABLE",
+			partitionBy: "updated_at",
+			clusterBy:   []string{"user_id", "country"},
+			wantDeleted: true,
+		},
+		{
+			name:        "clustering removed",
+			tableType:   "TABLE",
+			partitionBy: "created_at",
+			wantDeleted: true,
+		},
+		{
+			name:        "clustering of a materialized view",
+			tableType:   "MATERIALIZED_VIEW",
+			partitionBy: "created_at",
+			clusterBy:   []string{"user_id", "country"},
+			wantDeleted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var patched *bigquery2.Table
+			deleted := false
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				switch r.Method {
+				case http.MethodGet:
+					_ = json.NewEncoder(w).Encode(&bigquery2.Table{
+						Type:             tt.tableType,
+						Etag:             "etag-1",
+						TimePartitioning: &bigquery2.TimePartitioning{Type: "DAY", Field: "created_at"},
+						Clustering:       &bigquery2.Clustering{Fields: []string{"country", "user_id"}},
+					})
+				case http.MethodPatch:
+					patched = &bigquery2.Table{}
+					_ = json.NewDecoder(r.Body).Decode(patched)
+					_ = json.NewEncoder(w).Encode(patched)
+				case http.MethodDelete:
+					deleted = true
+				default:
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			matType := pipeline.MaterializationTypeTable
+			if tt.tableType == "MATERIALIZED_VIEW" {
+				matType = pipeline.MaterializationTypeMaterializedView
+			}
+			err := d.DropTableOnMismatch(context.Background(), "dataset.events", &pipeline.Asset{
+				Name: "dataset.events",
+				Materialization: pipeline.Materialization{
+					Type:        matType,
+					PartitionBy: tt.partitionBy,
+					ClusterBy:   tt.clusterBy,
+				},
+			})
+			require.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tt.wantDeleted, deleted)
+			if tt.wantDeleted {
+				assert.Nil(t, patched)
+				return
+			}
+			require.NotNil(t, patched)
+			require.NotNil(t, patched.Clustering)
+			assert.Equal(t, tt.wantClustering, patched.Clustering.Fields)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package bigquery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+)
+
+// clientPool shares the underlying BigQuery clients, and with them their connections, between the Clients created
+// with the same project, credentials and location.
+var clientPool = &sharedClients{clients: make(map[string]*sharedClient)}
+
+type sharedClient struct {
+	client *bigquery.Client
+	refs   int
+}
+
+type sharedClients struct {
+	mu      sync.Mutex
+	clients map[string]*sharedClient
+}
+
+// acquire returns the client registered for the key, creating it if there is none, and takes a reference on it.
+func (p *sharedClients) acquire(key string, create func() (*bigquery.Client, error)) (*bigquery.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if shared, ok := p.clients[key]; ok {
+		shared.refs++
+		return shared.client, nil
+	}
+
+	client, err := create()
+	if err != nil {
+		return nil, err
+	}
+
+	p.clients[key] = &sharedClient{client: client, refs: 1}
+	return client, nil
+}
+
+// release drops a reference on the client registered for the key, closing it once the last one is gone.
+func (p *sharedClients) release(key string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	shared, ok := p.clients[key]
+	if !ok {
+		return nil
+	}
+
+	shared.refs--
+	if shared.refs > 0 {
+		return nil
+	}
+
+	delete(p.clients, key)
+	return errors.Wrap(shared.client.Close(), "failed to close bigquery client")
+}
+
+// clientPoolKey hashes the settings the underlying client is created from. Credentials given as an object are
+// compared by their JSON; the ones without it, e.g. built from a token source, cannot be told apart by value, their
+// clients are not pooled and the key is empty.
+func clientPoolKey(c *Config) (string, error) {
+	credentials := ""
+	if c.CredentialsJSON == "" && c.CredentialsFilePath == "" && c.Credentials != nil {
+		if len(c.Credentials.JSON) == 0 {
+			return "", nil
+		}
+		credentials = string(c.Credentials.JSON)
+	}
+
+	workloadIdentity, err := json.Marshal(c.WorkloadIdentity)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to hash the workload identity configuration")
+	}
+
+	h := sha256.New()
+	for _, part := range []string{
+		c.ProjectID,
+		c.Location,
+		c.CredentialsJSON,
+		c.CredentialsFilePath,
+		credentials,
+		string(workloadIdentity),
+	} {
+		_, _ = fmt.Fprintf(h, "%d:%s", len(part), part)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Close releases the client. The underlying BigQuery client is shared between the clients created with the same
+// connection settings, it is only closed once all of them are closed. Closing a client more than once is a no-op.
+func (d *Client) Close() error {
+	if !d.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	if d.poolKey == "" {
+		return d.client.Close()
+	}
+
+	return clientPool.release(d.poolKey)
+}
@@ -0,0 +1,68 @@
+package bigquery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+func TestNewDB_SharesClients(t *testing.T) {
+	t.Parallel()
+
+	newCreds := func() *google.Credentials {
+		return &google.Credentials{
+			ProjectID:   "pool-project",
+			TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "some-token"}),
+			JSON:        []byte(`{"type": "service_account", "client_email": "pool@pool-project.iam.gserviceaccount.com"}`),
+		}
+	}
+	creds := newCreds()
+
+	first, err := NewDB(&Config{ProjectID: "pool-project", Location: "EU", Credentials: creds})
+	require.NoError(t, err)
+	// credentials are compared by their JSON rather than by their address
+	second, err := NewDB(&Config{ProjectID: "pool-project", Location: "EU", Credentials: newCreds(), MaximumBytesBilled: 100})
+	require.NoError(t, err)
+	otherLocation, err := NewDB(&Config{ProjectID: "pool-project", Location: "US", Credentials: creds})
+	require.NoError(t, err)
+
+	assert.Same(t, first.client, second.client)
+	assert.NotSame(t, first.client, otherLocation.client)
+	assert.Equal(t, "EU", first.client.Location)
+
+	clientPool.mu.Lock()
+	assert.Equal(t, 2, clientPool.clients[first.poolKey].refs)
+	clientPool.mu.Unlock()
+
+	// the shared client stays open until its last user is closed, closing twice does not release it twice
+	require.NoError(t, first.Close())
+	require.NoError(t, first.Close())
+
+	clientPool.mu.Lock()
+	assert.Equal(t, 1, clientPool.clients[second.poolKey].refs)
+	clientPool.mu.Unlock()
+
+	require.NoError(t, second.Close())
+	require.NoError(t, otherLocation.Close())
+
+	clientPool.mu.Lock()
+	assert.NotContains(t, clientPool.clients, first.poolKey)
+	assert.NotContains(t, clientPool.clients, otherLocation.poolKey)
+	clientPool.mu.Unlock()
+
+	// a new client is created once the shared one was closed
+	third, err := NewDB(&Config{ProjectID: "pool-project", Location: "EU", Credentials: creds})
+	require.NoError(t, err)
+	assert.NotSame(t, first.client, third.client)
+	require.NoError(t, third.Close())
+
+	// credentials without JSON cannot be told apart, their clients are not shared
+	tokenOnly := &google.Credentials{TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "some-token"})}
+	unpooled, err := NewDB(&Config{ProjectID: "pool-project", Location: "EU", Credentials: tokenOnly})
+	require.NoError(t, err)
+	assert.Empty(t, unpooled.poolKey)
+	require.NoError(t, unpooled.Close())
+}
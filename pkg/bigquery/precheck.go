@@ -0,0 +1,50 @@
+package bigquery
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// unrenderedTemplateRegex matches the Jinja variable references and tags left in a query that was not fully
+// rendered, e.g. `{{ start_date }}` or `{% if full_refresh %}`. Only plain identifiers are matched as variables so
+// that literals such as the `{2,3}` quantifiers of a regular expression are not mistaken for them.
+var unrenderedTemplateRegex = regexp.MustCompile(`\{\{\s*([A-Za-z_][\w.]*)\s*}}|\{%.*?%}`)
+
+// PrecheckQuery runs cheap local checks that catch obviously broken queries without calling BigQuery: empty
+// queries, unbalanced parentheses and template variables that were not rendered. Passing the checks does not
+// mean the query is valid, only a dry run can tell that.
+func PrecheckQuery(query string) error {
+	tokens := stripLiteralsAndComments(query)
+	if strings.TrimSpace(strings.ReplaceAll(tokens, ";", "")) == "" {
+		return errors.New("query is empty")
+	}
+
+	// template syntax in literals and comments, e.g. a Jinja snippet in a string, is part of the data
+	if match := unrenderedTemplateRegex.FindStringSubmatch(tokens); match != nil {
+		if match[1] != "" {
+			return fmt.Errorf("query references the undefined template variable '%s'", match[1])
+		}
+		return fmt.Errorf("query contains the unrendered template tag '%s'", match[0])
+	}
+
+	depth := 0
+	for _, c := range tokens {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return errors.New("unbalanced parentheses: ')' without a matching '('")
+			}
+		}
+	}
+	if depth > 0 {
+		return fmt.Errorf("unbalanced parentheses: %d '(' not closed", depth)
+	}
+
+	return nil
+}
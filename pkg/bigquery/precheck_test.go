@@ -0,0 +1,64 @@
+package bigquery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrecheckQuery(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{
+			name:  "valid query",
+			query: "SELECT COUNT(*) FROM (SELECT id FROM t WHERE REGEXP_CONTAINS(name, r'a{2,3}(')) -- (",
+		},
+		{
+			name:    "empty query",
+			query:   "  -- just a comment\n;\n",
+			wantErr: "query is empty",
+		},
+		{
+			name:    "unclosed parenthesis",
+			query:   "SELECT COUNT(* FROM (SELECT 1)",
+			wantErr: "unbalanced parentheses: 1 '(' not closed",
+		},
+		{
+			name:    "unexpected closing parenthesis",
+			query:   "SELECT 1) FROM t",
+			wantErr: "unbalanced parentheses: ')' without a matching '('",
+		},
+		{
+			name:    "unrendered variable",
+			query:   "SELECT * FROM t WHERE dt = DATE({{ start_dat }})",
+			wantErr: "query references the undefined template variable 'start_dat'",
+		},
+		{
+			name:  "template syntax in literals and comments",
+			query: "SELECT '{{ name }}' AS template, \"{% raw %}\" AS tag FROM t -- {{ start_date }}\n/* {% if x %} */",
+		},
+		{
+			name:    "unrendered tag",
+			query:   "SELECT * FROM t {% if full_refresh %}WHERE 1 = 1{% endif %}",
+			wantErr: "query contains the unrendered template tag '{% if full_refresh %}'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := PrecheckQuery(tt.query)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+)
+
+// unorderedFieldTypes are the types that have no ordering and cannot be grouped, which rules out MIN, MAX and
+// APPROX_COUNT_DISTINCT on them. Repeated fields are not orderable either.
+var unorderedFieldTypes = map[bigquery.FieldType]bool{
+	bigquery.RecordFieldType:    true,
+	bigquery.GeographyFieldType: true,
+	bigquery.JSONFieldType:      true,
+	bigquery.RangeFieldType:     true,
+}
+
+// ColumnProfile holds the statistics of a single column.
+type ColumnProfile struct {
+	// Count is the number of non-NULL values.
+	Count     int64
+	NullCount int64
+
+	// DistinctCount is an approximation with an error of about 1%, see APPROX_COUNT_DISTINCT.
+	DistinctCount int64
+	Min           interface{}
+	Max           interface{}
+
+	// CountsOnly is set for the columns whose type cannot be ordered, e.g. STRUCT, ARRAY, GEOGRAPHY or JSON,
+	// for which only Count and NullCount are computed.
+	CountsOnly bool
+}
+
+// BuildColumnProfileQuery builds a query that computes the statistics of all the given columns of the table in a
+// single pass. The statistics of the i-th column are aliased with the `c<i>_` prefix.
+func BuildColumnProfileQuery(table *ResolvedTable, fields []*bigquery.FieldSchema) string {
+	aggregates := make([]string, 0, len(fields)*5)
+	for i, field := range fields {
+		column := QuoteIdentifier(field.Name)
+		aggregates = append(aggregates,
+			fmt.Sprintf("COUNT(%s) AS c%d_count", column, i),
+			fmt.Sprintf("COUNTIF(%s IS NULL) AS c%d_nulls", column, i),
+		)
+		if isProfileOrderable(field) {
+			aggregates = append(aggregates,
+				fmt.Sprintf("APPROX_COUNT_DISTINCT(%s) AS c%d_distinct", column, i),
+				fmt.Sprintf("MIN(%s) AS c%d_min", column, i),
+				fmt.Sprintf("MAX(%s) AS c%d_max", column, i),
+			)
+		}
+	}
+
+	return fmt.Sprintf("SELECT\n  %s\nFROM %s", strings.Join(aggregates, ",\n  "), QuoteIdentifier(table.String()))
+}
+
+func isProfileOrderable(field *bigquery.FieldSchema) bool {
+	return !field.Repeated && !unorderedFieldTypes[field.Type]
+}
+
+// ProfileColumns computes the count, NULL count, approximate distinct count, minimum and maximum of the given top-level
+// columns of the table with a single query; all the columns are profiled if none are given. The profiles are keyed by
+// the column names as given.
+func (d *Client) ProfileColumns(ctx context.Context, tableName string, columns []string) (map[string]ColumnProfile, error) {
+	// the statistics are computed on the same table the schema is read from
+	resolved, err := d.ResolveTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := d.client.DatasetInProject(resolved.ProjectID, resolved.DatasetID).Table(resolved.TableID).Metadata(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(formatError(err), "failed to fetch metadata for table '%s'", tableName)
+	}
+
+	fieldsByName := make(map[string]*bigquery.FieldSchema, len(meta.Schema))
+	for _, field := range meta.Schema {
+		// column names are case-insensitive in BigQuery
+		fieldsByName[strings.ToLower(field.Name)] = field
+	}
+
+	if len(columns) == 0 {
+		columns = make([]string, 0, len(meta.Schema))
+		for _, field := range meta.Schema {
+			columns = append(columns, field.Name)
+		}
+	}
+
+	fields := make([]*bigquery.FieldSchema, 0, len(columns))
+	for _, column := range columns {
+		field, ok := fieldsByName[strings.ToLower(column)]
+		if !ok {
+			return nil, fmt.Errorf("column '%s' does not exist in table '%s'", column, tableName)
+		}
+		fields = append(fields, field)
+	}
+
+	rows, err := d.Select(ctx, &query.Query{Query: BuildColumnProfileQuery(resolved, fields)})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to profile the columns of table '%s'", tableName)
+	}
+	if len(rows) != 1 {
+		return nil, fmt.Errorf("expected a single row of column statistics, got %d", len(rows))
+	}
+
+	profiles := make(map[string]ColumnProfile, len(columns))
+	values := rows[0]
+	for i, column := range columns {
+		profile := ColumnProfile{CountsOnly: !isProfileOrderable(fields[i])}
+
+		n := 2
+		if !profile.CountsOnly {
+			n = 5
+		}
+		if len(values) < n {
+			return nil, fmt.Errorf("missing statistics for column '%s'", column)
+		}
+
+		profile.Count, _ = values[0].(int64)
+		profile.NullCount, _ = values[1].(int64)
+		if !profile.CountsOnly {
+			profile.DistinctCount, _ = values[2].(int64)
+			profile.Min = values[3]
+			profile.Max = values[4]
+		}
+
+		profiles[column] = profile
+		values = values[n:]
+	}
+
+	return profiles, nil
+}
@@ -0,0 +1,115 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestBuildColumnProfileQuery(t *testing.T) {
+	t.Parallel()
+
+	got := BuildColumnProfileQuery(&ResolvedTable{ProjectID: "project", DatasetID: "dataset", TableID: "table"}, []*bigquery.FieldSchema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+	})
+
+	assert.Equal(t, "SELECT\n"+
+		"  COUNT(`id`) AS c0_count,\n"+
+		"  COUNTIF(`id` IS NULL) AS c0_nulls,\n"+
+		"  APPROX_COUNT_DISTINCT(`id`) AS c0_distinct,\n"+
+		"  MIN(`id`) AS c0_min,\n"+
+		"  MAX(`id`) AS c0_max,\n"+
+		"  COUNT(`tags`) AS c1_count,\n"+
+		"  COUNTIF(`tags` IS NULL) AS c1_nulls\n"+
+		"FROM `project.dataset.table`", got)
+}
+
+func TestClient_ProfileColumns(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		dataProject string
+		wantProject string
+	}{
+		{
+			name:        "tables of the connection project",
+			wantProject: testProjectID,
+		},
+		{
+			name:        "tables of a separate data project",
+			dataProject: "data-project",
+			wantProject: "data-project",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &recordingQueryHandler{
+				response: func(q string) *bigquery2.QueryResponse {
+					fields := []*bigquery2.TableFieldSchema{
+						{Name: "c0_count", Type: "INTEGER"},
+						{Name: "c0_nulls", Type: "INTEGER"},
+						{Name: "c0_distinct", Type: "INTEGER"},
+						{Name: "c0_min", Type: "STRING"},
+						{Name: "c0_max", Type: "STRING"},
+						{Name: "c1_count", Type: "INTEGER"},
+						{Name: "c1_nulls", Type: "INTEGER"},
+					}
+					return &bigquery2.QueryResponse{
+						JobComplete:  true,
+						JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+						Schema:       &bigquery2.TableSchema{Fields: fields},
+						Rows: []*bigquery2.TableRow{{F: []*bigquery2.TableCell{
+							{V: "8"}, {V: "2"}, {V: "5"}, {V: "amsterdam"}, {V: "zurich"}, {V: "10"}, {V: "0"},
+						}}},
+						TotalRows: 1,
+					}
+				},
+				fallback: func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != fmt.Sprintf("/projects/%s/datasets/dataset/tables/cities", tt.wantProject) {
+						http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+						return
+					}
+					_ = json.NewEncoder(w).Encode(&bigquery2.Table{Schema: &bigquery2.TableSchema{Fields: []*bigquery2.TableFieldSchema{
+						{Name: "name", Type: "STRING"},
+						{Name: "location", Type: "RECORD", Fields: []*bigquery2.TableFieldSchema{{Name: "lat", Type: "FLOAT"}}},
+					}}})
+				},
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			if tt.dataProject != "" {
+				d.config.DataProjectID = tt.dataProject
+				d.config.ProjectResolution = ProjectResolutionData
+			}
+
+			got, err := d.ProfileColumns(context.Background(), "dataset.cities", []string{"Name", "location"})
+			require.NoError(t, err)
+			assert.Equal(t, map[string]ColumnProfile{
+				"Name":     {Count: 8, NullCount: 2, DistinctCount: 5, Min: "amsterdam", Max: "zurich"},
+				"location": {Count: 10, NullCount: 0, CountsOnly: true},
+			}, got)
+
+			queries := handler.recordedQueries()
+			require.Len(t, queries, 1)
+			assert.Contains(t, queries[0], "FROM `"+tt.wantProject+".dataset.cities`")
+
+			_, err = d.ProfileColumns(context.Background(), "dataset.cities", []string{"population"})
+			require.EqualError(t, err, "column 'population' does not exist in table 'dataset.cities'")
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package bigquery
+
+import (
+	"context"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+)
+
+// DefaultQueryTagLabel is the job label the query tag is stored in unless Config.QueryTagLabel is set.
+const DefaultQueryTagLabel = "bruin_query_tag"
+
+type queryTagKey struct{}
+
+// QueryTagSource picks the query tag of an asset, e.g. the team owning it. An empty tag means no tag is set.
+type QueryTagSource func(asset *pipeline.Asset) string
+
+// QueryTagFromOwner tags the queries of an asset with its owner.
+func QueryTagFromOwner(asset *pipeline.Asset) string {
+	return asset.Owner
+}
+
+// QueryTagFromMetadata tags the queries of an asset with the value of the given metadata key.
+func QueryTagFromMetadata(key string) QueryTagSource {
+	return func(asset *pipeline.Asset) string {
+		return asset.Metadata[key]
+	}
+}
+
+// WithQueryTag returns a context that makes the queries run with it carry the tag as a job label, so that the
+// logical owner of a query can be found in the audit logs even though every query runs as the same service
+// account. The tag is sanitized to meet the label value constraints.
+func WithQueryTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, queryTagKey{}, tag)
+}
+
+// WithAssetQueryTag is like WithQueryTag, with the tag picked from the asset by the given source.
+func WithAssetQueryTag(ctx context.Context, asset *pipeline.Asset, source QueryTagSource) context.Context {
+	if asset == nil || source == nil {
+		return ctx
+	}
+
+	return WithQueryTag(ctx, source(asset))
+}
+
+// queryTagLabel returns the job label carrying the query tag of the context, if there is any.
+func (d *Client) queryTagLabel(ctx context.Context) (string, string, bool) {
+	tag, ok := ctx.Value(queryTagKey{}).(string)
+	if !ok || tag == "" {
+		return "", "", false
+	}
+
+	key := DefaultQueryTagLabel
+	if d.config != nil && d.config.QueryTagLabel != "" {
+		key = sanitizeLabelKey(d.config.QueryTagLabel)
+	}
+
+	return key, sanitizeLabelValue(tag), true
+}
@@ -0,0 +1,80 @@
+package bigquery
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_QueryTag(t *testing.T) {
+	t.Parallel()
+
+	asset := &pipeline.Asset{
+		Name:     "dataset.orders",
+		Owner:    "Data Team",
+		Metadata: pipeline.EmptyStringMap{"cost_center": "cc-42"},
+	}
+
+	tests := []struct {
+		name       string
+		ctx        func(ctx context.Context) context.Context
+		labelKey   string
+		wantLabels map[string]string
+	}{
+		{
+			name: "no tag",
+			ctx:  func(ctx context.Context) context.Context { return ctx },
+		},
+		{
+			name:       "explicit tag",
+			ctx:        func(ctx context.Context) context.Context { return WithQueryTag(ctx, "finance") },
+			wantLabels: map[string]string{DefaultQueryTagLabel: "finance"},
+		},
+		{
+			name: "tag from the asset owner",
+			ctx: func(ctx context.Context) context.Context {
+				return WithAssetQueryTag(ctx, asset, QueryTagFromOwner)
+			},
+			wantLabels: map[string]string{DefaultQueryTagLabel: "data_team"},
+		},
+		{
+			name: "tag from the asset metadata with a custom label",
+			ctx: func(ctx context.Context) context.Context {
+				return WithAssetQueryTag(ctx, asset, QueryTagFromMetadata("cost_center"))
+			},
+			labelKey:   "Owner",
+			wantLabels: map[string]string{"owner": "cc-42"},
+		},
+		{
+			name: "empty tag",
+			ctx: func(ctx context.Context) context.Context {
+				return WithAssetQueryTag(ctx, asset, QueryTagFromMetadata("missing"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &recordingQueryHandler{}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.QueryTagLabel = tt.labelKey
+
+			err := d.RunQueryWithoutResult(tt.ctx(context.Background()), &query.Query{Query: "SELECT 1"})
+			require.NoError(t, err)
+
+			requests := handler.recordedRequests()
+			require.Len(t, requests, 1)
+			assert.Equal(t, tt.wantLabels, requests[0].Labels)
+		})
+	}
+}
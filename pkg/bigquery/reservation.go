@@ -0,0 +1,121 @@
+package bigquery
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+// reservationIDRegex matches the resource names of reservations, e.g.
+// `projects/my-project/locations/US/reservations/etl`.
+var reservationIDRegex = regexp.MustCompile(`^projects/([^/]+)/locations/([^/]+)/reservations/([a-z][a-z0-9_-]{0,63})$`)
+
+// validateReservationID checks that the reservation is a reservation resource name that belongs to the project
+// of the connection. An empty reservation is valid, the queries then run with on-demand pricing.
+func validateReservationID(projectID, reservationID string) error {
+	if reservationID == "" {
+		return nil
+	}
+
+	matches := reservationIDRegex.FindStringSubmatch(reservationID)
+	if matches == nil {
+		return fmt.Errorf("invalid reservation '%s', expected a reservation name such as projects/<project>/locations/<location>/reservations/<reservation>", reservationID)
+	}
+	if projectID != "" && matches[1] != projectID {
+		return fmt.Errorf("reservation '%s' belongs to project '%s', but the connection uses project '%s'", reservationID, matches[1], projectID)
+	}
+
+	return nil
+}
+
+// declareRegex matches a DECLARE statement at the start of the text.
+var declareRegex = regexp.MustCompile(`(?i)^\s*declare\b`)
+
+// withReservation adds the statement that runs the query in the configured reservation, since neither the client nor
+// the API it uses can set the reservation of a query job. The statement goes after the DECLARE statements the query
+// starts with, which BigQuery only accepts at the start of a script. The query then runs as a script, whose results
+// are the ones of its last statement; the DML statistics are reported on its child jobs, see RunQueryWithJobInfo.
+// Script jobs cannot have a destination table, which is why BackfillPartitions refuses to run with a reservation.
+func (d *Client) withReservation(q string) string {
+	if d.config == nil || d.config.ReservationID == "" {
+		return q
+	}
+
+	set := fmt.Sprintf("SET @@reservation = %s;\n", quoteStringLiteral(d.config.ReservationID))
+	end, terminated := leadingDeclaresEnd(q)
+	switch {
+	case end == 0:
+		return set + q
+	case !terminated:
+		// the last DECLARE may end with a comment, the semicolon goes on a line of its own
+		return q + "\n;\n" + set
+	default:
+		return q[:end] + "\n" + set + q[end:]
+	}
+}
+
+// leadingDeclaresEnd returns the offset right after the DECLARE statements the query starts with, zero if there are
+// none, and whether the last of them is terminated by a semicolon. Literals and comments are skipped.
+func leadingDeclaresEnd(q string) (int, bool) {
+	masked := maskLiteralsAndComments(q, false)
+
+	end := 0
+	for declareRegex.MatchString(masked[end:]) {
+		semicolon := strings.IndexByte(masked[end:], ';')
+		if semicolon < 0 {
+			return len(q), false
+		}
+		end += semicolon + 1
+	}
+
+	return end, true
+}
+
+// ReservationPermissionError is returned when a query was not run because the credentials of the connection are
+// not allowed to use the configured reservation.
+type ReservationPermissionError struct {
+	Message string
+	err     error
+}
+
+func (e *ReservationPermissionError) Error() string {
+	return "the credentials of the connection are not allowed to run queries in the configured reservation, " +
+		"grant them the BigQuery Resource User role on the reservation or unset it to use on-demand pricing: " + e.Message
+}
+
+func (e *ReservationPermissionError) Unwrap() error {
+	return e.err
+}
+
+// asReservationPermissionError maps the API and job errors that signal a denied access to a reservation to
+// ReservationPermissionError, returning nil for every other error.
+func asReservationPermissionError(err error) *ReservationPermissionError {
+	var message string
+
+	var googleError *googleapi.Error
+	var jobError *bigquery.Error
+	switch {
+	case errors.As(err, &googleError):
+		if googleError.Code != 403 {
+			return nil
+		}
+		message = googleError.Message
+	case errors.As(err, &jobError):
+		if jobError.Reason != "accessDenied" {
+			return nil
+		}
+		message = jobError.Message
+	default:
+		return nil
+	}
+
+	if !strings.Contains(strings.ToLower(message), "reservation") {
+		return nil
+	}
+
+	return &ReservationPermissionError{Message: message, err: err}
+}
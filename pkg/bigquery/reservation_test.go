@@ -0,0 +1,147 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+)
+
+func TestValidateReservationID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		reservation string
+		wantErr     string
+	}{
+		{
+			name: "no reservation",
+		},
+		{
+			name:        "reservation of the project",
+			reservation: "projects/test-project/locations/US/reservations/etl",
+		},
+		{
+			name:        "reservation of another project",
+			reservation: "projects/admin-project/locations/US/reservations/etl",
+			wantErr:     "reservation 'projects/admin-project/locations/US/reservations/etl' belongs to project 'admin-project', but the connection uses project 'test-project'",
+		},
+		{
+			name:        "reservation without its project and location",
+			reservation: "etl",
+			wantErr:     "invalid reservation 'etl', expected a reservation name such as projects/<project>/locations/<location>/reservations/<reservation>",
+		},
+		{
+			name:        "reservation name with a quote",
+			reservation: "projects/test-project/locations/US/reservations/etl'",
+			wantErr:     "invalid reservation 'projects/test-project/locations/US/reservations/etl'', expected a reservation name such as projects/<project>/locations/<location>/reservations/<reservation>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateReservationID(testProjectID, tt.reservation)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestClient_ReservationID(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	require.NoError(t, d.RunQueryWithoutResult(context.Background(), &query.Query{Query: "SELECT 1"}))
+
+	d.config.ReservationID = "projects/test-project/locations/US/reservations/etl"
+	require.NoError(t, d.RunQueryWithoutResult(context.Background(), &query.Query{Query: "SELECT 1"}))
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	assert.Equal(t, []string{
+		"SELECT 1",
+		"SET @@reservation = 'projects/test-project/locations/US/reservations/etl';\nSELECT 1",
+	}, handler.queries)
+
+	_, err := NewDB(&Config{ProjectID: testProjectID, ReservationID: "projects/admin-project/locations/US/reservations/etl"})
+	require.ErrorContains(t, err, "belongs to project 'admin-project'")
+}
+
+func TestClient_withReservation(t *testing.T) {
+	t.Parallel()
+
+	set := "SET @@reservation = 'projects/test-project/locations/US/reservations/etl';\n"
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "plain query",
+			query: "SELECT 1",
+			want:  set + "SELECT 1",
+		},
+		{
+			name:  "after the leading declarations",
+			query: "DECLARE day DATE DEFAULT '2024-01-01';\ndeclare x INT64; -- the counter\nSELECT day, x",
+			want:  "DECLARE day DATE DEFAULT '2024-01-01';\ndeclare x INT64;\n" + set + " -- the counter\nSELECT day, x",
+		},
+		{
+			name:  "semicolons in literals and comments",
+			query: "DECLARE s STRING DEFAULT 'a;b'; # done;\nSELECT s",
+			want:  "DECLARE s STRING DEFAULT 'a;b';\n" + set + " # done;\nSELECT s",
+		},
+		{
+			name:  "declarations later in the script stay",
+			query: "SELECT 1;\nDECLARE x INT64;",
+			want:  set + "SELECT 1;\nDECLARE x INT64;",
+		},
+		{
+			name:  "unterminated declaration",
+			query: "DECLARE x INT64 -- nothing else",
+			want:  "DECLARE x INT64 -- nothing else\n;\n" + set,
+		},
+	}
+
+	d := newTestClient(t, "http://localhost")
+	d.config.ReservationID = "projects/test-project/locations/US/reservations/etl"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, d.withReservation(tt.query))
+		})
+	}
+}
+
+func TestFormatError_ReservationPermission(t *testing.T) {
+	t.Parallel()
+
+	denied := "Access Denied: Reservation projects/test-project/locations/US/reservations/etl: Permission bigquery.reservations.use denied"
+
+	var reservationErr *ReservationPermissionError
+	require.ErrorAs(t, formatError(&googleapi.Error{Code: 403, Message: denied}), &reservationErr)
+	assert.Equal(t, denied, reservationErr.Message)
+	assert.Contains(t, reservationErr.Error(), "not allowed to run queries in the configured reservation")
+
+	require.ErrorAs(t, formatError(fmt.Errorf("job failed: %w", &bigquery.Error{Reason: "accessDenied", Message: denied})), &reservationErr)
+
+	assert.False(t, errors.As(formatError(&googleapi.Error{Code: 403, Message: "Access Denied: Table test-project:dataset.users"}), &reservationErr))
+	assert.False(t, errors.As(formatError(&googleapi.Error{Code: 400, Message: "Invalid reservation in SET statement"}), &reservationErr))
+}
@@ -0,0 +1,152 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+// DefaultRetryBaseDelay is the delay before the first retry unless configured otherwise, it doubles with every attempt.
+const DefaultRetryBaseDelay = time.Second
+
+// transientErrorCodes are the HTTP codes that signal a temporary failure. 400 and 404 are deterministic and
+// never retried.
+var transientErrorCodes = map[int]bool{
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusServiceUnavailable:  true,
+}
+
+// transientJobErrorReasons are the reasons of the job errors that signal a temporary failure of BigQuery rather than
+// a problem with the query.
+var transientJobErrorReasons = map[string]bool{
+	"backendError":      true,
+	"internalError":     true,
+	"rateLimitExceeded": true,
+}
+
+func isTransientError(err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return transientErrorCodes[apiErr.Code]
+	}
+
+	var jobErr *bigquery.Error
+	return errors.As(err, &jobErr) && transientJobErrorReasons[jobErr.Reason]
+}
+
+// withRetry calls fn until it succeeds, fails with a non-transient error or Config.MaxRetries retries were made.
+// The delay between attempts grows exponentially from Config.RetryBaseDelay, with jitter so that many assets
+// failing at the same time do not retry in lockstep.
+func (d *Client) withRetry(ctx context.Context, fn func() error) error {
+	maxRetries := 0
+	baseDelay := DefaultRetryBaseDelay
+	if d.config != nil {
+		maxRetries = d.config.MaxRetries
+		if d.config.RetryBaseDelay > 0 {
+			baseDelay = d.config.RetryBaseDelay
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxRetries || !isTransientError(err) {
+			return err
+		}
+
+		delay := baseDelay << attempt
+		// wait between half and the full delay
+		delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)) //nolint:gosec
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// readWithRetry runs the query and returns its results, retrying transient failures, see withStableJobID.
+func (d *Client) readWithRetry(ctx context.Context, q *bigquery.Query) (*bigquery.RowIterator, error) {
+	d.withStableJobID(q)
+
+	var rows *bigquery.RowIterator
+	err := d.withRetry(ctx, func() error {
+		var err error
+		rows, err = d.read(ctx, q)
+		return err
+	})
+
+	return rows, timeoutCause(ctx, err)
+}
+
+// runWithRetry starts the query job, retrying transient failures, see withStableJobID.
+func (d *Client) runWithRetry(ctx context.Context, q *bigquery.Query) (*bigquery.Job, error) {
+	d.withStableJobID(q)
+
+	var job *bigquery.Job
+	err := d.withRetry(ctx, func() error {
+		var err error
+		job, err = d.runOrAttach(ctx, q)
+		return err
+	})
+
+	return job, timeoutCause(ctx, err)
+}
+
+// withStableJobID gives the query a job ID of its own when the retries are enabled, so that a retry after BigQuery
+// accepted the job but the response got lost, e.g. with a 503, attaches to that job instead of running the
+// statement a second time, see runOrAttach. Dry runs and queries that already have a job ID are left as they are.
+func (d *Client) withStableJobID(q *bigquery.Query) {
+	if d.config == nil || d.config.MaxRetries <= 0 || q.DryRun || q.JobID != "" {
+		return
+	}
+
+	q.JobID = newRetryJobID()
+	q.AddJobIDSuffix = false
+}
+
+func newRetryJobID() string {
+	return "bruin_" + uuid.NewString()
+}
+
+// runOrAttach starts the query job. If a job with the ID of the query exists already, i.e. an earlier attempt was
+// accepted, it returns that job instead, unless that job failed, which leaves no changes behind: the statement is
+// run again under the job ID of the next attempt then, `<job ID>_<attempt>`, which a later call attaches to the same
+// way.
+func (d *Client) runOrAttach(ctx context.Context, q *bigquery.Query) (*bigquery.Job, error) {
+	baseID := q.JobID
+	for attempt := 1; ; attempt++ {
+		job, err := q.Run(ctx)
+		if err == nil || baseID == "" {
+			return job, err
+		}
+		var apiErr *googleapi.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != http.StatusConflict {
+			return nil, err
+		}
+
+		location := q.Location
+		if location == "" {
+			location = d.client.Location
+		}
+		job, err = d.client.JobFromIDLocation(ctx, q.JobID, location)
+		if err != nil {
+			return nil, err
+		}
+		if status := job.LastStatus(); status == nil || !status.Done() || status.Err() == nil {
+			return job, nil
+		}
+
+		q.JobID = fmt.Sprintf("%s_%d", baseID, attempt)
+	}
+}
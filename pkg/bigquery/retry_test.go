@@ -0,0 +1,252 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+)
+
+func TestClient_withRetry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		maxRetries   int
+		errs         []error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "success on the first attempt",
+			maxRetries:   3,
+			errs:         []error{nil},
+			wantAttempts: 1,
+		},
+		{
+			name:         "transient errors are retried",
+			maxRetries:   3,
+			errs:         []error{&googleapi.Error{Code: http.StatusTooManyRequests}, &googleapi.Error{Code: http.StatusServiceUnavailable}, nil},
+			wantAttempts: 3,
+		},
+		{
+			name:         "retries are bounded",
+			maxRetries:   2,
+			errs:         []error{&googleapi.Error{Code: http.StatusInternalServerError}, &googleapi.Error{Code: http.StatusInternalServerError}, &googleapi.Error{Code: http.StatusInternalServerError}, nil},
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+		{
+			name:         "transient job errors are retried",
+			maxRetries:   3,
+			errs:         []error{&bigquery.Error{Reason: "backendError"}, nil},
+			wantAttempts: 2,
+		},
+		{
+			name:         "job errors of the query are not retried",
+			maxRetries:   3,
+			errs:         []error{&bigquery.Error{Reason: "invalidQuery"}, nil},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "deterministic errors are not retried",
+			maxRetries:   3,
+			errs:         []error{&googleapi.Error{Code: http.StatusBadRequest}, nil},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "non-API errors are not retried",
+			maxRetries:   3,
+			errs:         []error{errors.New("some error"), nil},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "retries are disabled by default",
+			errs:         []error{&googleapi.Error{Code: http.StatusServiceUnavailable}, nil},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := &Client{config: &Config{MaxRetries: tt.maxRetries, RetryBaseDelay: time.Millisecond}}
+
+			attempts := 0
+			err := d.withRetry(context.Background(), func() error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			})
+
+			assert.Equal(t, tt.wantAttempts, attempts)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestClient_withRetry_RespectsContext(t *testing.T) {
+	t.Parallel()
+
+	d := &Client{config: &Config{MaxRetries: 5, RetryBaseDelay: time.Hour}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	err := d.withRetry(ctx, func() error {
+		attempts++
+		return &googleapi.Error{Code: http.StatusServiceUnavailable}
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestClient_RunQueryWithoutResult_Retry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		failures        int
+		failureCode     int
+		acceptFailed    bool
+		firstJobFails   bool
+		wantSubmissions int
+		wantJobs        int
+		wantErr         bool
+	}{
+		{
+			name:            "rate limited requests are retried",
+			failures:        2,
+			failureCode:     http.StatusTooManyRequests,
+			wantSubmissions: 3,
+			wantJobs:        1,
+		},
+		{
+			name:            "bad requests are not retried",
+			failures:        1,
+			failureCode:     http.StatusBadRequest,
+			wantSubmissions: 1,
+			wantErr:         true,
+		},
+		{
+			name:            "a job accepted before the failure is not run again",
+			failures:        1,
+			failureCode:     http.StatusServiceUnavailable,
+			acceptFailed:    true,
+			wantSubmissions: 2,
+			wantJobs:        1,
+		},
+		{
+			name:            "jobs failing with a backend error are run again",
+			firstJobFails:   true,
+			wantSubmissions: 3,
+			wantJobs:        2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var mu sync.Mutex
+			var submissions []string
+			var jobs []string
+			jobsPath := fmt.Sprintf("/projects/%s/jobs", testProjectID)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == jobsPath:
+					var job bigquery2.Job
+					_ = json.NewDecoder(r.Body).Decode(&job)
+					id := job.JobReference.JobId
+					submissions = append(submissions, id)
+
+					if len(submissions) <= tt.failures {
+						if tt.acceptFailed {
+							jobs = append(jobs, id)
+						}
+						w.WriteHeader(tt.failureCode)
+						_, _ = w.Write([]byte(`{"error": {"message": "request failed"}}`))
+						return
+					}
+					if slices.Contains(jobs, id) {
+						w.WriteHeader(http.StatusConflict)
+						_, _ = w.Write([]byte(`{"error": {"code": 409, "message": "Already Exists: Job"}}`))
+						return
+					}
+
+					jobs = append(jobs, id)
+					job.Status = &bigquery2.JobStatus{State: "RUNNING"}
+					_ = json.NewEncoder(w).Encode(&job)
+				case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, jobsPath+"/"):
+					id := strings.TrimPrefix(r.URL.Path, jobsPath+"/")
+					status := &bigquery2.JobStatus{State: "DONE"}
+					if tt.firstJobFails && id == jobs[0] {
+						status.ErrorResult = &bigquery2.ErrorProto{Reason: "backendError", Message: "Backend error"}
+					}
+					_ = json.NewEncoder(w).Encode(&bigquery2.Job{
+						JobReference:  &bigquery2.JobReference{JobId: id, ProjectId: testProjectID, Location: "US"},
+						Configuration: &bigquery2.JobConfiguration{Query: &bigquery2.JobConfigurationQuery{Query: "INSERT INTO t VALUES (1)"}},
+						Status:        status,
+					})
+				case strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/queries/", testProjectID)):
+					_ = json.NewEncoder(w).Encode(&bigquery2.GetQueryResultsResponse{
+						JobComplete:  true,
+						JobReference: &bigquery2.JobReference{JobId: path.Base(r.URL.Path), ProjectId: testProjectID},
+					})
+				default:
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.MaxRetries = 3
+			d.config.RetryBaseDelay = time.Millisecond
+
+			err := d.RunQueryWithoutResult(context.Background(), &query.Query{Query: "INSERT INTO t VALUES (1)"})
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Len(t, submissions, tt.wantSubmissions)
+			assert.Len(t, jobs, tt.wantJobs, "the statement must run once per job that did not fail")
+			if !tt.firstJobFails {
+				for _, id := range submissions {
+					assert.Equal(t, submissions[0], id, "the retries must reuse the job ID")
+				}
+			}
+		})
+	}
+}
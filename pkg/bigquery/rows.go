@@ -0,0 +1,313 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/iterator"
+)
+
+// PartialResultError is returned when reading the results fails midway. Rows holds the rows read before the
+// failure, Row the zero-based index of the row that could not be read, so that callers can decide whether the
+// partial results are usable. Its message is the one of the underlying error.
+type PartialResultError struct {
+	Rows [][]interface{}
+	Row  int
+	Err  error
+}
+
+func (e *PartialResultError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PartialResultError) Unwrap() error {
+	return e.Err
+}
+
+// selectRows runs the query and reads all of its rows. If fetching the results fails midway, the error is a
+// PartialResultError holding the rows read before the failure. With Config.ResultMemoryCap set, reading stops with a
+// ResultMemoryCapError once the results exceed the cap.
+func (d *Client) selectRows(ctx context.Context, queryObj *query.Query) ([][]interface{}, error) {
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer cancel()
+
+	q, err := d.newQuery(ctx, queryObj)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := d.readWithRetry(ctx, q)
+	if err != nil {
+		return nil, formatError(err)
+	}
+
+	memoryCap := 0
+	if d.config != nil {
+		memoryCap = d.config.ResultMemoryCap
+	}
+
+	result := make([][]interface{}, 0)
+	for i := 0; ; i++ {
+		var values []bigquery.Value
+		err := rows.Next(&values)
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, &PartialResultError{Rows: result, Row: i, Err: timeoutCause(ctx, err)}
+		}
+		if memoryCap > 0 && i >= memoryCap {
+			return nil, &ResultMemoryCapError{Cap: memoryCap}
+		}
+
+		interfaces := make([]interface{}, len(values))
+		for j, v := range values {
+			interfaces[j] = v
+		}
+
+		result = append(result, interfaces)
+	}
+
+	return result, nil
+}
+
+// DefaultMaxBadRows is the number of bad rows that are skipped before a read is aborted, unless configured otherwise.
+const DefaultMaxBadRows = 100
+
+// RowError describes a single row that could not be read. Row is the zero-based index of the row in the result.
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// TooManyBadRowsError is returned when more bad rows than allowed were found while skipping bad rows.
+type TooManyBadRowsError struct {
+	Max    int
+	Errors []*RowError
+}
+
+func (e *TooManyBadRowsError) Error() string {
+	return fmt.Sprintf("more than %d rows could not be read, the first error was: %s", e.Max, e.Errors[0])
+}
+
+// SelectWithRowErrors runs the query and skips the rows that cannot be read, returning the good rows together
+// with the errors of the skipped ones. The read is aborted with a TooManyBadRowsError once more than
+// Config.MaxBadRows rows were skipped.
+//
+// Rows fail to read when one of their values cannot be converted to its Go type, e.g. a RANGE value, which the
+// BigQuery client does not support. The client converts whole pages at once and gives up on the first bad value,
+// so the results are fetched as raw pages from the BigQuery API instead and converted row by row. The values are
+// converted the same way the client does; Select is not affected unless Config.SkipBadRows is set.
+func (d *Client) SelectWithRowErrors(ctx context.Context, queryObj *query.Query) ([][]interface{}, []*RowError, error) {
+	if d.service == nil {
+		return nil, nil, errors.New("skipping bad rows requires the BigQuery API client, create the client with NewDB")
+	}
+
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer cancel()
+
+	q, err := d.newQuery(ctx, queryObj)
+	if err != nil {
+		return nil, nil, err
+	}
+	job, err := d.runWithRetry(ctx, q)
+	if err != nil {
+		return nil, nil, formatError(err)
+	}
+	status, err := d.waitJob(ctx, job)
+	if err != nil {
+		return nil, nil, formatError(timeoutCause(ctx, err))
+	}
+	if err := status.Err(); err != nil {
+		return nil, nil, formatError(err)
+	}
+
+	maxBadRows := DefaultMaxBadRows
+	memoryCap := 0
+	if d.config != nil {
+		if d.config.MaxBadRows > 0 {
+			maxBadRows = d.config.MaxBadRows
+		}
+		memoryCap = d.config.ResultMemoryCap
+	}
+
+	result := make([][]interface{}, 0)
+	rowErrors := make([]*RowError, 0)
+	row := 0
+	pageToken := ""
+	for {
+		call := d.service.Jobs.GetQueryResults(job.ProjectID(), job.ID()).
+			Location(job.Location()).
+			FormatOptionsUseInt64Timestamp(true).
+			Context(ctx)
+		if pageToken != "" {
+			call = call.PageToken(pageToken)
+		}
+		page, err := call.Do()
+		if err != nil {
+			return nil, nil, &PartialResultError{Rows: result, Row: row, Err: formatError(timeoutCause(ctx, err))}
+		}
+
+		var fields []*bigquery2.TableFieldSchema
+		if page.Schema != nil {
+			fields = page.Schema.Fields
+		}
+		for _, raw := range page.Rows {
+			values, err := convertTableRow(raw, fields)
+			if err != nil {
+				rowErrors = append(rowErrors, &RowError{Row: row, Err: err})
+				if len(rowErrors) > maxBadRows {
+					return nil, nil, &TooManyBadRowsError{Max: maxBadRows, Errors: rowErrors}
+				}
+				row++
+				continue
+			}
+			if memoryCap > 0 && len(result) >= memoryCap {
+				return nil, nil, &ResultMemoryCapError{Cap: memoryCap}
+			}
+
+			result = append(result, values)
+			row++
+		}
+
+		pageToken = page.PageToken
+		if pageToken == "" {
+			return result, rowErrors, nil
+		}
+	}
+}
+
+// convertTableRow converts the raw cells of a row to the values the BigQuery client returns for them.
+func convertTableRow(row *bigquery2.TableRow, fields []*bigquery2.TableFieldSchema) ([]interface{}, error) {
+	if len(row.F) != len(fields) {
+		return nil, fmt.Errorf("row has %d values but the schema has %d columns", len(row.F), len(fields))
+	}
+
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		value, err := convertCell(row.F[i].V, field)
+		if err != nil {
+			return nil, errors.Wrapf(err, "column '%s'", field.Name)
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// convertCell converts a raw cell: repeated values come as lists of `{"v": ...}` objects, records as `{"f": [...]}`
+// objects holding their fields, and scalars as strings.
+func convertCell(raw interface{}, field *bigquery2.TableFieldSchema) (bigquery.Value, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		element := *field
+		element.Mode = ""
+		values := make([]bigquery.Value, 0, len(v))
+		for _, item := range v {
+			cell, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("unexpected repeated value %v", item)
+			}
+			value, err := convertCell(cell["v"], &element)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case map[string]interface{}:
+		cells, ok := v["f"].([]interface{})
+		if !ok || len(cells) != len(field.Fields) {
+			return nil, fmt.Errorf("record does not match the %d fields of its schema", len(field.Fields))
+		}
+		values := make([]bigquery.Value, len(cells))
+		for i, item := range cells {
+			cell, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("unexpected record value %v", item)
+			}
+			value, err := convertCell(cell["v"], field.Fields[i])
+			if err != nil {
+				return nil, errors.Wrapf(err, "field '%s'", field.Fields[i].Name)
+			}
+			values[i] = value
+		}
+		return values, nil
+	case string:
+		return convertScalar(v, field.Type)
+	default:
+		return nil, fmt.Errorf("unexpected value %v of type %s", raw, field.Type)
+	}
+}
+
+// convertScalar converts a scalar the way the BigQuery client does, TIMESTAMP values are expected in microseconds.
+func convertScalar(v string, typ string) (bigquery.Value, error) {
+	switch bigquery.FieldType(strings.ToUpper(typ)) {
+	case bigquery.StringFieldType, bigquery.GeographyFieldType, bigquery.JSONFieldType:
+		return v, nil
+	case bigquery.BytesFieldType:
+		return base64.StdEncoding.DecodeString(v)
+	case bigquery.IntegerFieldType, "INT64":
+		return strconv.ParseInt(v, 10, 64)
+	case bigquery.FloatFieldType, "FLOAT64":
+		return strconv.ParseFloat(v, 64)
+	case bigquery.BooleanFieldType, "BOOL":
+		return strconv.ParseBool(v)
+	case bigquery.TimestampFieldType:
+		micros, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return time.UnixMicro(micros).UTC(), nil
+	case bigquery.DateFieldType:
+		return civil.ParseDate(v)
+	case bigquery.TimeFieldType:
+		return civil.ParseTime(v)
+	case bigquery.DateTimeFieldType:
+		return civil.ParseDateTime(v)
+	case bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+		r, ok := new(big.Rat).SetString(v)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %q", typ, v)
+		}
+		return r, nil
+	case bigquery.IntervalFieldType:
+		return bigquery.ParseInterval(v)
+	default:
+		return nil, fmt.Errorf("values of type %s are not supported", typ)
+	}
+}
+
+func (d *Client) selectSkippingBadRows(ctx context.Context, queryObj *query.Query) ([][]interface{}, error) {
+	result, rowErrors, err := d.SelectWithRowErrors(ctx, queryObj)
+	if err != nil {
+		return nil, err
+	}
+	if len(rowErrors) > 0 {
+		contextLogger(ctx).Warnf("skipped %d rows that could not be read, the first error was: %s", len(rowErrors), rowErrors[0])
+	}
+	if len(result) == 0 && d.errorOnNoRows() {
+		return nil, ErrNoRows
+	}
+
+	return result, nil
+}
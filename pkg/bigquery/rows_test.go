@@ -0,0 +1,191 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/option"
+)
+
+func TestClient_Select_PartialResult(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			response := stringQueryResponse("a", "b")
+			// the second page of the results cannot be fetched
+			response.PageToken = "page-2"
+			response.TotalRows = 4
+			return response
+		},
+		fallback: func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"code": 400, "message": "the results expired"}}`))
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	rows, err := d.Select(context.Background(), &query.Query{Query: "SELECT * FROM dataset.users"})
+	require.Error(t, err)
+	assert.Nil(t, rows)
+	assert.ErrorContains(t, err, "the results expired")
+
+	var partialErr *PartialResultError
+	require.ErrorAs(t, err, &partialErr)
+	assert.Equal(t, 2, partialErr.Row)
+	assert.Equal(t, [][]interface{}{{"a"}, {"b"}}, partialErr.Rows)
+}
+
+func TestClient_Select_ResultMemoryCap(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			return stringQueryResponse("a", "b", "c")
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	d.config.ResultMemoryCap = 3
+	rows, err := d.Select(context.Background(), &query.Query{Query: "SELECT * FROM dataset.users"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"a"}, {"b"}, {"c"}}, rows)
+
+	d.config.ResultMemoryCap = 2
+	rows, err = d.Select(context.Background(), &query.Query{Query: "SELECT * FROM dataset.users"})
+	require.EqualError(t, err, "the query returned more than the 2 rows the result memory cap allows, read its results with SelectIterator")
+	assert.Nil(t, rows)
+
+	var capErr *ResultMemoryCapError
+	require.ErrorAs(t, err, &capErr)
+}
+
+func TestClient_Select_SkipBadRows(t *testing.T) {
+	t.Parallel()
+
+	schema := &bigquery2.TableSchema{
+		Fields: []*bigquery2.TableFieldSchema{
+			{Name: "id", Type: "INTEGER"},
+			{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+		},
+	}
+	row := func(id interface{}, tags ...string) *bigquery2.TableRow {
+		values := make([]interface{}, 0, len(tags))
+		for _, tag := range tags {
+			values = append(values, map[string]interface{}{"v": tag})
+		}
+		return &bigquery2.TableRow{F: []*bigquery2.TableCell{{V: id}, {V: values}}}
+	}
+	ref := &bigquery2.JobReference{ProjectId: testProjectID, JobId: "job-1"}
+	pages := map[string]*bigquery2.GetQueryResultsResponse{
+		"": {
+			JobComplete:  true,
+			JobReference: ref,
+			Schema:       schema,
+			Rows:         []*bigquery2.TableRow{row("1", "a"), row("abc", "b"), row(nil)},
+			PageToken:    "page-2",
+		},
+		"page-2": {
+			JobComplete:  true,
+			JobReference: ref,
+			Schema:       schema,
+			Rows:         []*bigquery2.TableRow{row("not-a-number"), row("4", "c", "d")},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		maxBadRows int
+		want       [][]interface{}
+		wantErrors []string
+		wantErr    string
+	}{
+		{
+			name: "bad rows are skipped and reported",
+			want: [][]interface{}{
+				{int64(1), []bigquery.Value{"a"}},
+				{nil, []bigquery.Value{}},
+				{int64(4), []bigquery.Value{"c", "d"}},
+			},
+			wantErrors: []string{
+				`row 1: column 'id': strconv.ParseInt: parsing "abc": invalid syntax`,
+				`row 3: column 'id': strconv.ParseInt: parsing "not-a-number": invalid syntax`,
+			},
+		},
+		{
+			name:       "more bad rows than allowed",
+			maxBadRows: 1,
+			wantErr:    `more than 1 rows could not be read, the first error was: row 1: column 'id': strconv.ParseInt: parsing "abc": invalid syntax`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/jobs", testProjectID):
+					_ = json.NewEncoder(w).Encode(&bigquery2.Job{
+						JobReference:  ref,
+						Configuration: &bigquery2.JobConfiguration{Query: &bigquery2.JobConfigurationQuery{Query: "SELECT * FROM dataset.users"}},
+						Status:        &bigquery2.JobStatus{State: "RUNNING"},
+					})
+				case r.URL.Path == fmt.Sprintf("/projects/%s/queries/job-1", testProjectID):
+					_ = json.NewEncoder(w).Encode(pages[r.URL.Query().Get("pageToken")])
+				case r.URL.Path == fmt.Sprintf("/projects/%s/jobs/job-1", testProjectID):
+					_ = json.NewEncoder(w).Encode(&bigquery2.Job{
+						JobReference:  ref,
+						Configuration: &bigquery2.JobConfiguration{Query: &bigquery2.JobConfigurationQuery{Query: "SELECT * FROM dataset.users"}},
+						Status:        &bigquery2.JobStatus{State: "DONE"},
+					})
+				default:
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+				}
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.SkipBadRows = true
+			d.config.MaxBadRows = tt.maxBadRows
+			service, err := bigquery2.NewService(context.Background(), option.WithEndpoint(server.URL), option.WithoutAuthentication())
+			require.NoError(t, err)
+			d.service = service
+
+			rows, rowErrors, err := d.SelectWithRowErrors(context.Background(), &query.Query{Query: "SELECT * FROM dataset.users"})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				var tooManyErr *TooManyBadRowsError
+				require.ErrorAs(t, err, &tooManyErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rows)
+
+			messages := make([]string, 0, len(rowErrors))
+			for _, rowErr := range rowErrors {
+				messages = append(messages, rowErr.Error())
+			}
+			assert.Equal(t, tt.wantErrors, messages)
+
+			rows, err = d.Select(context.Background(), &query.Query{Query: "SELECT * FROM dataset.users"})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, rows)
+		})
+	}
+}
@@ -0,0 +1,528 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+// maxColumnNameLength is the maximum length of a column name in BigQuery.
+const maxColumnNameLength = 300
+
+var fieldTypeAliases = map[string]bigquery.FieldType{
+	"STRING":     bigquery.StringFieldType,
+	"VARCHAR":    bigquery.StringFieldType,
+	"CHAR":       bigquery.StringFieldType,
+	"TEXT":       bigquery.StringFieldType,
+	"BYTES":      bigquery.BytesFieldType,
+	"INT64":      bigquery.IntegerFieldType,
+	"INT":        bigquery.IntegerFieldType,
+	"INTEGER":    bigquery.IntegerFieldType,
+	"SMALLINT":   bigquery.IntegerFieldType,
+	"BIGINT":     bigquery.IntegerFieldType,
+	"TINYINT":    bigquery.IntegerFieldType,
+	"BYTEINT":    bigquery.IntegerFieldType,
+	"FLOAT64":    bigquery.FloatFieldType,
+	"FLOAT":      bigquery.FloatFieldType,
+	"NUMERIC":    bigquery.NumericFieldType,
+	"DECIMAL":    bigquery.NumericFieldType,
+	"BIGNUMERIC": bigquery.BigNumericFieldType,
+	"BIGDECIMAL": bigquery.BigNumericFieldType,
+	"BOOL":       bigquery.BooleanFieldType,
+	"BOOLEAN":    bigquery.BooleanFieldType,
+	"TIMESTAMP":  bigquery.TimestampFieldType,
+	"DATE":       bigquery.DateFieldType,
+	"TIME":       bigquery.TimeFieldType,
+	"DATETIME":   bigquery.DateTimeFieldType,
+	"GEOGRAPHY":  bigquery.GeographyFieldType,
+	"JSON":       bigquery.JSONFieldType,
+	"INTERVAL":   bigquery.IntervalFieldType,
+	"STRUCT":     bigquery.RecordFieldType,
+	"RECORD":     bigquery.RecordFieldType,
+}
+
+// SchemaFromAsset builds the BigQuery schema of the asset from its declared columns. Column types use the
+// GoogleSQL syntax, e.g. `INT64`, `NUMERIC(10, 2)`, `ARRAY<STRING>` or `STRUCT<city STRING, zip INT64>`; the
+// common `VARCHAR`, `CHAR` and `TEXT` are read as STRING.
+func SchemaFromAsset(asset *pipeline.Asset) (bigquery.Schema, error) {
+	if len(asset.Columns) == 0 {
+		return nil, fmt.Errorf("asset '%s' has no columns defined to build a schema from", asset.Name)
+	}
+
+	schema := make(bigquery.Schema, 0, len(asset.Columns))
+	for _, col := range asset.Columns {
+		field, err := parseFieldSchema(col.Name, col.Type)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid column '%s' in asset '%s'", col.Name, asset.Name)
+		}
+		field.Description = col.Description
+		field.Collation = col.Collation
+		field.DefaultValueExpression = col.DefaultValueExpression
+		schema = append(schema, field)
+	}
+
+	if err := ValidateSchema(schema); err != nil {
+		return nil, errors.Wrapf(err, "invalid schema for asset '%s'", asset.Name)
+	}
+
+	return schema, nil
+}
+
+// ValidateSchema checks the given schema for problems that BigQuery would otherwise only report after a job
+// has been submitted.
+func ValidateSchema(schema bigquery.Schema) error {
+	if len(schema) == 0 {
+		return errors.New("schema must have at least one field")
+	}
+
+	seen := make(map[string]bool, len(schema))
+	for _, field := range schema {
+		if field.Name == "" {
+			return errors.New("schema fields must have a name")
+		}
+
+		// column names are case-insensitive in BigQuery
+		key := strings.ToLower(field.Name)
+		if seen[key] {
+			return fmt.Errorf("duplicate field '%s'", field.Name)
+		}
+		seen[key] = true
+
+		if field.Type == "" {
+			return fmt.Errorf("field '%s' has no type", field.Name)
+		}
+
+		if field.Type == bigquery.RecordFieldType {
+			if err := ValidateSchema(field.Schema); err != nil {
+				return errors.Wrapf(err, "invalid nested schema for field '%s'", field.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetTableSchema returns the schema of the given table.
+func (d *Client) GetTableSchema(ctx context.Context, tableName string) (bigquery.Schema, error) {
+	tableRef, err := d.getTableRef(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := tableRef.Metadata(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(formatError(err), "failed to fetch metadata for table '%s'", tableName)
+	}
+
+	return meta.Schema, nil
+}
+
+// ErrTableNotFound is returned by DescribeTable and ExportToGCS when the table does not exist.
+var ErrTableNotFound = errors.New("table does not exist")
+
+// TableSchema describes the columns of an existing table.
+type TableSchema struct {
+	// Table is the fully-qualified name of the table, i.e. `project.dataset.table`.
+	Table       string
+	Type        string
+	Description string
+	Columns     []ColumnSchema
+}
+
+// ColumnSchema describes a single column, with the fields of RECORD columns nested in Fields.
+type ColumnSchema struct {
+	Name string
+	Type string
+
+	// Mode is one of NULLABLE, REQUIRED or REPEATED.
+	Mode        string
+	Description string
+	Fields      []ColumnSchema
+}
+
+// DescribeTable returns the schema of the given table, view or materialized view, including the descriptions and
+// the nested fields of the columns. Missing tables are reported as ErrTableNotFound.
+func (d *Client) DescribeTable(ctx context.Context, tableName string) (*TableSchema, error) {
+	resolved, err := d.ResolveTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := d.client.DatasetInProject(resolved.ProjectID, resolved.DatasetID).Table(resolved.TableID).Metadata(ctx)
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+			return nil, errors.Wrapf(ErrTableNotFound, "failed to describe table '%s'", tableName)
+		}
+		return nil, errors.Wrapf(formatError(err), "failed to fetch metadata for table '%s'", tableName)
+	}
+
+	return &TableSchema{
+		Table:       resolved.String(),
+		Type:        string(meta.Type),
+		Description: meta.Description,
+		Columns:     describeColumns(meta.Schema),
+	}, nil
+}
+
+func describeColumns(schema bigquery.Schema) []ColumnSchema {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	columns := make([]ColumnSchema, 0, len(schema))
+	for _, field := range schema {
+		mode := "NULLABLE"
+		switch {
+		case field.Repeated:
+			mode = "REPEATED"
+		case field.Required:
+			mode = "REQUIRED"
+		}
+
+		columns = append(columns, ColumnSchema{
+			Name:        field.Name,
+			Type:        string(field.Type),
+			Mode:        mode,
+			Description: field.Description,
+			Fields:      describeColumns(field.Schema),
+		})
+	}
+
+	return columns
+}
+
+// ValidateColumnsExist returns an error for the first of the given columns that does not exist on the table.
+// Nested fields can be referenced with dotted paths, e.g. `address.city`. The validation is opt-in through
+// Config.ValidateCheckColumns, it does nothing otherwise.
+func (d *Client) ValidateColumnsExist(ctx context.Context, tableName string, columns []string) error {
+	if !d.validatesCheckColumns() || len(columns) == 0 {
+		return nil
+	}
+
+	schema, err := d.GetTableSchema(ctx, tableName)
+	if err != nil {
+		return err
+	}
+
+	for _, col := range columns {
+		if !schemaHasField(schema, col) {
+			return fmt.Errorf("column %s does not exist on %s", col, tableName)
+		}
+	}
+
+	return nil
+}
+
+func (d *Client) validatesCheckColumns() bool {
+	return d.config != nil && d.config.ValidateCheckColumns
+}
+
+func schemaHasField(schema bigquery.Schema, path string) bool {
+	name, rest, nested := strings.Cut(strings.Trim(path, "`"), ".")
+	for _, field := range schema {
+		// column names are case-insensitive in BigQuery
+		if !strings.EqualFold(field.Name, name) {
+			continue
+		}
+
+		if !nested {
+			return true
+		}
+
+		return schemaHasField(field.Schema, rest)
+	}
+
+	return false
+}
+
+func parseFieldSchema(name, typ string) (*bigquery.FieldSchema, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("field name cannot be empty")
+	}
+
+	typ = strings.TrimSpace(typ)
+	if typ == "" {
+		return nil, fmt.Errorf("field '%s' has no type", name)
+	}
+
+	field := &bigquery.FieldSchema{Name: name}
+
+	upper := strings.ToUpper(typ)
+	if strings.HasSuffix(upper, " NOT NULL") {
+		field.Required = true
+		typ = strings.TrimSpace(typ[:len(typ)-len(" NOT NULL")])
+		upper = strings.ToUpper(typ)
+	}
+
+	if inner, ok := unwrapGeneric(typ, "ARRAY"); ok {
+		element, err := parseFieldSchema(name, inner)
+		if err != nil {
+			return nil, err
+		}
+		if element.Repeated {
+			return nil, fmt.Errorf("field '%s' is an array of arrays, which is not supported", name)
+		}
+		element.Repeated = true
+		element.Required = false
+		return element, nil
+	}
+
+	for _, keyword := range []string{"STRUCT", "RECORD"} {
+		inner, ok := unwrapGeneric(typ, keyword)
+		if !ok {
+			continue
+		}
+
+		field.Type = bigquery.RecordFieldType
+		for _, member := range splitTopLevel(inner, ',') {
+			parts := strings.SplitN(strings.TrimSpace(member), " ", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("invalid struct member '%s' in field '%s'", member, name)
+			}
+
+			nested, err := parseFieldSchema(strings.Trim(parts[0], "`"), parts[1])
+			if err != nil {
+				return nil, err
+			}
+			field.Schema = append(field.Schema, nested)
+		}
+
+		return field, nil
+	}
+
+	if inner, ok := unwrapGeneric(typ, "RANGE"); ok {
+		elementType, found := fieldTypeAliases[strings.ToUpper(strings.TrimSpace(inner))]
+		if !found {
+			return nil, fmt.Errorf("unsupported range element type '%s' in field '%s'", inner, name)
+		}
+		field.Type = bigquery.RangeFieldType
+		field.RangeElementType = &bigquery.RangeElementType{Type: elementType}
+		return field, nil
+	}
+
+	base := upper
+	params := ""
+	if idx := strings.Index(upper, "("); idx > 0 && strings.HasSuffix(upper, ")") {
+		base = strings.TrimSpace(upper[:idx])
+		params = upper[idx+1 : len(upper)-1]
+	}
+
+	fieldType, found := fieldTypeAliases[base]
+	if !found || fieldType == bigquery.RecordFieldType {
+		return nil, fmt.Errorf("unsupported type '%s' for field '%s'", typ, name)
+	}
+	field.Type = fieldType
+
+	if params != "" {
+		if err := applyTypeParameters(field, params); err != nil {
+			return nil, errors.Wrapf(err, "invalid type parameters for field '%s'", name)
+		}
+	}
+
+	return field, nil
+}
+
+// ddlTypeNames are the GoogleSQL names of the field types whose name in the API differs.
+var ddlTypeNames = map[bigquery.FieldType]string{
+	bigquery.IntegerFieldType: "INT64",
+	bigquery.FloatFieldType:   "FLOAT64",
+	bigquery.BooleanFieldType: "BOOL",
+}
+
+// fieldDDLType renders the type of the field the way DDL statements declare it, e.g. `ARRAY<STRING>`,
+// `NUMERIC(10, 2)` or `STRUCT<city STRING, zip INT64>`, the inverse of parseFieldSchema.
+func fieldDDLType(field *bigquery.FieldSchema) string {
+	typ := fieldElementDDLType(field)
+	if field.Repeated {
+		return "ARRAY<" + typ + ">"
+	}
+	if field.Required {
+		typ += " NOT NULL"
+	}
+
+	return typ
+}
+
+func fieldElementDDLType(field *bigquery.FieldSchema) string {
+	switch field.Type { //nolint:exhaustive
+	case bigquery.RecordFieldType:
+		members := make([]string, 0, len(field.Schema))
+		for _, nested := range field.Schema {
+			members = append(members, QuoteIdentifier(nested.Name)+" "+fieldDDLType(nested))
+		}
+		return "STRUCT<" + strings.Join(members, ", ") + ">"
+	case bigquery.RangeFieldType:
+		if field.RangeElementType == nil {
+			return string(field.Type)
+		}
+		return "RANGE<" + string(field.RangeElementType.Type) + ">"
+	}
+
+	typ := string(field.Type)
+	if name, ok := ddlTypeNames[field.Type]; ok {
+		typ = name
+	}
+	switch {
+	case field.MaxLength > 0:
+		return fmt.Sprintf("%s(%d)", typ, field.MaxLength)
+	case field.Precision > 0 && field.Scale > 0:
+		return fmt.Sprintf("%s(%d, %d)", typ, field.Precision, field.Scale)
+	case field.Precision > 0:
+		return fmt.Sprintf("%s(%d)", typ, field.Precision)
+	}
+
+	return typ
+}
+
+func applyTypeParameters(field *bigquery.FieldSchema, params string) error {
+	values := make([]int64, 0, 2)
+	for _, p := range strings.Split(params, ",") {
+		v, err := strconv.ParseInt(strings.TrimSpace(p), 10, 64)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a valid number", p)
+		}
+		values = append(values, v)
+	}
+
+	switch field.Type { //nolint:exhaustive
+	case bigquery.StringFieldType, bigquery.BytesFieldType:
+		if len(values) != 1 {
+			return fmt.Errorf("%s accepts a single max length parameter", field.Type)
+		}
+		field.MaxLength = values[0]
+	case bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+		if len(values) > 2 {
+			return fmt.Errorf("%s accepts at most precision and scale parameters", field.Type)
+		}
+		field.Precision = values[0]
+		if len(values) == 2 {
+			field.Scale = values[1]
+		}
+	default:
+		return fmt.Errorf("%s does not accept parameters", field.Type)
+	}
+
+	return nil
+}
+
+// unwrapGeneric returns the inner part of a generic type such as `ARRAY<STRING>` if the type uses the given keyword.
+func unwrapGeneric(typ, keyword string) (string, bool) {
+	upper := strings.ToUpper(typ)
+	if !strings.HasPrefix(upper, keyword) || !strings.HasSuffix(upper, ">") {
+		return "", false
+	}
+
+	rest := strings.TrimSpace(typ[len(keyword):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", false
+	}
+
+	return strings.TrimSpace(rest[1 : len(rest)-1]), true
+}
+
+// splitTopLevel splits the string on the given separator, ignoring separators nested in <> or ().
+func splitTopLevel(s string, sep rune) []string {
+	parts := make([]string, 0)
+	depth := 0
+	start := 0
+	for i, r := range s {
+		switch r {
+		case '<', '(':
+			depth++
+		case '>', ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// ColumnRename records a column that was renamed during sanitization. Nested fields use dotted paths.
+type ColumnRename struct {
+	From string
+	To   string
+}
+
+// SanitizeColumnName replaces the characters BigQuery does not accept in column names with underscores,
+// and makes sure the name starts with a letter or an underscore.
+func SanitizeColumnName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteRune('_')
+	}
+
+	sanitized := b.String()
+	if sanitized == "" || (sanitized[0] >= '0' && sanitized[0] <= '9') {
+		sanitized = "_" + sanitized
+	}
+
+	if len(sanitized) > maxColumnNameLength {
+		sanitized = sanitized[:maxColumnNameLength]
+	}
+
+	return sanitized
+}
+
+// SanitizeSchema returns a copy of the schema with every field name sanitized, see SanitizeColumnName.
+// Names that collide after sanitization get a numeric suffix. The applied renames are returned in schema order.
+func SanitizeSchema(schema bigquery.Schema) (bigquery.Schema, []ColumnRename) {
+	return sanitizeSchema(schema, "")
+}
+
+func sanitizeSchema(schema bigquery.Schema, prefix string) (bigquery.Schema, []ColumnRename) {
+	renames := make([]ColumnRename, 0)
+	sanitized := make(bigquery.Schema, 0, len(schema))
+
+	// column names are case-insensitive, collisions are checked accordingly
+	taken := make(map[string]bool, len(schema))
+	for _, field := range schema {
+		if SanitizeColumnName(field.Name) == field.Name {
+			taken[strings.ToLower(field.Name)] = true
+		}
+	}
+
+	for _, field := range schema {
+		copied := *field
+		name := SanitizeColumnName(field.Name)
+		if name != field.Name {
+			base := name
+			for i := 2; taken[strings.ToLower(name)]; i++ {
+				suffix := "_" + strconv.Itoa(i)
+				if len(base)+len(suffix) > maxColumnNameLength {
+					base = base[:maxColumnNameLength-len(suffix)]
+				}
+				name = base + suffix
+			}
+			taken[strings.ToLower(name)] = true
+			renames = append(renames, ColumnRename{From: prefix + field.Name, To: prefix + name})
+		}
+		copied.Name = name
+
+		if len(field.Schema) > 0 {
+			var nested []ColumnRename
+			copied.Schema, nested = sanitizeSchema(field.Schema, prefix+name+".")
+			renames = append(renames, nested...)
+		}
+
+		sanitized = append(sanitized, &copied)
+	}
+
+	return sanitized, renames
+}
@@ -0,0 +1,187 @@
+package bigquery
+
+import (
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// SchemaChangeKind is the kind of difference a SchemaChange describes.
+type SchemaChangeKind string
+
+const (
+	SchemaChangeAdded       SchemaChangeKind = "added"
+	SchemaChangeRemoved     SchemaChangeKind = "removed"
+	SchemaChangeTypeChanged SchemaChangeKind = "type_changed"
+	SchemaChangeModeChanged SchemaChangeKind = "mode_changed"
+
+	// SchemaChangeCollationChanged and SchemaChangeDefaultChanged are changes to the settings of an existing column,
+	// which are applied in place through a metadata update.
+	SchemaChangeCollationChanged SchemaChangeKind = "collation_changed"
+	SchemaChangeDefaultChanged   SchemaChangeKind = "default_changed"
+)
+
+// SchemaChange is a single difference between two schemas. Path is the dotted path of the field, From and To
+// hold the old and new type, mode, collation or default value expression where relevant.
+type SchemaChange struct {
+	Path     string
+	Kind     SchemaChangeKind
+	From     string
+	To       string
+	Breaking bool
+}
+
+// SchemaDiff lists the differences between the schema of a table and the desired schema.
+//
+// The order of the fields is not part of the diff: BigQuery keeps the column order of a table fixed and appends
+// new columns at the end, so a desired schema listing the same fields in a different order is not a change and
+// never requires the table to be rebuilt. Reordered only reports that the order differs.
+type SchemaDiff struct {
+	Changes   []SchemaChange
+	Reordered bool
+}
+
+// IsEmpty reports whether the schemas have the same fields, regardless of their order.
+func (d *SchemaDiff) IsEmpty() bool {
+	return len(d.Changes) == 0
+}
+
+// IsBreaking reports whether the table has to be rebuilt to get to the desired schema. Adding NULLABLE or REPEATED
+// fields, relaxing REQUIRED fields to NULLABLE and changing the collation or the default value of fields can be
+// applied in place, every other change is breaking.
+func (d *SchemaDiff) IsBreaking() bool {
+	for _, change := range d.Changes {
+		if change.Breaking {
+			return true
+		}
+	}
+
+	return false
+}
+
+// OnlySettingsChanged reports whether the only differences are the collations or default values of existing
+// fields, which a metadata update applies without touching the data.
+func (d *SchemaDiff) OnlySettingsChanged() bool {
+	for _, change := range d.Changes {
+		if change.Kind != SchemaChangeCollationChanged && change.Kind != SchemaChangeDefaultChanged {
+			return false
+		}
+	}
+
+	return len(d.Changes) > 0
+}
+
+// DiffSchema compares the schema of a table with the desired schema. Field names are compared case-insensitively,
+// as BigQuery does, and the fields of records are compared one by one. The collation and the default value of a
+// field are only compared when the desired field declares them, so that the settings made outside of the desired
+// schema are not reported.
+func DiffSchema(current, desired bigquery.Schema) *SchemaDiff {
+	diff := &SchemaDiff{Changes: []SchemaChange{}}
+	diffSchema(current, desired, "", diff)
+
+	return diff
+}
+
+func diffSchema(current, desired bigquery.Schema, prefix string, diff *SchemaDiff) {
+	currentByName := make(map[string]*bigquery.FieldSchema, len(current))
+	currentOrder := make([]string, 0, len(current))
+	for _, field := range current {
+		key := strings.ToLower(field.Name)
+		currentByName[key] = field
+		currentOrder = append(currentOrder, key)
+	}
+
+	desiredOrder := make([]string, 0, len(desired))
+	seen := make(map[string]bool, len(desired))
+	for _, field := range desired {
+		key := strings.ToLower(field.Name)
+		seen[key] = true
+		path := prefix + field.Name
+
+		existing, ok := currentByName[key]
+		if !ok {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Path:     path,
+				Kind:     SchemaChangeAdded,
+				To:       CanonicalType(field),
+				Breaking: field.Required,
+			})
+			continue
+		}
+		desiredOrder = append(desiredOrder, key)
+
+		if existing.Type != field.Type {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Path:     path,
+				Kind:     SchemaChangeTypeChanged,
+				From:     CanonicalType(existing),
+				To:       CanonicalType(field),
+				Breaking: true,
+			})
+			continue
+		}
+
+		if from, to := FieldMode(existing), FieldMode(field); from != to {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Path:     path,
+				Kind:     SchemaChangeModeChanged,
+				From:     from,
+				To:       to,
+				Breaking: !(from == FieldModeRequired && to == FieldModeNullable),
+			})
+		}
+
+		if field.Collation != "" && existing.Collation != field.Collation {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Path: path,
+				Kind: SchemaChangeCollationChanged,
+				From: existing.Collation,
+				To:   field.Collation,
+			})
+		}
+
+		if field.DefaultValueExpression != "" && !isSameDefaultValue(existing.DefaultValueExpression, field.DefaultValueExpression) {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Path: path,
+				Kind: SchemaChangeDefaultChanged,
+				From: existing.DefaultValueExpression,
+				To:   field.DefaultValueExpression,
+			})
+		}
+
+		if field.Type == bigquery.RecordFieldType {
+			diffSchema(existing.Schema, field.Schema, path+".", diff)
+		}
+	}
+
+	for _, field := range current {
+		if !seen[strings.ToLower(field.Name)] {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Path:     prefix + field.Name,
+				Kind:     SchemaChangeRemoved,
+				From:     CanonicalType(field),
+				Breaking: true,
+			})
+		}
+	}
+
+	// only the fields present in both schemas decide whether they were reordered
+	common := make([]string, 0, len(desiredOrder))
+	for _, key := range currentOrder {
+		if seen[key] {
+			common = append(common, key)
+		}
+	}
+	for i := range common {
+		if common[i] != desiredOrder[i] {
+			diff.Reordered = true
+			break
+		}
+	}
+}
+
+// isSameDefaultValue compares default value expressions regardless of their whitespace and comments, see
+// NormalizeQuery.
+func isSameDefaultValue(current, desired string) bool {
+	return NormalizeQuery(current) == NormalizeQuery(desired)
+}
@@ -0,0 +1,127 @@
+package bigquery
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSchema(t *testing.T) {
+	t.Parallel()
+
+	current := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+		{Name: "name", Type: bigquery.StringFieldType, Collation: "und:ci", DefaultValueExpression: "'unknown'"},
+		{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "city", Type: bigquery.StringFieldType},
+			{Name: "zip", Type: bigquery.StringFieldType},
+		}},
+	}
+
+	tests := []struct {
+		name          string
+		desired       bigquery.Schema
+		wantChanges   []SchemaChange
+		wantReordered bool
+		wantBreaking  bool
+		wantSettings  bool
+	}{
+		{
+			name:        "identical schemas",
+			desired:     current,
+			wantChanges: []SchemaChange{},
+		},
+		{
+			name: "reordered fields are not a change",
+			desired: bigquery.Schema{
+				{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+					{Name: "zip", Type: bigquery.StringFieldType},
+					{Name: "city", Type: bigquery.StringFieldType},
+				}},
+				{Name: "NAME", Type: bigquery.StringFieldType},
+				{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+			},
+			wantChanges:   []SchemaChange{},
+			wantReordered: true,
+		},
+		{
+			name: "additive changes are not breaking",
+			desired: bigquery.Schema{
+				{Name: "id", Type: bigquery.IntegerFieldType},
+				{Name: "name", Type: bigquery.StringFieldType},
+				{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+					{Name: "city", Type: bigquery.StringFieldType},
+					{Name: "zip", Type: bigquery.StringFieldType},
+					{Name: "country", Type: bigquery.StringFieldType},
+				}},
+				{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+			},
+			wantChanges: []SchemaChange{
+				{Path: "id", Kind: SchemaChangeModeChanged, From: "REQUIRED", To: "NULLABLE"},
+				{Path: "address.country", Kind: SchemaChangeAdded, To: "string"},
+				{Path: "tags", Kind: SchemaChangeAdded, To: "array<string>"},
+			},
+		},
+		{
+			name: "collation and default value drift is not breaking",
+			desired: bigquery.Schema{
+				{Name: "id", Type: bigquery.IntegerFieldType, Required: true, DefaultValueExpression: "GENERATE_UUID()"},
+				{Name: "name", Type: bigquery.StringFieldType, Collation: "", DefaultValueExpression: "'n/a'"},
+				{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+					{Name: "city", Type: bigquery.StringFieldType, Collation: "und:ci"},
+					{Name: "zip", Type: bigquery.StringFieldType},
+				}},
+			},
+			wantChanges: []SchemaChange{
+				{Path: "id", Kind: SchemaChangeDefaultChanged, To: "GENERATE_UUID()"},
+				{Path: "name", Kind: SchemaChangeDefaultChanged, From: "'unknown'", To: "'n/a'"},
+				{Path: "address.city", Kind: SchemaChangeCollationChanged, To: "und:ci"},
+			},
+			wantSettings: true,
+		},
+		{
+			name: "default values are compared regardless of whitespace",
+			desired: bigquery.Schema{
+				{Name: "id", Type: bigquery.IntegerFieldType, Required: true},
+				{Name: "name", Type: bigquery.StringFieldType, Collation: "und:ci", DefaultValueExpression: "  'unknown' "},
+				{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+					{Name: "city", Type: bigquery.StringFieldType},
+					{Name: "zip", Type: bigquery.StringFieldType},
+				}},
+			},
+			wantChanges: []SchemaChange{},
+		},
+		{
+			name: "breaking changes",
+			desired: bigquery.Schema{
+				{Name: "id", Type: bigquery.StringFieldType},
+				{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+					{Name: "city", Type: bigquery.StringFieldType, Required: true},
+					{Name: "zip", Type: bigquery.StringFieldType},
+				}},
+				{Name: "created_at", Type: bigquery.TimestampFieldType, Required: true},
+			},
+			wantChanges: []SchemaChange{
+				{Path: "id", Kind: SchemaChangeTypeChanged, From: "int64", To: "string", Breaking: true},
+				{Path: "address.city", Kind: SchemaChangeModeChanged, From: "NULLABLE", To: "REQUIRED", Breaking: true},
+				{Path: "created_at", Kind: SchemaChangeAdded, To: "timestamp", Breaking: true},
+				{Path: "name", Kind: SchemaChangeRemoved, From: "string", Breaking: true},
+			},
+			wantBreaking: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			diff := DiffSchema(current, tt.desired)
+			assert.Equal(t, tt.wantChanges, diff.Changes)
+			assert.Equal(t, tt.wantReordered, diff.Reordered)
+			assert.Equal(t, len(tt.wantChanges) == 0, diff.IsEmpty())
+			assert.Equal(t, tt.wantBreaking, diff.IsBreaking())
+			assert.Equal(t, tt.wantSettings, diff.OnlySettingsChanged())
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+	"google.golang.org/api/googleapi"
+)
+
+// SchemaEvolutionError is returned when the declared columns of an asset differ from the schema of its table in a
+// way that cannot be applied in place, e.g. a removed column or a changed type, which would lose data. The table
+// has to be rebuilt, e.g. with a full refresh, to get to the declared schema.
+type SchemaEvolutionError struct {
+	Table    string
+	Problems []string
+}
+
+func (e *SchemaEvolutionError) Error() string {
+	return fmt.Sprintf(
+		"cannot evolve the schema of table '%s' in place: %s; run a full refresh to rebuild the table",
+		e.Table,
+		strings.Join(e.Problems, "; "),
+	)
+}
+
+// EvolveSchema adds the columns the asset declares but its table does not have yet as NULLABLE columns and widens
+// the columns whose declared type holds every value of their current type, e.g. INT64 to NUMERIC, so that writing
+// into the existing table does not fail with a schema mismatch. It only applies to the strategies that write into
+// the existing table, i.e. every table strategy but create+replace, and only if Config.AllowSchemaEvolution is set.
+//
+// Only the columns that declare a type are compared, the columns of the table the asset does not declare are left
+// as they are, and so are its REQUIRED columns. Retypes that could lose data and changes to the fields of records
+// are not applied, they are reported through a SchemaEvolutionError without changing the table. The applied
+// changes are returned, nothing is done for tables that do not exist yet or assets that do not declare typed columns.
+func (d *Client) EvolveSchema(ctx context.Context, asset *pipeline.Asset) ([]SchemaChange, error) {
+	if d.config == nil || !d.config.AllowSchemaEvolution || !writesIntoExistingTable(asset) {
+		return nil, nil
+	}
+
+	typed := make([]pipeline.Column, 0, len(asset.Columns))
+	for _, column := range asset.Columns {
+		if strings.TrimSpace(column.Type) != "" {
+			typed = append(typed, column)
+		}
+	}
+	if len(typed) == 0 {
+		return nil, nil
+	}
+
+	desiredAsset := *asset
+	desiredAsset.Columns = typed
+	desired, err := SchemaFromAsset(&desiredAsset)
+	if err != nil {
+		return nil, err
+	}
+
+	tableRef, err := d.getTableRef(asset.Name)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := tableRef.Metadata(ctx)
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == 404 {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(formatError(err), "failed to fetch metadata for table '%s'", asset.Name)
+	}
+
+	problems := make([]string, 0)
+	applied := make([]SchemaChange, 0)
+	for _, change := range DiffSchema(meta.Schema, desired).Changes {
+		if problem := schemaEvolutionProblem(change); problem != "" {
+			problems = append(problems, problem)
+			continue
+		}
+		if change.Kind == SchemaChangeAdded || change.Kind == SchemaChangeTypeChanged {
+			applied = append(applied, change)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &SchemaEvolutionError{Table: asset.Name, Problems: problems}
+	}
+	if len(applied) == 0 {
+		return nil, nil
+	}
+
+	reference, err := d.TableReference(asset.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, statement := range buildSchemaEvolutionQueries(reference, desired, applied) {
+		if err := d.RunQueryWithoutResult(ctx, &query.Query{Query: statement}); err != nil {
+			return nil, errors.Wrapf(err, "failed to evolve the schema of table '%s'", asset.Name)
+		}
+	}
+
+	return applied, nil
+}
+
+// writesIntoExistingTable reports whether the materialization of the asset writes into its existing table rather
+// than replacing it.
+func writesIntoExistingTable(asset *pipeline.Asset) bool {
+	if asset.Materialization.Type != pipeline.MaterializationTypeTable {
+		return false
+	}
+
+	switch asset.Materialization.Strategy { //nolint:exhaustive
+	case pipeline.MaterializationStrategyNone, pipeline.MaterializationStrategyCreateReplace:
+		return false
+	default:
+		return true
+	}
+}
+
+// widenableTypes are the canonical types each type can be changed to in place, without losing any value.
+var widenableTypes = map[string][]string{
+	"int64":   {"numeric", "bignumeric", "float64"},
+	"numeric": {"bignumeric", "float64"},
+}
+
+func isWidening(from, to string) bool {
+	for _, wider := range widenableTypes[from] {
+		if wider == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// schemaEvolutionProblem describes why the change cannot be applied in place, or returns an empty string if it can
+// or needs nothing to be done.
+func schemaEvolutionProblem(change SchemaChange) string {
+	switch change.Kind {
+	case SchemaChangeRemoved, SchemaChangeCollationChanged, SchemaChangeDefaultChanged:
+		// columns the asset does not declare are kept, metadata is updated separately
+		return ""
+	}
+	if strings.Contains(change.Path, ".") && change.Kind != SchemaChangeModeChanged {
+		return fmt.Sprintf("field '%s' of a record changed, which DDL statements cannot apply", change.Path)
+	}
+
+	switch change.Kind { //nolint:exhaustive
+	case SchemaChangeAdded:
+		if change.Breaking {
+			return fmt.Sprintf("column '%s' is added as REQUIRED, which existing rows cannot satisfy", change.Path)
+		}
+	case SchemaChangeTypeChanged:
+		if !isWidening(change.From, change.To) {
+			return fmt.Sprintf("column '%s' changed type from %s to %s, which could lose data", change.Path, change.From, change.To)
+		}
+	case SchemaChangeModeChanged:
+		// the columns of assets are always NULLABLE, REQUIRED columns of the table are kept
+		if change.Breaking {
+			return fmt.Sprintf("column '%s' changed mode from %s to %s", change.Path, change.From, change.To)
+		}
+	}
+
+	return ""
+}
+
+// buildSchemaEvolutionQueries builds the statements that apply the changes to the table: one that adds the new
+// columns and one that widens the retyped ones, with the types of the desired schema.
+func buildSchemaEvolutionQueries(tableName string, desired bigquery.Schema, changes []SchemaChange) []string {
+	fields := make(map[string]*bigquery.FieldSchema, len(desired))
+	for _, field := range desired {
+		fields[strings.ToLower(field.Name)] = field
+	}
+
+	added := make([]string, 0, len(changes))
+	retyped := make([]string, 0, len(changes))
+	for _, change := range changes {
+		field, ok := fields[strings.ToLower(change.Path)]
+		if !ok {
+			continue
+		}
+		switch change.Kind { //nolint:exhaustive
+		case SchemaChangeAdded:
+			added = append(added, fmt.Sprintf("ADD COLUMN IF NOT EXISTS %s %s", QuoteIdentifier(change.Path), fieldDDLType(field)))
+		case SchemaChangeTypeChanged:
+			retyped = append(retyped, fmt.Sprintf("ALTER COLUMN %s SET DATA TYPE %s", QuoteIdentifier(change.Path), fieldElementDDLType(field)))
+		}
+	}
+
+	statements := make([]string, 0, 2)
+	for _, actions := range [][]string{added, retyped} {
+		if len(actions) > 0 {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s %s;", tableName, strings.Join(actions, ", ")))
+		}
+	}
+
+	return statements
+}
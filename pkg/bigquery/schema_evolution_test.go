@@ -0,0 +1,180 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestBuildSchemaEvolutionQueries(t *testing.T) {
+	t.Parallel()
+
+	desired, err := SchemaFromAsset(&pipeline.Asset{
+		Name: "mart.events",
+		Columns: []pipeline.Column{
+			{Name: "id", Type: "NUMERIC(20)"},
+			{Name: "Country", Type: "VARCHAR"},
+			{Name: "tags", Type: "ARRAY<STRING(10)>"},
+			{Name: "order", Type: "INTEGER"},
+			{Name: "address", Type: "STRUCT<city TEXT, zip INT, location RANGE<DATE>>"},
+		},
+	})
+	require.NoError(t, err)
+
+	got := buildSchemaEvolutionQueries("`project.mart.events`", desired, []SchemaChange{
+		{Path: "id", Kind: SchemaChangeTypeChanged, From: "int64", To: "numeric"},
+		{Path: "country", Kind: SchemaChangeAdded, To: "string"},
+		{Path: "tags", Kind: SchemaChangeAdded, To: "array<string>"},
+		{Path: "order", Kind: SchemaChangeAdded, To: "int64"},
+		{Path: "address", Kind: SchemaChangeAdded, To: "struct<city string, zip int64, location range<date>>"},
+	})
+	assert.Equal(t, []string{
+		"ALTER TABLE `project.mart.events` ADD COLUMN IF NOT EXISTS `country` STRING, ADD COLUMN IF NOT EXISTS `tags` ARRAY<STRING(10)>, " +
+			"ADD COLUMN IF NOT EXISTS `order` INT64, ADD COLUMN IF NOT EXISTS `address` STRUCT<`city` STRING, `zip` INT64, `location` RANGE<DATE>>;",
+		"ALTER TABLE `project.mart.events` ALTER COLUMN `id` SET DATA TYPE NUMERIC(20);",
+	}, got)
+}
+
+func TestClient_EvolveSchema(t *testing.T) {
+	t.Parallel()
+
+	currentSchema := &bigquery2.TableSchema{Fields: []*bigquery2.TableFieldSchema{
+		{Name: "id", Type: "INTEGER", Mode: "REQUIRED"},
+		{Name: "name", Type: "STRING"},
+	}}
+	appendAsset := func(columns ...pipeline.Column) *pipeline.Asset {
+		return &pipeline.Asset{
+			Name:    "mart.events",
+			Columns: columns,
+			Materialization: pipeline.Materialization{
+				Type:     pipeline.MaterializationTypeTable,
+				Strategy: pipeline.MaterializationStrategyAppend,
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		disabled    bool
+		missing     bool
+		asset       *pipeline.Asset
+		wantChanges []SchemaChange
+		wantQueries []string
+		wantErr     string
+	}{
+		{
+			name:  "new nullable columns are added",
+			asset: appendAsset(pipeline.Column{Name: "id", Type: "INT64"}, pipeline.Column{Name: "name", Type: "STRING"}, pipeline.Column{Name: "country", Type: "STRING"}),
+			wantChanges: []SchemaChange{
+				{Path: "country", Kind: SchemaChangeAdded, To: "string"},
+			},
+			wantQueries: []string{
+				"ALTER TABLE mart.events ADD COLUMN IF NOT EXISTS `country` STRING;",
+			},
+		},
+		{
+			name: "undeclared and untyped columns are left as they are",
+			asset: appendAsset(
+				pipeline.Column{Name: "name"},
+				pipeline.Column{Name: "country", Type: "VARCHAR(2)"},
+				pipeline.Column{Name: "visits"},
+			),
+			wantChanges: []SchemaChange{
+				{Path: "country", Kind: SchemaChangeAdded, To: "string"},
+			},
+			wantQueries: []string{
+				"ALTER TABLE mart.events ADD COLUMN IF NOT EXISTS `country` STRING(2);",
+			},
+		},
+		{
+			name:  "widened columns are retyped",
+			asset: appendAsset(pipeline.Column{Name: "id", Type: "NUMERIC"}),
+			wantChanges: []SchemaChange{
+				{Path: "id", Kind: SchemaChangeTypeChanged, From: "int64", To: "numeric", Breaking: true},
+			},
+			wantQueries: []string{
+				"ALTER TABLE mart.events ALTER COLUMN `id` SET DATA TYPE NUMERIC;",
+			},
+		},
+		{
+			name: "same schema",
+			asset: appendAsset(
+				pipeline.Column{Name: "id", Type: "INT64", Checks: []pipeline.ColumnCheck{{Name: "not_null"}}},
+				pipeline.Column{Name: "name", Type: "STRING"},
+			),
+		},
+		{
+			name:    "narrowing retypes are rejected",
+			asset:   appendAsset(pipeline.Column{Name: "id", Type: "STRING"}, pipeline.Column{Name: "name", Type: "INT64"}),
+			wantErr: "cannot evolve the schema of table 'mart.events' in place: column 'id' changed type from int64 to string, which could lose data; column 'name' changed type from string to int64, which could lose data; run a full refresh to rebuild the table",
+		},
+		{
+			name:     "disabled",
+			disabled: true,
+			asset:    appendAsset(pipeline.Column{Name: "country", Type: "STRING"}),
+		},
+		{
+			name:    "table does not exist yet",
+			missing: true,
+			asset:   appendAsset(pipeline.Column{Name: "country", Type: "STRING"}),
+		},
+		{
+			name: "create+replace rebuilds the table",
+			asset: &pipeline.Asset{
+				Name:            "mart.events",
+				Columns:         []pipeline.Column{{Name: "country", Type: "STRING"}},
+				Materialization: pipeline.Materialization{Type: pipeline.MaterializationTypeTable},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &recordingQueryHandler{
+				fallback: func(w http.ResponseWriter, r *http.Request) {
+					if r.Method != http.MethodGet || r.URL.Path != fmt.Sprintf("/projects/%s/datasets/mart/tables/events", testProjectID) {
+						http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+						return
+					}
+					if tt.missing {
+						w.WriteHeader(http.StatusNotFound)
+						_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table test-project:mart.events"}}`))
+						return
+					}
+					_ = json.NewEncoder(w).Encode(&bigquery2.Table{Schema: currentSchema})
+				},
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.AllowSchemaEvolution = !tt.disabled
+
+			changes, err := d.EvolveSchema(context.Background(), tt.asset)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+
+				var evolutionErr *SchemaEvolutionError
+				require.ErrorAs(t, err, &evolutionErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantChanges, changes)
+			if tt.wantQueries == nil {
+				assert.Empty(t, handler.recordedQueries())
+			} else {
+				assert.Equal(t, tt.wantQueries, handler.recordedQueries())
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestSchemaFromAsset(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		columns []pipeline.Column
+		want    bigquery.Schema
+		wantErr string
+	}{
+		{
+			name:    "no columns",
+			wantErr: "asset 'dataset.table' has no columns defined to build a schema from",
+		},
+		{
+			name: "scalar types and aliases",
+			columns: []pipeline.Column{
+				{Name: "id", Type: "int64", Description: "the id"},
+				{Name: "name", Type: "STRING(20)"},
+				{Name: "price", Type: "NUMERIC(10, 2)"},
+				{Name: "active", Type: "bool NOT NULL"},
+				{Name: "created_at", Type: "timestamp"},
+				{Name: "email", Type: "VARCHAR(255)"},
+			},
+			want: bigquery.Schema{
+				{Name: "id", Type: bigquery.IntegerFieldType, Description: "the id"},
+				{Name: "name", Type: bigquery.StringFieldType, MaxLength: 20},
+				{Name: "price", Type: bigquery.NumericFieldType, Precision: 10, Scale: 2},
+				{Name: "active", Type: bigquery.BooleanFieldType, Required: true},
+				{Name: "created_at", Type: bigquery.TimestampFieldType},
+				{Name: "email", Type: bigquery.StringFieldType, MaxLength: 255},
+			},
+		},
+		{
+			name: "arrays and structs",
+			columns: []pipeline.Column{
+				{Name: "tags", Type: "ARRAY<STRING>"},
+				{Name: "address", Type: "STRUCT<city STRING, zip INT64, geo STRUCT<lat FLOAT64, lng FLOAT64>>"},
+				{Name: "items", Type: "array<struct<sku string, qty int64>>"},
+				{Name: "validity", Type: "RANGE<DATE>"},
+			},
+			want: bigquery.Schema{
+				{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+				{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+					{Name: "city", Type: bigquery.StringFieldType},
+					{Name: "zip", Type: bigquery.IntegerFieldType},
+					{Name: "geo", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+						{Name: "lat", Type: bigquery.FloatFieldType},
+						{Name: "lng", Type: bigquery.FloatFieldType},
+					}},
+				}},
+				{Name: "items", Type: bigquery.RecordFieldType, Repeated: true, Schema: bigquery.Schema{
+					{Name: "sku", Type: bigquery.StringFieldType},
+					{Name: "qty", Type: bigquery.IntegerFieldType},
+				}},
+				{Name: "validity", Type: bigquery.RangeFieldType, RangeElementType: &bigquery.RangeElementType{Type: bigquery.DateFieldType}},
+			},
+		},
+		{
+			name:    "missing type",
+			columns: []pipeline.Column{{Name: "id"}},
+			wantErr: "invalid column 'id' in asset 'dataset.table': field 'id' has no type",
+		},
+		{
+			name:    "unknown type",
+			columns: []pipeline.Column{{Name: "id", Type: "UUID"}},
+			wantErr: "invalid column 'id' in asset 'dataset.table': unsupported type 'UUID' for field 'id'",
+		},
+		{
+			name:    "nested arrays",
+			columns: []pipeline.Column{{Name: "matrix", Type: "ARRAY<ARRAY<INT64>>"}},
+			wantErr: "invalid column 'matrix' in asset 'dataset.table': field 'matrix' is an array of arrays, which is not supported",
+		},
+		{
+			name:    "parameters on a type without parameters",
+			columns: []pipeline.Column{{Name: "id", Type: "INT64(10)"}},
+			wantErr: "invalid column 'id' in asset 'dataset.table': invalid type parameters for field 'id': INTEGER does not accept parameters",
+		},
+		{
+			name: "duplicate columns",
+			columns: []pipeline.Column{
+				{Name: "id", Type: "INT64"},
+				{Name: "ID", Type: "STRING"},
+			},
+			wantErr: "invalid schema for asset 'dataset.table': duplicate field 'ID'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := SchemaFromAsset(&pipeline.Asset{Name: "dataset.table", Columns: tt.columns})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	t.Parallel()
+
+	require.EqualError(t, ValidateSchema(nil), "schema must have at least one field")
+	require.EqualError(t, ValidateSchema(bigquery.Schema{{Name: "a"}}), "field 'a' has no type")
+	require.EqualError(t, ValidateSchema(bigquery.Schema{{Name: "a", Type: bigquery.RecordFieldType}}), "invalid nested schema for field 'a': schema must have at least one field")
+	require.NoError(t, ValidateSchema(bigquery.Schema{{Name: "a", Type: bigquery.StringFieldType}}))
+}
+
+func TestSanitizeColumnName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"id":                     "id",
+		"first name":             "first_name",
+		"e-mail":                 "e_mail",
+		"2fa_enabled":            "_2fa_enabled",
+		"":                       "_",
+		"price (€)":              "price____",
+		strings.Repeat("a", 310): strings.Repeat("a", 300),
+	}
+
+	for input, want := range tests {
+		assert.Equal(t, want, SanitizeColumnName(input), input)
+	}
+}
+
+func TestSanitizeSchema(t *testing.T) {
+	t.Parallel()
+
+	original := bigquery.Schema{
+		{Name: "user id", Type: bigquery.IntegerFieldType},
+		{Name: "User_ID", Type: bigquery.StringFieldType},
+		{Name: "user.id", Type: bigquery.StringFieldType},
+		{Name: "home address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "zip code", Type: bigquery.StringFieldType},
+		}},
+	}
+
+	got, renames := SanitizeSchema(original)
+	assert.Equal(t, bigquery.Schema{
+		{Name: "user_id_2", Type: bigquery.IntegerFieldType},
+		{Name: "User_ID", Type: bigquery.StringFieldType},
+		{Name: "user_id_3", Type: bigquery.StringFieldType},
+		{Name: "home_address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "zip_code", Type: bigquery.StringFieldType},
+		}},
+	}, got)
+	assert.Equal(t, []ColumnRename{
+		{From: "user id", To: "user_id_2"},
+		{From: "user.id", To: "user_id_3"},
+		{From: "home address", To: "home_address"},
+		{From: "home_address.zip code", To: "home_address.zip_code"},
+	}, renames)
+
+	// the original schema must not be modified
+	assert.Equal(t, "user id", original[0].Name)
+	assert.Equal(t, "zip code", original[3].Schema[0].Name)
+}
+
+func TestClient_ValidateColumnsExist(t *testing.T) {
+	t.Parallel()
+
+	var metadataCalls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != fmt.Sprintf("/projects/%s/datasets/dataset/tables/users", testProjectID) {
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+			return
+		}
+
+		metadataCalls.Add(1)
+		_ = json.NewEncoder(w).Encode(&bigquery2.Table{
+			Schema: &bigquery2.TableSchema{Fields: []*bigquery2.TableFieldSchema{
+				{Name: "id", Type: "INTEGER"},
+				{Name: "address", Type: "RECORD", Fields: []*bigquery2.TableFieldSchema{
+					{Name: "city", Type: "STRING"},
+				}},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	// validation is opt-in, no API call must be made by default
+	require.NoError(t, d.ValidateColumnsExist(context.Background(), "dataset.users", []string{"missing"}))
+	assert.Equal(t, int32(0), metadataCalls.Load())
+
+	d.config.ValidateCheckColumns = true
+	require.NoError(t, d.ValidateColumnsExist(context.Background(), "dataset.users", []string{"ID", "address.city"}))
+	require.EqualError(t, d.ValidateColumnsExist(context.Background(), "dataset.users", []string{"id", "email"}), "column email does not exist on dataset.users")
+	require.EqualError(t, d.ValidateColumnsExist(context.Background(), "dataset.users", []string{"address.zip"}), "column address.zip does not exist on dataset.users")
+	assert.Equal(t, int32(3), metadataCalls.Load())
+}
+
+func TestClient_DescribeTable(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/projects/%s/datasets/dataset/tables/users", testProjectID):
+			_ = json.NewEncoder(w).Encode(&bigquery2.Table{
+				TableReference: &bigquery2.TableReference{ProjectId: testProjectID, DatasetId: "dataset", TableId: "users"},
+				Type:           "TABLE",
+				Description:    "All the users",
+				Schema: &bigquery2.TableSchema{Fields: []*bigquery2.TableFieldSchema{
+					{Name: "id", Type: "INTEGER", Mode: "REQUIRED", Description: "The user identifier"},
+					{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+					{Name: "address", Type: "RECORD", Fields: []*bigquery2.TableFieldSchema{
+						{Name: "city", Type: "STRING", Description: "The city"},
+					}},
+				}},
+			})
+		case fmt.Sprintf("/projects/%s/datasets/dataset/tables/missing", testProjectID):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": {"code": 404, "message": "Not found: Table test-project:dataset.missing"}}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error": {"code": 403, "message": "Access Denied"}}`))
+		}
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	got, err := d.DescribeTable(context.Background(), "dataset.users")
+	require.NoError(t, err)
+	assert.Equal(t, &TableSchema{
+		Table:       "test-project.dataset.users",
+		Type:        "TABLE",
+		Description: "All the users",
+		Columns: []ColumnSchema{
+			{Name: "id", Type: "INTEGER", Mode: "REQUIRED", Description: "The user identifier"},
+			{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+			{Name: "address", Type: "RECORD", Mode: "NULLABLE", Fields: []ColumnSchema{
+				{Name: "city", Type: "STRING", Mode: "NULLABLE", Description: "The city"},
+			}},
+		},
+	}, got)
+
+	_, err = d.DescribeTable(context.Background(), "dataset.missing")
+	require.ErrorIs(t, err, ErrTableNotFound)
+	require.EqualError(t, err, "failed to describe table 'dataset.missing': table does not exist")
+
+	_, err = d.DescribeTable(context.Background(), "dataset.forbidden")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, ErrTableNotFound)
+}
@@ -0,0 +1,292 @@
+package bigquery
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// ResultIterator iterates over the rows read by SelectIterator, first over the rows held in memory and then over
+// the rows spilled to its temporary file. It must be closed to remove the file.
+type ResultIterator struct {
+	Schema bigquery.Schema
+
+	rows      [][]interface{}
+	next      int
+	totalRows int64
+
+	file    *os.File
+	decoder *json.Decoder
+}
+
+// ResultMemoryCapError is returned by Select when the results of the query have more rows than
+// Config.ResultMemoryCap allows to hold in memory, SelectIterator reads such results by spilling them to a file.
+type ResultMemoryCapError struct {
+	Cap int
+}
+
+func (e *ResultMemoryCapError) Error() string {
+	return fmt.Sprintf("the query returned more than the %d rows the result memory cap allows, read its results with SelectIterator", e.Cap)
+}
+
+// SelectIterator runs the query and reads all of its rows, holding the first Config.ResultMemoryCap rows in memory
+// and spilling the following ones to a temporary JSONL file, so that results too large for memory can still be
+// read like the results of Select. The rows are read back from the file with the types Select returns, see
+// decodeSpilledValue. Without a memory cap every row is held in memory.
+//
+// The temporary file is removed when the iterator is closed, or right away if reading the results fails.
+func (d *Client) SelectIterator(ctx context.Context, queryObj *query.Query) (_ *ResultIterator, err error) {
+	memoryCap := 0
+	if d.config != nil {
+		memoryCap = d.config.ResultMemoryCap
+	}
+
+	it := &ResultIterator{rows: make([][]interface{}, 0)}
+	defer func() {
+		if err != nil {
+			_ = it.Close()
+		}
+	}()
+
+	var encoder *json.Encoder
+	var buffered *bufio.Writer
+	err = d.SelectStreamWithSchema(ctx, queryObj,
+		func(schema bigquery.Schema) error {
+			it.Schema = schema
+			return nil
+		},
+		func(row []interface{}) error {
+			it.totalRows++
+			if memoryCap <= 0 || len(it.rows) < memoryCap {
+				it.rows = append(it.rows, row)
+				return nil
+			}
+
+			if it.file == nil {
+				file, err := os.CreateTemp("", "bruin-bigquery-results-*.jsonl")
+				if err != nil {
+					return errors.Wrap(err, "failed to create the file to spill the results to")
+				}
+				it.file = file
+				buffered = bufio.NewWriter(file)
+				encoder = json.NewEncoder(buffered)
+			}
+
+			if err := encoder.Encode(spilledRow(row)); err != nil {
+				return errors.Wrapf(err, "failed to spill row %d of the results", it.totalRows-1)
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if it.totalRows == 0 && d.errorOnNoRows() {
+		return nil, ErrNoRows
+	}
+
+	if it.file != nil {
+		if err := buffered.Flush(); err != nil {
+			return nil, errors.Wrap(err, "failed to spill the results")
+		}
+		if _, err := it.file.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap(err, "failed to read back the spilled results")
+		}
+		it.decoder = json.NewDecoder(bufio.NewReader(it.file))
+	}
+
+	return it, nil
+}
+
+// Next returns the next row, or iterator.Done once every row was returned.
+func (it *ResultIterator) Next() ([]interface{}, error) {
+	if it.next < len(it.rows) {
+		row := it.rows[it.next]
+		// the rows are only returned once, they can be freed as soon as they are
+		it.rows[it.next] = nil
+		it.next++
+		return row, nil
+	}
+
+	if it.decoder == nil {
+		return nil, iterator.Done
+	}
+
+	var raw []json.RawMessage
+	if err := it.decoder.Decode(&raw); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, iterator.Done
+		}
+		return nil, errors.Wrap(err, "failed to read back a spilled row")
+	}
+	if len(raw) != len(it.Schema) {
+		return nil, fmt.Errorf("spilled row has %d values but the schema has %d columns", len(raw), len(it.Schema))
+	}
+
+	row := make([]interface{}, len(raw))
+	for i, field := range it.Schema {
+		value, err := decodeSpilledValue(field, raw[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read back the value of column '%s'", field.Name)
+		}
+		row[i] = value
+	}
+
+	return row, nil
+}
+
+// TotalRows is the number of rows the query returned, whether they are held in memory or spilled.
+func (it *ResultIterator) TotalRows() int64 {
+	return it.totalRows
+}
+
+// Spilled reports whether some of the rows were spilled to a temporary file.
+func (it *ResultIterator) Spilled() bool {
+	return it.file != nil
+}
+
+// Close removes the temporary file the rows were spilled to, if any. It is safe to call more than once.
+func (it *ResultIterator) Close() error {
+	it.rows = nil
+	it.decoder = nil
+	if it.file == nil {
+		return nil
+	}
+
+	file := it.file
+	it.file = nil
+	closeErr := file.Close()
+	if err := os.Remove(file.Name()); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to remove the spilled results")
+	}
+
+	return closeErr
+}
+
+// spilledRow prepares the values of a row to be encoded as JSON, which has no representation for the non-finite
+// FLOAT values, NaN and the infinities, therefore they are encoded as strings.
+func spilledRow(row []interface{}) []interface{} {
+	prepared := make([]interface{}, len(row))
+	for i, value := range row {
+		prepared[i] = spilledValue(value)
+	}
+
+	return prepared
+}
+
+func spilledValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		return v
+	case []bigquery.Value:
+		prepared := make([]interface{}, len(v))
+		for i, nested := range v {
+			prepared[i] = spilledValue(nested)
+		}
+		return prepared
+	default:
+		return v
+	}
+}
+
+// decodeSpilledValue decodes a spilled value back into the type Select returns for the column: int64, float64,
+// bool, string, []byte, *big.Rat, time.Time, the civil types, *bigquery.IntervalValue, and []bigquery.Value for
+// RECORD and repeated columns. The values of the other types, e.g. RANGE, are returned as decoded from JSON.
+func decodeSpilledValue(field *bigquery.FieldSchema, raw json.RawMessage) (bigquery.Value, error) {
+	if string(raw) == "null" {
+		return nil, nil
+	}
+
+	if field.Repeated || field.Type == bigquery.RecordFieldType {
+		var elements []json.RawMessage
+		if err := json.Unmarshal(raw, &elements); err != nil {
+			return nil, err
+		}
+
+		values := make([]bigquery.Value, len(elements))
+		for i, element := range elements {
+			elementField := field
+			if field.Repeated {
+				copied := *field
+				copied.Repeated = false
+				elementField = &copied
+			} else if i < len(field.Schema) {
+				elementField = field.Schema[i]
+			} else {
+				return nil, fmt.Errorf("record has %d values but %d fields", len(elements), len(field.Schema))
+			}
+
+			value, err := decodeSpilledValue(elementField, element)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+
+	switch field.Type {
+	case bigquery.IntegerFieldType:
+		return decodeJSON[int64](raw)
+	case bigquery.FloatFieldType:
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return strconv.ParseFloat(s, 64)
+		}
+		return decodeJSON[float64](raw)
+	case bigquery.BooleanFieldType:
+		return decodeJSON[bool](raw)
+	case bigquery.StringFieldType, bigquery.GeographyFieldType, bigquery.JSONFieldType:
+		return decodeJSON[string](raw)
+	case bigquery.BytesFieldType:
+		return decodeJSON[[]byte](raw)
+	case bigquery.NumericFieldType, bigquery.BigNumericFieldType:
+		value := new(big.Rat)
+		if err := json.Unmarshal(raw, value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	case bigquery.TimestampFieldType:
+		return decodeJSON[time.Time](raw)
+	case bigquery.DateFieldType:
+		return decodeJSON[civil.Date](raw)
+	case bigquery.TimeFieldType:
+		return decodeJSON[civil.Time](raw)
+	case bigquery.DateTimeFieldType:
+		return decodeJSON[civil.DateTime](raw)
+	case bigquery.IntervalFieldType:
+		value := new(bigquery.IntervalValue)
+		if err := json.Unmarshal(raw, value); err != nil {
+			return nil, err
+		}
+		return value, nil
+	default:
+		return decodeJSON[interface{}](raw)
+	}
+}
+
+func decodeJSON[T any](raw json.RawMessage) (bigquery.Value, error) {
+	var value T
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
@@ -0,0 +1,180 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/iterator"
+)
+
+func TestClient_SelectIterator(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		memoryCap   int
+		wantSpilled bool
+	}{
+		{
+			name: "every row is held in memory without a cap",
+		},
+		{
+			name:      "rows within the cap are not spilled",
+			memoryCap: 5,
+		},
+		{
+			name:        "rows after the cap are spilled",
+			memoryCap:   2,
+			wantSpilled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &recordingQueryHandler{
+				response: func(q string) *bigquery2.QueryResponse {
+					return stringQueryResponse("a", "b", "c", "d", "e")
+				},
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.ResultMemoryCap = tt.memoryCap
+
+			it, err := d.SelectIterator(context.Background(), &query.Query{Query: "SELECT * FROM dataset.users"})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSpilled, it.Spilled())
+			assert.Equal(t, int64(5), it.TotalRows())
+			require.Len(t, it.Schema, 1)
+
+			var spillPath string
+			if it.Spilled() {
+				spillPath = it.file.Name()
+				assert.FileExists(t, spillPath)
+			}
+
+			got := make([][]interface{}, 0)
+			for {
+				row, err := it.Next()
+				if err == iterator.Done { //nolint:errorlint
+					break
+				}
+				require.NoError(t, err)
+				got = append(got, row)
+			}
+			assert.Equal(t, [][]interface{}{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}, got)
+
+			require.NoError(t, it.Close())
+			require.NoError(t, it.Close())
+			if spillPath != "" {
+				_, err := os.Stat(spillPath)
+				assert.True(t, os.IsNotExist(err), "the spilled results must be removed on close")
+			}
+		})
+	}
+}
+
+func TestClient_SelectIterator_ErrorOnNoRows(t *testing.T) {
+	t.Parallel()
+
+	emptyResult := queryResultResponse{
+		response: &bigquery2.GetQueryResultsResponse{
+			JobReference: &bigquery2.JobReference{JobId: "job-id"},
+			JobComplete:  true,
+			Schema: &bigquery2.TableSchema{
+				Fields: []*bigquery2.TableFieldSchema{{Name: "id", Type: "INTEGER"}},
+			},
+		},
+		statusCode: http.StatusOK,
+	}
+
+	jobID := "test-job"
+	server := httptest.NewServer(mockBqHandler(t, testProjectID, jobID, successfulJobResponse(jobID), emptyResult))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	d.config.ErrorOnNoRows = true
+
+	_, err := d.SelectIterator(context.Background(), &query.Query{Query: "SELECT id FROM t WHERE false"})
+	require.ErrorIs(t, err, ErrNoRows)
+}
+
+func TestDecodeSpilledValue(t *testing.T) {
+	t.Parallel()
+
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "score", Type: bigquery.FloatFieldType},
+		{Name: "ratio", Type: bigquery.FloatFieldType},
+		{Name: "active", Type: bigquery.BooleanFieldType},
+		{Name: "payload", Type: bigquery.BytesFieldType},
+		{Name: "amount", Type: bigquery.NumericFieldType},
+		{Name: "created_at", Type: bigquery.TimestampFieldType},
+		{Name: "day", Type: bigquery.DateFieldType},
+		{Name: "at", Type: bigquery.TimeFieldType},
+		{Name: "local", Type: bigquery.DateTimeFieldType},
+		{Name: "duration", Type: bigquery.IntervalFieldType},
+		{Name: "tags", Type: bigquery.StringFieldType, Repeated: true},
+		{Name: "address", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{
+			{Name: "city", Type: bigquery.StringFieldType},
+			{Name: "zip", Type: bigquery.IntegerFieldType},
+		}},
+		{Name: "missing", Type: bigquery.StringFieldType},
+	}
+	row := []interface{}{
+		int64(math.MaxInt64),
+		1.5,
+		math.Inf(-1),
+		true,
+		[]byte{0x00, 0xff},
+		big.NewRat(3, 2),
+		time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC),
+		civil.Date{Year: 2024, Month: time.March, Day: 1},
+		civil.Time{Hour: 12, Minute: 30, Second: 1},
+		civil.DateTime{Date: civil.Date{Year: 2024, Month: time.March, Day: 1}, Time: civil.Time{Hour: 8}},
+		&bigquery.IntervalValue{Years: 1, Days: -2, Hours: 3},
+		[]bigquery.Value{"a", "b"},
+		[]bigquery.Value{"Berlin", int64(10115)},
+		nil,
+	}
+
+	encoded, err := json.Marshal(spilledRow(row))
+	require.NoError(t, err)
+
+	var raw []json.RawMessage
+	require.NoError(t, json.Unmarshal(encoded, &raw))
+	require.Len(t, raw, len(schema))
+
+	for i, field := range schema {
+		got, err := decodeSpilledValue(field, raw[i])
+		require.NoError(t, err, field.Name)
+		if want, ok := row[i].(*big.Rat); ok {
+			require.IsType(t, want, got)
+			assert.Equal(t, 0, want.Cmp(got.(*big.Rat)), field.Name)
+			continue
+		}
+		assert.Equal(t, row[i], got, field.Name)
+	}
+
+	nan, err := json.Marshal(spilledValue(math.NaN()))
+	require.NoError(t, err)
+	got, err := decodeSpilledValue(&bigquery.FieldSchema{Type: bigquery.FloatFieldType}, nan)
+	require.NoError(t, err)
+	assert.True(t, math.IsNaN(got.(float64)))
+}
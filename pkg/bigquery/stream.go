@@ -0,0 +1,76 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+	"google.golang.org/api/iterator"
+)
+
+// SelectStream runs the query and calls fn for every row as it is read, without keeping the rows in memory.
+// The iteration stops at the first error returned by fn, which is then returned as is.
+func (d *Client) SelectStream(ctx context.Context, queryObj *query.Query, fn func(row []interface{}) error) error {
+	return d.SelectStreamWithSchema(ctx, queryObj, nil, fn)
+}
+
+// SelectStreamWithSchema is like SelectStream, calling onSchema with the schema of the results once before the
+// first row, or once at the end for empty results, so that the caller can prepare the output of the columns.
+func (d *Client) SelectStreamWithSchema(ctx context.Context, queryObj *query.Query, onSchema func(schema bigquery.Schema) error, fn func(row []interface{}) error) error {
+	ctx, cancel := d.withQueryTimeout(ctx)
+	defer cancel()
+
+	q, err := d.newQuery(ctx, queryObj)
+	if err != nil {
+		return err
+	}
+	rows, err := d.readWithRetry(ctx, q)
+	if err != nil {
+		return formatError(err)
+	}
+
+	schemaSent := onSchema == nil
+	for {
+		var values []bigquery.Value
+		err := rows.Next(&values)
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read row: %w", timeoutCause(ctx, err))
+		}
+
+		// the schema is only available after the first call to Next
+		if !schemaSent {
+			if err := onSchema(rows.Schema); err != nil {
+				return err
+			}
+			schemaSent = true
+		}
+
+		row := make([]interface{}, len(values))
+		for i, v := range values {
+			row[i] = v
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	if schemaSent {
+		return nil
+	}
+
+	schema := rows.Schema
+	if schema == nil {
+		// empty results may come without a schema, the dry run still knows the shape of the output
+		schema, err = d.DryRunSchema(ctx, queryObj)
+		if err != nil {
+			return errors.Wrap(err, "schema information is not available")
+		}
+	}
+
+	return onSchema(schema)
+}
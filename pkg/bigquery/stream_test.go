@@ -0,0 +1,139 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_SelectStream(t *testing.T) {
+	t.Parallel()
+
+	errStop := errors.New("stop")
+
+	tests := []struct {
+		name     string
+		stopAt   int
+		wantRows [][]interface{}
+		wantErr  error
+	}{
+		{
+			name:     "all rows are streamed",
+			wantRows: [][]interface{}{{"a"}, {"b"}, {"c"}},
+		},
+		{
+			name:     "callback error stops the iteration",
+			stopAt:   2,
+			wantRows: [][]interface{}{{"a"}, {"b"}},
+			wantErr:  errStop,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &recordingQueryHandler{
+				response: func(q string) *bigquery2.QueryResponse {
+					return stringQueryResponse("a", "b", "c")
+				},
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			var events []string
+			var got [][]interface{}
+			err := d.SelectStreamWithSchema(context.Background(), &query.Query{Query: "SELECT * FROM dataset.users"},
+				func(schema bigquery.Schema) error {
+					require.Len(t, schema, 1)
+					events = append(events, "schema")
+					return nil
+				},
+				func(row []interface{}) error {
+					events = append(events, "row")
+					got = append(got, row)
+					if len(got) == tt.stopAt {
+						return errStop
+					}
+					return nil
+				},
+			)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantRows, got)
+			require.NotEmpty(t, events)
+			assert.Equal(t, "schema", events[0])
+			assert.Len(t, events, len(tt.wantRows)+1)
+		})
+	}
+}
+
+func TestClient_SelectStream_EmptyResult(t *testing.T) {
+	t.Parallel()
+
+	jobs := &jobsHandler{
+		jobStatus: func(job *bigquery2.Job) {
+			job.Statistics = &bigquery2.JobStatistics{
+				Query: &bigquery2.JobStatistics2{
+					Schema: &bigquery2.TableSchema{Fields: []*bigquery2.TableFieldSchema{{Name: "id", Type: "INTEGER"}}},
+				},
+			}
+		},
+	}
+	handler := &recordingQueryHandler{
+		// empty results without a schema
+		response: func(q string) *bigquery2.QueryResponse {
+			return &bigquery2.QueryResponse{
+				JobComplete:  true,
+				JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+			}
+		},
+		fallback: func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/queries/", testProjectID)) {
+				_ = json.NewEncoder(w).Encode(&bigquery2.GetQueryResultsResponse{
+					JobComplete:  true,
+					JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+				})
+				return
+			}
+			jobs.ServeHTTP(w, r)
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	var schemas []bigquery.Schema
+	err := d.SelectStreamWithSchema(context.Background(), &query.Query{Query: "SELECT id FROM dataset.users WHERE false"},
+		func(schema bigquery.Schema) error {
+			schemas = append(schemas, schema)
+			return nil
+		},
+		func(row []interface{}) error {
+			t.Fatal("no rows expected")
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, schemas, 1)
+	require.Len(t, schemas[0], 1)
+	assert.Equal(t, "id", schemas[0][0].Name)
+}
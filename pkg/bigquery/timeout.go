@@ -0,0 +1,192 @@
+package bigquery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/bruin-data/bruin/pkg/executor"
+	"go.uber.org/zap"
+)
+
+// jobCancelTimeout bounds the request that cancels a job, which must outlive the context of the query itself.
+const jobCancelTimeout = 30 * time.Second
+
+// QueryTimeoutError is returned when a query runs longer than Config.QueryTimeout, or its job longer than
+// Config.HardQueryTimeout, in which case JobID is the ID of the cancelled job. It matches context.DeadlineExceeded
+// through errors.Is.
+type QueryTimeoutError struct {
+	Timeout time.Duration
+	JobID   string
+}
+
+func (e *QueryTimeoutError) Error() string {
+	if e.JobID != "" {
+		return fmt.Sprintf("BigQuery job '%s' was cancelled after exceeding the configured timeout of %s", e.JobID, e.Timeout)
+	}
+
+	return fmt.Sprintf("query exceeded the configured timeout of %s", e.Timeout)
+}
+
+func (e *QueryTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// withQueryTimeout bounds the context by Config.QueryTimeout, if set. The expiry of the deadline is reported as a
+// QueryTimeoutError through timeoutCause, so that it is not mistaken for a deadline set by the caller.
+func (d *Client) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d.config == nil || d.config.QueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeoutCause(ctx, d.config.QueryTimeout, &QueryTimeoutError{Timeout: d.config.QueryTimeout})
+}
+
+// timeoutCause returns the QueryTimeoutError in place of the error if the error is due to the deadline set by
+// withQueryTimeout, and the error as is otherwise.
+func timeoutCause(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	var timeoutErr *QueryTimeoutError
+	if errors.As(context.Cause(ctx), &timeoutErr) {
+		return timeoutErr
+	}
+
+	return err
+}
+
+// read runs the query and returns its results. Without Config.QueryTimeout, Config.SoftQueryTimeout and
+// Config.HardQueryTimeout the query is read directly; otherwise the job is started first so that it can be watched
+// and cancelled.
+func (d *Client) read(ctx context.Context, q *bigquery.Query) (*bigquery.RowIterator, error) {
+	var soft, hard, timeout time.Duration
+	if d.config != nil {
+		soft, hard, timeout = d.config.SoftQueryTimeout, d.config.HardQueryTimeout, d.config.QueryTimeout
+	}
+	// queries with a job ID of their own are submitted as jobs, see withStableJobID
+	if soft <= 0 && hard <= 0 && timeout <= 0 && q.JobID == "" {
+		return q.Read(ctx)
+	}
+
+	job, err := d.runOrAttach(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return readWithDeadlines(ctx, job, soft, hard)
+}
+
+// readJob waits for the job and reads its results, watched by Config.SoftQueryTimeout and Config.HardQueryTimeout
+// like the queries run through read.
+func (d *Client) readJob(ctx context.Context, job *bigquery.Job) (*bigquery.RowIterator, error) {
+	var soft, hard time.Duration
+	if d.config != nil {
+		soft, hard = d.config.SoftQueryTimeout, d.config.HardQueryTimeout
+	}
+
+	return readWithDeadlines(ctx, job, soft, hard)
+}
+
+// waitJob waits for the job like readJob, for the jobs whose results are not read, e.g. DML statements.
+func (d *Client) waitJob(ctx context.Context, job *bigquery.Job) (*bigquery.JobStatus, error) {
+	var soft, hard time.Duration
+	if d.config != nil {
+		soft, hard = d.config.SoftQueryTimeout, d.config.HardQueryTimeout
+	}
+
+	return waitWithDeadlines(ctx, job, soft, hard)
+}
+
+// readWithDeadlines waits for the job with waitWithDeadlines and reads its results.
+func readWithDeadlines(ctx context.Context, job *bigquery.Job, soft, hard time.Duration) (*bigquery.RowIterator, error) {
+	status, err := waitWithDeadlines(ctx, job, soft, hard)
+	if err != nil {
+		return nil, err
+	}
+	if err := status.Err(); err != nil {
+		return nil, err
+	}
+
+	// the results are read with the original context once the job is done, the iterator keeps it for the next pages
+	return job.Read(ctx)
+}
+
+// waitWithDeadlines waits for the job. Once the soft timeout passes a warning is logged through the logger of the
+// context; once the hard timeout passes the job is cancelled in BigQuery, so that it stops consuming slots, and a
+// QueryTimeoutError is returned. Zero disables either timeout. The job is cancelled as well if the context is done
+// before the job, rather than being left running unattended.
+func waitWithDeadlines(ctx context.Context, job *bigquery.Job, soft, hard time.Duration) (*bigquery.JobStatus, error) {
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var softTimer, hardTimer <-chan time.Time
+	if soft > 0 {
+		t := time.NewTimer(soft)
+		defer t.Stop()
+		softTimer = t.C
+	}
+	if hard > 0 {
+		t := time.NewTimer(hard)
+		defer t.Stop()
+		hardTimer = t.C
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	var cancelled atomic.Bool
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-softTimer:
+				softTimer = nil
+				contextLogger(ctx).Warnf("BigQuery job '%s' is still running after the soft timeout of %s", job.ID(), soft)
+			case <-hardTimer:
+				cancelled.Store(true)
+				cancelJob(ctx, job)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	status, err := job.Wait(waitCtx)
+	if err != nil {
+		if cancelled.Load() {
+			return nil, &QueryTimeoutError{Timeout: hard, JobID: job.ID()}
+		}
+		if ctx.Err() != nil {
+			cancelJob(ctx, job)
+		}
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// cancelJob requests the cancellation of the job in BigQuery, so that it stops consuming slots and billing, with a
+// context of its own since the one of the query may be done already. Failures are only logged.
+func cancelJob(ctx context.Context, job *bigquery.Job) {
+	cancelCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobCancelTimeout)
+	defer cancel()
+
+	if err := job.Cancel(cancelCtx); err != nil {
+		contextLogger(ctx).Warnf("failed to cancel BigQuery job '%s': %v", job.ID(), err)
+	}
+}
+
+// contextLogger returns the logger the executor passes through the context, or a no-op logger outside of it.
+func contextLogger(ctx context.Context) *zap.SugaredLogger {
+	if logger, ok := ctx.Value(executor.ContextLogger).(*zap.SugaredLogger); ok && logger != nil {
+		return logger
+	}
+
+	return zap.NewNop().Sugar()
+}
@@ -0,0 +1,246 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bruin-data/bruin/pkg/executor"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_QueryTimeouts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		soft          time.Duration
+		hard          time.Duration
+		timeout       time.Duration
+		ctxTimeout    time.Duration
+		queryDuration time.Duration
+		wantWarning   bool
+		wantCancelled bool
+		wantTimeout   bool
+		wantErr       string
+	}{
+		{
+			name:          "query finishing before the soft timeout",
+			soft:          time.Minute,
+			hard:          time.Minute,
+			queryDuration: 0,
+		},
+		{
+			name:          "query exceeding the soft timeout is only logged",
+			soft:          10 * time.Millisecond,
+			queryDuration: 200 * time.Millisecond,
+			wantWarning:   true,
+		},
+		{
+			name:          "query exceeding the hard timeout is cancelled",
+			soft:          10 * time.Millisecond,
+			hard:          100 * time.Millisecond,
+			queryDuration: time.Minute,
+			wantWarning:   true,
+			wantCancelled: true,
+			wantTimeout:   true,
+			wantErr:       "BigQuery job 'job-1' was cancelled after exceeding the configured timeout of 100ms",
+		},
+		{
+			name:          "query finishing before the configured timeout",
+			timeout:       time.Minute,
+			queryDuration: 0,
+		},
+		{
+			name:          "query exceeding the configured timeout is cancelled",
+			timeout:       100 * time.Millisecond,
+			queryDuration: time.Minute,
+			wantCancelled: true,
+			wantTimeout:   true,
+			wantErr:       "query exceeded the configured timeout of 100ms",
+		},
+		{
+			name:          "query exceeding the deadline of the caller is cancelled",
+			timeout:       time.Minute,
+			ctxTimeout:    100 * time.Millisecond,
+			queryDuration: time.Minute,
+			wantCancelled: true,
+			wantErr:       "context deadline exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cancelled atomic.Bool
+			server := httptest.NewServer(slowJobHandler(tt.queryDuration, &cancelled))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.SoftQueryTimeout = tt.soft
+			d.config.HardQueryTimeout = tt.hard
+			d.config.QueryTimeout = tt.timeout
+
+			core, logs := observer.New(zapcore.WarnLevel)
+			ctx := context.WithValue(context.Background(), executor.ContextLogger, zap.New(core).Sugar())
+			if tt.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.ctxTimeout)
+				defer cancel()
+			}
+
+			rows, err := d.Select(ctx, &query.Query{Query: "SELECT 1"})
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+
+				var timeoutErr *QueryTimeoutError
+				assert.Equal(t, tt.wantTimeout, errors.As(err, &timeoutErr))
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, [][]interface{}{{int64(1)}}, rows)
+			}
+
+			warnings := logs.FilterMessageSnippet("still running after the soft timeout").Len()
+			assert.Equal(t, tt.wantWarning, warnings == 1)
+			assert.Equal(t, tt.wantCancelled, cancelled.Load())
+		})
+	}
+}
+
+func TestClient_QueryTimeout_Streaming(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context, d *Client) error
+	}{
+		{
+			name: "SelectStream",
+			run: func(ctx context.Context, d *Client) error {
+				return d.SelectStream(ctx, &query.Query{Query: "SELECT 1"}, func(row []interface{}) error { return nil })
+			},
+		},
+		{
+			name: "SelectColumnar",
+			run: func(ctx context.Context, d *Client) error {
+				_, err := d.SelectColumnar(ctx, &query.Query{Query: "SELECT 1"})
+				return err
+			},
+		},
+		{
+			name: "SelectPage",
+			run: func(ctx context.Context, d *Client) error {
+				_, _, err := d.SelectPage(ctx, &query.Query{Query: "SELECT 1"}, "", 10)
+				return err
+			},
+		},
+		{
+			name: "SelectIterator",
+			run: func(ctx context.Context, d *Client) error {
+				_, err := d.SelectIterator(ctx, &query.Query{Query: "SELECT 1"})
+				return err
+			},
+		},
+		{
+			name: "SelectToFile",
+			run: func(ctx context.Context, d *Client) error {
+				return d.SelectToFile(ctx, &query.Query{Query: "SELECT 1"}, filepath.Join(t.TempDir(), "out.csv"), FileFormatCSV)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cancelled atomic.Bool
+			server := httptest.NewServer(slowJobHandler(time.Minute, &cancelled))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.QueryTimeout = 100 * time.Millisecond
+
+			err := tt.run(context.Background(), d)
+			require.ErrorContains(t, err, "query exceeded the configured timeout of 100ms")
+
+			var timeoutErr *QueryTimeoutError
+			require.ErrorAs(t, err, &timeoutErr)
+			assert.True(t, cancelled.Load(), "the job must be cancelled in BigQuery")
+		})
+	}
+}
+
+func TestClient_RunQueryWithJobInfo_HardTimeout(t *testing.T) {
+	t.Parallel()
+
+	var cancelled atomic.Bool
+	server := httptest.NewServer(slowJobHandler(time.Minute, &cancelled))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	d.config.SoftQueryTimeout = 10 * time.Millisecond
+	d.config.HardQueryTimeout = 100 * time.Millisecond
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	ctx := context.WithValue(context.Background(), executor.ContextLogger, zap.New(core).Sugar())
+
+	info, err := d.RunQueryWithJobInfo(ctx, &query.Query{Query: "SELECT 1"})
+	require.EqualError(t, err, "BigQuery job 'job-1' was cancelled after exceeding the configured timeout of 100ms")
+	require.NotNil(t, info)
+	assert.Equal(t, "job-1", info.JobID)
+	assert.Equal(t, 1, logs.FilterMessageSnippet("still running after the soft timeout").Len())
+	assert.True(t, cancelled.Load(), "the job must be cancelled in BigQuery")
+}
+
+// slowJobHandler serves a query job named job-1 whose results take queryDuration to be ready, recording whether the
+// job was cancelled.
+func slowJobHandler(queryDuration time.Duration, cancelled *atomic.Bool) http.HandlerFunc {
+	ref := &bigquery2.JobReference{ProjectId: testProjectID, JobId: "job-1", Location: "US"}
+	job := &bigquery2.Job{
+		JobReference:  ref,
+		Configuration: &bigquery2.JobConfiguration{Query: &bigquery2.JobConfigurationQuery{Query: "SELECT 1"}},
+		Status:        &bigquery2.JobStatus{State: "DONE"},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/jobs", testProjectID):
+			_ = json.NewEncoder(w).Encode(job)
+		case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/projects/%s/jobs/job-1/cancel", testProjectID):
+			cancelled.Store(true)
+			_ = json.NewEncoder(w).Encode(&bigquery2.JobCancelResponse{Job: job})
+		case strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/queries/job-1", testProjectID)):
+			select {
+			case <-time.After(queryDuration):
+			case <-r.Context().Done():
+				return
+			}
+			_ = json.NewEncoder(w).Encode(&bigquery2.GetQueryResultsResponse{
+				JobComplete:  true,
+				JobReference: ref,
+				Schema:       &bigquery2.TableSchema{Fields: []*bigquery2.TableFieldSchema{{Name: "one", Type: "INTEGER"}}},
+				Rows:         []*bigquery2.TableRow{{F: []*bigquery2.TableCell{{V: "1"}}}},
+				TotalRows:    1,
+			})
+		case strings.HasPrefix(r.URL.Path, fmt.Sprintf("/projects/%s/jobs/job-1", testProjectID)):
+			_ = json.NewEncoder(w).Encode(job)
+		default:
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+		}
+	})
+}
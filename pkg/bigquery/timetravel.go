@@ -0,0 +1,204 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+)
+
+// TimeTravelWindow is the longest time in the past BigQuery can read tables as of. Datasets may be configured
+// with a shorter window of down to 2 days, which is only known to BigQuery.
+const TimeTravelWindow = 7 * 24 * time.Hour
+
+// tableReference matches a name following FROM or JOIN, or following a comma once handled by the rewrite, e.g.
+// `dataset.table`, `project-id.dataset.table` or a name quoted with backticks in whole or in parts.
+var tableReference = regexp.MustCompile("^(?:`[^`]+`|[\\w-]+)(?:\\.(?:`[^`]+`|[\\w-]+))*")
+
+var (
+	sqlToken         = regexp.MustCompile("`[^`]*`|\\w+|\\S")
+	tableAlias       = regexp.MustCompile("(?i)^\\s+(?:AS\\s+)?(`[^`]+`|\\w+)")
+	systemTimeClause = regexp.MustCompile(`(?i)^\s+FOR\s+SYSTEM_TIME\b`)
+	commaSeparator   = regexp.MustCompile(`^\s*,\s*`)
+)
+
+// aliasStopWords are the keywords that may follow a table name in place of an alias.
+var aliasStopWords = map[string]bool{
+	"CROSS": true, "EXCEPT": true, "FOR": true, "FULL": true, "GROUP": true, "HAVING": true, "INNER": true,
+	"INTERSECT": true, "JOIN": true, "LEFT": true, "LIMIT": true, "ON": true, "ORDER": true, "PIVOT": true,
+	"QUALIFY": true, "RIGHT": true, "TABLESAMPLE": true, "UNION": true, "UNPIVOT": true, "USING": true,
+	"WHERE": true, "WINDOW": true,
+}
+
+// ValidateTimeTravel checks that the tables can be read as of the given time, i.e. that it is neither in the future
+// nor further in the past than TimeTravelWindow.
+func ValidateTimeTravel(asOf, now time.Time) error {
+	if asOf.After(now) {
+		return fmt.Errorf("cannot read tables as of %s, which is in the future", asOf.UTC().Format(time.RFC3339))
+	}
+	if age := now.Sub(asOf); age > TimeTravelWindow {
+		return fmt.Errorf("cannot read tables as of %s, which is %s ago; BigQuery only keeps the history of tables for the last %d days", asOf.UTC().Format(time.RFC3339), age.Round(time.Minute), int(TimeTravelWindow.Hours()/24))
+	}
+
+	return nil
+}
+
+// WithSystemTimeAsOf rewrites the query so that every table it reads is read as it was at the given time, by adding
+// a `FOR SYSTEM_TIME AS OF` clause to the qualified table names following the FROM and JOIN keywords of its FROM
+// clauses, see clauseKeywords. Names that are not qualified with a dataset, e.g. CTEs, subqueries, UNNEST calls, the
+// arrays of aliased tables and the tables that already have the clause are left as they are.
+//
+// The rewrite is lexical: string literals and comments are ignored, but tables read through views are read as of
+// the current time.
+func WithSystemTimeAsOf(queryString string, asOf time.Time) (string, error) {
+	masked := maskLiteralsAndComments(queryString, true)
+	clause := fmt.Sprintf(" FOR SYSTEM_TIME AS OF TIMESTAMP '%s UTC'", asOf.UTC().Format("2006-01-02 15:04:05.999999"))
+	keywords := clauseKeywords(masked)
+
+	// the aliases are collected first, a correlated subquery may read an array of a table aliased later in the query
+	aliases := make(map[string]bool)
+	forEachTarget(masked, keywords, func(pos int) int {
+		end, alias, _ := timeTravelTarget(masked, pos, nil)
+		if alias != "" {
+			aliases[strings.ToLower(alias)] = true
+		}
+		return end
+	})
+
+	insertAt := make([]int, 0)
+	forEachTarget(masked, keywords, func(pos int) int {
+		end, _, ok := timeTravelTarget(masked, pos, aliases)
+		if ok {
+			insertAt = append(insertAt, end)
+		}
+		return end
+	})
+
+	if len(insertAt) == 0 {
+		return "", errors.New("the query does not read any table qualified with a dataset, there is nothing to read as of a point in time")
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, pos := range insertAt {
+		b.WriteString(queryString[last:pos])
+		b.WriteString(clause)
+		last = pos
+	}
+	b.WriteString(queryString[last:])
+
+	return b.String(), nil
+}
+
+// forEachTarget calls fn with the position of every table following the keywords, including the comma-separated
+// ones, which are cross joined. fn returns the position after the table and its alias.
+func forEachTarget(masked string, keywords [][]int, fn func(pos int) int) {
+	for _, keyword := range keywords {
+		pos := keyword[1]
+		for {
+			end := fn(pos)
+			comma := commaSeparator.FindStringIndex(masked[end:])
+			if comma == nil || end == pos {
+				break
+			}
+			pos = end + comma[1]
+		}
+	}
+}
+
+// clauseKeywords returns the positions of the FROM and JOIN keywords of the masked query that start a FROM clause or
+// a join, each together with the whitespace following it. The keywords within the arguments of functions, such as
+// `EXTRACT(DATE FROM created_at)`, and the ones of `IS DISTINCT FROM` comparisons are skipped.
+func clauseKeywords(masked string) [][]int {
+	tokens := sqlToken.FindAllStringIndex(masked, -1)
+	token := func(i int) string {
+		if i < 0 || i >= len(tokens) {
+			return ""
+		}
+		return strings.ToUpper(masked[tokens[i][0]:tokens[i][1]])
+	}
+
+	// whether each of the open parentheses holds a query rather than the arguments of a function or an expression
+	queryParens := make([]bool, 0)
+	keywords := make([][]int, 0)
+	for i, position := range tokens {
+		switch current := token(i); current {
+		case "(":
+			previous, next := token(i-1), token(i+1)
+			queryParens = append(queryParens, previous == "FROM" || previous == "JOIN" || next == "SELECT" || next == "WITH")
+		case ")":
+			if len(queryParens) > 0 {
+				queryParens = queryParens[:len(queryParens)-1]
+			}
+		case "FROM", "JOIN":
+			if len(queryParens) > 0 && !queryParens[len(queryParens)-1] {
+				continue
+			}
+			if current == "FROM" && token(i-1) == "DISTINCT" {
+				continue
+			}
+
+			end := position[1] + len(masked[position[1]:]) - len(strings.TrimLeft(masked[position[1]:], " \t\r\n"))
+			if end > position[1] {
+				keywords = append(keywords, []int{position[0], end})
+			}
+		}
+	}
+
+	return keywords
+}
+
+// timeTravelTarget parses the table name and the optional alias at the given position. It returns the position
+// after them, the alias, and whether the table should get the time travel clause.
+func timeTravelTarget(masked string, pos int, aliases map[string]bool) (int, string, bool) {
+	name := tableReference.FindString(masked[pos:])
+	if name == "" {
+		return pos, "", false
+	}
+	end := pos + len(name)
+
+	// table-valued functions such as UNNEST(...) are not tables
+	if strings.HasPrefix(strings.TrimLeft(masked[end:], " \t\r\n"), "(") {
+		return end, "", false
+	}
+
+	alias := ""
+	if match := tableAlias.FindStringSubmatch(masked[end:]); match != nil && !aliasStopWords[strings.ToUpper(match[1])] {
+		alias = strings.Trim(match[1], "`")
+		end += len(match[0])
+	}
+
+	if systemTimeClause.MatchString(masked[end:]) {
+		return end, alias, false
+	}
+
+	unquoted := strings.ReplaceAll(name, "`", "")
+	first, _, qualified := strings.Cut(unquoted, ".")
+	if !qualified || aliases[strings.ToLower(first)] {
+		return end, alias, false
+	}
+
+	return end, alias, true
+}
+
+// SelectAsOf runs the query with every table it reads as it was at the given time, e.g. to investigate an incident
+// after the data was overwritten, see WithSystemTimeAsOf.
+func (d *Client) SelectAsOf(ctx context.Context, queryObj *query.Query, asOf time.Time) (*query.QueryResult, error) {
+	if err := ValidateTimeTravel(asOf, time.Now()); err != nil {
+		return nil, err
+	}
+
+	rewritten, err := WithSystemTimeAsOf(queryObj.Query, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	timeTravelQuery := *queryObj
+	timeTravelQuery.Query = rewritten
+
+	return d.SelectWithSchema(ctx, &timeTravelQuery)
+}
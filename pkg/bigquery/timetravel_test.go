@@ -0,0 +1,158 @@
+package bigquery
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestWithSystemTimeAsOf(t *testing.T) {
+	t.Parallel()
+
+	asOf := time.Date(2024, 5, 1, 10, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	const clause = " FOR SYSTEM_TIME AS OF TIMESTAMP '2024-05-01 08:30:00 UTC'"
+
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "single table",
+			query: "SELECT * FROM dataset.events WHERE id = 1",
+			want:  "SELECT * FROM dataset.events" + clause + " WHERE id = 1",
+		},
+		{
+			name:  "alias comes before the clause",
+			query: "SELECT e.id FROM `project-id.dataset.events` AS e JOIN dataset.users u ON e.user_id = u.id",
+			want:  "SELECT e.id FROM `project-id.dataset.events` AS e" + clause + " JOIN dataset.users u" + clause + " ON e.user_id = u.id",
+		},
+		{
+			name:  "comma joins and implicit unnest",
+			query: "SELECT * FROM dataset.orders o, o.items, dataset.users",
+			want:  "SELECT * FROM dataset.orders o" + clause + ", o.items, dataset.users" + clause,
+		},
+		{
+			name:  "CTEs, subqueries and UNNEST are left as they are",
+			query: "WITH recent AS (SELECT * FROM (SELECT * FROM dataset.events)) SELECT * FROM recent JOIN UNNEST([1, 2]) AS n ON true",
+			want:  "WITH recent AS (SELECT * FROM (SELECT * FROM dataset.events" + clause + ")) SELECT * FROM recent JOIN UNNEST([1, 2]) AS n ON true",
+		},
+		{
+			name:  "tables that already travel in time are left as they are",
+			query: "SELECT * FROM dataset.a FOR SYSTEM_TIME AS OF TIMESTAMP '2024-04-30' JOIN dataset.b USING (id)",
+			want:  "SELECT * FROM dataset.a FOR SYSTEM_TIME AS OF TIMESTAMP '2024-04-30' JOIN dataset.b" + clause + " USING (id)",
+		},
+		{
+			name:  "literals and comments are ignored",
+			query: "SELECT 'FROM dataset.fake' AS s -- JOIN dataset.commented\nFROM dataset.real",
+			want:  "SELECT 'FROM dataset.fake' AS s -- JOIN dataset.commented\nFROM dataset.real" + clause,
+		},
+		{
+			name:  "FROM within function arguments and comparisons",
+			query: "SELECT EXTRACT(DATE FROM e.created_at) AS day, e.a IS DISTINCT FROM e.b FROM dataset.events AS e",
+			want:  "SELECT EXTRACT(DATE FROM e.created_at) AS day, e.a IS DISTINCT FROM e.b FROM dataset.events AS e" + clause,
+		},
+		{
+			name:  "arrays of tables aliased later in the query",
+			query: "SELECT (SELECT COUNT(*) FROM o.items) AS n FROM dataset.orders o",
+			want:  "SELECT (SELECT COUNT(*) FROM o.items) AS n FROM dataset.orders o" + clause,
+		},
+		{
+			name:    "no table to read",
+			query:   "SELECT 1",
+			wantErr: "the query does not read any table qualified with a dataset, there is nothing to read as of a point in time",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := WithSystemTimeAsOf(tt.query, asOf)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateTimeTravel(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		asOf    time.Time
+		wantErr string
+	}{
+		{
+			name: "within the window",
+			asOf: now.Add(-6 * time.Hour),
+		},
+		{
+			name: "at the edge of the window",
+			asOf: now.Add(-TimeTravelWindow),
+		},
+		{
+			name:    "older than the window",
+			asOf:    now.Add(-TimeTravelWindow - time.Hour),
+			wantErr: "cannot read tables as of 2024-05-01T11:00:00Z, which is 169h0m0s ago; BigQuery only keeps the history of tables for the last 7 days",
+		},
+		{
+			name:    "in the future",
+			asOf:    now.Add(time.Minute),
+			wantErr: "cannot read tables as of 2024-05-08T12:01:00Z, which is in the future",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateTimeTravel(tt.asOf, now)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestClient_SelectAsOf(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			return stringQueryResponse("a")
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	asOf := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	result, err := d.SelectAsOf(context.Background(), &query.Query{Query: "SELECT value FROM dataset.events"}, asOf)
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"a"}}, result.Rows)
+	assert.Equal(t, []string{
+		"SELECT value FROM dataset.events FOR SYSTEM_TIME AS OF TIMESTAMP '" + asOf.UTC().Format("2006-01-02 15:04:05") + " UTC'",
+	}, handler.recordedQueries())
+
+	_, err = d.SelectAsOf(context.Background(), &query.Query{Query: "SELECT value FROM dataset.events"}, time.Now().Add(-8*24*time.Hour))
+	require.ErrorContains(t, err, "BigQuery only keeps the history of tables for the last 7 days")
+	assert.Len(t, handler.recordedQueries(), 1)
+}
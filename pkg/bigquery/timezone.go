@@ -0,0 +1,43 @@
+package bigquery
+
+import (
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/pkg/errors"
+)
+
+// outputLocation returns the time zone DATETIME values are rendered in, or nil if they are kept as civil.DateTime.
+// It is validated by NewDB already, the queries check it again before they run for the clients built otherwise.
+func (c *Config) outputLocation() (*time.Location, error) {
+	if c == nil || c.OutputTimeZone == "" {
+		return nil, nil
+	}
+
+	loc, err := time.LoadLocation(c.OutputTimeZone)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid output time zone '%s'", c.OutputTimeZone)
+	}
+
+	return loc, nil
+}
+
+// normalizeDateTimes replaces the civil.DateTime values of the top-level DATETIME columns with the time.Time of
+// the same wall-clock time in the given location. TIMESTAMP values are instants and are left as they are.
+func normalizeDateTimes(rows [][]interface{}, schema bigquery.Schema, loc *time.Location) {
+	for i, field := range schema {
+		if field.Type != bigquery.DateTimeFieldType || field.Repeated {
+			continue
+		}
+
+		for _, row := range rows {
+			if i >= len(row) {
+				continue
+			}
+			if dt, ok := row[i].(civil.DateTime); ok {
+				row[i] = dt.In(loc)
+			}
+		}
+	}
+}
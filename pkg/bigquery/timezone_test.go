@@ -0,0 +1,92 @@
+package bigquery
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestDB_SelectWithSchema_OutputTimeZone(t *testing.T) {
+	t.Parallel()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	instant := time.Unix(1700000000, 0).UTC()
+	wallClock := civil.DateTime{Date: civil.Date{Year: 2023, Month: 11, Day: 14}, Time: civil.Time{Hour: 22, Minute: 13, Second: 20}}
+
+	tests := []struct {
+		name     string
+		timeZone string
+		want     []interface{}
+		wantErr  string
+	}{
+		{
+			name: "raw civil types by default",
+			want: []interface{}{instant, wallClock, nil},
+		},
+		{
+			name:     "DATETIME rendered in the output time zone",
+			timeZone: "Europe/Berlin",
+			want:     []interface{}{instant, time.Date(2023, 11, 14, 22, 13, 20, 0, berlin), nil},
+		},
+		{
+			name:     "invalid time zone",
+			timeZone: "Mars/Olympus",
+			wantErr:  "invalid output time zone 'Mars/Olympus'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := &recordingQueryHandler{
+				response: func(q string) *bigquery2.QueryResponse {
+					return &bigquery2.QueryResponse{
+						JobComplete:  true,
+						JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+						Schema: &bigquery2.TableSchema{
+							Fields: []*bigquery2.TableFieldSchema{
+								{Name: "created_at", Type: "TIMESTAMP"},
+								{Name: "local_time", Type: "DATETIME"},
+								{Name: "missing_time", Type: "DATETIME"},
+							},
+						},
+						Rows: []*bigquery2.TableRow{
+							{F: []*bigquery2.TableCell{{V: "1700000000000000"}, {V: "2023-11-14T22:13:20"}, {V: nil}}},
+						},
+						TotalRows: 1,
+					}
+				},
+			}
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+			d.config.OutputTimeZone = tt.timeZone
+
+			got, err := d.SelectWithSchema(context.Background(), &query.Query{Query: "SELECT * FROM dataset.events"})
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				assert.Empty(t, handler.recordedQueries(), "the query must not run with an invalid time zone")
+
+				_, err = NewDB(&Config{ProjectID: testProjectID, OutputTimeZone: tt.timeZone})
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{"TIMESTAMP", "DATETIME", "DATETIME"}, got.ColumnTypes)
+			require.Len(t, got.Rows, 1)
+			assert.Equal(t, tt.want, got.Rows[0])
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/bruin-data/bruin/pkg/query"
+)
+
+// SelectWithSchemaTyped is like SelectWithSchema, but converts the values into predictable, JSON-serializable Go
+// types based on the BigQuery type of their column:
+//   - NUMERIC and BIGNUMERIC into strings, which keep their precision
+//   - TIMESTAMP into time.Time in UTC
+//   - DATE, DATETIME and TIME into strings, e.g. `2024-05-01`, `2024-05-01T10:00:00` and `10:00:00`
+//   - BYTES into base64 strings
+//   - RECORD into maps keyed by the field names and repeated fields into slices
+//
+// The other types, e.g. INTEGER, FLOAT, BOOLEAN and STRING, are kept as they are, and so are NULL values.
+// DATETIME values already rendered in Config.OutputTimeZone are kept as time.Time.
+func (d *Client) SelectWithSchemaTyped(ctx context.Context, queryObj *query.Query) (*query.QueryResult, error) {
+	return d.selectWithSchema(ctx, queryObj, -1, true)
+}
+
+func typeRows(rows [][]interface{}, schema bigquery.Schema) {
+	for _, row := range rows {
+		for i := range row {
+			if i < len(schema) {
+				row[i] = typedValue(schema[i], row[i])
+			}
+		}
+	}
+}
+
+// typedValue converts a value read from a column of the given field, see SelectWithSchemaTyped.
+func typedValue(field *bigquery.FieldSchema, value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	if field.Repeated {
+		values, ok := value.([]bigquery.Value)
+		if !ok {
+			return value
+		}
+
+		element := *field
+		element.Repeated = false
+		typed := make([]interface{}, len(values))
+		for i, v := range values {
+			typed[i] = typedValue(&element, v)
+		}
+		return typed
+	}
+
+	switch v := value.(type) {
+	case *big.Rat:
+		return query.FormatDecimal(v)
+	case time.Time:
+		if field.Type == bigquery.TimestampFieldType {
+			return v.UTC()
+		}
+		return v
+	case civil.Date, civil.DateTime, civil.Time:
+		return v.(fmt.Stringer).String()
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case []bigquery.Value:
+		if field.Type != bigquery.RecordFieldType {
+			return v
+		}
+		record := make(map[string]interface{}, len(field.Schema))
+		for i, nested := range field.Schema {
+			if i < len(v) {
+				record[nested.Name] = typedValue(nested, v[i])
+			}
+		}
+		return record
+	default:
+		return value
+	}
+}
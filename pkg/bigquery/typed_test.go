@@ -0,0 +1,89 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_SelectWithSchemaTyped(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			return &bigquery2.QueryResponse{
+				JobComplete:  true,
+				JobReference: &bigquery2.JobReference{JobId: "job-id", ProjectId: testProjectID},
+				Schema: &bigquery2.TableSchema{
+					Fields: []*bigquery2.TableFieldSchema{
+						{Name: "id", Type: "INTEGER"},
+						{Name: "amount", Type: "BIGNUMERIC"},
+						{Name: "created_at", Type: "TIMESTAMP"},
+						{Name: "day", Type: "DATE"},
+						{Name: "updated_at", Type: "DATETIME"},
+						{Name: "payload", Type: "BYTES"},
+						{Name: "address", Type: "RECORD", Fields: []*bigquery2.TableFieldSchema{
+							{Name: "city", Type: "STRING"},
+							{Name: "moved_on", Type: "DATE"},
+						}},
+						{Name: "scores", Type: "NUMERIC", Mode: "REPEATED"},
+					},
+				},
+				Rows: []*bigquery2.TableRow{
+					{F: []*bigquery2.TableCell{
+						{V: "1"},
+						{V: "12345678901234567890.123456789012345678"},
+						{V: "1714557600000000"},
+						{V: "2024-05-01"},
+						{V: "2024-05-01T10:30:00"},
+						{V: "aGVsbG8="},
+						{V: map[string]interface{}{"f": []interface{}{
+							map[string]interface{}{"v": "Berlin"},
+							map[string]interface{}{"v": "2020-01-15"},
+						}}},
+						{V: []interface{}{map[string]interface{}{"v": "0.1"}, map[string]interface{}{"v": "2"}}},
+					}},
+					{F: []*bigquery2.TableCell{
+						{V: "2"}, {V: nil}, {V: nil}, {V: nil}, {V: nil}, {V: nil}, {V: nil}, {V: []interface{}{}},
+					}},
+				},
+				TotalRows: 2,
+			}
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	result, err := d.SelectWithSchemaTyped(context.Background(), &query.Query{Query: "SELECT * FROM dataset.users"})
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]interface{}{
+		{
+			int64(1),
+			"12345678901234567890.123456789012345678",
+			time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			"2024-05-01",
+			"2024-05-01T10:30:00",
+			"aGVsbG8=",
+			map[string]interface{}{"city": "Berlin", "moved_on": "2020-01-15"},
+			[]interface{}{"0.1", "2"},
+		},
+		{int64(2), nil, nil, nil, nil, nil, nil, []interface{}{}},
+	}, result.Rows)
+	assert.Equal(t, time.UTC, result.Rows[0][2].(time.Time).Location())
+
+	// the typed values can be serialized as they are
+	encoded, err := json.Marshal(result.Rows[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `[1, "12345678901234567890.123456789012345678", "2024-05-01T10:00:00Z", "2024-05-01",
+		"2024-05-01T10:30:00", "aGVsbG8=", {"city": "Berlin", "moved_on": "2020-01-15"}, ["0.1", "2"]]`, string(encoded))
+}
@@ -0,0 +1,97 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/pkg/errors"
+)
+
+// BuildUnusedTablesQuery builds a query that lists the tables of the given dataset that were not referenced by any
+// job of the given job projects within the given window. Jobs are listed in the JOBS view of the project they ran in,
+// which is the project billed for them rather than the one of the tables. The project and dataset are bound as query
+// parameters where they are compared as values, and quoted where they name the views.
+func BuildUnusedTablesQuery(projectID, datasetID, location string, jobProjects []string, since time.Duration) *query.Query {
+	region := QuoteIdentifier("region-" + strings.ToLower(location))
+	jobs := make([]string, 0, len(jobProjects))
+	for _, jobProject := range jobProjects {
+		jobs = append(jobs, fmt.Sprintf(`    SELECT rt.table_id
+    FROM %s.%s.INFORMATION_SCHEMA.JOBS_BY_PROJECT AS j, UNNEST(j.referenced_tables) AS rt
+    WHERE j.creation_time >= TIMESTAMP_SUB(CURRENT_TIMESTAMP(), INTERVAL %d SECOND)
+      AND rt.project_id = @project_id
+      AND rt.dataset_id = @dataset_id`,
+			QuoteIdentifier(jobProject),
+			region,
+			int64(since.Seconds()),
+		))
+	}
+
+	qq := fmt.Sprintf(`SELECT t.table_name
+FROM %s.INFORMATION_SCHEMA.TABLES AS t
+WHERE t.table_type = 'BASE TABLE'
+  AND t.table_name NOT IN (
+%s
+  )
+ORDER BY t.table_name`,
+		QuoteIdentifier(projectID+"."+datasetID),
+		strings.Join(jobs, "\n    UNION DISTINCT\n"),
+	)
+
+	return &query.Query{
+		Query: qq,
+		Parameters: []query.Parameter{
+			{Name: "project_id", Value: projectID},
+			{Name: "dataset_id", Value: datasetID},
+		},
+	}
+}
+
+// FindUnusedTables returns the tables of the given dataset, either `dataset` or `project.dataset`, that were not
+// referenced by any job within the given window. The jobs of the connection project, which runs the queries of bruin,
+// and of the project of the dataset are looked at. The results are candidates for cleanup, not a guarantee that the
+// tables are unused:
+//   - reads through the Storage Read API, table previews, exports and streaming do not appear in the JOBS view,
+//   - the JOBS view only retains the last 180 days, longer windows are capped by BigQuery,
+//   - jobs of other projects that read the tables are not visible.
+//
+// The credentials need the `bigquery.jobs.listAll` permission on both projects, e.g. through the BigQuery Resource
+// Viewer role, and `bigquery.tables.list` on the dataset. The JOBS view is regional; the configured location is
+// used, falling back to US.
+func (d *Client) FindUnusedTables(ctx context.Context, datasetName string, since time.Duration) ([]string, error) {
+	if since <= 0 {
+		return nil, errors.New("the window to look for table usage must be positive")
+	}
+
+	projectID, datasetID, err := d.resolveDataset(datasetName)
+	if err != nil {
+		return nil, err
+	}
+
+	location := "US"
+	if d.config != nil && d.config.Location != "" {
+		location = d.config.Location
+	}
+
+	jobProjects := []string{projectID}
+	if d.config != nil && d.config.ProjectID != "" && d.config.ProjectID != projectID {
+		jobProjects = []string{d.config.ProjectID, projectID}
+	}
+
+	rows, err := d.Select(ctx, BuildUnusedTablesQuery(projectID, datasetID, location, jobProjects, since))
+	if err != nil && !errors.Is(err, ErrNoRows) {
+		return nil, errors.Wrapf(err, "failed to find unused tables in dataset '%s'", datasetName)
+	}
+
+	tables := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if len(row) == 0 || row[0] == nil {
+			continue
+		}
+		tables = append(tables, fmt.Sprint(row[0]))
+	}
+
+	return tables, nil
+}
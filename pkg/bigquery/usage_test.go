@@ -0,0 +1,82 @@
+package bigquery
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bruin-data/bruin/pkg/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestBuildUnusedTablesQuery(t *testing.T) {
+	t.Parallel()
+
+	got := BuildUnusedTablesQuery("my-project", "analytics", "EU", []string{"billing-project", "my-project"}, 30*24*time.Hour)
+	assert.Equal(t, []query.Parameter{
+		{Name: "project_id", Value: "my-project"},
+		{Name: "dataset_id", Value: "analytics"},
+	}, got.Parameters)
+	assert.Equal(t, "SELECT t.table_name\n"+
+		"FROM `my-project.analytics`.INFORMATION_SCHEMA.TABLES AS t\n"+
+		"WHERE t.table_type = 'BASE TABLE'\n"+
+		"  AND t.table_name NOT IN (\n"+
+		"    SELECT rt.table_id\n"+
+		"    FROM `billing-project`.`region-eu`.INFORMATION_SCHEMA.JOBS_BY_PROJECT AS j, UNNEST(j.referenced_tables) AS rt\n"+
+		"    WHERE j.creation_time >= TIMESTAMP_SUB(CURRENT_TIMESTAMP(), INTERVAL 2592000 SECOND)\n"+
+		"      AND rt.project_id = @project_id\n"+
+		"      AND rt.dataset_id = @dataset_id\n"+
+		"    UNION DISTINCT\n"+
+		"    SELECT rt.table_id\n"+
+		"    FROM `my-project`.`region-eu`.INFORMATION_SCHEMA.JOBS_BY_PROJECT AS j, UNNEST(j.referenced_tables) AS rt\n"+
+		"    WHERE j.creation_time >= TIMESTAMP_SUB(CURRENT_TIMESTAMP(), INTERVAL 2592000 SECOND)\n"+
+		"      AND rt.project_id = @project_id\n"+
+		"      AND rt.dataset_id = @dataset_id\n"+
+		"  )\n"+
+		"ORDER BY t.table_name", got.Query)
+}
+
+func TestClient_FindUnusedTables(t *testing.T) {
+	t.Parallel()
+
+	handler := &recordingQueryHandler{
+		response: func(q string) *bigquery2.QueryResponse {
+			return stringQueryResponse("old_events", "tmp_backup")
+		},
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+
+	got, err := d.FindUnusedTables(context.Background(), "analytics", 7*24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old_events", "tmp_backup"}, got)
+
+	requests := handler.recordedRequests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, BuildUnusedTablesQuery(testProjectID, "analytics", "US", []string{testProjectID}, 7*24*time.Hour).Query, requests[0].Query)
+	require.Len(t, requests[0].QueryParameters, 2)
+	assert.Equal(t, "analytics", requests[0].QueryParameters[1].ParameterValue.Value)
+
+	// the jobs run in the connection project, the one of the dataset is looked at as well
+	got, err = d.FindUnusedTables(context.Background(), "data-project.analytics", 7*24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"old_events", "tmp_backup"}, got)
+
+	requests = handler.recordedRequests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, BuildUnusedTablesQuery("data-project", "analytics", "US", []string{testProjectID, "data-project"}, 7*24*time.Hour).Query, requests[1].Query)
+	assert.Contains(t, requests[1].Query, "FROM `"+testProjectID+"`.`region-us`.INFORMATION_SCHEMA.JOBS_BY_PROJECT")
+	assert.Contains(t, requests[1].Query, "FROM `data-project.analytics`.INFORMATION_SCHEMA.TABLES")
+	assert.Equal(t, "data-project", requests[1].QueryParameters[0].ParameterValue.Value)
+
+	_, err = d.FindUnusedTables(context.Background(), "a.b.c", time.Hour)
+	require.EqualError(t, err, "dataset name must be in dataset or project.dataset format, 'a.b.c' given")
+
+	_, err = d.FindUnusedTables(context.Background(), "analytics", 0)
+	require.EqualError(t, err, "the window to look for table usage must be positive")
+}
@@ -0,0 +1,71 @@
+package bigquery
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/pkg/errors"
+)
+
+// DefaultWatermarkLabel is the table label the watermark is stored in unless Config.WatermarkLabel is set.
+const DefaultWatermarkLabel = "bruin_watermark"
+
+func (d *Client) watermarkLabel() string {
+	if d.config != nil && d.config.WatermarkLabel != "" {
+		return sanitizeLabelKey(d.config.WatermarkLabel)
+	}
+
+	return DefaultWatermarkLabel
+}
+
+// GetWatermark returns the watermark stored on the table by SetWatermark, e.g. the latest timestamp an incremental
+// pipeline processed. The zero time is returned if the table has no watermark.
+func (d *Client) GetWatermark(ctx context.Context, tableName string) (time.Time, error) {
+	tableRef, err := d.getTableRef(tableName)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	meta, err := tableRef.Metadata(ctx)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(formatError(err), "failed to fetch metadata for table '%s'", tableName)
+	}
+
+	value, ok := meta.Labels[d.watermarkLabel()]
+	if !ok || value == "" {
+		return time.Time{}, nil
+	}
+
+	micros, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid watermark '%s' on table '%s': %w", value, tableName, err)
+	}
+
+	return time.UnixMicro(micros).UTC(), nil
+}
+
+// SetWatermark stores the watermark as a label on the table. Label values are limited to 63 lowercase letters,
+// digits, underscores and dashes, which is why the watermark is stored as the number of microseconds since the
+// Unix epoch; anything finer than microseconds is truncated, and times before the epoch cannot be stored.
+func (d *Client) SetWatermark(ctx context.Context, tableName string, t time.Time) error {
+	if t.Before(time.Unix(0, 0)) {
+		return fmt.Errorf("watermark %s is before the Unix epoch and cannot be stored", t.Format(time.RFC3339))
+	}
+
+	tableRef, err := d.getTableRef(tableName)
+	if err != nil {
+		return err
+	}
+
+	update := bigquery.TableMetadataToUpdate{}
+	update.SetLabel(d.watermarkLabel(), strconv.FormatInt(t.UnixMicro(), 10))
+
+	if _, err := tableRef.Update(ctx, update, ""); err != nil {
+		return errors.Wrapf(formatError(err), "failed to set the watermark of table '%s'", tableName)
+	}
+
+	return nil
+}
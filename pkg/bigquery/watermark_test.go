@@ -0,0 +1,104 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bigquery2 "google.golang.org/api/bigquery/v2"
+)
+
+func TestClient_GetWatermark(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		labels  map[string]string
+		want    time.Time
+		wantErr string
+	}{
+		{
+			name:   "watermark is set",
+			labels: map[string]string{DefaultWatermarkLabel: "1700000000123456", "team": "data"},
+			want:   time.Date(2023, 11, 14, 22, 13, 20, 123456000, time.UTC),
+		},
+		{
+			name:   "no watermark",
+			labels: map[string]string{"team": "data"},
+			want:   time.Time{},
+		},
+		{
+			name:    "invalid watermark",
+			labels:  map[string]string{DefaultWatermarkLabel: "yesterday"},
+			wantErr: "invalid watermark 'yesterday' on table 'dataset.events'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != fmt.Sprintf("/projects/%s/datasets/dataset/tables/events", testProjectID) {
+					http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+					return
+				}
+
+				_ = json.NewEncoder(w).Encode(&bigquery2.Table{Labels: tt.labels})
+			}))
+			defer server.Close()
+
+			d := newTestClient(t, server.URL)
+
+			got, err := d.GetWatermark(context.Background(), "dataset.events")
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "expected %s, got %s", tt.want, got)
+		})
+	}
+}
+
+func TestClient_SetWatermark(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var patched *bigquery2.Table
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != fmt.Sprintf("/projects/%s/datasets/dataset/tables/events", testProjectID) {
+			http.Error(w, "unexpected request: "+r.Method+" "+r.RequestURI, http.StatusInternalServerError)
+			return
+		}
+
+		var table bigquery2.Table
+		_ = json.NewDecoder(r.Body).Decode(&table)
+		mu.Lock()
+		patched = &table
+		mu.Unlock()
+		_ = json.NewEncoder(w).Encode(&table)
+	}))
+	defer server.Close()
+
+	d := newTestClient(t, server.URL)
+	d.config.WatermarkLabel = "Last_Build"
+
+	err := d.SetWatermark(context.Background(), "dataset.events", time.Date(2023, 11, 14, 22, 13, 20, 123456789, time.UTC))
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotNil(t, patched)
+	assert.Equal(t, map[string]string{"last_build": "1700000000123456"}, patched.Labels)
+
+	err = d.SetWatermark(context.Background(), "dataset.events", time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.EqualError(t, err, "watermark 1960-01-01T00:00:00Z is before the Unix epoch and cannot be stored")
+}
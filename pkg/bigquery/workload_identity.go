@@ -0,0 +1,123 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/google/externalaccount"
+)
+
+const (
+	// DefaultSTSTokenURL is the Security Token Service endpoint external tokens are exchanged at.
+	DefaultSTSTokenURL = "https://sts.googleapis.com/v1/token"
+
+	// SubjectTokenTypeJWT is the type of OIDC tokens, e.g. the ones GitHub Actions issues.
+	SubjectTokenTypeJWT = "urn:ietf:params:oauth:token-type:jwt"
+
+	externalAccountCredentialsType = "external_account"
+)
+
+// WorkloadIdentityConfig configures workload identity federation, where a token issued by an external identity
+// provider, e.g. the OIDC token of a GitHub Actions job, is exchanged for Google credentials instead of using a
+// service account key. The subject token is read from either SubjectTokenFile or SubjectTokenURL.
+type WorkloadIdentityConfig struct {
+	// Audience is the full resource name of the workload identity pool provider, e.g.
+	// `//iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/my-pool/providers/my-provider`.
+	Audience string
+
+	// SubjectTokenType is the type of the external token, it defaults to SubjectTokenTypeJWT.
+	SubjectTokenType string
+
+	SubjectTokenFile string
+	SubjectTokenURL  string
+
+	// SubjectTokenHeaders are sent with the requests to SubjectTokenURL, e.g. the bearer token GitHub Actions
+	// requires in the Authorization header.
+	SubjectTokenHeaders map[string]string
+
+	// SubjectTokenFieldName is the field that holds the token when the file or the URL response is a JSON object,
+	// e.g. `value` for GitHub Actions. The token is read as plain text if it is empty.
+	SubjectTokenFieldName string
+
+	// ServiceAccountEmail is the service account impersonated with the federated token, if any. Without it the
+	// federated identity is granted access directly.
+	ServiceAccountEmail string
+
+	// TokenURL is the endpoint the token is exchanged at, it defaults to DefaultSTSTokenURL.
+	TokenURL string
+}
+
+// Validate checks that the configuration identifies the provider and exactly one source of the subject token.
+func (w *WorkloadIdentityConfig) Validate() error {
+	if w.Audience == "" {
+		return errors.New("workload identity requires the audience, the full resource name of the workload identity pool provider")
+	}
+	if (w.SubjectTokenFile == "") == (w.SubjectTokenURL == "") {
+		return errors.New("workload identity requires exactly one of the subject token file or the subject token URL")
+	}
+
+	return nil
+}
+
+// TokenSource builds the token source that exchanges the external token for Google access tokens.
+func (w *WorkloadIdentityConfig) TokenSource(ctx context.Context) (oauth2.TokenSource, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	source := &externalaccount.CredentialSource{
+		File:    w.SubjectTokenFile,
+		URL:     w.SubjectTokenURL,
+		Headers: w.SubjectTokenHeaders,
+	}
+	if w.SubjectTokenFieldName != "" {
+		source.Format = externalaccount.Format{Type: "json", SubjectTokenFieldName: w.SubjectTokenFieldName}
+	}
+
+	conf := externalaccount.Config{
+		Audience:         w.Audience,
+		SubjectTokenType: w.SubjectTokenType,
+		TokenURL:         w.TokenURL,
+		CredentialSource: source,
+		Scopes:           scopes,
+	}
+	if conf.SubjectTokenType == "" {
+		conf.SubjectTokenType = SubjectTokenTypeJWT
+	}
+	if conf.TokenURL == "" {
+		conf.TokenURL = DefaultSTSTokenURL
+	}
+	if w.ServiceAccountEmail != "" {
+		conf.ServiceAccountImpersonationURL = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/" + w.ServiceAccountEmail + ":generateAccessToken"
+	}
+
+	ts, err := externalaccount.NewTokenSource(ctx, conf)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to configure workload identity federation")
+	}
+
+	return ts, nil
+}
+
+// externalAccountCredentials parses the credentials JSON if it holds `external_account` credentials, i.e. a workload
+// identity federation configuration rather than a service account key, reporting false for other credentials.
+// Parsing them upfront surfaces an incomplete configuration when the client is created rather than on the first query.
+func externalAccountCredentials(ctx context.Context, credentialsJSON []byte) (*google.Credentials, bool, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(credentialsJSON, &header); err != nil || header.Type != externalAccountCredentialsType {
+		// other credentials are left to the client to parse and report on
+		return nil, false, nil
+	}
+
+	creds, err := google.CredentialsFromJSON(ctx, credentialsJSON, scopes...)
+	if err != nil {
+		return nil, true, errors.Wrap(err, "invalid workload identity federation credentials")
+	}
+
+	return creds, true, nil
+}
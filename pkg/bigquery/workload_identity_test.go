@@ -0,0 +1,161 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSTSServer mocks the Security Token Service, recording the token exchange requests.
+func newSTSServer(t *testing.T) (*httptest.Server, func() []map[string]string) {
+	var mu sync.Mutex
+	exchanges := make([]map[string]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		exchanges = append(exchanges, map[string]string{
+			"audience":           r.PostForm.Get("audience"),
+			"subject_token":      r.PostForm.Get("subject_token"),
+			"subject_token_type": r.PostForm.Get("subject_token_type"),
+		})
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":      "federated-token",
+			"issued_token_type": "urn:ietf:params:oauth:token-type:access_token",
+			"token_type":        "Bearer",
+			"expires_in":        3600,
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []map[string]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]map[string]string{}, exchanges...)
+	}
+}
+
+const testWorkloadIdentityAudience = "//iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/github/providers/actions"
+
+func TestWorkloadIdentityConfig_TokenSource(t *testing.T) {
+	t.Parallel()
+
+	server, exchanges := newSTSServer(t)
+
+	tokenFile := filepath.Join(t.TempDir(), "token.json")
+	require.NoError(t, os.WriteFile(tokenFile, []byte(`{"value": "github-oidc-token"}`), 0o600))
+
+	w := &WorkloadIdentityConfig{
+		Audience:              testWorkloadIdentityAudience,
+		SubjectTokenFile:      tokenFile,
+		SubjectTokenFieldName: "value",
+		TokenURL:              server.URL,
+	}
+
+	ts, err := w.TokenSource(context.Background())
+	require.NoError(t, err)
+
+	token, err := ts.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "federated-token", token.AccessToken)
+	assert.Equal(t, []map[string]string{{
+		"audience":           testWorkloadIdentityAudience,
+		"subject_token":      "github-oidc-token",
+		"subject_token_type": SubjectTokenTypeJWT,
+	}}, exchanges())
+}
+
+func TestWorkloadIdentityConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  WorkloadIdentityConfig
+		wantErr string
+	}{
+		{
+			name:   "token file",
+			config: WorkloadIdentityConfig{Audience: testWorkloadIdentityAudience, SubjectTokenFile: "/tmp/token"},
+		},
+		{
+			name:    "missing audience",
+			config:  WorkloadIdentityConfig{SubjectTokenFile: "/tmp/token"},
+			wantErr: "workload identity requires the audience, the full resource name of the workload identity pool provider",
+		},
+		{
+			name:    "no token source",
+			config:  WorkloadIdentityConfig{Audience: testWorkloadIdentityAudience},
+			wantErr: "workload identity requires exactly one of the subject token file or the subject token URL",
+		},
+		{
+			name:    "both token sources",
+			config:  WorkloadIdentityConfig{Audience: testWorkloadIdentityAudience, SubjectTokenFile: "/tmp/token", SubjectTokenURL: "http://localhost/token"},
+			wantErr: "workload identity requires exactly one of the subject token file or the subject token URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.config.Validate()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestExternalAccountCredentials(t *testing.T) {
+	t.Parallel()
+
+	server, exchanges := newSTSServer(t)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("github-oidc-token"), 0o600))
+
+	credentialsJSON := fmt.Sprintf(`{
+  "type": "external_account",
+  "audience": %q,
+  "subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+  "token_url": %q,
+  "credential_source": {"file": %q}
+}`, testWorkloadIdentityAudience, server.URL, tokenFile)
+
+	creds, isExternalAccount, err := externalAccountCredentials(context.Background(), []byte(credentialsJSON))
+	require.NoError(t, err)
+	require.True(t, isExternalAccount)
+
+	token, err := creds.TokenSource.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "federated-token", token.AccessToken)
+	require.Len(t, exchanges(), 1)
+	assert.Equal(t, "github-oidc-token", exchanges()[0]["subject_token"])
+
+	// service account keys are left to the client
+	creds, isExternalAccount, err = externalAccountCredentials(context.Background(), []byte(`{"type": "service_account"}`))
+	require.NoError(t, err)
+	assert.False(t, isExternalAccount)
+	assert.Nil(t, creds)
+
+	_, isExternalAccount, err = externalAccountCredentials(context.Background(), []byte(`{"type": "external_account", "audience": "x"}`))
+	require.ErrorContains(t, err, "invalid workload identity federation credentials")
+	assert.True(t, isExternalAccount)
+}
@@ -1443,14 +1443,33 @@ func (m *Manager) AddBqConnectionFromConfig(connection *config.GoogleCloudPlatfo
 		return err
 	}
 
-	// Lock and store the new BigQuery client.
+	// Lock and store the new BigQuery client, releasing the one it replaces.
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
+	if previous, ok := m.BigQuery[connection.Name]; ok {
+		_ = previous.Close()
+	}
 	m.BigQuery[connection.Name] = db
 
 	return nil
 }
 
+// Close releases the clients the manager holds on to. The BigQuery clients share their underlying connections with
+// the other clients created with the same settings, see bigquery.NewDB, these are only closed once nobody uses them.
+func (m *Manager) Close() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var firstErr error
+	for name, db := range m.BigQuery {
+		if err := db.Close(); err != nil && firstErr == nil {
+			firstErr = errors.Wrapf(err, "failed to close the bigquery connection '%s'", name)
+		}
+	}
+
+	return firstErr
+}
+
 func (m *Manager) AddSfConnectionFromConfig(connection *config.SnowflakeConnection) error {
 	m.mutex.Lock()
 	if m.Snowflake == nil {
@@ -50,6 +50,10 @@ const (
 	materializationPartitionByNotSupportedForViews    = "Materialization partition by is not supported for views because views cannot be partitioned"
 	materializationIncrementalKeyNotSupportedForViews = "Materialization incremental key is not supported for views because views cannot be updated incrementally"
 	materializationClusterByNotSupportedForViews      = "Materialization cluster by is not supported for views because views cannot be clustered"
+
+	materializedViewsOnlySupportedForBigQuery                     = "Materialization type 'materialized_view' is only supported for BigQuery assets"
+	materializationStrategyIsNotSupportedForMaterializedViews     = "Materialization strategy is not supported for materialized views because they are refreshed by the platform"
+	materializationIncrementalKeyNotSupportedForMaterializedViews = "Materialization incremental key is not supported for materialized views because they are refreshed by the platform"
 )
 
 var validIDRegexCompiled = regexp.MustCompile(validIDRegex)
@@ -860,6 +864,28 @@ func EnsureMaterializationValuesAreValidForSingleAsset(ctx context.Context, p *p
 			})
 		}
 
+	case pipeline.MaterializationTypeMaterializedView:
+		if asset.Type != pipeline.AssetTypeBigqueryQuery {
+			issues = append(issues, &Issue{
+				Task:        asset,
+				Description: materializedViewsOnlySupportedForBigQuery,
+			})
+		}
+
+		if asset.Materialization.Strategy != pipeline.MaterializationStrategyNone {
+			issues = append(issues, &Issue{
+				Task:        asset,
+				Description: materializationStrategyIsNotSupportedForMaterializedViews,
+			})
+		}
+
+		if asset.Materialization.IncrementalKey != "" {
+			issues = append(issues, &Issue{
+				Task:        asset,
+				Description: materializationIncrementalKeyNotSupportedForMaterializedViews,
+			})
+		}
+
 	case pipeline.MaterializationTypeTable:
 		if asset.Materialization.Strategy == pipeline.MaterializationStrategyNone {
 			return issues, nil
@@ -938,6 +964,7 @@ func EnsureMaterializationValuesAreValidForSingleAsset(ctx context.Context, p *p
 				[]pipeline.MaterializationType{
 					pipeline.MaterializationTypeView,
 					pipeline.MaterializationTypeTable,
+					pipeline.MaterializationTypeMaterializedView,
 				},
 			),
 		})
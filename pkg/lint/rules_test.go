@@ -1309,6 +1309,40 @@ func TestEnsureMaterializationValuesAreValid(t *testing.T) {
 				materializationPartitionByNotSupportedForViews,
 			},
 		},
+		{
+			name: "materialized view on BigQuery, all good",
+			assets: []*pipeline.Asset{
+				{
+					Name: "task1",
+					Type: pipeline.AssetTypeBigqueryQuery,
+					Materialization: pipeline.Materialization{
+						Type:        pipeline.MaterializationTypeMaterializedView,
+						PartitionBy: "dt",
+					},
+				},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "materialized view has extra fields",
+			assets: []*pipeline.Asset{
+				{
+					Name: "task1",
+					Type: pipeline.AssetTypeSnowflakeQuery,
+					Materialization: pipeline.Materialization{
+						Type:           pipeline.MaterializationTypeMaterializedView,
+						Strategy:       pipeline.MaterializationStrategyMerge,
+						IncrementalKey: "dt",
+					},
+				},
+			},
+			wantErr: assert.NoError,
+			want: []string{
+				materializedViewsOnlySupportedForBigQuery,
+				materializationStrategyIsNotSupportedForMaterializedViews,
+				materializationIncrementalKeyNotSupportedForMaterializedViews,
+			},
+		},
 		{
 			name: "table materialization has create+replace, all good",
 			assets: []*pipeline.Asset{
@@ -1404,6 +1438,7 @@ func TestEnsureMaterializationValuesAreValid(t *testing.T) {
 					[]pipeline.MaterializationType{
 						pipeline.MaterializationTypeView,
 						pipeline.MaterializationTypeTable,
+						pipeline.MaterializationTypeMaterializedView,
 					},
 				),
 			},
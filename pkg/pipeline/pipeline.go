@@ -261,9 +261,10 @@ type (
 )
 
 const (
-	MaterializationTypeNone  MaterializationType = ""
-	MaterializationTypeView  MaterializationType = "view"
-	MaterializationTypeTable MaterializationType = "table"
+	MaterializationTypeNone             MaterializationType = ""
+	MaterializationTypeView             MaterializationType = "view"
+	MaterializationTypeTable            MaterializationType = "table"
+	MaterializationTypeMaterializedView MaterializationType = "materialized_view"
 )
 
 type (
@@ -283,6 +284,23 @@ const (
 	MaterializationStrategyCreateTable      DDLStrategy                    = "create_table"
 )
 
+type (
+	PartitionType        string
+	PartitionGranularity string
+)
+
+const (
+	PartitionTypeNone  PartitionType = ""
+	PartitionTypeTime  PartitionType = "time"
+	PartitionTypeRange PartitionType = "range"
+
+	PartitionGranularityNone  PartitionGranularity = ""
+	PartitionGranularityHour  PartitionGranularity = "hour"
+	PartitionGranularityDay   PartitionGranularity = "day"
+	PartitionGranularityMonth PartitionGranularity = "month"
+	PartitionGranularityYear  PartitionGranularity = "year"
+)
+
 var AllAvailableMaterializationStrategies = []MaterializationStrategy{
 	MaterializationStrategyCreateReplace,
 	MaterializationStrategyDeleteInsert,
@@ -298,10 +316,46 @@ type Materialization struct {
 	ClusterBy       []string                       `json:"cluster_by" yaml:"cluster_by,omitempty" mapstructure:"cluster_by"`
 	IncrementalKey  string                         `json:"incremental_key" yaml:"incremental_key,omitempty" mapstructure:"incremental_key"`
 	TimeGranularity MaterializationTimeGranularity `json:"time_granularity" yaml:"time_granularity,omitempty" mapstructure:"time_granularity"`
+	PartitionRange  *PartitionRange                `json:"partition_range,omitempty" yaml:"partition_range,omitempty" mapstructure:"partition_range"`
+
+	// PartitionType is the kind of partitioning, by time or by integer range, which is otherwise derived from the
+	// presence of PartitionRange. PartitionGranularity is the size of the time partitions, derived from the
+	// partitioning expression if not set and one day by default.
+	PartitionType        PartitionType        `json:"partition_type,omitempty" yaml:"partition_type,omitempty" mapstructure:"partition_type"`
+	PartitionGranularity PartitionGranularity `json:"partition_granularity,omitempty" yaml:"partition_granularity,omitempty" mapstructure:"partition_granularity"`
+
+	// PartitionExpirationDays deletes the partitions of time partitioned tables once they are older than the
+	// given number of days, zero keeps them forever.
+	PartitionExpirationDays int `json:"partition_expiration_days,omitempty" yaml:"partition_expiration_days,omitempty" mapstructure:"partition_expiration_days"`
+	// RequirePartitionFilter rejects the queries on the table that do not filter on the partitioning column.
+	RequirePartitionFilter bool `json:"require_partition_filter,omitempty" yaml:"require_partition_filter,omitempty" mapstructure:"require_partition_filter"`
+	// NeverExpire keeps the table forever even if its dataset sets a default table expiration.
+	NeverExpire bool `json:"never_expire,omitempty" yaml:"never_expire,omitempty" mapstructure:"never_expire"`
+
+	// EnableRefresh turns the automatic refresh of materialized views on or off, nil keeps the default of the platform.
+	EnableRefresh *bool `json:"enable_refresh,omitempty" yaml:"enable_refresh,omitempty" mapstructure:"enable_refresh"`
+	// RefreshIntervalMinutes is the frequency materialized views are refreshed at, zero keeps the default.
+	RefreshIntervalMinutes int `json:"refresh_interval_minutes,omitempty" yaml:"refresh_interval_minutes,omitempty" mapstructure:"refresh_interval_minutes"`
+
+	// DeduplicateOnIngest keeps a single row per primary key out of the rows the query returns, the one with the
+	// highest DeduplicateOrderBy value.
+	DeduplicateOnIngest bool   `json:"deduplicate_on_ingest,omitempty" yaml:"deduplicate_on_ingest,omitempty" mapstructure:"deduplicate_on_ingest"`
+	DeduplicateOrderBy  string `json:"deduplicate_order_by,omitempty" yaml:"deduplicate_order_by,omitempty" mapstructure:"deduplicate_order_by"`
+}
+
+// PartitionRange configures integer range partitioning on the PartitionBy column: the values between Start
+// (inclusive) and End (exclusive) are split into partitions of Interval values each.
+type PartitionRange struct {
+	Start    int64 `json:"start" yaml:"start" mapstructure:"start"`
+	End      int64 `json:"end" yaml:"end" mapstructure:"end"`
+	Interval int64 `json:"interval" yaml:"interval" mapstructure:"interval"`
 }
 
 func (m Materialization) MarshalJSON() ([]byte, error) {
-	if m.Type == "" && m.Strategy == "" && m.PartitionBy == "" && len(m.ClusterBy) == 0 && m.IncrementalKey == "" {
+	if m.Type == "" && m.Strategy == "" && m.PartitionBy == "" && len(m.ClusterBy) == 0 && m.IncrementalKey == "" && m.PartitionRange == nil &&
+		m.PartitionType == "" && m.PartitionGranularity == "" &&
+		m.PartitionExpirationDays == 0 && !m.RequirePartitionFilter && !m.NeverExpire && m.EnableRefresh == nil && m.RefreshIntervalMinutes == 0 &&
+		!m.DeduplicateOnIngest && m.DeduplicateOrderBy == "" {
 		return []byte("null"), nil
 	}
 
@@ -471,15 +525,17 @@ type UpstreamColumn struct {
 }
 
 type Column struct {
-	EntityAttribute *EntityAttribute  `json:"entity_attribute" yaml:"-" mapstructure:"-"`
-	Name            string            `json:"name" yaml:"name,omitempty" mapstructure:"name"`
-	Type            string            `json:"type" yaml:"type,omitempty" mapstructure:"type"`
-	Description     string            `json:"description" yaml:"description,omitempty" mapstructure:"description"`
-	PrimaryKey      bool              `json:"primary_key" yaml:"primary_key,omitempty" mapstructure:"primary_key"`
-	UpdateOnMerge   bool              `json:"update_on_merge" yaml:"update_on_merge,omitempty" mapstructure:"update_on_merge"`
-	Extends         string            `json:"-" yaml:"extends,omitempty" mapstructure:"extends"`
-	Checks          []ColumnCheck     `json:"checks" yaml:"checks,omitempty" mapstructure:"checks"`
-	Upstreams       []*UpstreamColumn `json:"upstreams" yaml:"-" mapstructure:"-"`
+	EntityAttribute        *EntityAttribute  `json:"entity_attribute" yaml:"-" mapstructure:"-"`
+	Name                   string            `json:"name" yaml:"name,omitempty" mapstructure:"name"`
+	Type                   string            `json:"type" yaml:"type,omitempty" mapstructure:"type"`
+	Description            string            `json:"description" yaml:"description,omitempty" mapstructure:"description"`
+	PrimaryKey             bool              `json:"primary_key" yaml:"primary_key,omitempty" mapstructure:"primary_key"`
+	UpdateOnMerge          bool              `json:"update_on_merge" yaml:"update_on_merge,omitempty" mapstructure:"update_on_merge"`
+	Collation              string            `json:"collation,omitempty" yaml:"collation,omitempty" mapstructure:"collation"`
+	DefaultValueExpression string            `json:"default_value_expression,omitempty" yaml:"default_value_expression,omitempty" mapstructure:"default_value_expression"`
+	Extends                string            `json:"-" yaml:"extends,omitempty" mapstructure:"extends"`
+	Checks                 []ColumnCheck     `json:"checks" yaml:"checks,omitempty" mapstructure:"checks"`
+	Upstreams              []*UpstreamColumn `json:"upstreams" yaml:"-" mapstructure:"-"`
 }
 
 func (c *Column) HasCheck(check string) bool {
@@ -650,6 +706,10 @@ type Asset struct {
 	Athena            AthenaConfig       `json:"athena" yaml:"athena,omitempty" mapstructure:"athena"`
 	IntervalModifiers IntervalModifiers  `json:"interval_modifiers" yaml:"interval_modifiers,omitempty" mapstructure:"interval_modifiers"`
 
+	// Labels are synced onto the table the asset materializes into, where the platform supports them. An empty
+	// but non-nil map removes the labels of the table, nil leaves them as they are.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty" mapstructure:"labels"`
+
 	upstream   []*Asset
 	downstream []*Asset
 }
@@ -198,12 +198,26 @@ func (a *clusterBy) UnmarshalYAML(value *yaml.Node) error {
 }
 
 type materialization struct {
-	Type            string    `yaml:"type"`
-	Strategy        string    `yaml:"strategy"`
-	PartitionBy     string    `yaml:"partition_by"`
-	ClusterBy       clusterBy `yaml:"cluster_by"`
-	IncrementalKey  string    `yaml:"incremental_key"`
-	TimeGranularity string    `yaml:"time_granularity,omitempty"`
+	Type            string          `yaml:"type"`
+	Strategy        string          `yaml:"strategy"`
+	PartitionBy     string          `yaml:"partition_by"`
+	ClusterBy       clusterBy       `yaml:"cluster_by"`
+	IncrementalKey  string          `yaml:"incremental_key"`
+	TimeGranularity string          `yaml:"time_granularity,omitempty"`
+	PartitionRange  *PartitionRange `yaml:"partition_range"`
+
+	PartitionType        string `yaml:"partition_type"`
+	PartitionGranularity string `yaml:"partition_granularity"`
+
+	PartitionExpirationDays int  `yaml:"partition_expiration_days"`
+	RequirePartitionFilter  bool `yaml:"require_partition_filter"`
+	NeverExpire             bool `yaml:"never_expire"`
+
+	EnableRefresh          *bool `yaml:"enable_refresh"`
+	RefreshIntervalMinutes int   `yaml:"refresh_interval_minutes"`
+
+	DeduplicateOnIngest bool   `yaml:"deduplicate_on_ingest"`
+	DeduplicateOrderBy  string `yaml:"deduplicate_order_by"`
 }
 
 type columnCheckValue struct {
@@ -266,14 +280,16 @@ type columnUpstream struct {
 }
 
 type column struct {
-	Extends       string           `yaml:"extends"`
-	Name          string           `yaml:"name"`
-	Type          string           `yaml:"type"`
-	Description   string           `yaml:"description"`
-	Tests         []columnCheck    `yaml:"checks"`
-	PrimaryKey    bool             `yaml:"primary_key"`
-	UpdateOnMerge bool             `yaml:"update_on_merge"`
-	Upstreams     []columnUpstream `yaml:"upstreams"`
+	Extends                string           `yaml:"extends"`
+	Name                   string           `yaml:"name"`
+	Type                   string           `yaml:"type"`
+	Description            string           `yaml:"description"`
+	Tests                  []columnCheck    `yaml:"checks"`
+	PrimaryKey             bool             `yaml:"primary_key"`
+	UpdateOnMerge          bool             `yaml:"update_on_merge"`
+	Collation              string           `yaml:"collation"`
+	DefaultValueExpression string           `yaml:"default_value_expression"`
+	Upstreams              []columnUpstream `yaml:"upstreams"`
 }
 
 type secretMapping struct {
@@ -319,6 +335,7 @@ type taskDefinition struct {
 	Snowflake         snowflake         `yaml:"snowflake"`
 	Athena            athena            `yaml:"athena"`
 	IntervalModifiers IntervalModifiers `yaml:"interval_modifiers"`
+	Labels            map[string]string `yaml:"labels"`
 }
 
 func CreateTaskFromYamlDefinition(fs afero.Fs) TaskCreator {
@@ -389,6 +406,20 @@ func ConvertYamlToTask(content []byte) (*Asset, error) {
 		PartitionBy:     definition.Materialization.PartitionBy,
 		IncrementalKey:  definition.Materialization.IncrementalKey,
 		TimeGranularity: MaterializationTimeGranularity(strings.ToLower(definition.Materialization.TimeGranularity)),
+		PartitionRange:  definition.Materialization.PartitionRange,
+
+		PartitionType:        PartitionType(strings.ToLower(definition.Materialization.PartitionType)),
+		PartitionGranularity: PartitionGranularity(strings.ToLower(definition.Materialization.PartitionGranularity)),
+
+		PartitionExpirationDays: definition.Materialization.PartitionExpirationDays,
+		RequirePartitionFilter:  definition.Materialization.RequirePartitionFilter,
+		NeverExpire:             definition.Materialization.NeverExpire,
+
+		EnableRefresh:          definition.Materialization.EnableRefresh,
+		RefreshIntervalMinutes: definition.Materialization.RefreshIntervalMinutes,
+
+		DeduplicateOnIngest: definition.Materialization.DeduplicateOnIngest,
+		DeduplicateOrderBy:  definition.Materialization.DeduplicateOrderBy,
 	}
 
 	columns := make([]Column, len(definition.Columns))
@@ -433,15 +464,17 @@ func ConvertYamlToTask(content []byte) (*Asset, error) {
 		}
 
 		columns[index] = Column{
-			Name:            column.Name,
-			Type:            strings.TrimSpace(column.Type),
-			Description:     column.Description,
-			Checks:          tests,
-			PrimaryKey:      column.PrimaryKey,
-			UpdateOnMerge:   column.UpdateOnMerge,
-			EntityAttribute: entityDefinition,
-			Extends:         column.Extends,
-			Upstreams:       upstreamColumns,
+			Name:                   column.Name,
+			Type:                   strings.TrimSpace(column.Type),
+			Description:            column.Description,
+			Checks:                 tests,
+			PrimaryKey:             column.PrimaryKey,
+			UpdateOnMerge:          column.UpdateOnMerge,
+			Collation:              column.Collation,
+			DefaultValueExpression: column.DefaultValueExpression,
+			EntityAttribute:        entityDefinition,
+			Extends:                column.Extends,
+			Upstreams:              upstreamColumns,
 		}
 	}
 
@@ -486,6 +519,7 @@ func ConvertYamlToTask(content []byte) (*Asset, error) {
 		Snowflake:         SnowflakeConfig{Warehouse: definition.Snowflake.Warehouse},
 		Athena:            AthenaConfig{Location: definition.Athena.QueryResultsPath},
 		IntervalModifiers: definition.IntervalModifiers,
+		Labels:            definition.Labels,
 	}
 
 	for index, check := range definition.CustomChecks {
@@ -311,3 +311,133 @@ func TestUpstreams(t *testing.T) {
 	// Compare the expected and actual results
 	require.Equal(t, expected, got)
 }
+
+func TestConvertYamlToTask_PartitionRange(t *testing.T) {
+	t.Parallel()
+
+	task, err := pipeline.ConvertYamlToTask([]byte(`
+name: dataset.events
+type: bq.sql
+materialization:
+  type: table
+  partition_by: customer_id
+  partition_range:
+    start: 0
+    end: 1000
+    interval: 10
+`))
+	require.NoError(t, err)
+	require.Equal(t, "customer_id", task.Materialization.PartitionBy)
+	require.Equal(t, &pipeline.PartitionRange{Start: 0, End: 1000, Interval: 10}, task.Materialization.PartitionRange)
+}
+
+func TestConvertYamlToTask_PartitionOptions(t *testing.T) {
+	t.Parallel()
+
+	task, err := pipeline.ConvertYamlToTask([]byte(`
+name: dataset.events
+type: bq.sql
+materialization:
+  type: table
+  partition_by: created_at
+  partition_expiration_days: 90
+  require_partition_filter: true
+  never_expire: true
+`))
+	require.NoError(t, err)
+	require.Equal(t, 90, task.Materialization.PartitionExpirationDays)
+	require.True(t, task.Materialization.RequirePartitionFilter)
+	require.True(t, task.Materialization.NeverExpire)
+}
+
+func TestConvertYamlToTask_MaterializedView(t *testing.T) {
+	t.Parallel()
+
+	task, err := pipeline.ConvertYamlToTask([]byte(`
+name: dataset.daily_revenue
+type: bq.sql
+materialization:
+  type: materialized_view
+  enable_refresh: true
+  refresh_interval_minutes: 30
+`))
+	require.NoError(t, err)
+	require.Equal(t, pipeline.MaterializationTypeMaterializedView, task.Materialization.Type)
+	require.NotNil(t, task.Materialization.EnableRefresh)
+	require.True(t, *task.Materialization.EnableRefresh)
+	require.Equal(t, 30, task.Materialization.RefreshIntervalMinutes)
+}
+
+func TestConvertYamlToTask_PartitionGranularity(t *testing.T) {
+	t.Parallel()
+
+	task, err := pipeline.ConvertYamlToTask([]byte(`
+name: dataset.events
+type: bq.sql
+materialization:
+  type: table
+  partition_by: created_at
+  partition_type: TIME
+  partition_granularity: Month
+`))
+	require.NoError(t, err)
+	require.Equal(t, pipeline.PartitionTypeTime, task.Materialization.PartitionType)
+	require.Equal(t, pipeline.PartitionGranularityMonth, task.Materialization.PartitionGranularity)
+}
+
+func TestConvertYamlToTask_Deduplication(t *testing.T) {
+	t.Parallel()
+
+	task, err := pipeline.ConvertYamlToTask([]byte(`
+name: dataset.users
+type: bq.sql
+materialization:
+  type: table
+  deduplicate_on_ingest: true
+  deduplicate_order_by: updated_at
+`))
+	require.NoError(t, err)
+	require.True(t, task.Materialization.DeduplicateOnIngest)
+	require.Equal(t, "updated_at", task.Materialization.DeduplicateOrderBy)
+}
+
+func TestConvertYamlToTask_ColumnSettings(t *testing.T) {
+	t.Parallel()
+
+	task, err := pipeline.ConvertYamlToTask([]byte(`
+name: dataset.users
+type: bq.sql
+columns:
+  - name: email
+    type: STRING
+    collation: und:ci
+    default_value_expression: "'unknown'"
+`))
+	require.NoError(t, err)
+	require.Len(t, task.Columns, 1)
+	require.Equal(t, "und:ci", task.Columns[0].Collation)
+	require.Equal(t, "'unknown'", task.Columns[0].DefaultValueExpression)
+}
+
+func TestConvertYamlToTask_Labels(t *testing.T) {
+	t.Parallel()
+
+	task, err := pipeline.ConvertYamlToTask([]byte(`
+name: dataset.events
+type: bq.sql
+labels:
+  team: growth
+  cost_center: marketing
+`))
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"team": "growth", "cost_center": "marketing"}, task.Labels)
+
+	task, err = pipeline.ConvertYamlToTask([]byte(`
+name: dataset.events
+type: bq.sql
+labels: {}
+`))
+	require.NoError(t, err)
+	require.NotNil(t, task.Labels)
+	require.Empty(t, task.Labels)
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import "strings"
+
+// canonicalTypes maps the Postgres type names to the cross-platform type vocabulary shared with the other
+// platforms, see query.QueryResult.CanonicalColumnTypes.
+var canonicalTypes = map[string]string{
+	"int2":        "int64",
+	"int4":        "int64",
+	"int8":        "int64",
+	"float4":      "float64",
+	"float8":      "float64",
+	"numeric":     "numeric",
+	"text":        "string",
+	"varchar":     "string",
+	"bpchar":      "string",
+	"name":        "string",
+	"bool":        "bool",
+	"bytea":       "bytes",
+	"date":        "date",
+	"time":        "time",
+	"timestamp":   "datetime",
+	"timestamptz": "timestamp",
+	"interval":    "interval",
+	"json":        "json",
+	"jsonb":       "json",
+}
+
+// canonicalType returns the cross-platform type of the Postgres type, e.g. `int64` for `int8` or `array<string>`
+// for `_text`, the name of array types. Unknown types are returned as they are.
+func canonicalType(typeName string) string {
+	if element, ok := strings.CutPrefix(typeName, "_"); ok && element != "" {
+		return "array<" + canonicalType(element) + ">"
+	}
+	if typ, ok := canonicalTypes[typeName]; ok {
+		return typ
+	}
+
+	return typeName
+}
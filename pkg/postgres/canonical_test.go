@@ -0,0 +1,27 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalType(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"int4":        "int64",
+		"float8":      "float64",
+		"bpchar":      "string",
+		"timestamp":   "datetime",
+		"timestamptz": "timestamp",
+		"jsonb":       "json",
+		"_text":       "array<string>",
+		"uuid":        "uuid",
+		"":            "",
+	}
+
+	for typeName, want := range tests {
+		assert.Equal(t, want, canonicalType(typeName), typeName)
+	}
+}
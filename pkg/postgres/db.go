@@ -91,6 +91,7 @@ func (c *Client) SelectWithSchema(ctx context.Context, queryObj *query.Query) (*
 	// Extract column names
 	columns := make([]string, len(fieldDescriptions))
 	columnTypes := make([]string, len(fieldDescriptions))
+	canonicalColumnTypes := make([]string, len(fieldDescriptions))
 	for i, field := range fieldDescriptions {
 		columns[i] = field.Name
 		dataType, ok := typeMap.TypeForOID(field.DataTypeOID)
@@ -99,6 +100,7 @@ func (c *Client) SelectWithSchema(ctx context.Context, queryObj *query.Query) (*
 		} else {
 			columnTypes[i] = dataType.Name
 		}
+		canonicalColumnTypes[i] = canonicalType(columnTypes[i])
 	}
 
 	// Collect rows
@@ -109,9 +111,10 @@ func (c *Client) SelectWithSchema(ctx context.Context, queryObj *query.Query) (*
 		return nil, errors.Wrap(err, "failed to collect row values")
 	}
 	result := &query.QueryResult{
-		Columns:     columns,
-		Rows:        collectedRows,
-		ColumnTypes: columnTypes,
+		Columns:              columns,
+		Rows:                 collectedRows,
+		ColumnTypes:          columnTypes,
+		CanonicalColumnTypes: canonicalColumnTypes,
 	}
 	return result, nil
 }
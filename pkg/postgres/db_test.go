@@ -135,7 +135,8 @@ func TestClient_SelectWithSchema(t *testing.T) {
 					{1, "John Doe"},
 					{2, "Jane Doe"},
 				},
-				ColumnTypes: []string{"int8", "varchar"},
+				ColumnTypes:          []string{"int8", "varchar"},
+				CanonicalColumnTypes: []string{"int64", "string"},
 			},
 			setupMock: func(mock pgxmock.PgxPoolIface) {
 				rows := pgxmock.NewRowsWithColumnDefinition(
@@ -149,9 +150,10 @@ func TestClient_SelectWithSchema(t *testing.T) {
 			name:  "test select empty rows with schema",
 			query: "SELECT * FROM table",
 			expected: &query.QueryResult{
-				Columns:     []string{"id", "name"},
-				Rows:        [][]interface{}{},
-				ColumnTypes: []string{"int8", "varchar"},
+				Columns:              []string{"id", "name"},
+				Rows:                 [][]interface{}{},
+				ColumnTypes:          []string{"int8", "varchar"},
+				CanonicalColumnTypes: []string{"int64", "string"},
 			},
 			setupMock: func(mock pgxmock.PgxPoolIface) {
 				rows := pgxmock.NewRowsWithColumnDefinition(
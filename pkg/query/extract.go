@@ -14,12 +14,41 @@ import (
 type Query struct {
 	VariableDefinitions []string
 	Query               string
+
+	// MaxBytesBilled overrides the maximum bytes billed limit of the connection for this query, on the platforms
+	// that support it. nil keeps the connection default, zero removes the limit.
+	MaxBytesBilled *int64
+
+	// Priority overrides the job priority of the connection for this query, on the platforms that support it,
+	// e.g. "batch" or "interactive" in BigQuery. Empty keeps the connection default.
+	Priority string
+
+	// Parameters are bound to the `@name` or `?` placeholders of the query on the platforms that support
+	// parameterized queries, e.g. BigQuery, so that values never have to be interpolated into the SQL.
+	Parameters []Parameter
+}
+
+// Parameter is a value bound to a placeholder of a query. Named parameters are bound to the `@name` placeholders,
+// the ones without a name to the positional `?` placeholders in the order they are given. The platforms convert the
+// value to their own parameter types, e.g. a civil.Date becomes a DATE parameter in BigQuery.
+type Parameter struct {
+	Name  string
+	Value interface{}
 }
 
 type QueryResult struct {
 	Columns     []string
 	Rows        [][]interface{}
 	ColumnTypes []string
+
+	// ColumnModes holds the mode of each column for platforms that expose it, e.g. NULLABLE, REQUIRED or
+	// REPEATED in BigQuery. It is empty for the platforms that do not have a concept of column modes.
+	ColumnModes []string
+
+	// CanonicalColumnTypes holds the type of each column in a vocabulary shared across platforms, e.g. int64,
+	// float64 or array<struct<id int64>>, while ColumnTypes keeps the platform-specific types. It is empty for
+	// the platforms that do not provide it.
+	CanonicalColumnTypes []string
 }
 
 type QueryExtractor interface {
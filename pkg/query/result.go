@@ -0,0 +1,135 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/civil"
+)
+
+// NullPolicy decides how the typed column helpers of QueryResult treat NULL values, it is given with each of them.
+type NullPolicy int
+
+const (
+	// NullPolicyError fails the extraction when the column contains a NULL value.
+	NullPolicyError NullPolicy = iota
+	// NullPolicyZero replaces NULL values with the zero value of the column type.
+	NullPolicyZero
+)
+
+// The column types accepted by the typed column helpers, both in the canonical vocabulary and in the
+// platform-specific type names.
+var (
+	int64ColumnTypes = map[string]bool{
+		"int64": true, "integer": true, "int": true, "bigint": true, "smallint": true, "tinyint": true,
+		"int2": true, "int4": true, "int8": true, "int16": true, "int32": true,
+	}
+	stringColumnTypes = map[string]bool{
+		"string": true, "varchar": true, "text": true, "char": true, "bpchar": true, "nvarchar": true, "character varying": true,
+	}
+	timeColumnTypes = map[string]bool{
+		"timestamp": true, "datetime": true, "date": true, "timestamptz": true,
+		"timestamp_ntz": true, "timestamp_ltz": true, "timestamp_tz": true, "datetime64": true,
+	}
+)
+
+// Int64Column returns the values of the given integer column.
+func (r *QueryResult) Int64Column(name string, policy NullPolicy) ([]int64, error) {
+	return extractColumn(r, name, policy, "int64", int64ColumnTypes, func(v interface{}) (int64, bool) {
+		switch n := v.(type) {
+		case int64:
+			return n, true
+		case int:
+			return int64(n), true
+		case int32:
+			return int64(n), true
+		case int16:
+			return int64(n), true
+		case int8:
+			return int64(n), true
+		default:
+			return 0, false
+		}
+	})
+}
+
+// StringColumn returns the values of the given string column.
+func (r *QueryResult) StringColumn(name string, policy NullPolicy) ([]string, error) {
+	return extractColumn(r, name, policy, "string", stringColumnTypes, func(v interface{}) (string, bool) {
+		s, ok := v.(string)
+		return s, ok
+	})
+}
+
+// TimeColumn returns the values of the given date or time column. Values without a time zone, such as DATETIME
+// or DATE values in BigQuery, are returned in UTC.
+func (r *QueryResult) TimeColumn(name string, policy NullPolicy) ([]time.Time, error) {
+	return extractColumn(r, name, policy, "time", timeColumnTypes, func(v interface{}) (time.Time, bool) {
+		switch t := v.(type) {
+		case time.Time:
+			return t, true
+		case civil.DateTime:
+			return t.In(time.UTC), true
+		case civil.Date:
+			return t.In(time.UTC), true
+		default:
+			return time.Time{}, false
+		}
+	})
+}
+
+// columnType returns the type of the column at the given index, preferring the canonical type if there is one.
+func (r *QueryResult) columnType(i int) string {
+	if len(r.CanonicalColumnTypes) == len(r.Columns) {
+		return r.CanonicalColumnTypes[i]
+	}
+	if len(r.ColumnTypes) == len(r.Columns) {
+		return r.ColumnTypes[i]
+	}
+
+	return ""
+}
+
+func extractColumn[T any](r *QueryResult, name string, policy NullPolicy, kind string, allowedTypes map[string]bool, convert func(v interface{}) (T, bool)) ([]T, error) {
+	index := -1
+	for i, column := range r.Columns {
+		if column == name {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return nil, fmt.Errorf("column '%s' does not exist in the result", name)
+	}
+
+	columnType := r.columnType(index)
+	if !allowedTypes[strings.ToLower(columnType)] {
+		return nil, fmt.Errorf("column '%s' has type '%s', which cannot be read as %s", name, columnType, kind)
+	}
+
+	values := make([]T, 0, len(r.Rows))
+	for i, row := range r.Rows {
+		if index >= len(row) {
+			return nil, fmt.Errorf("row %d has no value for column '%s'", i, name)
+		}
+
+		v := row[index]
+		if v == nil {
+			if policy != NullPolicyZero {
+				return nil, fmt.Errorf("column '%s' has a NULL value in row %d", name, i)
+			}
+			var zero T
+			values = append(values, zero)
+			continue
+		}
+
+		converted, ok := convert(v)
+		if !ok {
+			return nil, fmt.Errorf("column '%s' has a value of type %T in row %d, which cannot be read as %s", name, v, i, kind)
+		}
+		values = append(values, converted)
+	}
+
+	return values, nil
+}
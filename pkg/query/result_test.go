@@ -0,0 +1,77 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryResult_TypedColumns(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	result := &QueryResult{
+		Columns:     []string{"id", "name", "created_at", "local_time", "score"},
+		ColumnTypes: []string{"INTEGER", "STRING", "TIMESTAMP", "DATETIME", "FLOAT"},
+		Rows: [][]interface{}{
+			{int64(1), "jane", created, civil.DateTimeOf(created), 1.5},
+			{int64(2), "john", created, civil.DateTimeOf(created), 2.5},
+		},
+	}
+
+	ids, err := result.Int64Column("id", NullPolicyError)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids)
+
+	names, err := result.StringColumn("name", NullPolicyError)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jane", "john"}, names)
+
+	times, err := result.TimeColumn("created_at", NullPolicyError)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{created, created}, times)
+
+	localTimes, err := result.TimeColumn("local_time", NullPolicyError)
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{created, created}, localTimes)
+
+	_, err = result.Int64Column("score", NullPolicyError)
+	require.EqualError(t, err, "column 'score' has type 'FLOAT', which cannot be read as int64")
+
+	_, err = result.StringColumn("missing", NullPolicyError)
+	require.EqualError(t, err, "column 'missing' does not exist in the result")
+}
+
+func TestQueryResult_TypedColumns_Nulls(t *testing.T) {
+	t.Parallel()
+
+	result := &QueryResult{
+		Columns:              []string{"id"},
+		ColumnTypes:          []string{"INTEGER"},
+		CanonicalColumnTypes: []string{"int64"},
+		Rows:                 [][]interface{}{{int64(1)}, {nil}},
+	}
+
+	_, err := result.Int64Column("id", NullPolicyError)
+	require.EqualError(t, err, "column 'id' has a NULL value in row 1")
+
+	ids, err := result.Int64Column("id", NullPolicyZero)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 0}, ids)
+}
+
+func TestQueryResult_TypedColumns_UnexpectedValue(t *testing.T) {
+	t.Parallel()
+
+	result := &QueryResult{
+		Columns:     []string{"id"},
+		ColumnTypes: []string{"INT8"},
+		Rows:        [][]interface{}{{"1"}},
+	}
+
+	_, err := result.Int64Column("id", NullPolicyError)
+	require.EqualError(t, err, "column 'id' has a value of type string in row 0, which cannot be read as int64")
+}
@@ -0,0 +1,233 @@
+package query
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// numericScale is the number of decimal digits kept when formatting exact decimal values, the scale of BIGNUMERIC
+// values in BigQuery, which is the largest of the supported platforms.
+const numericScale = 38
+
+// numericColumnTypePrefixes are the prefixes of the exact decimal column types, which may carry a precision and
+// scale such as `NUMERIC(10, 2)`.
+var numericColumnTypePrefixes = []string{"numeric", "bignumeric", "decimal", "bigdecimal", "number"}
+
+// WriteCSV writes the result as CSV with a header row of the column names. NULL values are written as empty fields,
+// times as RFC3339 and nested values as JSON.
+func (r *QueryResult) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(r.Columns); err != nil {
+		return errors.Wrap(err, "failed to write the CSV header")
+	}
+
+	record := make([]string, len(r.Columns))
+	for i, row := range r.Rows {
+		if len(row) != len(r.Columns) {
+			return fmt.Errorf("row %d has %d values, expected %d", i, len(row), len(r.Columns))
+		}
+
+		for j, value := range row {
+			formatted, err := r.formatValue(j, value)
+			if err != nil {
+				return errors.Wrapf(err, "failed to format column '%s' in row %d", r.Columns[j], i)
+			}
+			record[j] = formatted
+		}
+		if err := writer.Write(record); err != nil {
+			return errors.Wrapf(err, "failed to write row %d", i)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteJSON writes the result as a JSON array with an object per row, whose keys are the column names in the order
+// of the columns. NULL values are written as null, times as RFC3339 strings and exact decimal values such as NUMERIC
+// as unquoted numbers, without losing precision.
+func (r *QueryResult) WriteJSON(w io.Writer) error {
+	keys := make([][]byte, len(r.Columns))
+	for i, column := range r.Columns {
+		key, err := json.Marshal(column)
+		if err != nil {
+			return errors.Wrapf(err, "failed to encode the column name '%s'", column)
+		}
+		keys[i] = key
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("[")
+	for i, row := range r.Rows {
+		if len(row) != len(r.Columns) {
+			return fmt.Errorf("row %d has %d values, expected %d", i, len(row), len(r.Columns))
+		}
+
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n  {")
+		for j, value := range row {
+			encoded, err := r.marshalValue(j, value)
+			if err != nil {
+				return errors.Wrapf(err, "failed to encode column '%s' in row %d", r.Columns[j], i)
+			}
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			buf.Write(keys[j])
+			buf.WriteString(": ")
+			buf.Write(encoded)
+		}
+		buf.WriteString("}")
+	}
+	if len(r.Rows) > 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// formatValue formats the value of the column at the given index as text, see FormatValue.
+func (r *QueryResult) formatValue(column int, value interface{}) (string, error) {
+	if t, ok := value.(time.Time); ok {
+		return r.formatTime(column, t), nil
+	}
+
+	return FormatValue(value)
+}
+
+// FormatValue formats a value the way it is written to text formats such as CSV: NULL as an empty string, times
+// as RFC3339 in UTC, exact decimal values without trailing zeros and nested values as JSON. Repeated values that
+// were read as nil slices are written as `[]`, only the value itself being nil stands for NULL.
+func FormatValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case *big.Rat:
+		return FormatDecimal(v), nil
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano), nil
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+
+	if isNilSlice(value) {
+		return "[]", nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to encode the value of type %T", value)
+	}
+	return string(encoded), nil
+}
+
+// isNilSlice reports whether the value is a nil slice, which is how empty repeated values are read.
+func isNilSlice(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Slice && v.IsNil()
+}
+
+// marshalValue encodes the value of the column at the given index as JSON.
+func (r *QueryResult) marshalValue(column int, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return []byte("null"), nil
+	case *big.Rat:
+		return []byte(FormatDecimal(v)), nil
+	case time.Time:
+		return json.Marshal(r.formatTime(column, v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			// JSON has no representation for them
+			return json.Marshal(strconv.FormatFloat(v, 'g', -1, 64))
+		}
+	case string:
+		// some drivers return exact decimal values as strings
+		if r.isNumericColumn(column) && isJSONNumber(v) {
+			return []byte(v), nil
+		}
+	}
+
+	if isNilSlice(value) {
+		return []byte("[]"), nil
+	}
+
+	if stringer, ok := value.(fmt.Stringer); ok {
+		if _, isMarshaler := value.(json.Marshaler); !isMarshaler {
+			return json.Marshal(stringer.String())
+		}
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to encode the value of type %T", value)
+	}
+
+	return encoded, nil
+}
+
+// formatTime formats the time as RFC3339 in UTC, or as a plain date for the values of DATE columns.
+func (r *QueryResult) formatTime(column int, t time.Time) string {
+	if strings.EqualFold(r.columnType(column), "date") {
+		return t.Format(time.DateOnly)
+	}
+
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func (r *QueryResult) isNumericColumn(column int) bool {
+	columnType := strings.ToLower(r.columnType(column))
+	for _, prefix := range numericColumnTypePrefixes {
+		if strings.HasPrefix(columnType, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isJSONNumber(s string) bool {
+	var number json.Number
+	return s != "" && json.Unmarshal([]byte(s), &number) == nil
+}
+
+// FormatDecimal formats an exact decimal value without trailing zeros, e.g. `1.5` rather than `1.500000000`.
+func FormatDecimal(v *big.Rat) string {
+	if v.IsInt() {
+		return v.Num().String()
+	}
+
+	return strings.TrimRight(strings.TrimRight(v.FloatString(numericScale), "0"), ".")
+}
@@ -0,0 +1,88 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryResult_Write(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	result := &QueryResult{
+		Columns:     []string{"id", "name", "created_at", "day", "local_time", "amount", "price", "tags"},
+		ColumnTypes: []string{"INTEGER", "STRING", "TIMESTAMP", "DATE", "DATETIME", "NUMERIC", "numeric(10, 2)", "ARRAY<STRING>"},
+		Rows: [][]interface{}{
+			{int64(1), "jane, \"j\"", created, created, civil.DateTimeOf(created), big.NewRat(3, 2), "12.30", []interface{}{"a", "b"}},
+			{int64(2), nil, nil, nil, nil, nil, nil, nil},
+		},
+	}
+
+	var csvOut bytes.Buffer
+	require.NoError(t, result.WriteCSV(&csvOut))
+	assert.Equal(t, "id,name,created_at,day,local_time,amount,price,tags\n"+
+		"1,\"jane, \"\"j\"\"\",2024-01-02T03:04:05.6Z,2024-01-02,2024-01-02T03:04:05.600000000,1.5,12.30,\"[\"\"a\"\",\"\"b\"\"]\"\n"+
+		"2,,,,,,,\n", csvOut.String())
+
+	var jsonOut bytes.Buffer
+	require.NoError(t, result.WriteJSON(&jsonOut))
+	assert.Equal(t, "[\n"+
+		"  {\"id\": 1, \"name\": \"jane, \\\"j\\\"\", \"created_at\": \"2024-01-02T03:04:05.6Z\", \"day\": \"2024-01-02\", \"local_time\": \"2024-01-02T03:04:05.600000000\", \"amount\": 1.5, \"price\": 12.30, \"tags\": [\"a\",\"b\"]},\n"+
+		"  {\"id\": 2, \"name\": null, \"created_at\": null, \"day\": null, \"local_time\": null, \"amount\": null, \"price\": null, \"tags\": null}\n"+
+		"]\n", jsonOut.String())
+	assert.True(t, json.Valid(jsonOut.Bytes()))
+
+	empty := &QueryResult{Columns: []string{"id"}}
+	jsonOut.Reset()
+	require.NoError(t, empty.WriteJSON(&jsonOut))
+	assert.Equal(t, "[]\n", jsonOut.String())
+
+	invalid := &QueryResult{Columns: []string{"id", "name"}, Rows: [][]interface{}{{int64(1)}}}
+	require.EqualError(t, invalid.WriteCSV(&csvOut), "row 0 has 1 values, expected 2")
+	require.EqualError(t, invalid.WriteJSON(&jsonOut), "row 0 has 1 values, expected 2")
+}
+
+func TestFormatValue(t *testing.T) {
+	t.Parallel()
+
+	berlin := time.FixedZone("CET", 3600)
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "null", value: nil, want: ""},
+		{name: "time in another zone", value: time.Date(2024, 1, 2, 4, 4, 5, 0, berlin), want: "2024-01-02T03:04:05Z"},
+		{name: "decimal", value: big.NewRat(5, 4), want: "1.25"},
+		{name: "whole decimal", value: big.NewRat(10, 1), want: "10"},
+		{name: "empty repeated value", value: []string(nil), want: "[]"},
+		{name: "repeated value", value: []interface{}{int64(1), "a"}, want: "[1,\"a\"]"},
+		{name: "record", value: map[string]interface{}{"city": "Berlin"}, want: "{\"city\":\"Berlin\"}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := FormatValue(tt.value)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// the JSON output agrees on empty repeated values and times
+	result := &QueryResult{
+		Columns: []string{"tags", "created_at"},
+		Rows:    [][]interface{}{{[]string(nil), time.Date(2024, 1, 2, 4, 4, 5, 0, berlin)}},
+	}
+	var jsonOut bytes.Buffer
+	require.NoError(t, result.WriteJSON(&jsonOut))
+	assert.Equal(t, "[\n  {\"tags\": [], \"created_at\": \"2024-01-02T03:04:05Z\"}\n]\n", jsonOut.String())
+}
@@ -0,0 +1,42 @@
+package snowflake
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// canonicalTypes maps the Snowflake type names reported by the driver to the cross-platform type vocabulary shared
+// with the other platforms, see query.QueryResult.CanonicalColumnTypes. NUMBER columns are reported as FIXED and
+// handled by canonicalType.
+var canonicalTypes = map[string]string{
+	"REAL":          "float64",
+	"TEXT":          "string",
+	"BOOLEAN":       "bool",
+	"BINARY":        "bytes",
+	"DATE":          "date",
+	"TIME":          "time",
+	"TIMESTAMP_NTZ": "datetime",
+	"TIMESTAMP_LTZ": "timestamp",
+	"TIMESTAMP_TZ":  "timestamp",
+	"VARIANT":       "json",
+	"OBJECT":        "json",
+	"ARRAY":         "array<json>",
+	"GEOGRAPHY":     "geography",
+}
+
+// canonicalType returns the cross-platform type of the column, e.g. `int64` for a NUMBER column without decimals
+// and `numeric` for one with. Unknown types are lowercased as they are.
+func canonicalType(column *sql.ColumnType) string {
+	typeName := strings.ToUpper(column.DatabaseTypeName())
+	if typeName == "FIXED" {
+		if _, scale, ok := column.DecimalSize(); ok && scale == 0 {
+			return "int64"
+		}
+		return "numeric"
+	}
+	if typ, ok := canonicalTypes[typeName]; ok {
+		return typ
+	}
+
+	return strings.ToLower(typeName)
+}
@@ -182,10 +182,13 @@ func (db *DB) SelectWithSchema(ctx context.Context, queryObj *query.Query) (*que
 		return nil, errors.Wrap(err, "failed to retrieve column types")
 	}
 	typeStrings := make([]string, len(columnTypes))
+	canonicalTypeStrings := make([]string, len(columnTypes))
 	for i, ct := range columnTypes {
 		typeStrings[i] = ct.DatabaseTypeName()
+		canonicalTypeStrings[i] = canonicalType(ct)
 	}
 	result.ColumnTypes = typeStrings
+	result.CanonicalColumnTypes = canonicalTypeStrings
 	for rows.Next() {
 		row := make([]interface{}, len(cols))
 		columnPointers := make([]interface{}, len(cols))
@@ -267,7 +267,29 @@ func TestDB_SelectWithSchema(t *testing.T) {
 					{"jane", "doe", int64(30)},
 					{"joe", "doe", int64(28)},
 				},
-				ColumnTypes: []string{"", "", ""},
+				ColumnTypes:          []string{"", "", ""},
+				CanonicalColumnTypes: []string{"", "", ""},
+			},
+		},
+		{
+			name: "column types are mapped to canonical types",
+			mockConnection: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT name, age, price, created_at FROM users`).
+					WillReturnRows(sqlmock.NewRowsWithColumnDefinition(
+						sqlmock.NewColumn("name").OfType("TEXT", ""),
+						sqlmock.NewColumn("age").OfType("FIXED", int64(0)).WithPrecisionAndScale(38, 0),
+						sqlmock.NewColumn("price").OfType("FIXED", "").WithPrecisionAndScale(10, 2),
+						sqlmock.NewColumn("created_at").OfType("TIMESTAMP_NTZ", ""),
+					).AddRow("jane", 30, "12.50", "2024-01-01 00:00:00"))
+			},
+			query: query.Query{
+				Query: "SELECT name, age, price, created_at FROM users",
+			},
+			want: &query.QueryResult{
+				Columns:              []string{"name", "age", "price", "created_at"},
+				Rows:                 [][]interface{}{{"jane", int64(30), "12.50", "2024-01-01 00:00:00"}},
+				ColumnTypes:          []string{"TEXT", "FIXED", "FIXED", "TIMESTAMP_NTZ"},
+				CanonicalColumnTypes: []string{"string", "int64", "numeric", "datetime"},
 			},
 		},
 		{